@@ -0,0 +1,94 @@
+/*
+Copyright © 2025 Jakub Scholz
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// commandLine returns the command line equivalent to the one the command was started with, but with
+// the given cluster and listener names. It contains only the flags set by the user, so that the
+// defaults apply the same way.
+func commandLine(cmd *cobra.Command, clusterNames []string, listenerNames []string) string {
+	args := []string{cmd.CommandPath()}
+
+	cmd.Flags().Visit(func(f *pflag.Flag) {
+		if f.Name != "cluster-name" && f.Name != "listener-name" {
+			args = append(args, flagArgs(f)...)
+		}
+	})
+
+	for _, clusterName := range clusterNames {
+		args = append(args, "--cluster-name", shellQuote(clusterName))
+	}
+	for _, listenerName := range listenerNames {
+		args = append(args, "--listener-name", shellQuote(listenerName))
+	}
+
+	return strings.Join(args, " ")
+}
+
+// flagArgs returns the arguments setting the flag to its current value.
+func flagArgs(f *pflag.Flag) []string {
+	name := "--" + f.Name
+
+	if slice, ok := f.Value.(pflag.SliceValue); ok {
+		values := slice.GetSlice()
+		if len(values) == 0 {
+			return []string{name + "="}
+		}
+
+		args := make([]string, 0, 2*len(values))
+		for _, value := range values {
+			args = append(args, name, shellQuote(value))
+		}
+
+		return args
+	}
+
+	switch f.Value.Type() {
+	case "bool":
+		if f.Value.String() == "true" {
+			return []string{name}
+		}
+
+		return []string{name + "=false"}
+	case "count":
+		// The count flags are repeated, e.g. -vv
+		if count, err := strconv.Atoi(f.Value.String()); err == nil && count > 0 && f.Shorthand != "" {
+			return []string{"-" + strings.Repeat(f.Shorthand, count)}
+		}
+
+		return []string{name + "=" + f.Value.String()}
+	default:
+		return []string{name, shellQuote(f.Value.String())}
+	}
+}
+
+func shellQuote(value string) string {
+	if value != "" && strings.IndexFunc(value, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("-_./:=@", r))
+	}) < 0 {
+		return value
+	}
+
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
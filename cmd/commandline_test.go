@@ -0,0 +1,24 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommandLine(t *testing.T) {
+	cmd := &cobra.Command{Use: "kekspose"}
+	addExposeFlags(cmd)
+
+	require.NoError(t, cmd.ParseFlags([]string{
+		"--kubeconfig", "/tmp/my config", "-n", "kafka", "-c", "my-cluster", "-l", "plain,tls",
+		"-vv", "--log-api", "Metadata,Produce", "--log-topic", "orders-*", "--redact=",
+		"--allow-insecure-tls", "--lazy-connect=false", "--drain-timeout", "30s", "--pod-port", "9094",
+	}))
+
+	assert.Equal(t, "kekspose --allow-insecure-tls --drain-timeout 30s --kubeconfig '/tmp/my config' --lazy-connect=false "+
+		"--log-api Metadata --log-api Produce --log-topic 'orders-*' --namespace kafka --pod-port 9094 --redact= -vv "+
+		"--cluster-name other-cluster --listener-name plain", commandLine(cmd, []string{"other-cluster"}, []string{"plain"}))
+}
@@ -0,0 +1,67 @@
+/*
+Copyright © 2025 Jakub Scholz
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"os"
+
+	"github.com/scholzj/kekspose/pkg/kekspose"
+	"github.com/spf13/cobra"
+)
+
+// doctorCmd represents the doctor command
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Checks whether Keksposé can expose the Kafka cluster",
+	Long: `Runs preflight diagnostics for everything Keksposé needs to expose the Kafka cluster and reports the result of each check:
+the kubeconfig and context, the namespace, the access rights, the Kafka cluster readiness, the listener, the broker pods,
+the forwarded HTTP APIs, and the local ports.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := validateTargetFlags(cmd); err != nil {
+			return err
+		}
+
+		kekspose := kekspose.Kekspose{
+			KubeConfigPath:         kubeconfigpath,
+			Context:                contextName,
+			Namespace:              namespace,
+			ClusterNames:           clusterNames,
+			ListenerNames:          listenerNames,
+			StartingPort:           startingPort,
+			AllowUnready:           allowUnready,
+			AllowInsecureTLS:       allowInsecureTLS,
+			KafkaConnect:           kafkaConnect,
+			KafkaBridge:            kafkaBridge,
+			CruiseControl:          cruiseControl || cruiseControlAuth,
+			PodSelector:            podSelector,
+			PodPort:                podPort,
+			PodTLS:                 podTLS,
+			NodeIdRule:             nodeIdRule,
+			CruiseControlAuth:      cruiseControlAuth,
+			SchemaRegistrySelector: schemaRegistrySelector,
+			SchemaRegistryPort:     schemaRegistryPort,
+		}
+
+		return kekspose.Doctor(os.Stdout)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(doctorCmd)
+	addKafkaFlags(doctorCmd)
+	addTargetFlags(doctorCmd)
+}
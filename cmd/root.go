@@ -20,24 +20,62 @@ import (
 	"fmt"
 	"log/slog"
 	"os"
-	"strings"
+	"time"
 
-	"github.com/scholzj/go-kafka-protocol/messages"
 	"github.com/scholzj/kekspose/pkg/kekspose"
+	"github.com/scholzj/kekspose/pkg/kekspose/keks"
+	"github.com/scholzj/kekspose/pkg/kekspose/proxiedforward"
+	"github.com/scholzj/kekspose/pkg/kekspose/records"
 	"github.com/spf13/cobra"
+	"golang.org/x/term"
 )
 
 var kubeconfigpath string
 var contextName string
 var namespace string
-var clusterName string
-var listenerName string
+var clusterNames []string
+var listenerNames []string
 var startingPort uint32
 var allowUnready bool
 var allowInsecureTLS bool
+var autoPort bool
+var kafkaConnect bool
+var kafkaBridge bool
+var cruiseControl bool
+var cruiseControlAuth bool
+var podSelector string
+var podPort uint32
+var podTLS bool
+var nodeIdRule string
+var discoverNodes bool
+var waitTimeout time.Duration
+var partialStart bool
+var lazyConnect bool
+var idleTimeout time.Duration
+var transport string
+var tlsHandshakeTimeout time.Duration
+var connectionIdleTimeout time.Duration
+var readTimeout time.Duration
+var drainTimeout time.Duration
 var verbose int
 var logApis []string
 var traceApis []string
+var logClientIds []string
+var logTopics []string
+var logGroups []string
+var logRecords []string
+var recordFormat string
+var recordMaxBytes int
+var redact []string
+var logErrors bool
+var monitorGroups bool
+var schemaRegistryURL string
+var schemaRegistrySelector string
+var schemaRegistryPort uint32
+var sessionName string
+var sessionDir string
+var adminAddress string
+var adminTokenFile string
 
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
@@ -46,76 +84,147 @@ var rootCmd = &cobra.Command{
 	Long:          `Expose your Kafka cluster outside your Minikube, Kind, or Docker Desktop clusters`,
 	SilenceErrors: true,
 	SilenceUsage:  true,
-	RunE: func(cmd *cobra.Command, args []string) error {
-		// Configure the logging
-		if verbose <= 0 {
-			slog.SetLogLoggerLevel(slog.LevelInfo)
-		} else if verbose == 1 {
-			slog.SetLogLoggerLevel(slog.LevelDebug)
-		} else {
-			slog.SetLogLoggerLevel(slog.Level(-10))
-		}
+	RunE:          runExpose,
+}
 
-		logKeys, err := resolveAPIKeys(logApis)
-		if err != nil {
-			return fmt.Errorf("invalid --log-api: %w", err)
-		}
-		bodyKeys, err := resolveAPIKeys(traceApis)
-		if err != nil {
-			return fmt.Errorf("invalid --trace-api: %w", err)
-		}
+// exposeKafka runs the configured Keksposé. It is replaced in the tests, which do not have any Kafka
+// cluster to expose.
+var exposeKafka = func(k *kekspose.Kekspose) error {
+	return k.ExposeKafka()
+}
 
-		kekspose := kekspose.Kekspose{
-			KubeConfigPath:   kubeconfigpath,
-			Context:          contextName,
-			Namespace:        namespace,
-			ClusterName:      clusterName,
-			ListenerName:     listenerName,
-			StartingPort:     startingPort,
-			AllowUnready:     allowUnready,
-			AllowInsecureTLS: allowInsecureTLS,
-			LogAPIKeys:       logKeys,
-			BodyAPIKeys:      bodyKeys,
-		}
+// runExpose exposes the Kafka cluster and forwards the ports until it is stopped. It is used by the root
+// command and by the start command.
+func runExpose(cmd *cobra.Command, args []string) error {
+	// Configure the logging
+	kekspose.SetVerbosity(verbose)
 
-		if err := kekspose.ExposeKafka(); err != nil {
-			slog.Error("Kekspose failed", "error", err)
-			return err
-		}
+	logKeys, err := kekspose.ResolveAPIKeys(logApis)
+	if err != nil {
+		return fmt.Errorf("invalid --log-api: %w", err)
+	}
+	bodyKeys, err := kekspose.ResolveAPIKeys(traceApis)
+	if err != nil {
+		return fmt.Errorf("invalid --trace-api: %w", err)
+	}
+	if err := kekspose.ValidatePatterns(logClientIds); err != nil {
+		return fmt.Errorf("invalid --log-client-id: %w", err)
+	}
+	if err := kekspose.ValidatePatterns(logTopics); err != nil {
+		return fmt.Errorf("invalid --log-topic: %w", err)
+	}
+	if err := kekspose.ValidatePatterns(logGroups); err != nil {
+		return fmt.Errorf("invalid --log-group: %w", err)
+	}
+	if err := kekspose.ValidatePatterns(logRecords); err != nil {
+		return fmt.Errorf("invalid --log-records: %w", err)
+	}
+	format, err := records.ParseFormat(recordFormat)
+	if err != nil {
+		return fmt.Errorf("invalid --record-format: %w", err)
+	}
+	if err := kekspose.ValidateRedaction(redact); err != nil {
+		return fmt.Errorf("invalid --redact: %w", err)
+	}
+	if schemaRegistryURL != "" && schemaRegistrySelector != "" {
+		return fmt.Errorf("use either --schema-registry-url or --schema-registry-selector, not both")
+	}
 
-		return nil
-	},
-}
+	// Offer the interactive selection only when the cluster name was not chosen explicitly and
+	// there is a user on the other side of the terminal to answer.
+	interactive := !cmd.Flags().Changed("cluster-name") && podSelector == "" && term.IsTerminal(int(os.Stdin.Fd()))
 
-// resolveAPIKeys turns a list of Kafka API names (e.g. "Metadata", "Produce") into their numeric
-// API keys, matching case-insensitively against the protocol registry. It returns an error naming
-// any API it does not recognise, so a typo fails fast rather than silently logging nothing.
-func resolveAPIKeys(names []string) ([]int16, error) {
-	if len(names) == 0 {
-		return nil, nil
+	if err := kekspose.ValidatePort(startingPort); err != nil {
+		return fmt.Errorf("invalid --starting-port: %w", err)
+	}
+	if err := kekspose.ValidatePort(podPort); err != nil {
+		return fmt.Errorf("invalid --pod-port: %w", err)
+	}
+	if err := kekspose.ValidatePort(schemaRegistryPort); err != nil {
+		return fmt.Errorf("invalid --schema-registry-port: %w", err)
 	}
 
-	// Build a case-insensitive name -> key index from the generated registry.
-	index := make(map[string]int16)
-	for k := int16(0); k < 1000; k++ {
-		if name := messages.Name(k); name != "Unknown" {
-			index[strings.ToLower(name)] = k
-		}
+	if err := kekspose.ValidateTransport(transport); err != nil {
+		return fmt.Errorf("invalid --transport: %w", err)
 	}
 
-	keys := make([]int16, 0, len(names))
-	for _, name := range names {
-		name = strings.TrimSpace(name)
-		if name == "" {
-			continue
-		}
-		key, ok := index[strings.ToLower(name)]
-		if !ok {
-			return nil, fmt.Errorf("unknown Kafka API %q", name)
+	if err := validateTargetFlags(cmd); err != nil {
+		return err
+	}
+
+	kekspose := kekspose.Kekspose{
+		KubeConfigPath:         kubeconfigpath,
+		Context:                contextName,
+		Namespace:              namespace,
+		ClusterNames:           clusterNames,
+		ListenerNames:          listenerNames,
+		StartingPort:           startingPort,
+		AllowUnready:           allowUnready,
+		AllowInsecureTLS:       allowInsecureTLS,
+		AutoPort:               autoPort,
+		KafkaConnect:           kafkaConnect,
+		KafkaBridge:            kafkaBridge,
+		CruiseControl:          cruiseControl || cruiseControlAuth,
+		CruiseControlAuth:      cruiseControlAuth,
+		PodSelector:            podSelector,
+		PodPort:                podPort,
+		PodTLS:                 podTLS,
+		NodeIdRule:             nodeIdRule,
+		DiscoverNodes:          discoverNodes,
+		WaitTimeout:            waitTimeout,
+		PartialStart:           partialStart,
+		LazyConnect:            lazyConnect,
+		IdleTimeout:            idleTimeout,
+		Transport:              transport,
+		TLSHandshakeTimeout:    tlsHandshakeTimeout,
+		ConnectionIdleTimeout:  connectionIdleTimeout,
+		ReadTimeout:            readTimeout,
+		DrainTimeout:           drainTimeout,
+		AdminAddress:           adminAddress,
+		AdminTokenFile:         adminTokenFile,
+		LogAPIKeys:             logKeys,
+		BodyAPIKeys:            bodyKeys,
+		LogClientIDs:           logClientIds,
+		LogTopics:              logTopics,
+		LogGroups:              logGroups,
+		RecordTopics:           logRecords,
+		RecordFormat:           format,
+		RecordMaxBytes:         recordMaxBytes,
+		Redact:                 redact,
+		LogErrors:              logErrors,
+		MonitorGroups:          monitorGroups,
+		SchemaRegistryURL:      schemaRegistryURL,
+		SchemaRegistrySelector: schemaRegistrySelector,
+		SchemaRegistryPort:     schemaRegistryPort,
+		SessionName:            sessionName,
+		SessionDir:             sessionDir,
+		Interactive:            interactive,
+		CommandLine: func(clusterNames []string, listenerNames []string) string {
+			return commandLine(cmd, clusterNames, listenerNames)
+		},
+	}
+
+	if err := exposeKafka(&kekspose); err != nil {
+		slog.Error("Kekspose failed", "error", err)
+		return err
+	}
+
+	return nil
+}
+
+// strimziFlags are the flags which use the Strimzi resources and cannot be used with --pod-selector.
+var strimziFlags = []string{"cluster-name", "listener-name", "kafka-connect", "kafka-bridge", "cruise-control", "cruise-control-auth"}
+
+// validatePodSelectorFlags rejects the flags which use the Strimzi resources, because the Kafka cluster
+// selected by --pod-selector is not managed by Strimzi.
+func validatePodSelectorFlags(cmd *cobra.Command) error {
+	for _, name := range strimziFlags {
+		if f := cmd.Flags().Lookup(name); f != nil && f.Changed && f.Value.String() != "false" {
+			return fmt.Errorf("--%s cannot be used with --pod-selector, because the Kafka cluster is not managed by Strimzi", name)
 		}
-		keys = append(keys, key)
 	}
-	return keys, nil
+
+	return nil
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
@@ -137,15 +246,87 @@ func init() {
 
 	// Cobra also supports local flags, which will only run
 	// when this action is called directly.
-	rootCmd.Flags().StringVar(&kubeconfigpath, "kubeconfig", "", "Path to the kubeconfig file to use for Kubernetes API requests.")
-	rootCmd.Flags().StringVar(&contextName, "context", "", "Name of the Kubernetes context to use from the kubeconfig file.")
-	rootCmd.Flags().StringVarP(&namespace, "namespace", "n", "", "Namespace of the Kafka cluster.")
-	rootCmd.Flags().StringVarP(&clusterName, "cluster-name", "c", "my-cluster", "Name of the Kafka cluster.")
-	rootCmd.Flags().StringVarP(&listenerName, "listener-name", "l", "", "Name of the listener that should be exposed.")
-	rootCmd.Flags().Uint32VarP(&startingPort, "starting-port", "p", 50000, "The starting port number. This port number will be used for the bootstrap connection and will be used as the basis to calculate the per-broker ports.")
-	rootCmd.Flags().BoolVar(&allowUnready, "allow-unready", false, "Allow connecting to Kafka clusters even when the Kafka resource is not Ready.")
-	rootCmd.Flags().BoolVar(&allowInsecureTLS, "allow-insecure-tls", false, "Allow using TLS-encrypted Kafka listeners with certificate verification disabled.")
-	rootCmd.Flags().CountVarP(&verbose, "verbose", "v", "Enables verbose logging (can be repeated: -v, -vv, -vvv).")
-	rootCmd.Flags().StringSliceVar(&logApis, "log-api", nil, "Restrict RPC logging to these Kafka APIs (comma-separated names, e.g. Metadata,Produce). Default: all APIs. Requires -v.")
-	rootCmd.Flags().StringSliceVar(&traceApis, "trace-api", nil, "Decode and log full message bodies only for these Kafka APIs (comma-separated names, e.g. Metadata). Default: all logged APIs. Requires -vv.")
+	addExposeFlags(rootCmd)
+}
+
+// addExposeFlags registers the flags configuring the port forwarding. They are shared by the root
+// command and the start command.
+func addExposeFlags(cmd *cobra.Command) {
+	addKafkaFlags(cmd)
+	addTargetFlags(cmd)
+	cmd.Flags().BoolVar(&autoPort, "auto-port", false, "Use the next block of free local ports when some of the ports starting from --starting-port are already in use.")
+	cmd.Flags().BoolVar(&discoverNodes, "discover-nodes", false, "Verify the Kafka nodes from the node pools against the brokers returned by the Kafka cluster in the Metadata response.")
+	cmd.Flags().DurationVar(&waitTimeout, "wait-timeout", 0, "How long to wait for the pods to become ready before starting the port forwarding. By default, the port forwarding starts without checking the readiness.")
+	cmd.Flags().BoolVar(&partialStart, "partial-start", false, "Start the port forwarding for the ready pods right away and for the other pods once they become ready.")
+	cmd.Flags().BoolVar(&lazyConnect, "lazy-connect", false, "Connect to the pods only when the first client connects to the forwarded port instead of at startup.")
+	cmd.Flags().DurationVar(&idleTimeout, "idle-timeout", 5*time.Minute, "With --lazy-connect, close the connection to the pod after this time without any clients. Use 0 to keep it open.")
+	cmd.Flags().StringVar(&transport, "transport", kekspose.TransportSPDY, "Transport used for the port forwarding connections to the Kubernetes API server (auto, websocket, or spdy). The auto transport uses WebSockets with fallback to SPDY.")
+	cmd.Flags().DurationVar(&tlsHandshakeTimeout, "handshake-timeout", proxiedforward.DefaultTLSHandshakeTimeout, "How long to wait for the TLS handshake with the brokers when using --allow-insecure-tls.")
+	cmd.Flags().DurationVar(&connectionIdleTimeout, "conn-idle-timeout", 0, "Close the client connections without any traffic in either direction for this long. Use 0 to keep them open.")
+	cmd.Flags().DurationVar(&readTimeout, "read-timeout", 0, "Close the client connections when the pod does not respond to the data sent by the client for this long. Use 0 to wait forever.")
+	cmd.Flags().DurationVar(&drainTimeout, "drain-timeout", 0, "On shutdown, how long to wait for the requests in flight to complete before closing the connections. By default, the connections are closed right away.")
+	cmd.Flags().StringVar(&adminAddress, "admin-address", "", "Address of the local admin API for controlling the running session (e.g. localhost:8000). Disabled by default.")
+	cmd.Flags().StringVar(&adminTokenFile, "admin-token-file", "", "File where the token for accessing the admin API is written. Defaults to a file in the user cache directory.")
+	cmd.Flags().CountVarP(&verbose, "verbose", "v", "Enables verbose logging (can be repeated: -v, -vv, -vvv).")
+	cmd.Flags().StringSliceVar(&logApis, "log-api", nil, "Restrict RPC logging to these Kafka APIs (comma-separated names, e.g. Metadata,Produce). Default: all APIs. Requires -v.")
+	cmd.Flags().StringSliceVar(&traceApis, "trace-api", nil, "Decode and log full message bodies only for these Kafka APIs (comma-separated names, e.g. Metadata). Default: all logged APIs. Requires -vv.")
+	cmd.Flags().StringSliceVar(&logClientIds, "log-client-id", nil, "Restrict RPC logging to the requests from these client IDs (comma-separated patterns, e.g. console-*). Requires -v.")
+	cmd.Flags().StringSliceVar(&logTopics, "log-topic", nil, "Restrict RPC logging to the requests using these topics (comma-separated patterns, e.g. orders-*). Requires -v.")
+	cmd.Flags().StringSliceVar(&logGroups, "log-group", nil, "Restrict RPC logging to the requests using these consumer groups (comma-separated patterns, e.g. my-group). Requires -v.")
+	cmd.Flags().StringSliceVar(&logRecords, "log-records", nil, "Decode and log the records produced to and fetched from these topics (comma-separated patterns, e.g. orders-*). Requires -v.")
+	cmd.Flags().StringVar(&recordFormat, "record-format", string(records.FormatAuto), "Format of the keys, values, and headers of the records logged with --log-records (auto, text, json, or hex).")
+	cmd.Flags().IntVar(&recordMaxBytes, "record-max-bytes", kekspose.DefaultRecordMaxBytes, "Truncate the keys, values, and headers of the records logged with --log-records to this many bytes. Use 0 to log them in full.")
+	cmd.Flags().StringSliceVar(&redact, "redact", []string{kekspose.RedactConfigs}, "Redact these data in the RPC logs (comma-separated: values and headers of the records, and configs for the sensitive configuration options). The SASL authentication bytes are always redacted.")
+	cmd.Flags().BoolVar(&logErrors, "log-errors", false, "Log the error codes in the responses from the Kafka cluster as warnings and summarize them when stopping. Does not require -v.")
+	cmd.Flags().BoolVar(&monitorGroups, "monitor-groups", false, "Log the members joining and leaving the consumer groups, their generations, assignments, rebalances, and committed offsets. Does not require -v.")
+	cmd.Flags().StringVar(&schemaRegistryURL, "schema-registry-url", "", "URL of the schema registry used to decode the records logged with --log-records which use the Confluent wire format (e.g. http://localhost:8081).")
+}
+
+// addKafkaFlags registers the flags selecting the Kubernetes cluster, the Kafka cluster, and its listener.
+// They are shared by all commands working with the Kafka cluster.
+func addKafkaFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&kubeconfigpath, "kubeconfig", "", "Path to the kubeconfig file to use for Kubernetes API requests.")
+	cmd.Flags().StringVar(&contextName, "context", "", "Name of the Kubernetes context to use from the kubeconfig file.")
+	cmd.Flags().StringVarP(&namespace, "namespace", "n", "", "Namespace of the Kafka cluster.")
+	cmd.Flags().StringSliceVarP(&clusterNames, "cluster-name", "c", []string{"my-cluster"}, "Name of the Kafka cluster. Can be repeated or comma-separated to expose multiple clusters, optionally qualified with the namespace (<namespace>/<name>). When not set and the default cluster does not exist, Keksposé lets you pick the cluster interactively.")
+	cmd.Flags().StringSliceVarP(&listenerNames, "listener-name", "l", nil, "Name of the listener that should be exposed. Can be repeated or comma-separated to expose multiple listeners, each on its own range of ports.")
+	cmd.Flags().Uint32VarP(&startingPort, "starting-port", "p", kekspose.DefaultStartingPort, "The starting port number. This port number will be used for the bootstrap connection and will be used as the basis to calculate the per-broker ports.")
+	cmd.Flags().BoolVar(&allowUnready, "allow-unready", false, "Allow connecting to Kafka clusters even when the Kafka resource is not Ready.")
+	cmd.Flags().BoolVar(&allowInsecureTLS, "allow-insecure-tls", false, "Allow using TLS-encrypted Kafka listeners with certificate verification disabled.")
+}
+
+// addTargetFlags registers the flags selecting the pods and the HTTP APIs which are forwarded. They are
+// shared by the commands exposing the Kafka cluster and by the doctor command, which checks the same targets.
+func addTargetFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&kafkaConnect, "kafka-connect", false, "Forward also the REST API of the Kafka Connect clusters connected to the Kafka cluster.")
+	cmd.Flags().BoolVar(&kafkaBridge, "kafka-bridge", false, "Forward also the HTTP API of the Kafka Bridges connected to the Kafka cluster.")
+	cmd.Flags().BoolVar(&cruiseControl, "cruise-control", false, "Forward also the Cruise Control REST API of the Kafka cluster.")
+	cmd.Flags().BoolVar(&cruiseControlAuth, "cruise-control-auth", false, "Add the Cruise Control API credentials to the forwarded requests automatically. Implies --cruise-control.")
+	cmd.Flags().StringVar(&podSelector, "pod-selector", "", "Label selector of the broker pods of a Kafka cluster not managed by Strimzi. When set, the Strimzi resources are not used.")
+	cmd.Flags().Uint32Var(&podPort, "pod-port", 9092, "Container port of the Kafka listener of the pods selected by --pod-selector.")
+	cmd.Flags().BoolVar(&podTLS, "pod-tls", false, "Connect to the Kafka listener of the pods selected by --pod-selector using TLS. Requires --allow-insecure-tls, because the certificates are not verified.")
+	cmd.Flags().StringVar(&nodeIdRule, "node-id-rule", keks.NodeIdFromOrdinal, "Rule for extracting the node IDs of the pods selected by --pod-selector (ordinal, annotation:<key>, or label:<key>).")
+	cmd.Flags().StringVar(&schemaRegistrySelector, "schema-registry-selector", "", "Label selector of the schema registry pods in the namespace of the Kafka cluster. Their API is forwarded and used like --schema-registry-url.")
+	cmd.Flags().Uint32Var(&schemaRegistryPort, "schema-registry-port", keks.SchemaRegistryDefaultPort, "Container port of the API of the schema registry pods selected by --schema-registry-selector.")
+}
+
+// validateTargetFlags validates the flags registered by addTargetFlags.
+func validateTargetFlags(cmd *cobra.Command) error {
+	if podTLS && podSelector == "" {
+		return fmt.Errorf("--pod-tls can be used only with --pod-selector")
+	}
+	if podTLS && !allowInsecureTLS {
+		return fmt.Errorf("--pod-tls requires --allow-insecure-tls, because Keksposé does not verify the certificates of the brokers")
+	}
+
+	if podSelector != "" {
+		if err := validatePodSelectorFlags(cmd); err != nil {
+			return err
+		}
+		if err := keks.ValidateNodeIdRule(nodeIdRule); err != nil {
+			return fmt.Errorf("invalid --node-id-rule: %w", err)
+		}
+	}
+
+	return nil
 }
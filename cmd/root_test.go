@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidatePodSelectorFlags(t *testing.T) {
+	// The flags are bound to the global variables used by the other tests as well
+	t.Cleanup(func() { podSelector = "" })
+
+	parse := func(args ...string) *cobra.Command {
+		cmd := &cobra.Command{Use: "kekspose"}
+		addExposeFlags(cmd)
+		require.NoError(t, cmd.ParseFlags(append([]string{"--pod-selector", "app=kafka"}, args...)))
+
+		return cmd
+	}
+
+	assert.NoError(t, validatePodSelectorFlags(parse("--pod-port", "9094", "--kafka-connect=false")))
+	assert.EqualError(t, validatePodSelectorFlags(parse("--cluster-name", "my-cluster")), "--cluster-name cannot be used with --pod-selector, because the Kafka cluster is not managed by Strimzi")
+	assert.EqualError(t, validatePodSelectorFlags(parse("-l", "plain")), "--listener-name cannot be used with --pod-selector, because the Kafka cluster is not managed by Strimzi")
+	assert.EqualError(t, validatePodSelectorFlags(parse("--cruise-control-auth")), "--cruise-control-auth cannot be used with --pod-selector, because the Kafka cluster is not managed by Strimzi")
+}
+
+func TestValidateTargetFlagsWithPodTLS(t *testing.T) {
+	t.Cleanup(func() {
+		podSelector = ""
+		podTLS = false
+		allowInsecureTLS = false
+	})
+
+	parse := func(args ...string) *cobra.Command {
+		podSelector, podTLS, allowInsecureTLS = "", false, false
+
+		cmd := &cobra.Command{Use: "kekspose"}
+		addExposeFlags(cmd)
+		require.NoError(t, cmd.ParseFlags(args))
+
+		return cmd
+	}
+
+	assert.NoError(t, validateTargetFlags(parse("--pod-selector", "app=kafka", "--pod-tls", "--allow-insecure-tls")))
+	assert.EqualError(t, validateTargetFlags(parse("--pod-selector", "app=kafka", "--pod-tls")), "--pod-tls requires --allow-insecure-tls, because Keksposé does not verify the certificates of the brokers")
+	assert.EqualError(t, validateTargetFlags(parse("--pod-tls", "--allow-insecure-tls")), "--pod-tls can be used only with --pod-selector")
+}
@@ -0,0 +1,187 @@
+/*
+Copyright © 2025 Jakub Scholz
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/scholzj/kekspose/pkg/kekspose/session"
+	"github.com/spf13/cobra"
+)
+
+var detach bool
+var startTimeout time.Duration
+
+// detachedArgs returns the arguments of the process started in the background. They are replaced in
+// the tests, which run the test binary instead of Keksposé.
+var detachedArgs = func() []string {
+	return os.Args[1:]
+}
+
+// startCmd represents the start command
+var startCmd = &cobra.Command{
+	Use:   "start",
+	Short: "Exposes the Kafka cluster, optionally in the background",
+	Long: `Exposes the Kafka cluster the same way as running Keksposé without any command.
+With --detach, Keksposé keeps running in the background and writes the state of the session to a state file.
+Use the status command to list the running sessions and the stop command to stop them.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// The process started in the background gets the session name from its parent
+		if name := os.Getenv(session.EnvName); name != "" {
+			dir, err := session.Dir()
+			if err != nil {
+				return err
+			}
+
+			sessionName = name
+			sessionDir = dir
+			return runExpose(cmd, args)
+		}
+
+		if !detach {
+			sessionName = ""
+			return runExpose(cmd, args)
+		}
+
+		return startDetached()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(startCmd)
+	addExposeFlags(startCmd)
+	startCmd.Flags().BoolVarP(&detach, "detach", "d", false, "Run Keksposé in the background.")
+	startCmd.Flags().StringVar(&sessionName, "name", "", "Name of the session running in the background. Defaults to the name of the Kafka cluster.")
+	startCmd.Flags().DurationVar(&startTimeout, "start-timeout", 2*time.Minute, "How long to wait for the session running in the background to become ready, in addition to --wait-timeout. The process is killed when it does not become ready in time.")
+}
+
+// startDetached starts Keksposé again in the background with the same arguments and waits until the
+// port forwarding is ready. When it is not ready within the start timeout, the process is killed.
+func startDetached() error {
+	name := sessionName
+	if name == "" {
+		name = defaultSessionName()
+	}
+
+	if err := session.ValidateName(name); err != nil {
+		return err
+	}
+
+	dir, err := session.Dir()
+	if err != nil {
+		return err
+	}
+
+	if existing, err := session.Load(dir, name); err == nil {
+		if existing.Running() {
+			return fmt.Errorf("session %s is already running with PID %d. Use --name to start another session or kekspose stop %s to stop it", name, existing.PID, name)
+		}
+
+		// Left behind by a process which did not shut down cleanly
+		if err := session.Remove(dir, name); err != nil {
+			return err
+		}
+	}
+
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return fmt.Errorf("failed to create the session directory %s: %w", dir, err)
+	}
+
+	logPath := session.LogPath(dir, name)
+	logFile, err := os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to create the log file %s: %w", logPath, err)
+	}
+	defer logFile.Close()
+
+	executable, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to find the Keksposé executable: %w", err)
+	}
+
+	child := exec.Command(executable, detachedArgs()...)
+	child.Env = append(os.Environ(), session.EnvName+"="+name)
+	child.Stdout = logFile
+	child.Stderr = logFile
+	session.Detach(child)
+
+	if err := child.Start(); err != nil {
+		return fmt.Errorf("failed to start Keksposé in the background: %w", err)
+	}
+
+	exited := make(chan error, 1)
+	go func() {
+		exited <- child.Wait()
+	}()
+
+	ticker := time.NewTicker(200 * time.Millisecond)
+	defer ticker.Stop()
+
+	timeout := waitTimeout + startTimeout
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+
+	for {
+		select {
+		case err := <-exited:
+			if err == nil {
+				err = errors.New("the process exited before the port forwarding was ready")
+			}
+
+			//goland:noinspection GoErrorStringFormat
+			return fmt.Errorf("Keksposé failed to start in the background: %w. Check the log file %s for details", err, logPath)
+		case <-deadline.C:
+			// Do not leave behind a process which nobody knows about
+			_ = child.Process.Kill()
+			<-exited
+
+			//goland:noinspection GoErrorStringFormat
+			return fmt.Errorf("Keksposé did not start in the background within %s. Check the log file %s for details", timeout, logPath)
+		case <-ticker.C:
+			s, err := session.Load(dir, name)
+			if err != nil || s.PID != child.Process.Pid {
+				continue
+			}
+
+			slog.Info("Keksposé is running in the background", "session", s.Name, "pid", s.PID, "logFile", logPath)
+			for _, c := range s.Clusters {
+				slog.Info("Use the following address to access the Kafka cluster", "clusterName", c.Name, "namespace", c.Namespace, "listenerName", c.ListenerName, "address", c.BootstrapAddress)
+			}
+			for _, e := range s.Endpoints {
+				slog.Info("Use the following URL to access the HTTP API", "kind", e.Kind, "name", e.Name, "namespace", e.Namespace, "url", e.URL)
+			}
+			slog.Info("Use kekspose stop to stop port forwarding", "session", s.Name)
+
+			return nil
+		}
+	}
+}
+
+// defaultSessionName derives the session name from the names of the exposed Kafka clusters.
+func defaultSessionName() string {
+	if podSelector != "" {
+		return "pods"
+	}
+
+	return strings.ReplaceAll(strings.Join(clusterNames, "-"), "/", "-")
+}
@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/signal"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/scholzj/kekspose/pkg/kekspose"
+	"github.com/scholzj/kekspose/pkg/kekspose/session"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMain(m *testing.M) {
+	// The session started in the background by TestStartDetachedAndStop runs the test binary
+	if os.Getenv(session.EnvName) != "" {
+		exposeKafka = fakeExposeKafka
+		rootCmd.SetArgs(os.Args[1:])
+		if err := rootCmd.Execute(); err != nil {
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	os.Exit(m.Run())
+}
+
+// fakeExposeKafka writes the session state the same way as Keksposé once the port forwarding is ready
+// and keeps running until it is stopped.
+func fakeExposeKafka(k *kekspose.Kekspose) error {
+	if k.SessionName == "" || k.SessionDir == "" {
+		return errors.New("the session is not configured")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	s := &session.Session{Name: k.SessionName, PID: os.Getpid(), ProcessStart: session.ProcessStart(os.Getpid()), StartedAt: time.Now().UTC()}
+	if err := session.Save(k.SessionDir, s); err != nil {
+		return err
+	}
+
+	<-ctx.Done()
+	return session.Remove(k.SessionDir, k.SessionName)
+}
+
+func TestStartDetachedAndStop(t *testing.T) {
+	// The session directory is in the user cache directory
+	cache := t.TempDir()
+	t.Setenv("XDG_CACHE_HOME", cache)
+	t.Setenv("HOME", cache)
+	t.Setenv("LocalAppData", cache)
+
+	dir, err := session.Dir()
+	require.NoError(t, err)
+
+	// Do not leave the process in the background when the test fails
+	t.Cleanup(func() {
+		sessions, _ := session.List(dir)
+		for _, s := range sessions {
+			_ = s.Stop()
+		}
+	})
+
+	args := []string{"start", "--detach", "--cluster-name", "my-cluster", "--start-timeout", "30s"}
+	detachedArgs = func() []string { return args }
+
+	rootCmd.SetArgs(args)
+	require.NoError(t, rootCmd.Execute())
+
+	s, err := session.Load(dir, "my-cluster")
+	require.NoError(t, err)
+	assert.True(t, s.Running())
+	assert.NotEqual(t, os.Getpid(), s.PID)
+
+	rootCmd.SetArgs([]string{"stop", "my-cluster"})
+	require.NoError(t, rootCmd.Execute())
+
+	assert.False(t, s.Running())
+	_, err = session.Load(dir, "my-cluster")
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
@@ -0,0 +1,110 @@
+/*
+Copyright © 2025 Jakub Scholz
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/scholzj/kekspose/pkg/kekspose/session"
+	"github.com/spf13/cobra"
+)
+
+var statusOutput string
+
+// sessionStatus is the state of a session together with the result of its health check.
+type sessionStatus struct {
+	*session.Session
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// statusCmd represents the status command
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Lists the Keksposé sessions running in the background",
+	Long: `Lists the Keksposé sessions started with kekspose start --detach and checks their health.
+A session is running when its process is alive and its bootstrap addresses accept connections.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if statusOutput != "table" && statusOutput != "json" {
+			return fmt.Errorf("invalid --output %q. Use table or json", statusOutput)
+		}
+
+		dir, err := session.Dir()
+		if err != nil {
+			return err
+		}
+
+		sessions, err := session.List(dir)
+		if err != nil {
+			return err
+		}
+
+		statuses := make([]sessionStatus, 0, len(sessions))
+		for _, s := range sessions {
+			statuses = append(statuses, checkSession(s))
+		}
+
+		if statusOutput == "json" {
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(statuses)
+		}
+
+		if len(statuses) == 0 {
+			fmt.Println("No Keksposé sessions are running")
+			return nil
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+		_, _ = fmt.Fprintln(w, "NAME\tPID\tSTATUS\tAGE\tCLUSTERS\tBOOTSTRAP")
+		for _, s := range statuses {
+			clusters := make([]string, 0, len(s.Clusters))
+			bootstraps := make([]string, 0, len(s.Clusters))
+			for _, c := range s.Clusters {
+				clusters = append(clusters, c.Namespace+"/"+c.Name)
+				bootstraps = append(bootstraps, c.BootstrapAddress)
+			}
+
+			_, _ = fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%s\t%s\n", s.Name, s.PID, s.Status, time.Since(s.StartedAt).Round(time.Second), strings.Join(clusters, ","), strings.Join(bootstraps, " "))
+		}
+
+		return w.Flush()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(statusCmd)
+	statusCmd.Flags().StringVarP(&statusOutput, "output", "o", "table", "Output format (table or json).")
+}
+
+// checkSession checks whether the process of the session is alive and whether it accepts connections.
+func checkSession(s *session.Session) sessionStatus {
+	if !s.Running() {
+		return sessionStatus{Session: s, Status: "not running"}
+	}
+
+	if err := s.Check(time.Second); err != nil {
+		return sessionStatus{Session: s, Status: "unhealthy", Error: err.Error()}
+	}
+
+	return sessionStatus{Session: s, Status: "running"}
+}
@@ -0,0 +1,108 @@
+/*
+Copyright © 2025 Jakub Scholz
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/scholzj/kekspose/pkg/kekspose/session"
+	"github.com/spf13/cobra"
+)
+
+var stopTimeout time.Duration
+
+// stopCmd represents the stop command
+var stopCmd = &cobra.Command{
+	Use:   "stop [name]",
+	Short: "Stops a Keksposé session running in the background",
+	Long: `Stops a Keksposé session started with kekspose start --detach.
+The session is stopped gracefully the same way as with Ctrl+C. The name can be omitted when only one session exists.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dir, err := session.Dir()
+		if err != nil {
+			return err
+		}
+
+		var s *session.Session
+		if len(args) == 1 {
+			s, err = session.Load(dir, args[0])
+			if errors.Is(err, os.ErrNotExist) {
+				return fmt.Errorf("session %s was not found. Use kekspose status to list the sessions", args[0])
+			} else if err != nil {
+				return err
+			}
+		} else {
+			sessions, err := session.List(dir)
+			if err != nil {
+				return err
+			}
+
+			switch len(sessions) {
+			case 0:
+				return fmt.Errorf("no sessions are running")
+			case 1:
+				s = sessions[0]
+			default:
+				names := make([]string, 0, len(sessions))
+				for _, s := range sessions {
+					names = append(names, s.Name)
+				}
+				return fmt.Errorf("multiple sessions are running (%s). Specify the name of the session to stop", strings.Join(names, ", "))
+			}
+		}
+
+		return stopSession(dir, s)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(stopCmd)
+	stopCmd.Flags().DurationVar(&stopTimeout, "timeout", 30*time.Second, "How long to wait for the session to stop.")
+}
+
+// stopSession stops the process of the session and waits until it exits.
+func stopSession(dir string, s *session.Session) error {
+	if !s.Running() {
+		slog.Info("Session is not running, removing its state", "session", s.Name, "pid", s.PID)
+		return session.Remove(dir, s.Name)
+	}
+
+	slog.Info("Stopping session", "session", s.Name, "pid", s.PID)
+	if err := s.Stop(); err != nil {
+		return err
+	}
+
+	deadline := time.Now().Add(stopTimeout)
+	for s.Running() {
+		if time.Now().After(deadline) {
+			return fmt.Errorf("session %s did not stop within %s", s.Name, stopTimeout)
+		}
+
+		time.Sleep(200 * time.Millisecond)
+	}
+
+	slog.Info("Session stopped", "session", s.Name)
+
+	// The process removes the state on shutdown, but not when it was killed
+	return session.Remove(dir, s.Name)
+}
@@ -0,0 +1,386 @@
+/*
+Copyright © 2025 Jakub Scholz
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kekspose
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/scholzj/kekspose/pkg/kekspose/proxiedforward"
+	"github.com/scholzj/kekspose/pkg/kekspose/session"
+)
+
+// adminServer is the local HTTP API for controlling the running session. Every request has to carry
+// the random token written to the token file as a bearer token.
+type adminServer struct {
+	live      *liveSession
+	listener  net.Listener
+	server    *http.Server
+	token     string
+	tokenFile string
+}
+
+// adminCluster is an exposed Kafka listener as shown by the admin API.
+type adminCluster struct {
+	session.Cluster
+	Port           uint32           `json:"port"`
+	TLS            bool             `json:"tls"`
+	Authentication string           `json:"authentication,omitempty"`
+	Nodes          map[int32]string `json:"nodes"`
+}
+
+// adminSession is the state of the session as shown by the admin API.
+type adminSession struct {
+	Name      string             `json:"name,omitempty"`
+	PID       int                `json:"pid"`
+	StartedAt time.Time          `json:"startedAt"`
+	Clusters  []adminCluster     `json:"clusters"`
+	Endpoints []session.Endpoint `json:"endpoints,omitempty"`
+}
+
+// adminPortStats are the connection statistics of one forwarded port.
+type adminPortStats struct {
+	Namespace  string `json:"namespace"`
+	PodName    string `json:"podName"`
+	NodeId     *int32 `json:"nodeId,omitempty"`
+	LocalPort  uint32 `json:"localPort"`
+	RemotePort uint32 `json:"remotePort"`
+	proxiedforward.StatsSnapshot
+}
+
+// adminLogging are the logging options as shown and changed by the admin API. The options missing in
+// the change request are kept. Note is only shown and tells the user which connections use the options.
+type adminLogging struct {
+	Verbosity *int      `json:"verbosity,omitempty"`
+	APIs      *[]string `json:"apis,omitempty"`
+	BodyAPIs  *[]string `json:"bodyApis,omitempty"`
+	ClientIDs *[]string `json:"clientIds,omitempty"`
+	Topics    *[]string `json:"topics,omitempty"`
+	Groups    *[]string `json:"groups,omitempty"`
+	Note      string    `json:"note,omitempty"`
+}
+
+// newAdminServer starts listening on the address and writes a new random token to the token file.
+func newAdminServer(address string, tokenFile string, live *liveSession) (*adminServer, error) {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on the admin API address %s: %w", address, err)
+	}
+
+	if host, _, err := net.SplitHostPort(listener.Addr().String()); err == nil {
+		if ip := net.ParseIP(host); ip == nil || !ip.IsLoopback() {
+			slog.Warn("The admin API is reachable from other hosts, anyone who gets the token can control the session", "address", listener.Addr().String())
+		}
+	}
+
+	token, err := newAdminToken()
+	if err != nil {
+		_ = listener.Close()
+		return nil, err
+	}
+
+	if err := writeAdminToken(tokenFile, token); err != nil {
+		_ = listener.Close()
+		return nil, err
+	}
+
+	a := &adminServer{live: live, listener: listener, token: token, tokenFile: tokenFile}
+	a.server = &http.Server{Handler: a.handler(), ReadHeaderTimeout: 30 * time.Second}
+
+	return a, nil
+}
+
+// URL returns the base URL of the admin API.
+func (a *adminServer) URL() string {
+	return "http://" + a.listener.Addr().String()
+}
+
+// Serve handles the requests until the server is closed.
+func (a *adminServer) Serve() error {
+	if err := a.server.Serve(a.listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	return nil
+}
+
+// Close stops the server and removes the token file, so that the token cannot be used anymore.
+func (a *adminServer) Close() {
+	_ = a.server.Close()
+
+	if err := os.Remove(a.tokenFile); err != nil && !errors.Is(err, os.ErrNotExist) {
+		slog.Warn("Failed to remove the admin API token file", "file", a.tokenFile, "error", err)
+	}
+}
+
+func (a *adminServer) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/session", a.getSession)
+	mux.HandleFunc("GET /v1/stats", a.getStats)
+	mux.HandleFunc("POST /v1/reload", a.reload)
+	mux.HandleFunc("GET /v1/logging", a.getLogging)
+	mux.HandleFunc("PUT /v1/logging", a.putLogging)
+	mux.HandleFunc("POST /v1/stop", a.stop)
+
+	return a.authenticate(mux)
+}
+
+// authenticate rejects the requests without the right bearer token.
+func (a *adminServer) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !found || subtle.ConstantTimeCompare([]byte(token), []byte(a.token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="kekspose"`)
+			writeError(w, http.StatusUnauthorized, errors.New("missing or invalid token"))
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (a *adminServer) getSession(w http.ResponseWriter, _ *http.Request) {
+	k := a.live.k
+	state := adminSession{Name: k.SessionName, PID: os.Getpid(), StartedAt: a.live.startedAt, Clusters: make([]adminCluster, 0, len(a.live.exposures))}
+
+	k.engines.read(func() {
+		for _, e := range a.live.exposures {
+			state.Clusters = append(state.Clusters, adminCluster{
+				Cluster: session.Cluster{
+					Name:             e.Name(),
+					Namespace:        e.Namespace,
+					ListenerName:     e.Keks.ListenerName,
+					BootstrapAddress: k.bootstrapAddress(e.PortMapping),
+					PortMapping:      e.PortMapping,
+				},
+				Port:           e.Keks.Port,
+				TLS:            e.Keks.TLS,
+				Authentication: e.Keks.Authentication,
+				Nodes:          e.Keks.Nodes,
+			})
+		}
+	})
+
+	for _, e := range a.live.endpoints {
+		state.Endpoints = append(state.Endpoints, session.Endpoint{Kind: e.Kind, Name: e.Name, Namespace: e.Namespace, URL: e.URL()})
+	}
+
+	writeJSON(w, http.StatusOK, state)
+}
+
+func (a *adminServer) getStats(w http.ResponseWriter, _ *http.Request) {
+	stats := make([]adminPortStats, 0)
+
+	for _, pf := range a.live.allForwarders() {
+		for _, port := range pf.ForwardedPorts() {
+			portStats := adminPortStats{Namespace: pf.Namespace, PodName: pf.PodName, LocalPort: port.LocalPort, RemotePort: port.RemotePort}
+			if port.NodeId >= 0 {
+				// The HTTP APIs have no node ID
+				portStats.NodeId = &port.NodeId
+			}
+			if port.Stats != nil {
+				portStats.StatsSnapshot = port.Stats.Snapshot()
+			}
+
+			stats = append(stats, portStats)
+		}
+	}
+
+	writeJSON(w, http.StatusOK, stats)
+}
+
+func (a *adminServer) reload(w http.ResponseWriter, _ *http.Request) {
+	changes, err := a.live.reload()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, changes)
+}
+
+func (a *adminServer) getLogging(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, a.logging())
+}
+
+func (a *adminServer) putLogging(w http.ResponseWriter, r *http.Request) {
+	var logging adminLogging
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&logging); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("failed to decode the request: %w", err))
+		return
+	}
+
+	options := a.live.k.loggingOptions()
+
+	if logging.Verbosity != nil {
+		if *logging.Verbosity < 0 || *logging.Verbosity > MaxVerbosity {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("verbosity must be between 0 and %d", MaxVerbosity))
+			return
+		}
+		options.Verbosity = *logging.Verbosity
+	}
+
+	if logging.APIs != nil {
+		apiKeys, err := ResolveAPIKeys(*logging.APIs)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		options.LogAPIKeys = apiKeys
+	}
+
+	if logging.BodyAPIs != nil {
+		bodyAPIKeys, err := ResolveAPIKeys(*logging.BodyAPIs)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		options.BodyAPIKeys = bodyAPIKeys
+	}
+
+	for _, patterns := range []struct {
+		value  *[]string
+		target *[]string
+	}{
+		{logging.ClientIDs, &options.Filter.ClientIDs},
+		{logging.Topics, &options.Filter.Topics},
+		{logging.Groups, &options.Filter.Groups},
+	} {
+		if patterns.value == nil {
+			continue
+		}
+
+		if err := ValidatePatterns(*patterns.value); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		*patterns.target = *patterns.value
+	}
+
+	a.live.k.setLoggingOptions(options)
+	writeJSON(w, http.StatusOK, a.logging())
+}
+
+func (a *adminServer) logging() adminLogging {
+	options := a.live.k.loggingOptions()
+	apis := apiNames(options.LogAPIKeys)
+	bodyAPIs := apiNames(options.BodyAPIKeys)
+
+	clientIDs := nonNil(options.Filter.ClientIDs)
+	topics := nonNil(options.Filter.Topics)
+	groups := nonNil(options.Filter.Groups)
+
+	return adminLogging{Verbosity: &options.Verbosity, APIs: &apis, BodyAPIs: &bodyAPIs, ClientIDs: &clientIDs, Topics: &topics, Groups: &groups, Note: loggedRPCsNote}
+}
+
+func (a *adminServer) stop(w http.ResponseWriter, _ *http.Request) {
+	slog.Info("Received stop request through the admin API")
+	writeJSON(w, http.StatusAccepted, struct{}{})
+	a.live.shutdown()
+}
+
+// newAdminToken generates a random token for the admin API.
+func newAdminToken() (string, error) {
+	token := make([]byte, 32)
+	if _, err := rand.Read(token); err != nil {
+		return "", fmt.Errorf("failed to generate the admin API token: %w", err)
+	}
+
+	return hex.EncodeToString(token), nil
+}
+
+// writeAdminToken writes the token to a file readable only by the current user. The token is written
+// to a new temporary file, which replaces the token file. So an existing token file with a different
+// mode never gets the new token.
+func writeAdminToken(tokenFile string, token string) error {
+	dir := filepath.Dir(tokenFile)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return fmt.Errorf("failed to create the directory for the admin API token: %w", err)
+	}
+
+	// The temporary files are created with mode 0600
+	tmp, err := os.CreateTemp(dir, filepath.Base(tokenFile)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to write the admin API token: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.WriteString(token + "\n"); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to write the admin API token: %w", err)
+	}
+
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write the admin API token: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), tokenFile); err != nil {
+		return fmt.Errorf("failed to write the admin API token: %w", err)
+	}
+
+	return nil
+}
+
+// defaultAdminTokenFile returns the token file used when none is configured. The sessions running in
+// the background keep the token next to their state file. The other instances use a file with their
+// PID, so that they do not replace or remove the token of another instance.
+func (k *Kekspose) defaultAdminTokenFile() (string, error) {
+	if k.SessionName != "" {
+		return session.TokenPath(k.SessionDir, k.SessionName), nil
+	}
+
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find the directory for the admin API token: %w", err)
+	}
+
+	return filepath.Join(cacheDir, "kekspose", "admin-token-"+strconv.Itoa(os.Getpid())), nil
+}
+
+// nonNil returns an empty slice instead of nil, so that it is shown as an empty list.
+func nonNil(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+
+	return values
+}
+
+func writeJSON(w http.ResponseWriter, status int, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	if err := json.NewEncoder(w).Encode(value); err != nil {
+		slog.Debug("Failed to write the admin API response", "error", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
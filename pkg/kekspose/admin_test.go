@@ -0,0 +1,222 @@
+package kekspose
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	keks2 "github.com/scholzj/kekspose/pkg/kekspose/keks"
+	"github.com/scholzj/kekspose/pkg/kekspose/proxiedforward"
+	"github.com/scholzj/proksy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestAdmin(t *testing.T) (*adminServer, *liveSession, context.Context) {
+	t.Helper()
+
+	k := &Kekspose{SessionName: "my-session"}
+	ctx, shutdown := context.WithCancel(context.Background())
+	t.Cleanup(shutdown)
+
+	live := &liveSession{
+		k: k,
+		exposures: []*exposure{
+			{
+				clusterReference: clusterReference{Namespace: "my-namespace", ClusterName: "my-cluster"},
+				Keks:             &keks2.Keks{Nodes: map[int32]string{0: "my-cluster-pool-0"}, Port: 9092, ListenerName: "plain"},
+				PortMapping:      map[int32]uint32{0: 50000},
+			},
+		},
+		endpoints: []*endpointExposure{
+			{Endpoint: keks2.Endpoint{Kind: "KafkaConnect", Name: "my-connect", Namespace: "my-namespace", Scheme: "http"}, LocalPort: 50001, ForwardPort: 50001},
+		},
+		shutdown: shutdown,
+	}
+	live.addForwarders([]*PortForwarder{
+		{Namespace: "my-namespace", PodName: "my-cluster-pool-0", ForwardedPort: ForwardedPort{NodeId: 0, LocalPort: 50000, RemotePort: 9092, Stats: &proxiedforward.Stats{}}},
+		{Namespace: "my-namespace", PodName: "my-connect-0", ForwardedPort: ForwardedPort{NodeId: -1, LocalPort: 50001, RemotePort: 8083, Stats: &proxiedforward.Stats{}}},
+	})
+
+	return &adminServer{live: live, token: "my-token"}, live, ctx
+}
+
+func adminRequest(t *testing.T, a *adminServer, method string, path string, body string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer my-token")
+
+	rec := httptest.NewRecorder()
+	a.handler().ServeHTTP(rec, req)
+
+	return rec
+}
+
+func TestAdminAuthentication(t *testing.T) {
+	a, _, _ := newTestAdmin(t)
+
+	for _, header := range []string{"", "Bearer other-token", "Basic my-token"} {
+		req := httptest.NewRequest(http.MethodGet, "/v1/session", nil)
+		if header != "" {
+			req.Header.Set("Authorization", header)
+		}
+
+		rec := httptest.NewRecorder()
+		a.handler().ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusUnauthorized, rec.Code, header)
+		assert.NotEmpty(t, rec.Header().Get("WWW-Authenticate"), header)
+	}
+
+	assert.Equal(t, http.StatusOK, adminRequest(t, a, http.MethodGet, "/v1/session", "").Code)
+}
+
+func TestAdminSession(t *testing.T) {
+	a, _, _ := newTestAdmin(t)
+
+	rec := adminRequest(t, a, http.MethodGet, "/v1/session", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var state adminSession
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &state))
+	assert.Equal(t, "my-session", state.Name)
+	assert.Equal(t, os.Getpid(), state.PID)
+	require.Len(t, state.Clusters, 1)
+	assert.Equal(t, "my-cluster", state.Clusters[0].Name)
+	assert.Equal(t, "plain", state.Clusters[0].ListenerName)
+	assert.Equal(t, "localhost:50000", state.Clusters[0].BootstrapAddress)
+	assert.Equal(t, uint32(9092), state.Clusters[0].Port)
+	assert.Equal(t, map[int32]string{0: "my-cluster-pool-0"}, state.Clusters[0].Nodes)
+	require.Len(t, state.Endpoints, 1)
+	assert.Equal(t, "http://localhost:50001", state.Endpoints[0].URL)
+}
+
+func TestAdminStats(t *testing.T) {
+	a, _, _ := newTestAdmin(t)
+
+	rec := adminRequest(t, a, http.MethodGet, "/v1/stats", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var stats []map[string]any
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &stats))
+	require.Len(t, stats, 2)
+	assert.Equal(t, "my-cluster-pool-0", stats[0]["podName"])
+	assert.InDelta(t, 0, stats[0]["nodeId"], 0)
+	assert.InDelta(t, 50000, stats[0]["localPort"], 0)
+	assert.InDelta(t, 0, stats[0]["totalConnections"], 0)
+	assert.NotContains(t, stats[1], "nodeId")
+}
+
+func TestAdminLogging(t *testing.T) {
+	a, live, _ := newTestAdmin(t)
+	restoreVerbosity(t)
+
+	builds := 0
+	live.k.engines.add(func() *proksy.Engine {
+		builds++
+		return live.k.newProxyEngine(slog.Default(), live.exposures[0].PortMapping)
+	})
+
+	rec := adminRequest(t, a, http.MethodGet, "/v1/logging", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, withLoggingNote(`{"verbosity": 0, "apis": [], "bodyApis": [], "clientIds": [], "topics": [], "groups": []}`), rec.Body.String())
+
+	rec = adminRequest(t, a, http.MethodPut, "/v1/logging", `{"apis": ["metadata", "Produce"], "bodyApis": ["Metadata"]}`)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, withLoggingNote(`{"verbosity": 0, "apis": ["Metadata", "Produce"], "bodyApis": ["Metadata"], "clientIds": [], "topics": [], "groups": []}`), rec.Body.String())
+	assert.Equal(t, []int16{3, 0}, live.k.LogAPIKeys)
+	assert.Equal(t, []int16{3}, live.k.BodyAPIKeys)
+	assert.Equal(t, 2, builds)
+
+	// The missing options are kept
+	rec = adminRequest(t, a, http.MethodPut, "/v1/logging", `{"verbosity": 2, "topics": ["orders-*"]}`)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, withLoggingNote(`{"verbosity": 2, "apis": ["Metadata", "Produce"], "bodyApis": ["Metadata"], "clientIds": [], "topics": ["orders-*"], "groups": []}`), rec.Body.String())
+	assert.Equal(t, 2, Verbosity())
+	assert.Equal(t, []string{"orders-*"}, live.k.LogTopics)
+
+	rec = adminRequest(t, a, http.MethodPut, "/v1/logging", `{"apis": [], "topics": []}`)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, withLoggingNote(`{"verbosity": 2, "apis": [], "bodyApis": ["Metadata"], "clientIds": [], "topics": [], "groups": []}`), rec.Body.String())
+
+	rec = adminRequest(t, a, http.MethodPut, "/v1/logging", `{"groups": ["[invalid"]}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = adminRequest(t, a, http.MethodPut, "/v1/logging", `{"apis": ["NoSuchApi"]}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), "NoSuchApi")
+
+	rec = adminRequest(t, a, http.MethodPut, "/v1/logging", `{"verbosity": 3}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, 2, Verbosity())
+
+	rec = adminRequest(t, a, http.MethodPut, "/v1/logging", `not json`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestAdminStop(t *testing.T) {
+	a, _, ctx := newTestAdmin(t)
+
+	rec := adminRequest(t, a, http.MethodPost, "/v1/stop", "")
+	assert.Equal(t, http.StatusAccepted, rec.Code)
+	assert.Error(t, ctx.Err())
+}
+
+func TestNewAdminServer(t *testing.T) {
+	tokenFile := filepath.Join(t.TempDir(), "admin", "token")
+	a, _, _ := newTestAdmin(t)
+
+	// A token file readable by others is replaced instead of reused
+	require.NoError(t, os.MkdirAll(filepath.Dir(tokenFile), 0o700))
+	require.NoError(t, os.WriteFile(tokenFile, []byte("old-token\n"), 0o644))
+
+	server, err := newAdminServer("127.0.0.1:0", tokenFile, a.live)
+	require.NoError(t, err)
+	go func() { _ = server.Serve() }()
+
+	token, err := os.ReadFile(tokenFile)
+	require.NoError(t, err)
+	assert.Len(t, strings.TrimSpace(string(token)), 64)
+
+	info, err := os.Stat(tokenFile)
+	require.NoError(t, err)
+	if os.PathSeparator == '/' {
+		assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+	}
+
+	req, err := http.NewRequest(http.MethodGet, server.URL()+"/v1/session", nil)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	server.Close()
+	assert.NoFileExists(t, tokenFile)
+}
+
+func TestDefaultAdminTokenFile(t *testing.T) {
+	k := &Kekspose{}
+	tokenFile, err := k.defaultAdminTokenFile()
+	require.NoError(t, err)
+	assert.Equal(t, "admin-token-"+strconv.Itoa(os.Getpid()), filepath.Base(tokenFile))
+
+	k = &Kekspose{SessionName: "my-session", SessionDir: "/tmp/sessions"}
+	tokenFile, err = k.defaultAdminTokenFile()
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join("/tmp/sessions", "my-session.token"), tokenFile)
+}
+
+// withLoggingNote adds the note shown with the logging options to the expected JSON.
+func withLoggingNote(options string) string {
+	return strings.TrimSuffix(options, "}") + `, "note": ` + strconv.Quote(loggedRPCsNote) + "}"
+}
@@ -0,0 +1,65 @@
+/*
+Copyright © 2025 Jakub Scholz
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kekspose
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/scholzj/go-kafka-protocol/messages"
+)
+
+// ResolveAPIKeys turns a list of Kafka API names (e.g. "Metadata", "Produce") into their numeric
+// API keys, matching case-insensitively against the protocol registry. It returns an error naming
+// any API it does not recognise, so a typo fails fast rather than silently logging nothing.
+func ResolveAPIKeys(names []string) ([]int16, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	// Build a case-insensitive name -> key index from the generated registry.
+	index := make(map[string]int16)
+	for k := int16(0); k < 1000; k++ {
+		if name := messages.Name(k); name != "Unknown" {
+			index[strings.ToLower(name)] = k
+		}
+	}
+
+	keys := make([]int16, 0, len(names))
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		key, ok := index[strings.ToLower(name)]
+		if !ok {
+			return nil, fmt.Errorf("unknown Kafka API %q", name)
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// apiNames turns the Kafka API keys back into their names.
+func apiNames(keys []int16) []string {
+	names := make([]string, 0, len(keys))
+	for _, key := range keys {
+		names = append(names, messages.Name(key))
+	}
+
+	return names
+}
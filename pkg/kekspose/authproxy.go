@@ -0,0 +1,68 @@
+/*
+Copyright © 2025 Jakub Scholz
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kekspose
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// authProxy is a local HTTP reverse proxy which adds the basic authentication credentials to every
+// request and passes it to the port forwarded from the pod. It lets the user access APIs such as
+// the Cruise Control REST API without handling the credentials.
+type authProxy struct {
+	listener net.Listener
+	server   *http.Server
+}
+
+func newAuthProxy(localPort uint32, targetPort uint32, username string, password string) (*authProxy, error) {
+	listener, err := net.Listen("tcp4", net.JoinHostPort("127.0.0.1", strconv.FormatUint(uint64(localPort), 10)))
+	if err != nil {
+		return nil, err
+	}
+
+	target := &url.URL{Scheme: "http", Host: net.JoinHostPort("localhost", strconv.FormatUint(uint64(targetPort), 10))}
+	proxy := &httputil.ReverseProxy{
+		Rewrite: func(r *httputil.ProxyRequest) {
+			r.SetURL(target)
+			r.Out.SetBasicAuth(username, password)
+		},
+	}
+
+	return &authProxy{
+		listener: listener,
+		server:   &http.Server{Handler: proxy, ReadHeaderTimeout: 30 * time.Second},
+	}, nil
+}
+
+// Serve handles the requests until the proxy is closed.
+func (p *authProxy) Serve() error {
+	if err := p.server.Serve(p.listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	return nil
+}
+
+func (p *authProxy) Close() {
+	_ = p.server.Close()
+}
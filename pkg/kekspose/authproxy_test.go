@@ -0,0 +1,49 @@
+package kekspose
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuthProxyAddsCredentials(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		username, password, ok := r.BasicAuth()
+		if !ok || username != "admin" || password != "s3cr3t" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		_, _ = io.WriteString(w, r.URL.Path)
+	}))
+	defer target.Close()
+
+	targetURL, err := url.Parse(target.URL)
+	require.NoError(t, err)
+	targetPort, err := strconv.ParseUint(targetURL.Port(), 10, 32)
+	require.NoError(t, err)
+
+	localPort, err := ephemeralPort()
+	require.NoError(t, err)
+
+	proxy, err := newAuthProxy(localPort, uint32(targetPort), "admin", "s3cr3t")
+	require.NoError(t, err)
+	go func() { _ = proxy.Serve() }()
+	defer proxy.Close()
+
+	response, err := http.Get("http://" + net.JoinHostPort("127.0.0.1", strconv.FormatUint(uint64(localPort), 10)) + "/kafkacruisecontrol/state")
+	require.NoError(t, err)
+	defer response.Body.Close()
+
+	body, err := io.ReadAll(response.Body)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	assert.Equal(t, "/kafkacruisecontrol/state", string(body))
+}
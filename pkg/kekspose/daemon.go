@@ -0,0 +1,53 @@
+/*
+Copyright © 2025 Jakub Scholz
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kekspose
+
+import (
+	"os"
+	"time"
+
+	"github.com/scholzj/kekspose/pkg/kekspose/session"
+)
+
+// newSession describes the exposed clusters and HTTP APIs for the state file of the session running in
+// the background.
+func (k *Kekspose) newSession(exposures []*exposure, endpoints []*endpointExposure) *session.Session {
+	s := &session.Session{
+		Name:         k.SessionName,
+		PID:          os.Getpid(),
+		ProcessStart: session.ProcessStart(os.Getpid()),
+		StartedAt:    time.Now().UTC(),
+		LogFile:      session.LogPath(k.SessionDir, k.SessionName),
+		Clusters:     make([]session.Cluster, 0, len(exposures)),
+	}
+
+	for _, e := range exposures {
+		s.Clusters = append(s.Clusters, session.Cluster{
+			Name:             e.Name(),
+			Namespace:        e.Namespace,
+			ListenerName:     e.Keks.ListenerName,
+			BootstrapAddress: k.bootstrapAddress(e.PortMapping),
+			PortMapping:      e.PortMapping,
+		})
+	}
+
+	for _, e := range endpoints {
+		s.Endpoints = append(s.Endpoints, session.Endpoint{Kind: e.Kind, Name: e.Name, Namespace: e.Namespace, URL: e.URL()})
+	}
+
+	return s
+}
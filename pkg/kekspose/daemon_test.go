@@ -0,0 +1,40 @@
+package kekspose
+
+import (
+	"os"
+	"testing"
+
+	keks2 "github.com/scholzj/kekspose/pkg/kekspose/keks"
+	"github.com/scholzj/kekspose/pkg/kekspose/session"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewSession(t *testing.T) {
+	exposures := []*exposure{
+		{
+			clusterReference: clusterReference{Namespace: "my-namespace", ClusterName: "my-cluster"},
+			Keks:             &keks2.Keks{ListenerName: "plain"},
+			PortMapping:      map[int32]uint32{1: 50001, 0: 50000},
+		},
+	}
+	endpoints := []*endpointExposure{
+		{Endpoint: keks2.Endpoint{Kind: "KafkaConnect", Name: "my-connect", Namespace: "my-namespace", Scheme: "http"}, LocalPort: 50002},
+	}
+
+	k := Kekspose{SessionName: "my-session", SessionDir: "/tmp/sessions"}
+	s := k.newSession(exposures, endpoints)
+
+	assert.Equal(t, "my-session", s.Name)
+	assert.Equal(t, os.Getpid(), s.PID)
+	assert.Equal(t, session.LogPath("/tmp/sessions", "my-session"), s.LogFile)
+	require.Len(t, s.Clusters, 1)
+	assert.Equal(t, session.Cluster{
+		Name:             "my-cluster",
+		Namespace:        "my-namespace",
+		ListenerName:     "plain",
+		BootstrapAddress: "localhost:50000,localhost:50001",
+		PortMapping:      map[int32]uint32{0: 50000, 1: 50001},
+	}, s.Clusters[0])
+	assert.Equal(t, []session.Endpoint{{Kind: "KafkaConnect", Name: "my-connect", Namespace: "my-namespace", URL: "http://localhost:50002"}}, s.Endpoints)
+}
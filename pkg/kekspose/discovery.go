@@ -0,0 +1,222 @@
+/*
+Copyright © 2025 Jakub Scholz
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kekspose
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	keks2 "github.com/scholzj/kekspose/pkg/kekspose/keks"
+	"github.com/scholzj/kekspose/pkg/kekspose/protocol"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+const (
+	// discoveryTimeout limits how long the node discovery waits for the port forwarding and the
+	// Metadata response from a single broker.
+	discoveryTimeout  = 30 * time.Second
+	discoveryClientId = "kekspose"
+)
+
+// discoverNodes asks the Kafka cluster itself which brokers it has instead of relying on the node
+// pools only, which might lag behind reality. It forwards one of the brokers, sends the Metadata
+// request through it, and maps the advertised hosts of the brokers to the pods. The mismatches with
+// the node pools are reported and the nodes of all the listeners are replaced with the discovered ones.
+func (k *Kekspose) discoverNodes(kubeconfig *rest.Config, kubeclient *kubernetes.Clientset, reference clusterReference, kekses []*keks2.Keks) error {
+	// The Metadata request cannot authenticate, so it needs a listener without authentication
+	var keks *keks2.Keks
+	for _, candidate := range kekses {
+		if candidate.Authentication == "none" {
+			keks = candidate
+			break
+		}
+	}
+
+	if keks == nil {
+		slog.Warn("Skipping the node discovery, because it needs a listener without authentication", "clusterName", reference.ClusterName, "namespace", reference.Namespace)
+		return nil
+	}
+
+	var brokers []protocol.Broker
+	var lastErr error
+	for _, nodeId := range sortedNodeIDs(keks.Nodes) {
+		brokers, lastErr = fetchBrokers(kubeconfig, kubeclient, reference.Namespace, keks.Nodes[nodeId], nodeId, keks, k.Transport)
+		if lastErr == nil {
+			break
+		}
+
+		slog.Warn("Failed to discover the Kafka nodes through the broker", "node", nodeId, "podName", keks.Nodes[nodeId], "error", lastErr)
+	}
+
+	if lastErr != nil {
+		return fmt.Errorf("failed to discover the Kafka nodes of Kafka cluster %s in namespace %s: %w", reference.ClusterName, reference.Namespace, lastErr)
+	}
+
+	podExists := func(podName string) bool {
+		_, err := kubeclient.CoreV1().Pods(reference.Namespace).Get(context.TODO(), podName, v1.GetOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			slog.Warn("Failed to get the pod", "podName", podName, "error", err)
+		}
+
+		return err == nil
+	}
+
+	nodes, err := reconcileNodes(keks.Nodes, brokers, podExists)
+	if err != nil {
+		return err
+	}
+
+	slog.Info("Discovered Kafka nodes", "clusterName", reference.ClusterName, "namespace", reference.Namespace, "nodes", nodes)
+	for _, keks := range kekses {
+		keks.Nodes = nodes
+	}
+
+	return nil
+}
+
+// fetchBrokers forwards a single broker without the Kafka proxy and requests the list of brokers from it.
+func fetchBrokers(kubeconfig *rest.Config, kubeclient *kubernetes.Clientset, namespace string, podName string, nodeId int32, keks *keks2.Keks, transport string) ([]protocol.Broker, error) {
+	localPort, err := ephemeralPort()
+	if err != nil {
+		return nil, err
+	}
+
+	pf := NewPortForwarder(kubeconfig, kubeclient, namespace, podName, nodeId, localPort, keks.Port, keks.TLS, nil)
+	pf.Transport = transport
+	defer close(pf.Stop)
+
+	errors := make(chan error, 1)
+	go func() {
+		if err := pf.ForwardPorts(); err != nil {
+			errors <- err
+		}
+	}()
+
+	select {
+	case <-pf.Ready:
+	case err := <-errors:
+		return nil, err
+	case <-time.After(discoveryTimeout):
+		return nil, fmt.Errorf("timed out waiting for the port forwarding")
+	}
+
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort("localhost", strconv.FormatUint(uint64(localPort), 10)), discoveryTimeout)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if err := conn.SetDeadline(time.Now().Add(discoveryTimeout)); err != nil {
+		return nil, err
+	}
+
+	return requestBrokers(conn)
+}
+
+// reconcileNodes maps the brokers from the Metadata response to the pods and reports the differences
+// from the nodes found in the node pools. The pod is identified by the first DNS label of the advertised
+// host, which matches the pod name for the Strimzi internal listeners. For other listeners, the pod from
+// the node pools is used.
+func reconcileNodes(known map[int32]string, brokers []protocol.Broker, podExists func(string) bool) (map[int32]string, error) {
+	nodes := make(map[int32]string, len(brokers))
+
+	for _, broker := range brokers {
+		podName, _, _ := strings.Cut(broker.Host, ".")
+		knownPod, isKnown := known[broker.NodeID]
+
+		switch {
+		case podName != "" && podExists(podName):
+			if !isKnown {
+				slog.Warn("Kafka node is missing in the node pools", "node", broker.NodeID, "podName", podName, "advertisedHost", broker.Host)
+			} else if knownPod != podName {
+				slog.Warn("Kafka node is advertised by a different pod than expected from the node pools", "node", broker.NodeID, "podName", podName, "expectedPodName", knownPod, "advertisedHost", broker.Host)
+			}
+			nodes[broker.NodeID] = podName
+		case isKnown:
+			slog.Debug("Advertised host does not match any pod, using the pod from the node pools", "node", broker.NodeID, "podName", knownPod, "advertisedHost", broker.Host)
+			nodes[broker.NodeID] = knownPod
+		default:
+			slog.Warn("Skipping Kafka node without a matching pod", "node", broker.NodeID, "advertisedHost", broker.Host)
+		}
+	}
+
+	for _, nodeId := range sortedNodeIDs(known) {
+		if _, found := nodes[nodeId]; !found {
+			slog.Warn("Kafka node from the node pools is not part of the Kafka cluster", "node", nodeId, "podName", known[nodeId])
+		}
+	}
+
+	if len(nodes) == 0 {
+		return nil, fmt.Errorf("no Kafka nodes were discovered")
+	}
+
+	return nodes, nil
+}
+
+// requestBrokers sends the Metadata request without any topics and decodes the brokers from the
+// response.
+func requestBrokers(conn io.ReadWriter) ([]protocol.Broker, error) {
+	const correlationId int32 = 1
+
+	if _, err := conn.Write(protocol.AppendBrokersRequest(nil, correlationId, discoveryClientId)); err != nil {
+		return nil, fmt.Errorf("failed to send the Metadata request: %w", err)
+	}
+
+	var size int32
+	if err := binary.Read(conn, binary.BigEndian, &size); err != nil {
+		return nil, fmt.Errorf("failed to read the Metadata response: %w", err)
+	}
+	if size < 4 || size > 16*1024*1024 {
+		return nil, fmt.Errorf("invalid Metadata response size %d", size)
+	}
+
+	response := make([]byte, size)
+	if _, err := io.ReadFull(conn, response); err != nil {
+		return nil, fmt.Errorf("failed to read the Metadata response: %w", err)
+	}
+
+	return decodeBrokers(response, correlationId)
+}
+
+// decodeBrokers decodes the brokers from the Metadata response frame.
+func decodeBrokers(response []byte, expectedCorrelationId int32) ([]protocol.Broker, error) {
+	correlationId, r, err := protocol.ReadResponseHeader(response, protocol.Metadata, protocol.BrokersRequestVersion)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode the Metadata response: %w", err)
+	}
+
+	if correlationId != expectedCorrelationId {
+		return nil, fmt.Errorf("unexpected correlation ID %d in the Metadata response", correlationId)
+	}
+
+	brokers, err := protocol.ReadMetadataBrokers(protocol.BrokersRequestVersion, r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode the Metadata response: %w", err)
+	}
+
+	return brokers, nil
+}
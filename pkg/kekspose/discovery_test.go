@@ -0,0 +1,109 @@
+package kekspose
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/scholzj/kekspose/pkg/kekspose/protocol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestBrokers(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	serverErr := make(chan error, 1)
+	go func() {
+		var size int32
+		if err := binary.Read(server, binary.BigEndian, &size); err != nil {
+			serverErr <- err
+			return
+		}
+
+		request := make([]byte, size)
+		if _, err := io.ReadFull(server, request); err != nil {
+			serverErr <- err
+			return
+		}
+
+		// API key 3 (Metadata), version 4, correlation ID 1, client ID "kekspose", no topics, no auto-creation
+		expected := []byte{0, 3, 0, 4, 0, 0, 0, 1, 0, 8, 'k', 'e', 'k', 's', 'p', 'o', 's', 'e', 0, 0, 0, 0, 0}
+		if !bytes.Equal(expected, request) {
+			serverErr <- io.ErrUnexpectedEOF
+			return
+		}
+
+		response := new(bytes.Buffer)
+		_ = binary.Write(response, binary.BigEndian, []int32{1, 0, 2}) // correlation ID, throttle time, brokers
+		_ = binary.Write(response, binary.BigEndian, int32(0))
+		writeTestString(response, "my-cluster-brokers-0.my-cluster-kafka-brokers.my-namespace.svc")
+		_ = binary.Write(response, binary.BigEndian, int32(9092))
+		_ = binary.Write(response, binary.BigEndian, int16(-1)) // null rack
+		_ = binary.Write(response, binary.BigEndian, int32(1))
+		writeTestString(response, "my-cluster-brokers-1.my-cluster-kafka-brokers.my-namespace.svc")
+		_ = binary.Write(response, binary.BigEndian, int32(9092))
+		writeTestString(response, "zone-a")
+		writeTestString(response, "cluster-id")
+		_ = binary.Write(response, binary.BigEndian, []int32{0, 0}) // controller ID, topics
+
+		_ = binary.Write(server, binary.BigEndian, int32(response.Len()))
+		_, err := server.Write(response.Bytes())
+		serverErr <- err
+	}()
+
+	brokers, err := requestBrokers(client)
+
+	require.NoError(t, err)
+	require.NoError(t, <-serverErr)
+	assert.Equal(t, []protocol.Broker{
+		{NodeID: 0, Host: "my-cluster-brokers-0.my-cluster-kafka-brokers.my-namespace.svc", Port: 9092},
+		{NodeID: 1, Host: "my-cluster-brokers-1.my-cluster-kafka-brokers.my-namespace.svc", Port: 9092},
+	}, brokers)
+}
+
+func writeTestString(buffer *bytes.Buffer, value string) {
+	_ = binary.Write(buffer, binary.BigEndian, int16(len(value)))
+	buffer.WriteString(value)
+}
+
+func TestDecodeBrokersRejectsUnexpectedCorrelationId(t *testing.T) {
+	response := new(bytes.Buffer)
+	_ = binary.Write(response, binary.BigEndian, []int32{7, 0, 0})
+
+	_, err := decodeBrokers(response.Bytes(), 1)
+	require.EqualError(t, err, "unexpected correlation ID 7 in the Metadata response")
+}
+
+func TestDecodeBrokersRejectsTruncatedResponse(t *testing.T) {
+	response := new(bytes.Buffer)
+	_ = binary.Write(response, binary.BigEndian, []int32{1, 0, 1, 0})
+
+	_, err := decodeBrokers(response.Bytes(), 1)
+	require.EqualError(t, err, "failed to decode the Metadata response: malformed Kafka message")
+}
+
+func TestReconcileNodes(t *testing.T) {
+	known := map[int32]string{0: "my-cluster-a-0", 1: "my-cluster-a-1", 2: "my-cluster-a-2"}
+	brokers := []protocol.Broker{
+		{NodeID: 0, Host: "my-cluster-a-0.my-cluster-kafka-brokers.my-namespace.svc"},
+		{NodeID: 1, Host: "10.0.0.1"},
+		{NodeID: 3, Host: "my-cluster-b-3.my-cluster-kafka-brokers.my-namespace.svc"},
+		{NodeID: 4, Host: "10.0.0.4"},
+	}
+	pods := map[string]bool{"my-cluster-a-0": true, "my-cluster-a-1": true, "my-cluster-b-3": true}
+
+	nodes, err := reconcileNodes(known, brokers, func(podName string) bool { return pods[podName] })
+
+	require.NoError(t, err)
+	assert.Equal(t, map[int32]string{0: "my-cluster-a-0", 1: "my-cluster-a-1", 3: "my-cluster-b-3"}, nodes)
+}
+
+func TestReconcileNodesWithoutMatchingPods(t *testing.T) {
+	_, err := reconcileNodes(map[int32]string{}, []protocol.Broker{{NodeID: 0, Host: "10.0.0.1"}}, func(string) bool { return false })
+	require.EqualError(t, err, "no Kafka nodes were discovered")
+}
@@ -0,0 +1,348 @@
+/*
+Copyright © 2025 Jakub Scholz
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kekspose
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	keks2 "github.com/scholzj/kekspose/pkg/kekspose/keks"
+	strimzi "github.com/scholzj/strimzi-go/pkg/client/clientset/versioned"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+type checkStatus string
+
+const (
+	checkOK   checkStatus = "OK"
+	checkWarn checkStatus = "WARN"
+	checkFail checkStatus = "FAIL"
+)
+
+// doctor collects and prints the results of the individual preflight checks.
+type doctor struct {
+	out      io.Writer
+	failures int
+}
+
+func (d *doctor) report(status checkStatus, check string, format string, args ...any) {
+	if status == checkFail {
+		d.failures++
+	}
+
+	_, _ = fmt.Fprintf(d.out, "[%-4s] %s: %s\n", status, check, fmt.Sprintf(format, args...))
+}
+
+// Doctor runs the preflight diagnostics for everything ExposeKafka needs and prints each result to
+// out. It returns an error when any of the checks failed.
+func (k *Kekspose) Doctor(out io.Writer) error {
+	d := &doctor{out: out}
+
+	k.resolveKubeConfigPath()
+	clientConfig := k.newClientConfig()
+
+	rawConfig, err := clientConfig.RawConfig()
+	if err != nil {
+		d.report(checkFail, "Kubeconfig", "failed to load kubeconfig %s: %v", k.KubeConfigPath, err)
+		return d.result()
+	}
+
+	kubeconfig, err := clientConfig.ClientConfig()
+	if err != nil {
+		d.report(checkFail, "Kubeconfig", "failed to create Kubernetes client configuration: %v", err)
+		return d.result()
+	}
+	kubeconfig.WarningHandlerWithContext = rest.NoWarnings{}
+
+	contextName := rawConfig.CurrentContext
+	if k.Context != "" {
+		contextName = k.Context
+	}
+	d.report(checkOK, "Kubeconfig", "using context %s with API server %s from %s", contextName, kubeconfig.Host, k.KubeConfigPath)
+
+	if k.needsDefaultNamespace() {
+		if err := k.resolveNamespace(); err != nil {
+			d.report(checkFail, "Namespace", "failed to determine the namespace: %v", err)
+			return d.result()
+		}
+		d.report(checkOK, "Namespace", "using namespace %s", k.Namespace)
+	}
+
+	kubeclient, err := kubernetes.NewForConfig(kubeconfig)
+	if err != nil {
+		d.report(checkFail, "Kubernetes client", "failed to create Kubernetes client: %v", err)
+		return d.result()
+	}
+
+	strimziclient, err := strimzi.NewForConfig(kubeconfig)
+	if err != nil {
+		d.report(checkFail, "Strimzi client", "failed to create Strimzi client: %v", err)
+		return d.result()
+	}
+
+	k.diagnose(d, kubeclient, strimziclient)
+
+	return d.result()
+}
+
+func (d *doctor) result() error {
+	if d.failures > 0 {
+		return fmt.Errorf("%d preflight check(s) failed", d.failures)
+	}
+
+	return nil
+}
+
+// diagnose runs the checks that need the Kubernetes and Strimzi clients over the same Kafka clusters,
+// listeners, and HTTP APIs as ExposeKafka. Checks that depend on an earlier failed check are skipped,
+// because their results would be only confusing noise.
+func (k *Kekspose) diagnose(d *doctor, kubeclient kubernetes.Interface, strimziclient strimzi.Interface) {
+	var exposures []*exposure
+	if k.PodSelector != "" {
+		exposures = k.diagnosePods(d, kubeclient)
+	} else {
+		exposures = k.diagnoseClusters(d, kubeclient, strimziclient)
+	}
+
+	if len(exposures) == 0 {
+		return
+	}
+
+	nextPort := k.StartingPort
+	for _, e := range exposures {
+		for _, nodeId := range sortedNodeIDs(e.PortMapping) {
+			checkLocalPort(d, e.PortMapping[nodeId], fmt.Sprintf("node %d of Kafka cluster %s", nodeId, e.Name()))
+		}
+		nextPort = max(nextPort, maxPort(e.PortMapping)+1)
+	}
+
+	k.diagnoseEndpoints(d, kubeclient, strimziclient, exposures, nextPort)
+}
+
+// diagnoseClusters checks the Strimzi-managed Kafka clusters and returns their listeners with the local
+// ports assigned the same way as ExposeKafka assigns them.
+func (k *Kekspose) diagnoseClusters(d *doctor, kubeclient kubernetes.Interface, strimziclient strimzi.Interface) []*exposure {
+	references, err := k.clusterReferences()
+	if err != nil {
+		d.report(checkFail, "Kafka cluster", "%v", err)
+		return nil
+	}
+
+	checkedNamespaces := make(map[string]bool)
+	exposures := make([]*exposure, 0, len(references))
+	nextPort := k.StartingPort
+
+	for _, reference := range references {
+		if !checkedNamespaces[reference.Namespace] {
+			checkedNamespaces[reference.Namespace] = true
+
+			k.checkAccess(d, kubeclient, reference.Namespace, "get", "kafka.strimzi.io", "kafkas", "")
+			k.checkAccess(d, kubeclient, reference.Namespace, "list", "kafka.strimzi.io", "kafkanodepools", "")
+			k.checkAccess(d, kubeclient, reference.Namespace, "create", "", "pods", "portforward")
+			k.checkEndpointAccess(d, kubeclient, reference.Namespace)
+		}
+
+		for _, keks := range k.diagnoseCluster(d, kubeclient, strimziclient, reference) {
+			portMapping := preparePortMapping(keks, nextPort)
+			nextPort = maxPort(portMapping) + 1
+
+			exposures = append(exposures, &exposure{clusterReference: reference, Keks: keks, PortMapping: portMapping})
+		}
+	}
+
+	return exposures
+}
+
+// diagnosePods checks the brokers of the Kafka cluster not managed by Strimzi which are selected by the
+// pod selector.
+func (k *Kekspose) diagnosePods(d *doctor, kubeclient kubernetes.Interface) []*exposure {
+	k.checkAccess(d, kubeclient, k.Namespace, "list", "", "pods", "")
+	k.checkAccess(d, kubeclient, k.Namespace, "create", "", "pods", "portforward")
+
+	reference := clusterReference{Namespace: k.Namespace, PodSelector: k.PodSelector}
+
+	keks, err := keks2.BakeKeksFromPods(kubeclient, k.Namespace, k.PodSelector, k.PodPort, k.PodTLS, k.NodeIdRule, k.AllowUnready)
+	if err != nil {
+		d.report(checkFail, "Kafka pods", "%v", err)
+		return nil
+	}
+	d.report(checkOK, "Kafka pods", "found %d Kafka node(s) with pod selector %s in namespace %s", len(keks.Nodes), k.PodSelector, k.Namespace)
+
+	for _, nodeId := range sortedNodeIDs(keks.Nodes) {
+		checkPod(d, kubeclient, k.Namespace, "Broker", keks.Nodes[nodeId], fmt.Sprintf("node %d", nodeId))
+	}
+
+	return []*exposure{{clusterReference: reference, Keks: keks, PortMapping: preparePortMapping(keks, k.StartingPort)}}
+}
+
+// diagnoseEndpoints checks the HTTP APIs which are forwarded together with the Kafka clusters. They
+// use the local ports following the ports of the Kafka clusters.
+func (k *Kekspose) diagnoseEndpoints(d *doctor, kubeclient kubernetes.Interface, strimziclient strimzi.Interface, exposures []*exposure, nextPort uint32) {
+	seen := make(map[clusterReference]bool, len(exposures))
+
+	for i, e := range exposures {
+		if seen[e.clusterReference] {
+			continue
+		}
+		seen[e.clusterReference] = true
+
+		endpoints, err := k.findEndpoints(strimziclient, kubeclient, e.clusterReference, i == 0)
+		if err != nil {
+			d.report(checkFail, "HTTP API", "%v", err)
+			continue
+		}
+
+		for _, endpoint := range endpoints {
+			name := fmt.Sprintf("%s %s", endpoint.Kind, endpoint.Name)
+			d.report(checkOK, "HTTP API", "%s in namespace %s will be forwarded from pod %s on port %d", name, endpoint.Namespace, endpoint.PodName, endpoint.Port)
+
+			checkPod(d, kubeclient, endpoint.Namespace, endpoint.Kind, endpoint.PodName, name)
+			checkLocalPort(d, nextPort, name)
+			nextPort++
+		}
+	}
+}
+
+// checkEndpointAccess checks the access rights needed to find the enabled HTTP APIs.
+func (k *Kekspose) checkEndpointAccess(d *doctor, kubeclient kubernetes.Interface, namespace string) {
+	if k.KafkaConnect {
+		k.checkAccess(d, kubeclient, namespace, "list", "kafka.strimzi.io", "kafkaconnects", "")
+	}
+
+	if k.KafkaBridge {
+		k.checkAccess(d, kubeclient, namespace, "list", "kafka.strimzi.io", "kafkabridges", "")
+	}
+
+	if k.CruiseControl {
+		k.checkAccess(d, kubeclient, namespace, "get", "", "secrets", "")
+	}
+
+	if k.KafkaConnect || k.KafkaBridge || k.CruiseControl || k.SchemaRegistrySelector != "" {
+		k.checkAccess(d, kubeclient, namespace, "list", "", "pods", "")
+	}
+}
+
+func checkLocalPort(d *doctor, port uint32, target string) {
+	if err := checkPortAvailable(port); err != nil {
+		d.report(checkFail, "Local port", "port %d for %s is not available: %v", port, target, err)
+	} else {
+		d.report(checkOK, "Local port", "port %d for %s is available", port, target)
+	}
+}
+
+// diagnoseCluster checks the Kafka cluster, its listeners, and its broker pods. It returns the cluster
+// details for each listener when the listeners and nodes were found.
+func (k *Kekspose) diagnoseCluster(d *doctor, kubeclient kubernetes.Interface, strimziclient strimzi.Interface, reference clusterReference) []*keks2.Keks {
+	kafka, err := strimziclient.KafkaV1().Kafkas(reference.Namespace).Get(context.TODO(), reference.ClusterName, v1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			d.report(checkFail, "Kafka cluster", "Kafka cluster %s was not found in namespace %s", reference.ClusterName, reference.Namespace)
+		} else {
+			d.report(checkFail, "Kafka cluster", "failed to get Kafka cluster %s in namespace %s: %v", reference.ClusterName, reference.Namespace, err)
+		}
+		return nil
+	}
+
+	if keks2.IsKafkaReady(kafka) {
+		d.report(checkOK, "Kafka cluster", "Kafka cluster %s in namespace %s is ready", reference.ClusterName, reference.Namespace)
+	} else if k.AllowUnready {
+		d.report(checkWarn, "Kafka cluster", "Kafka cluster %s in namespace %s is not ready (allowed by --allow-unready)", reference.ClusterName, reference.Namespace)
+	} else {
+		d.report(checkFail, "Kafka cluster", "Kafka cluster %s in namespace %s is not ready. Use --allow-unready to override this check", reference.ClusterName, reference.Namespace)
+	}
+
+	// The readiness was already reported above, so it is overridden here to check the listener and nodes regardless
+	kekses, err := keks2.BakeKeksForListeners(strimziclient, reference.Namespace, reference.ClusterName, k.ListenerNames, true, k.AllowInsecureTLS)
+	if err != nil {
+		d.report(checkFail, "Listener and nodes", "%v", err)
+		return nil
+	}
+
+	for _, keks := range kekses {
+		if keks.TLS {
+			d.report(checkWarn, "Listener", "listener %s of Kafka cluster %s on port %d uses TLS (certificate verification will be disabled) and authentication %s", keks.ListenerName, reference.ClusterName, keks.Port, keks.Authentication)
+		} else {
+			d.report(checkOK, "Listener", "listener %s of Kafka cluster %s on port %d without TLS and with authentication %s", keks.ListenerName, reference.ClusterName, keks.Port, keks.Authentication)
+		}
+	}
+
+	// All listeners share the same nodes
+	for _, nodeId := range sortedNodeIDs(kekses[0].Nodes) {
+		checkPod(d, kubeclient, reference.Namespace, "Broker", kekses[0].Nodes[nodeId], fmt.Sprintf("node %d", nodeId))
+	}
+
+	return kekses
+}
+
+func (k *Kekspose) checkAccess(d *doctor, kubeclient kubernetes.Interface, namespace string, verb string, group string, resource string, subresource string) {
+	check := fmt.Sprintf("RBAC %s %s", verb, resource)
+	if subresource != "" {
+		check = fmt.Sprintf("RBAC %s %s/%s", verb, resource, subresource)
+	}
+
+	review := &authorizationv1.SelfSubjectAccessReview{
+		Spec: authorizationv1.SelfSubjectAccessReviewSpec{
+			ResourceAttributes: &authorizationv1.ResourceAttributes{
+				Namespace:   namespace,
+				Verb:        verb,
+				Group:       group,
+				Resource:    resource,
+				Subresource: subresource,
+			},
+		},
+	}
+
+	result, err := kubeclient.AuthorizationV1().SelfSubjectAccessReviews().Create(context.TODO(), review, v1.CreateOptions{})
+	if err != nil {
+		d.report(checkWarn, check, "failed to verify the access rights: %v", err)
+	} else if result.Status.Allowed {
+		d.report(checkOK, check, "allowed in namespace %s", namespace)
+	} else if result.Status.Reason != "" {
+		d.report(checkFail, check, "not allowed in namespace %s: %s", namespace, result.Status.Reason)
+	} else {
+		d.report(checkFail, check, "not allowed in namespace %s", namespace)
+	}
+}
+
+// checkPod checks that the pod forwarded for the target is running and ready.
+func checkPod(d *doctor, kubeclient kubernetes.Interface, namespace string, role string, podName string, target string) {
+	check := fmt.Sprintf("%s pod %s", role, podName)
+
+	pod, err := kubeclient.CoreV1().Pods(namespace).Get(context.TODO(), podName, v1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			d.report(checkFail, check, "pod for %s was not found", target)
+		} else {
+			d.report(checkFail, check, "failed to get pod for %s: %v", target, err)
+		}
+		return
+	}
+
+	if pod.Status.Phase != corev1.PodRunning {
+		d.report(checkFail, check, "pod for %s is in phase %s", target, pod.Status.Phase)
+	} else if !keks2.IsPodReady(pod) {
+		d.report(checkFail, check, "pod for %s is running, but not ready", target)
+	} else {
+		d.report(checkOK, check, "pod for %s is running and ready", target)
+	}
+}
@@ -0,0 +1,168 @@
+package kekspose
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"strconv"
+	"testing"
+
+	kafkav1 "github.com/scholzj/strimzi-go/pkg/apis/kafka.strimzi.io/v1"
+	strimzifake "github.com/scholzj/strimzi-go/pkg/client/clientset/versioned/fake"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestDiagnoseReportsEachCheck(t *testing.T) {
+	strimziclient := strimzifake.NewSimpleClientset()
+	createReadyKafka(t, strimziclient, "my-cluster", kafkav1.GenericKafkaListener{Name: "plain", Port: 9092})
+	createBrokerNodePool(t, strimziclient, "my-cluster", "pool-a", 0, 1)
+
+	kubeclient := fake.NewSimpleClientset(
+		brokerPod("my-cluster-pool-a-0", corev1.PodRunning, corev1.ConditionTrue),
+		brokerPod("my-cluster-pool-a-1", corev1.PodRunning, corev1.ConditionFalse),
+	)
+	kubeclient.PrependReactor("create", "selfsubjectaccessreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		review := action.(k8stesting.CreateAction).GetObject().(*authorizationv1.SelfSubjectAccessReview)
+		review.Status.Allowed = review.Spec.ResourceAttributes.Resource != "pods"
+		return true, review, nil
+	})
+
+	// Occupy the port for the second node
+	listener, err := net.Listen("tcp4", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	_, port, _ := net.SplitHostPort(listener.Addr().String())
+	busyPort, _ := strconv.ParseUint(port, 10, 32)
+
+	var out bytes.Buffer
+	d := &doctor{out: &out}
+	k := Kekspose{Namespace: "my-namespace", ClusterNames: []string{"my-cluster"}, StartingPort: uint32(busyPort) - 1}
+	k.diagnose(d, kubeclient, strimziclient)
+
+	assert.Contains(t, out.String(), "[OK  ] RBAC get kafkas: allowed in namespace my-namespace")
+	assert.Contains(t, out.String(), "[OK  ] RBAC list kafkanodepools: allowed in namespace my-namespace")
+	assert.Contains(t, out.String(), "[FAIL] RBAC create pods/portforward: not allowed in namespace my-namespace")
+	assert.Contains(t, out.String(), "[OK  ] Kafka cluster: Kafka cluster my-cluster in namespace my-namespace is ready")
+	assert.Contains(t, out.String(), "[OK  ] Listener: listener plain of Kafka cluster my-cluster on port 9092 without TLS and with authentication none")
+	assert.Contains(t, out.String(), "[OK  ] Broker pod my-cluster-pool-a-0: pod for node 0 is running and ready")
+	assert.Contains(t, out.String(), "[FAIL] Broker pod my-cluster-pool-a-1: pod for node 1 is running, but not ready")
+	assert.Contains(t, out.String(), "[FAIL] Local port: port "+port+" for node 1 of Kafka cluster my-cluster is not available")
+	assert.Equal(t, 3, d.failures)
+	assert.EqualError(t, d.result(), "3 preflight check(s) failed")
+}
+
+func TestDiagnoseStopsWhenKafkaIsMissing(t *testing.T) {
+	strimziclient := strimzifake.NewSimpleClientset()
+	kubeclient := fake.NewSimpleClientset()
+
+	var out bytes.Buffer
+	d := &doctor{out: &out}
+	k := Kekspose{Namespace: "my-namespace", ClusterNames: []string{"my-cluster"}, StartingPort: DefaultStartingPort}
+	k.diagnose(d, kubeclient, strimziclient)
+
+	assert.Contains(t, out.String(), "[FAIL] Kafka cluster: Kafka cluster my-cluster was not found in namespace my-namespace")
+	assert.NotContains(t, out.String(), "Listener")
+	assert.NotContains(t, out.String(), "Local port")
+}
+
+func TestDiagnoseWithPodSelector(t *testing.T) {
+	selected := brokerPod("kafka-0", corev1.PodRunning, corev1.ConditionTrue)
+	selected.Labels = map[string]string{"app": "kafka"}
+	kubeclient := fake.NewSimpleClientset(selected)
+	allowAccess(kubeclient)
+
+	var out bytes.Buffer
+	d := &doctor{out: &out}
+	k := Kekspose{Namespace: "my-namespace", PodSelector: "app=kafka", PodPort: 9092, NodeIdRule: "ordinal", StartingPort: freePort(t)}
+	k.diagnose(d, kubeclient, strimzifake.NewSimpleClientset())
+
+	assert.Contains(t, out.String(), "[OK  ] RBAC list pods: allowed in namespace my-namespace")
+	assert.Contains(t, out.String(), "[OK  ] Kafka pods: found 1 Kafka node(s) with pod selector app=kafka in namespace my-namespace")
+	assert.Contains(t, out.String(), "[OK  ] Broker pod kafka-0: pod for node 0 is running and ready")
+	assert.Contains(t, out.String(), "for node 0 of Kafka cluster app=kafka is available")
+	assert.NotContains(t, out.String(), "Kafka cluster:")
+	assert.Equal(t, 0, d.failures)
+
+	k.PodSelector = "app=other"
+	out.Reset()
+	d = &doctor{out: &out}
+	k.diagnose(d, kubeclient, strimzifake.NewSimpleClientset())
+
+	assert.Contains(t, out.String(), "[FAIL] Kafka pods: no Kafka pods to expose were found with label selector app=other in namespace my-namespace")
+	assert.NotContains(t, out.String(), "Local port")
+}
+
+func TestDiagnoseChecksEndpoints(t *testing.T) {
+	strimziclient := strimzifake.NewSimpleClientset(&kafkav1.KafkaBridge{
+		ObjectMeta: metav1.ObjectMeta{Name: "my-bridge", Namespace: "my-namespace"},
+		Spec:       &kafkav1.KafkaBridgeSpec{BootstrapServers: "my-cluster-kafka-bootstrap:9092"},
+		Status:     &kafkav1.KafkaBridgeStatus{Conditions: []kafkav1.Condition{{Type: "Ready", Status: "True"}}},
+	})
+	createReadyKafka(t, strimziclient, "my-cluster", kafkav1.GenericKafkaListener{Name: "plain", Port: 9092})
+	createBrokerNodePool(t, strimziclient, "my-cluster", "pool-a", 0)
+
+	bridgePod := brokerPod("my-bridge-bridge-abc", corev1.PodRunning, corev1.ConditionTrue)
+	bridgePod.Labels = map[string]string{"strimzi.io/cluster": "my-bridge", "strimzi.io/kind": "KafkaBridge"}
+	kubeclient := fake.NewSimpleClientset(brokerPod("my-cluster-pool-a-0", corev1.PodRunning, corev1.ConditionTrue), bridgePod)
+	allowAccess(kubeclient)
+
+	var out bytes.Buffer
+	d := &doctor{out: &out}
+	k := Kekspose{Namespace: "my-namespace", ClusterNames: []string{"my-cluster"}, KafkaBridge: true, StartingPort: freePort(t)}
+	k.diagnose(d, kubeclient, strimziclient)
+
+	assert.Contains(t, out.String(), "[OK  ] RBAC list kafkabridges: allowed in namespace my-namespace")
+	assert.Contains(t, out.String(), "[OK  ] HTTP API: KafkaBridge my-bridge in namespace my-namespace will be forwarded from pod my-bridge-bridge-abc on port 8080")
+	assert.Contains(t, out.String(), "[OK  ] KafkaBridge pod my-bridge-bridge-abc: pod for KafkaBridge my-bridge is running and ready")
+	assert.Contains(t, out.String(), "Local port: port "+strconv.FormatUint(uint64(k.StartingPort+1), 10)+" for KafkaBridge my-bridge is")
+}
+
+func allowAccess(kubeclient *fake.Clientset) {
+	kubeclient.PrependReactor("create", "selfsubjectaccessreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		review := action.(k8stesting.CreateAction).GetObject().(*authorizationv1.SelfSubjectAccessReview)
+		review.Status.Allowed = true
+		return true, review, nil
+	})
+}
+
+// freePort finds a local port which is likely to stay available, together with the port after it, for
+// the duration of the test.
+func freePort(t *testing.T) uint32 {
+	t.Helper()
+
+	listener, err := net.Listen("tcp4", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	return uint32(listener.Addr().(*net.TCPAddr).Port)
+}
+
+func createBrokerNodePool(t *testing.T, client *strimzifake.Clientset, clusterName string, name string, nodeIds ...int32) {
+	t.Helper()
+
+	nodePool := &kafkav1.KafkaNodePool{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "my-namespace", Labels: map[string]string{"strimzi.io/cluster": clusterName}},
+		Spec:       &kafkav1.KafkaNodePoolSpec{Replicas: int32(len(nodeIds)), Roles: []kafkav1.ProcessRoles{kafkav1.BROKER_PROCESSROLES}},
+		Status:     &kafkav1.KafkaNodePoolStatus{NodeIds: nodeIds},
+	}
+
+	_, err := client.KafkaV1().KafkaNodePools("my-namespace").Create(context.TODO(), nodePool, metav1.CreateOptions{})
+	require.NoError(t, err)
+}
+
+func brokerPod(name string, phase corev1.PodPhase, ready corev1.ConditionStatus) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "my-namespace"},
+		Status: corev1.PodStatus{
+			Phase:      phase,
+			Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: ready}},
+		},
+	}
+}
@@ -0,0 +1,55 @@
+/*
+Copyright © 2025 Jakub Scholz
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kekspose
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// drainPortForwarders lets the port forwarders finish the requests in flight before they are stopped.
+// It returns once all forwarders are drained, when the drain timeout expires, or when the force
+// context is cancelled by a second shutdown signal.
+func (k *Kekspose) drainPortForwarders(force context.Context, forwarders []*PortForwarder) {
+	if k.DrainTimeout <= 0 || len(forwarders) == 0 {
+		return
+	}
+
+	slog.Info("Waiting for the requests in flight to complete. Press Ctrl+C again to stop immediately", "drainTimeout", k.DrainTimeout)
+
+	for _, pf := range forwarders {
+		close(pf.Drain)
+	}
+
+	timeout := time.NewTimer(k.DrainTimeout)
+	defer timeout.Stop()
+
+	for _, pf := range forwarders {
+		select {
+		case <-pf.Done:
+		case <-timeout.C:
+			slog.Warn("Some requests did not complete within the drain timeout, closing their connections", "drainTimeout", k.DrainTimeout)
+			return
+		case <-force.Done():
+			slog.Info("Received second shutdown signal, stopping immediately")
+			return
+		}
+	}
+
+	slog.Info("All connections were drained")
+}
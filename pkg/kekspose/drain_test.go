@@ -0,0 +1,72 @@
+package kekspose
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func drainingForwarders(count int) []*PortForwarder {
+	forwarders := make([]*PortForwarder, 0, count)
+	for range count {
+		forwarders = append(forwarders, &PortForwarder{Drain: make(chan struct{}), Done: make(chan struct{})})
+	}
+	return forwarders
+}
+
+func TestDrainPortForwarders(t *testing.T) {
+	k := &Kekspose{DrainTimeout: 5 * time.Second}
+	forwarders := drainingForwarders(2)
+
+	// The forwarders finish draining once asked to
+	for _, pf := range forwarders {
+		go func(pf *PortForwarder) {
+			<-pf.Drain
+			close(pf.Done)
+		}(pf)
+	}
+
+	start := time.Now()
+	k.drainPortForwarders(context.Background(), forwarders)
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
+
+func TestDrainPortForwardersTimeout(t *testing.T) {
+	k := &Kekspose{DrainTimeout: 50 * time.Millisecond}
+	forwarders := drainingForwarders(1)
+
+	k.drainPortForwarders(context.Background(), forwarders)
+
+	select {
+	case <-forwarders[0].Drain:
+	default:
+		t.Fatal("the forwarder was not asked to drain")
+	}
+}
+
+func TestDrainPortForwardersForced(t *testing.T) {
+	k := &Kekspose{DrainTimeout: time.Minute}
+	forwarders := drainingForwarders(1)
+
+	force, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	start := time.Now()
+	k.drainPortForwarders(force, forwarders)
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
+
+func TestDrainPortForwardersDisabled(t *testing.T) {
+	k := &Kekspose{}
+	forwarders := drainingForwarders(1)
+
+	k.drainPortForwarders(context.Background(), forwarders)
+
+	select {
+	case <-forwarders[0].Drain:
+		t.Fatal("the forwarder should not be drained")
+	default:
+	}
+}
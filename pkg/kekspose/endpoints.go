@@ -0,0 +1,150 @@
+/*
+Copyright © 2025 Jakub Scholz
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kekspose
+
+import (
+	"fmt"
+	"log/slog"
+	"net"
+	"net/url"
+	"strconv"
+
+	keks2 "github.com/scholzj/kekspose/pkg/kekspose/keks"
+	strimzi "github.com/scholzj/strimzi-go/pkg/client/clientset/versioned"
+	"k8s.io/client-go/kubernetes"
+)
+
+// endpointExposure is one forwarded HTTP API of a component connected to an exposed Kafka cluster,
+// such as the Kafka Connect REST API, the Kafka Bridge HTTP API, or the Cruise Control REST API. It
+// is forwarded without the Kafka proxy.
+type endpointExposure struct {
+	keks2.Endpoint
+	LocalPort uint32
+	// ForwardPort is the local port of the port forwarder. It differs from LocalPort when the
+	// requests pass through the local authentication proxy first.
+	ForwardPort uint32
+}
+
+// injectsAuth returns true when the requests to the endpoint pass through the local proxy adding the
+// credentials.
+func (e *endpointExposure) injectsAuth() bool {
+	return e.ForwardPort != e.LocalPort
+}
+
+// URL returns the local URL of the forwarded HTTP API.
+func (e *endpointExposure) URL() string {
+	return (&url.URL{Scheme: e.Scheme, Host: net.JoinHostPort("localhost", strconv.FormatUint(uint64(e.LocalPort), 10))}).String()
+}
+
+// bakeEndpoints finds the enabled components connected to the exposed Kafka clusters and assigns each
+// of them a local port following the port ranges of the Kafka clusters.
+func (k *Kekspose) bakeEndpoints(strimziclient strimzi.Interface, kubeclient kubernetes.Interface, exposures []*exposure) ([]*endpointExposure, error) {
+	endpoints := make([]*endpointExposure, 0)
+	seen := make(map[clusterReference]bool, len(exposures))
+	nextPort := k.StartingPort
+
+	for _, e := range exposures {
+		nextPort = max(nextPort, maxPort(e.PortMapping)+1)
+	}
+
+	for i, e := range exposures {
+		// Multiple listeners of the same cluster share the same components
+		if seen[e.clusterReference] {
+			continue
+		}
+		seen[e.clusterReference] = true
+
+		// The schema registry is shared by all exposed clusters, so it is looked up only next to the first one
+		found, err := k.findEndpoints(strimziclient, kubeclient, e.clusterReference, i == 0)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, endpoint := range found {
+			localPort, err := k.resolvePort(nextPort)
+			if err != nil {
+				return nil, fmt.Errorf("failed to prepare the local ports: %w", err)
+			}
+			nextPort = localPort + 1
+
+			forwardPort := localPort
+			if endpoint.Username != "" && k.CruiseControlAuth {
+				forwardPort, err = ephemeralPort()
+				if err != nil {
+					return nil, fmt.Errorf("failed to prepare the local ports: %w", err)
+				}
+			}
+
+			slog.Info("Port mapping", "kind", endpoint.Kind, "name", endpoint.Name, "podName", endpoint.PodName, "localPort", localPort)
+			endpoints = append(endpoints, &endpointExposure{Endpoint: endpoint, LocalPort: localPort, ForwardPort: forwardPort})
+		}
+	}
+
+	return endpoints, nil
+}
+
+// findEndpoints finds the components enabled in the options which are connected to the Kafka cluster.
+// The schema registry is looked up only when schemaRegistry is true.
+func (k *Kekspose) findEndpoints(strimziclient strimzi.Interface, kubeclient kubernetes.Interface, reference clusterReference, schemaRegistry bool) ([]keks2.Endpoint, error) {
+	endpoints := make([]keks2.Endpoint, 0)
+
+	if k.KafkaConnect {
+		connects, err := keks2.FindKafkaConnects(strimziclient, kubeclient, reference.Namespace, reference.ClusterName, k.AllowUnready)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find the Kafka Connect clusters: %w", err)
+		}
+
+		endpoints = append(endpoints, connects...)
+	}
+
+	if k.KafkaBridge {
+		bridges, err := keks2.FindKafkaBridges(strimziclient, kubeclient, reference.Namespace, reference.ClusterName, k.AllowUnready)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find the Kafka Bridges: %w", err)
+		}
+
+		endpoints = append(endpoints, bridges...)
+	}
+
+	if k.CruiseControl {
+		cruiseControl, err := keks2.FindCruiseControl(strimziclient, kubeclient, reference.Namespace, reference.ClusterName, k.AllowUnready)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find Cruise Control: %w", err)
+		}
+
+		if cruiseControl != nil {
+			endpoints = append(endpoints, *cruiseControl)
+		} else {
+			slog.Warn("Cruise Control is not available for the Kafka cluster", "clusterName", reference.ClusterName, "namespace", reference.Namespace)
+		}
+	}
+
+	if schemaRegistry && k.SchemaRegistrySelector != "" {
+		registry, err := keks2.FindSchemaRegistry(kubeclient, reference.Namespace, k.SchemaRegistrySelector, k.SchemaRegistryPort)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find the schema registry: %w", err)
+		}
+
+		if registry != nil {
+			endpoints = append(endpoints, *registry)
+		} else {
+			slog.Warn("Schema registry does not have any ready pod, the records will not be decoded with their schemas", "labelSelector", k.SchemaRegistrySelector, "namespace", reference.Namespace)
+		}
+	}
+
+	return endpoints, nil
+}
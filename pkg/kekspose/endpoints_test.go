@@ -0,0 +1,61 @@
+package kekspose
+
+import (
+	"fmt"
+	"testing"
+
+	keks2 "github.com/scholzj/kekspose/pkg/kekspose/keks"
+	kafkav1 "github.com/scholzj/strimzi-go/pkg/apis/kafka.strimzi.io/v1"
+	strimzifake "github.com/scholzj/strimzi-go/pkg/client/clientset/versioned/fake"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestBakeEndpointsFollowsKafkaPorts(t *testing.T) {
+	strimziclient := strimzifake.NewSimpleClientset(&kafkav1.KafkaConnect{
+		ObjectMeta: metav1.ObjectMeta{Name: "my-connect", Namespace: "my-namespace"},
+		Spec:       &kafkav1.KafkaConnectSpec{BootstrapServers: "my-cluster-kafka-bootstrap:9092"},
+		Status:     &kafkav1.KafkaConnectStatus{Conditions: []kafkav1.Condition{{Type: "Ready", Status: "True"}}},
+	}, &kafkav1.KafkaBridge{
+		ObjectMeta: metav1.ObjectMeta{Name: "my-bridge", Namespace: "my-namespace"},
+		Spec:       &kafkav1.KafkaBridgeSpec{BootstrapServers: "my-cluster-kafka-bootstrap:9092"},
+		Status:     &kafkav1.KafkaBridgeStatus{Conditions: []kafkav1.Condition{{Type: "Ready", Status: "True"}}},
+	})
+	kubeclient := fake.NewSimpleClientset(&corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "my-connect-connect-0", Namespace: "my-namespace", Labels: map[string]string{"strimzi.io/cluster": "my-connect", "strimzi.io/kind": "KafkaConnect"}},
+		Status:     corev1.PodStatus{Phase: corev1.PodRunning, Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}}},
+	}, &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "my-bridge-bridge-abc", Namespace: "my-namespace", Labels: map[string]string{"strimzi.io/cluster": "my-bridge", "strimzi.io/kind": "KafkaBridge"}},
+		Status:     corev1.PodStatus{Phase: corev1.PodRunning, Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}}},
+	})
+
+	startingPort := occupyPort(t) + 1
+	reference := clusterReference{Namespace: "my-namespace", ClusterName: "my-cluster"}
+	exposures := []*exposure{
+		{clusterReference: reference, Keks: &keks2.Keks{ListenerName: "plain"}, PortMapping: map[int32]uint32{0: startingPort, 1: startingPort + 1}},
+		{clusterReference: reference, Keks: &keks2.Keks{ListenerName: "scram"}, PortMapping: map[int32]uint32{0: startingPort + 2, 1: startingPort + 3}},
+	}
+
+	k := Kekspose{StartingPort: startingPort, KafkaConnect: true, KafkaBridge: true}
+	endpoints, err := k.bakeEndpoints(strimziclient, kubeclient, exposures)
+
+	require.NoError(t, err)
+	require.Len(t, endpoints, 2)
+	assert.Equal(t, "my-connect-connect-0", endpoints[0].PodName)
+	assert.Equal(t, startingPort+4, endpoints[0].LocalPort)
+	assert.Equal(t, fmt.Sprintf("http://localhost:%d", startingPort+4), endpoints[0].URL())
+	assert.Equal(t, "my-bridge-bridge-abc", endpoints[1].PodName)
+	assert.Equal(t, keks2.KafkaBridgeDefaultHttpPort, endpoints[1].Port)
+	assert.Equal(t, startingPort+5, endpoints[1].LocalPort)
+}
+
+func TestBakeEndpointsWithoutComponents(t *testing.T) {
+	k := Kekspose{StartingPort: DefaultStartingPort}
+	endpoints, err := k.bakeEndpoints(strimzifake.NewSimpleClientset(), fake.NewSimpleClientset(), []*exposure{{clusterReference: clusterReference{Namespace: "my-namespace", ClusterName: "my-cluster"}}})
+
+	require.NoError(t, err)
+	assert.Empty(t, endpoints)
+}
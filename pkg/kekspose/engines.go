@@ -0,0 +1,113 @@
+/*
+Copyright © 2025 Jakub Scholz
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kekspose
+
+import (
+	"maps"
+	"sync"
+	"sync/atomic"
+
+	"github.com/scholzj/proksy"
+)
+
+// proxyEngines keeps the proxy engines of the forwarded Kafka ports, so that they can be rebuilt when
+// the RPC logging options or the port mappings change at runtime. All engines are replaced at once, so
+// the new connections never see a mix of the old and the new options. The lock also guards the logging
+// options and the port mappings of the exposures once the port forwarding is running.
+type proxyEngines struct {
+	lock    sync.Mutex
+	nextId  int
+	builds  map[int]func() *proksy.Engine
+	current atomic.Pointer[map[int]*proksy.Engine]
+}
+
+// proxyEngine is the engine of one forwarded port. The new connections use the engine which is
+// current when they are accepted.
+type proxyEngine struct {
+	engines *proxyEngines
+	id      int
+}
+
+// add builds the engine and keeps it for rebuilding later.
+func (p *proxyEngines) add(build func() *proksy.Engine) *proxyEngine {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	engines := make(map[int]*proksy.Engine)
+	if current := p.current.Load(); current != nil {
+		engines = maps.Clone(*current)
+	}
+
+	if p.builds == nil {
+		p.builds = make(map[int]func() *proksy.Engine)
+	}
+
+	id := p.nextId
+	p.nextId++
+
+	engines[id] = build()
+	p.builds[id] = build
+	p.current.Store(&engines)
+
+	return &proxyEngine{engines: p, id: id}
+}
+
+// remove forgets the engines of the ports which are not forwarded anymore, so that they are not
+// rebuilt again.
+func (p *proxyEngines) remove(removed ...*proxyEngine) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	current := p.current.Load()
+	if current == nil {
+		return
+	}
+
+	engines := maps.Clone(*current)
+	for _, e := range removed {
+		delete(p.builds, e.id)
+		delete(engines, e.id)
+	}
+	p.current.Store(&engines)
+}
+
+// update applies the change and replaces all engines with engines rebuilt with it.
+func (p *proxyEngines) update(change func()) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	change()
+
+	engines := make(map[int]*proksy.Engine, len(p.builds))
+	for id, build := range p.builds {
+		engines[id] = build()
+	}
+	p.current.Store(&engines)
+}
+
+// read runs fn with the lock held, so that it sees a consistent configuration.
+func (p *proxyEngines) read(fn func()) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	fn()
+}
+
+// Load returns the current engine.
+func (e *proxyEngine) Load() *proksy.Engine {
+	return (*e.engines.current.Load())[e.id]
+}
@@ -0,0 +1,128 @@
+/*
+Copyright © 2025 Jakub Scholz
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kekspose
+
+import (
+	"cmp"
+	"log/slog"
+	"slices"
+	"sync"
+
+	"github.com/scholzj/go-kafka-protocol/messages"
+	"github.com/scholzj/kekspose/pkg/kekspose/protocol"
+)
+
+// errorKey identifies the errors of one API with one error code.
+type errorKey struct {
+	apiKey int16
+	code   int16
+}
+
+// errorStats counts the error codes in the responses of all connections, so that they can be summarized
+// at the end of the session.
+type errorStats struct {
+	lock   sync.Mutex
+	counts map[errorKey]int
+}
+
+func (s *errorStats) add(apiKey int16, code int16) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.counts == nil {
+		s.counts = make(map[errorKey]int)
+	}
+	s.counts[errorKey{apiKey: apiKey, code: code}]++
+}
+
+// errorCount is the number of the errors of one API with one error code.
+type errorCount struct {
+	errorKey
+	count int
+}
+
+// summary returns the error counts with the most frequent errors first.
+func (s *errorStats) summary() []errorCount {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	counts := make([]errorCount, 0, len(s.counts))
+	for key, count := range s.counts {
+		counts = append(counts, errorCount{errorKey: key, count: count})
+	}
+
+	slices.SortFunc(counts, func(a, b errorCount) int {
+		return cmp.Or(cmp.Compare(b.count, a.count), cmp.Compare(a.apiKey, b.apiKey), cmp.Compare(a.code, b.code))
+	})
+
+	return counts
+}
+
+// logErrorSummary logs the numbers of the errors in the responses during the session by the API and
+// the error code.
+func (k *Kekspose) logErrorSummary() {
+	if !k.LogErrors {
+		return
+	}
+
+	counts := k.errors.summary()
+	if len(counts) == 0 {
+		slog.Info("No error responses during the session")
+		return
+	}
+
+	for _, c := range counts {
+		slog.Warn("Error responses during the session", "api", messages.Name(c.apiKey), "error", protocol.ErrorName(c.code), "errorCode", c.code, "count", c.count)
+	}
+}
+
+// logResponseErrors logs the error codes in the response at the warning level and counts them. The
+// topic IDs are logged with their names when they are known.
+func (c *connectionInspector) logResponseErrors(request *inspectedRequest, frame []byte) {
+	header := request.header
+
+	_, body, err := protocol.ReadResponseHeader(frame, header.APIKey, header.APIVersion)
+	if err != nil {
+		return
+	}
+
+	// Keep the errors decoded before an error
+	errs, _ := protocol.ReadResponseErrors(header, request.group, body)
+	for _, e := range errs {
+		c.errors.add(header.APIKey, e.Code)
+
+		attrs := []any{"api", messages.Name(header.APIKey), correlationIdKey, header.CorrelationID, "error", protocol.ErrorName(e.Code), "errorCode", e.Code}
+		switch {
+		case e.Topic != "":
+			attrs = append(attrs, "topic", e.Topic)
+		case e.TopicID != protocol.UUID{}:
+			if name, found := c.topics.name(e.TopicID); found {
+				attrs = append(attrs, "topic", name)
+			} else {
+				attrs = append(attrs, "topicId", e.TopicID.String())
+			}
+		}
+		if e.Partition >= 0 {
+			attrs = append(attrs, "partition", e.Partition)
+		}
+		if e.Group != "" {
+			attrs = append(attrs, "group", e.Group)
+		}
+
+		c.logger.Warn("Error response", attrs...)
+	}
+}
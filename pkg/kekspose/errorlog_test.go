@@ -0,0 +1,100 @@
+package kekspose
+
+import (
+	"bytes"
+	"encoding/binary"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/scholzj/go-kafka-protocol/messages"
+	keks2 "github.com/scholzj/kekspose/pkg/kekspose/keks"
+	"github.com/scholzj/kekspose/pkg/kekspose/protocol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// noTimestamp is the timestamp -1 as an unsigned integer.
+const noTimestamp = ^uint64(0)
+
+// produceResponse builds a Produce v3 response with one partition of the topic.
+func produceResponse(correlationId int32, topic string, partition int32, code int16) []byte {
+	response := binary.BigEndian.AppendUint32(nil, uint32(correlationId))
+	response = append(response, 0, 0, 0, 1) // One topic
+	response = appendString(response, topic)
+	response = append(response, 0, 0, 0, 1) // One partition
+	response = binary.BigEndian.AppendUint32(response, uint32(partition))
+	response = binary.BigEndian.AppendUint16(response, uint16(code))
+	response = binary.BigEndian.AppendUint64(response, 0)           // Base offset
+	response = binary.BigEndian.AppendUint64(response, noTimestamp) // Log append time
+	return append(response, 0, 0, 0, 0)                             // Throttle time
+}
+
+func newErrorInspector(output *bytes.Buffer, filter rpcFilter, stats *errorStats) *connectionInspector {
+	inspector := &connectionInspector{
+		filter:   filter,
+		errors:   stats,
+		topics:   &topicRegistry{},
+		requests: make(map[int32]*inspectedRequest),
+	}
+	inspector.logger = slog.New(&rpcLogHandler{Handler: slog.NewTextHandler(output, nil), inspector: inspector})
+
+	return inspector
+}
+
+func TestConnectionInspectorLogsErrors(t *testing.T) {
+	var output bytes.Buffer
+	stats := &errorStats{}
+	inspector := newErrorInspector(&output, rpcFilter{ClientIDs: []string{"my-*"}}, stats)
+
+	inspector.Request(produceRequest(7, map[string][]byte{"orders": nil}))
+	inspector.Response(produceResponse(7, "orders", 3, 6))
+
+	heartbeat := appendString(nil, "my-group")
+	heartbeat = append(heartbeat, 0, 0, 0, 1) // Generation
+	heartbeat = appendString(heartbeat, "member-1")
+	inspector.Request(testRequest(protocol.Heartbeat, 0, 8, "my-consumer", heartbeat...))
+	inspector.Response([]byte{0, 0, 0, 8, 0, 27})
+
+	// The responses without errors and the errors of the RPCs which are not logged are not logged
+	inspector.Request(produceRequest(9, map[string][]byte{"orders": nil}))
+	inspector.Response(produceResponse(9, "orders", 3, 0))
+	inspector.Request(testRequest(protocol.Heartbeat, 0, 10, "other-consumer", heartbeat...))
+	inspector.Response([]byte{0, 0, 0, 10, 0, 27})
+
+	lines := strings.Split(strings.TrimSpace(output.String()), "\n")
+	require.Len(t, lines, 2)
+	assert.Contains(t, lines[0], `level=WARN msg="Error response" api=Produce correlationId=7 error=NOT_LEADER_OR_FOLLOWER errorCode=6 topic=orders partition=3`)
+	assert.Contains(t, lines[1], `level=WARN msg="Error response" api=`+messages.Name(protocol.Heartbeat)+` correlationId=8 error=REBALANCE_IN_PROGRESS errorCode=27 group=my-group`)
+
+	assert.Equal(t, []errorCount{
+		{errorKey: errorKey{apiKey: protocol.Produce, code: 6}, count: 1},
+		{errorKey: errorKey{apiKey: protocol.Heartbeat, code: 27}, count: 1},
+	}, stats.summary())
+}
+
+func TestErrorStatsSummary(t *testing.T) {
+	stats := &errorStats{}
+	assert.Empty(t, stats.summary())
+
+	stats.add(protocol.Fetch, 1)
+	stats.add(protocol.Produce, 6)
+	stats.add(protocol.Fetch, 6)
+	stats.add(protocol.Fetch, 6)
+
+	assert.Equal(t, []errorCount{
+		{errorKey: errorKey{apiKey: protocol.Fetch, code: 6}, count: 2},
+		{errorKey: errorKey{apiKey: protocol.Produce, code: 6}, count: 1},
+		{errorKey: errorKey{apiKey: protocol.Fetch, code: 1}, count: 1},
+	}, stats.summary())
+}
+
+func TestInspectConnectionForErrors(t *testing.T) {
+	k := &Kekspose{LogErrors: true}
+	e := &exposure{Keks: &keks2.Keks{}, PortMapping: map[int32]uint32{0: 50000}}
+
+	engine, inspector := k.inspectConnection(slog.Default(), e)
+	assert.NotNil(t, engine)
+	require.NotNil(t, inspector)
+	assert.Same(t, &k.errors, inspector.(*connectionInspector).errors)
+}
@@ -0,0 +1,179 @@
+/*
+Copyright © 2025 Jakub Scholz
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kekspose
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+
+	keks2 "github.com/scholzj/kekspose/pkg/kekspose/keks"
+	strimzi "github.com/scholzj/strimzi-go/pkg/client/clientset/versioned"
+	"k8s.io/client-go/kubernetes"
+)
+
+// clusterReference identifies one Kafka cluster which should be exposed. The Kafka clusters not managed
+// by Strimzi do not have a cluster name and are identified by the label selector of their pods instead.
+type clusterReference struct {
+	Namespace   string
+	ClusterName string
+	PodSelector string
+}
+
+func (r clusterReference) String() string {
+	return r.Namespace + "/" + r.Name()
+}
+
+// Name returns the name identifying the Kafka cluster in the log messages and in the session state.
+func (r clusterReference) Name() string {
+	if r.PodSelector != "" {
+		return r.PodSelector
+	}
+
+	return r.ClusterName
+}
+
+// exposure is one exposed listener of a Kafka cluster together with its local port mapping. Each
+// exposure gets its own range of local ports, so the advertised addresses of different clusters and
+// listeners never overlap.
+type exposure struct {
+	clusterReference
+	Keks        *keks2.Keks
+	PortMapping map[int32]uint32
+}
+
+// clusterReferences parses the configured cluster names. Each name can be qualified with the
+// namespace (<namespace>/<name>). Names without namespace use the Namespace option.
+func (k *Kekspose) clusterReferences() ([]clusterReference, error) {
+	references := make([]clusterReference, 0, len(k.ClusterNames))
+	seen := make(map[clusterReference]bool, len(k.ClusterNames))
+
+	for _, name := range k.ClusterNames {
+		reference := clusterReference{Namespace: k.Namespace, ClusterName: strings.TrimSpace(name)}
+
+		if namespace, clusterName, found := strings.Cut(reference.ClusterName, "/"); found {
+			reference = clusterReference{Namespace: namespace, ClusterName: clusterName}
+		}
+
+		if reference.Namespace == "" || reference.ClusterName == "" || strings.Contains(reference.ClusterName, "/") {
+			return nil, fmt.Errorf("invalid Kafka cluster name %q. Use <name> or <namespace>/<name>", name)
+		}
+
+		if seen[reference] {
+			//goland:noinspection GoErrorStringFormat
+			return nil, fmt.Errorf("Kafka cluster %s in namespace %s is listed more than once", reference.ClusterName, reference.Namespace)
+		}
+		seen[reference] = true
+
+		references = append(references, reference)
+	}
+
+	if len(references) == 0 {
+		return nil, fmt.Errorf("no Kafka cluster to expose was specified")
+	}
+
+	return references, nil
+}
+
+// needsDefaultNamespace returns true when some of the cluster names are not qualified with the
+// namespace and the default namespace is therefore needed.
+func (k *Kekspose) needsDefaultNamespace() bool {
+	if len(k.ClusterNames) == 0 || k.PodSelector != "" {
+		return true
+	}
+
+	for _, name := range k.ClusterNames {
+		if !strings.Contains(name, "/") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// bakeExposures finds all the configured Kafka clusters and their listeners and assigns each of them
+// its own block of local ports, starting from StartingPort. When discover is set, it is used to
+// verify the nodes of each cluster before the ports are assigned.
+func (k *Kekspose) bakeExposures(strimziclient strimzi.Interface, discover func(clusterReference, []*keks2.Keks) error) ([]*exposure, error) {
+	references, err := k.clusterReferences()
+	if err != nil {
+		return nil, err
+	}
+
+	exposures := make([]*exposure, 0, len(references))
+	nextPort := k.StartingPort
+
+	for _, reference := range references {
+		kekses, err := keks2.BakeKeksForListeners(strimziclient, reference.Namespace, reference.ClusterName, k.ListenerNames, k.AllowUnready, k.AllowInsecureTLS)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find the Kafka cluster with a suitable listener: %w", err)
+		}
+
+		if discover != nil {
+			if err := discover(reference, kekses); err != nil {
+				return nil, err
+			}
+		}
+
+		for _, keks := range kekses {
+			if keks.TLS {
+				slog.Warn("Using TLS upstream with certificate verification disabled", "listenerName", keks.ListenerName, "overrideFlag", "--allow-insecure-tls")
+			}
+
+			portMapping, err := k.resolvePortMapping(keks, nextPort)
+			if err != nil {
+				return nil, fmt.Errorf("failed to prepare the local ports: %w", err)
+			}
+			nextPort = maxPort(portMapping) + 1
+
+			exposures = append(exposures, &exposure{clusterReference: reference, Keks: keks, PortMapping: portMapping})
+		}
+	}
+
+	return exposures, nil
+}
+
+// bakePodExposure finds the brokers of a Kafka cluster not managed by Strimzi using the pod label
+// selector instead of the Strimzi resources.
+func (k *Kekspose) bakePodExposure(kubeclient kubernetes.Interface) ([]*exposure, error) {
+	keks, err := keks2.BakeKeksFromPods(kubeclient, k.Namespace, k.PodSelector, k.PodPort, k.PodTLS, k.NodeIdRule, k.AllowUnready)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find the Kafka pods: %w", err)
+	}
+
+	if keks.TLS {
+		slog.Warn("Using TLS upstream with certificate verification disabled", "podSelector", k.PodSelector, "overrideFlag", "--allow-insecure-tls")
+	}
+
+	portMapping, err := k.resolvePortMapping(keks, k.StartingPort)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare the local ports: %w", err)
+	}
+
+	reference := clusterReference{Namespace: k.Namespace, PodSelector: k.PodSelector}
+
+	return []*exposure{{clusterReference: reference, Keks: keks, PortMapping: portMapping}}, nil
+}
+
+func maxPort(portMapping map[int32]uint32) uint32 {
+	var highest uint32
+	for _, port := range portMapping {
+		highest = max(highest, port)
+	}
+
+	return highest
+}
@@ -0,0 +1,84 @@
+package kekspose
+
+import (
+	"testing"
+
+	kafkav1 "github.com/scholzj/strimzi-go/pkg/apis/kafka.strimzi.io/v1"
+	"github.com/scholzj/strimzi-go/pkg/client/clientset/versioned/fake"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClusterReferences(t *testing.T) {
+	k := Kekspose{Namespace: "my-namespace", ClusterNames: []string{"source", "other-namespace/target"}}
+	references, err := k.clusterReferences()
+
+	require.NoError(t, err)
+	assert.Equal(t, []clusterReference{
+		{Namespace: "my-namespace", ClusterName: "source"},
+		{Namespace: "other-namespace", ClusterName: "target"},
+	}, references)
+}
+
+func TestClusterReferencesRejectsInvalidNames(t *testing.T) {
+	k := Kekspose{Namespace: "my-namespace", ClusterNames: []string{"a/b/c"}}
+	_, err := k.clusterReferences()
+	require.EqualError(t, err, `invalid Kafka cluster name "a/b/c". Use <name> or <namespace>/<name>`)
+
+	k = Kekspose{ClusterNames: []string{"source"}}
+	_, err = k.clusterReferences()
+	require.EqualError(t, err, `invalid Kafka cluster name "source". Use <name> or <namespace>/<name>`)
+
+	k = Kekspose{Namespace: "my-namespace", ClusterNames: []string{"source", "my-namespace/source"}}
+	_, err = k.clusterReferences()
+	require.EqualError(t, err, "Kafka cluster source in namespace my-namespace is listed more than once")
+}
+
+func TestNeedsDefaultNamespace(t *testing.T) {
+	assert.True(t, (&Kekspose{}).needsDefaultNamespace())
+	assert.True(t, (&Kekspose{ClusterNames: []string{"ns/a", "b"}}).needsDefaultNamespace())
+	assert.False(t, (&Kekspose{ClusterNames: []string{"ns/a", "ns/b"}}).needsDefaultNamespace())
+}
+
+func TestBakeExposuresAssignsSeparatePortRanges(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	createReadyKafka(t, client, "source", kafkav1.GenericKafkaListener{Name: "plain", Port: 9092})
+	createBrokerNodePool(t, client, "source", "source-brokers", 0, 1, 2)
+	createReadyKafka(t, client, "target", kafkav1.GenericKafkaListener{Name: "plain", Port: 9092})
+	createBrokerNodePool(t, client, "target", "target-brokers", 0, 1)
+
+	k := Kekspose{Namespace: "my-namespace", ClusterNames: []string{"source", "target"}, StartingPort: uint32(occupyPort(t)) + 1}
+	exposures, err := k.bakeExposures(client, nil)
+
+	require.NoError(t, err)
+	require.Len(t, exposures, 2)
+	assert.Equal(t, "source", exposures[0].ClusterName)
+	assert.Equal(t, map[int32]uint32{0: k.StartingPort, 1: k.StartingPort + 1, 2: k.StartingPort + 2}, exposures[0].PortMapping)
+	assert.Equal(t, "target", exposures[1].ClusterName)
+	assert.Equal(t, map[int32]uint32{0: k.StartingPort + 3, 1: k.StartingPort + 4}, exposures[1].PortMapping)
+}
+
+func TestBakeExposuresForMultipleListeners(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	createReadyKafka(t, client, "my-cluster", kafkav1.GenericKafkaListener{Name: "plain", Port: 9092}, kafkav1.GenericKafkaListener{Name: "scram", Port: 9094})
+	createBrokerNodePool(t, client, "my-cluster", "brokers", 0, 1)
+
+	k := Kekspose{Namespace: "my-namespace", ClusterNames: []string{"my-cluster"}, ListenerNames: []string{"plain", "scram"}, StartingPort: uint32(occupyPort(t)) + 1}
+	exposures, err := k.bakeExposures(client, nil)
+
+	require.NoError(t, err)
+	require.Len(t, exposures, 2)
+	assert.Equal(t, "plain", exposures[0].Keks.ListenerName)
+	assert.Equal(t, map[int32]uint32{0: k.StartingPort, 1: k.StartingPort + 1}, exposures[0].PortMapping)
+	assert.Equal(t, "scram", exposures[1].Keks.ListenerName)
+	assert.Equal(t, map[int32]uint32{0: k.StartingPort + 2, 1: k.StartingPort + 3}, exposures[1].PortMapping)
+}
+
+func TestClusterReferenceWithPodSelector(t *testing.T) {
+	reference := clusterReference{Namespace: "kafka", PodSelector: "app=kafka"}
+
+	assert.Empty(t, reference.ClusterName)
+	assert.Equal(t, "app=kafka", reference.Name())
+	assert.Equal(t, "kafka/app=kafka", reference.String())
+	assert.Equal(t, "kafka/my-cluster", clusterReference{Namespace: "kafka", ClusterName: "my-cluster"}.String())
+}
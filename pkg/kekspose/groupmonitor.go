@@ -0,0 +1,360 @@
+/*
+Copyright © 2025 Jakub Scholz
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kekspose
+
+import (
+	"cmp"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/scholzj/kekspose/pkg/kekspose/protocol"
+)
+
+// rebalanceInProgress is the error code returned to the members of a classic group when it rebalances.
+const rebalanceInProgress int16 = 27
+
+// groupKey identifies a consumer group in one of the exposed Kafka clusters.
+type groupKey struct {
+	cluster clusterReference
+	group   string
+}
+
+// groupState is what the group monitor learned about one consumer group. The epoch is the generation
+// of the classic groups or the highest member epoch of the groups using the consumer protocol. The
+// members map the member IDs to their epochs. The rebalance started at rebalanceStart and has not
+// completed yet when it is not zero.
+type groupState struct {
+	protocolType   string
+	consumer       bool
+	epoch          int32
+	members        map[string]int32
+	rebalanceStart time.Time
+	rebalances     int
+	rebalanceTime  time.Duration
+}
+
+// groupMonitor follows the activity of the consumer groups in the JoinGroup, SyncGroup, Heartbeat,
+// LeaveGroup, ConsumerGroupHeartbeat, and OffsetCommit RPCs of all connections. It logs a timeline of
+// the members joining and leaving, the generation changes, the assignments, the rebalances, and the
+// committed offsets of each group.
+type groupMonitor struct {
+	lock   sync.Mutex
+	groups map[groupKey]*groupState
+}
+
+func (m *groupMonitor) group(cluster clusterReference, group string) *groupState {
+	if m.groups == nil {
+		m.groups = make(map[groupKey]*groupState)
+	}
+
+	key := groupKey{cluster: cluster, group: group}
+	state, found := m.groups[key]
+	if !found {
+		state = &groupState{members: make(map[string]int32)}
+		m.groups[key] = state
+	}
+
+	return state
+}
+
+// request follows the group request sent at the given time.
+func (m *groupMonitor) request(logger *slog.Logger, cluster clusterReference, header protocol.RequestHeader, req protocol.GroupRequest, at time.Time) {
+	if req.Group == "" {
+		return
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	state := m.group(cluster, req.Group)
+	logger = logger.With("group", req.Group)
+
+	switch header.APIKey {
+	case protocol.JoinGroup:
+		state.protocolType = req.ProtocolType
+		state.startRebalance(logger, at, "member", req.MemberID, "clientId", header.ClientID)
+	case protocol.LeaveGroup:
+		for _, member := range req.Leaving {
+			logger.Info("Member leaving the group", "member", member, "clientId", header.ClientID)
+			delete(state.members, member)
+		}
+
+		// The remaining members of a classic group rebalance
+		if len(state.members) > 0 {
+			state.startRebalance(logger, at, "member", req.Leaving)
+		}
+	case protocol.ConsumerGroupHeartbeat:
+		state.consumer = true
+		switch {
+		case req.Epoch == 0:
+			logger.Info("Member joining the group", "member", req.MemberID, "instanceId", req.InstanceID, "clientId", header.ClientID)
+		case req.Epoch < 0:
+			logger.Info("Member leaving the group", "member", req.MemberID, "instanceId", req.InstanceID, "clientId", header.ClientID)
+			delete(state.members, req.MemberID)
+			state.completeRebalance(logger, at)
+		}
+	default:
+	}
+}
+
+// response follows the response to the group request received at the given time. The names of the
+// topics used by their IDs are looked up with the topic function.
+func (m *groupMonitor) response(logger *slog.Logger, cluster clusterReference, header protocol.RequestHeader, req protocol.GroupRequest, resp protocol.GroupResponse, topic func(protocol.UUID) (string, bool), at time.Time) {
+	if req.Group == "" {
+		return
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	state := m.group(cluster, req.Group)
+	logger = logger.With("group", req.Group)
+
+	switch header.APIKey {
+	case protocol.JoinGroup:
+		if resp.ErrorCode != 0 {
+			return
+		}
+
+		if resp.Epoch != state.epoch {
+			logger.Info("Group generation changed", "generation", resp.Epoch, "previousGeneration", state.epoch, "protocol", resp.Protocol, "leader", resp.Leader)
+			state.epoch = resp.Epoch
+		}
+		state.join(logger, resp.MemberID, resp.Epoch, header.ClientID)
+
+		// Only the leader gets all members of the new generation
+		if len(resp.Members) > 0 {
+			for _, member := range resp.Members {
+				state.join(logger, member, resp.Epoch, "")
+			}
+			for member := range state.members {
+				if !slices.Contains(resp.Members, member) {
+					logger.Info("Member removed from the group", "member", member, "generation", resp.Epoch)
+					delete(state.members, member)
+				}
+			}
+		}
+	case protocol.SyncGroup:
+		if resp.ErrorCode != 0 {
+			return
+		}
+
+		attrs := []any{"member", req.MemberID, "generation", req.Epoch}
+		if state.protocolType == protocol.ConsumerProtocolType {
+			if assignment, err := protocol.ReadConsumerAssignment(resp.AssignmentData); err == nil {
+				attrs = append(attrs, "assignment", formatAssignment(assignment, topic))
+			}
+		}
+		logger.Info("Member assignment", attrs...)
+
+		if req.Epoch == state.epoch {
+			state.completeRebalance(logger, at)
+		}
+	case protocol.Heartbeat:
+		if resp.ErrorCode == rebalanceInProgress {
+			state.startRebalance(logger, at, "member", req.MemberID)
+		}
+	case protocol.OffsetCommit:
+		state.logCommit(logger, req, resp, topic)
+	case protocol.ConsumerGroupHeartbeat:
+		if resp.ErrorCode != 0 || req.Epoch < 0 {
+			return
+		}
+
+		member := cmp.Or(resp.MemberID, req.MemberID)
+		state.join(logger, member, resp.Epoch, header.ClientID)
+		state.members[member] = resp.Epoch
+
+		if resp.Epoch > state.epoch {
+			logger.Info("Group epoch changed", "epoch", resp.Epoch, "previousEpoch", state.epoch)
+			state.epoch = resp.Epoch
+			state.startRebalance(logger, at, "member", member)
+		}
+		if resp.Assigned {
+			logger.Info("Member assignment", "member", member, "epoch", resp.Epoch, "assignment", formatAssignment(resp.Assignment, topic))
+		}
+
+		state.completeRebalance(logger, at)
+	default:
+	}
+}
+
+// join adds the member to the group if it is not known yet.
+func (s *groupState) join(logger *slog.Logger, member string, epoch int32, clientId string) {
+	if _, found := s.members[member]; found || member == "" {
+		return
+	}
+
+	attrs := []any{"member", member}
+	if clientId != "" {
+		attrs = append(attrs, "clientId", clientId)
+	}
+	logger.Info("Member joined the group", attrs...)
+	s.members[member] = epoch
+}
+
+// startRebalance starts a rebalance at the given time unless one is in progress already. The attributes
+// describe what started it.
+func (s *groupState) startRebalance(logger *slog.Logger, at time.Time, attrs ...any) {
+	if !s.rebalanceStart.IsZero() {
+		return
+	}
+
+	logger.Info("Group rebalance started", attrs...)
+	s.rebalanceStart = at
+}
+
+// completeRebalance completes the rebalance in progress at the given time. The rebalances of the groups
+// using the consumer protocol complete when all their members reach the epoch of the group.
+func (s *groupState) completeRebalance(logger *slog.Logger, at time.Time) {
+	if s.rebalanceStart.IsZero() {
+		return
+	}
+
+	if s.consumer {
+		for _, epoch := range s.members {
+			if epoch < s.epoch {
+				return
+			}
+		}
+	}
+
+	duration := at.Sub(s.rebalanceStart)
+	s.rebalanceStart = time.Time{}
+	s.rebalances++
+	s.rebalanceTime += duration
+
+	logger.Info("Group rebalance completed", s.epochKey(), s.epoch, "members", len(s.members), "duration", duration)
+}
+
+// epochKey returns the name of the epoch in the log messages. The classic groups have generations.
+func (s *groupState) epochKey() string {
+	if s.consumer {
+		return "epoch"
+	}
+
+	return "generation"
+}
+
+// logCommit logs the offsets committed by the OffsetCommit request. The offsets of the partitions with
+// errors are not logged.
+func (s *groupState) logCommit(logger *slog.Logger, req protocol.GroupRequest, resp protocol.GroupResponse, topic func(protocol.UUID) (string, bool)) {
+	offsets := make([]string, 0, len(req.Offsets))
+	for _, offset := range req.Offsets {
+		failed := slices.ContainsFunc(resp.Errors, func(e protocol.ResponseError) bool {
+			return e.Topic == offset.Topic && e.TopicID == offset.TopicID && e.Partition == offset.Partition
+		})
+		if !failed {
+			offsets = append(offsets, fmt.Sprintf("%s-%d:%d", topicName(offset.Topic, offset.TopicID, topic), offset.Partition, offset.Offset))
+		}
+	}
+
+	if len(offsets) == 0 {
+		return
+	}
+
+	attrs := []any{"member", req.MemberID}
+	if req.Epoch >= 0 {
+		attrs = append(attrs, "generation", req.Epoch)
+	}
+	logger.Info("Offsets committed", append(attrs, "offsets", strings.Join(offsets, ","))...)
+}
+
+// topicName returns the name of the topic or, when it is used by its unknown ID, the topic ID.
+func topicName(name string, id protocol.UUID, topic func(protocol.UUID) (string, bool)) string {
+	if name != "" || id == (protocol.UUID{}) {
+		return name
+	}
+
+	if name, found := topic(id); found {
+		return name
+	}
+
+	return id.String()
+}
+
+// formatAssignment formats the assigned partitions the same way as Kafka does (e.g. orders-0,orders-1).
+func formatAssignment(assignment []protocol.TopicPartitions, topic func(protocol.UUID) (string, bool)) string {
+	partitions := make([]string, 0, len(assignment))
+	for _, t := range assignment {
+		name := topicName(t.Topic, t.TopicID, topic)
+		for _, partition := range slices.Sorted(slices.Values(t.Partitions)) {
+			partitions = append(partitions, fmt.Sprintf("%s-%d", name, partition))
+		}
+	}
+
+	return strings.Join(partitions, ",")
+}
+
+// logGroupSummary logs the generation, the members, and the rebalances of each consumer group followed
+// during the session.
+func (k *Kekspose) logGroupSummary() {
+	if !k.MonitorGroups {
+		return
+	}
+
+	k.groups.lock.Lock()
+	defer k.groups.lock.Unlock()
+
+	keys := slices.SortedFunc(maps.Keys(k.groups.groups), func(a, b groupKey) int {
+		return cmp.Or(strings.Compare(a.cluster.String(), b.cluster.String()), strings.Compare(a.group, b.group))
+	})
+
+	for _, key := range keys {
+		state := k.groups.groups[key]
+		slog.Info("Consumer group activity during the session", "cluster", key.cluster.String(), "group", key.group, state.epochKey(), state.epoch, "members", len(state.members), "rebalances", state.rebalances, "rebalanceTime", state.rebalanceTime)
+	}
+}
+
+// monitorGroupRequest decodes the request of a consumer group member and remembers it, so that its
+// response can be followed as well.
+func (c *connectionInspector) monitorGroupRequest(request *inspectedRequest, frame []byte) {
+	header, body, err := protocol.ReadRequestHeader(frame)
+	if err != nil {
+		return
+	}
+
+	req, err := protocol.ReadGroupRequest(header, body)
+	if err != nil {
+		return
+	}
+
+	request.groupRequest = &req
+	c.groups.request(c.logger, c.cluster, header, req, time.Now())
+}
+
+// monitorGroupResponse decodes the response to the request of a consumer group member.
+func (c *connectionInspector) monitorGroupResponse(request *inspectedRequest, frame []byte) {
+	header := request.header
+
+	_, body, err := protocol.ReadResponseHeader(frame, header.APIKey, header.APIVersion)
+	if err != nil {
+		return
+	}
+
+	resp, err := protocol.ReadGroupResponse(header, body)
+	if err != nil {
+		return
+	}
+
+	c.groups.response(c.logger, c.cluster, header, *request.groupRequest, resp, c.topics.name, time.Now())
+}
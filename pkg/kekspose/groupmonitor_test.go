@@ -0,0 +1,121 @@
+package kekspose
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	keks2 "github.com/scholzj/kekspose/pkg/kekspose/keks"
+	"github.com/scholzj/kekspose/pkg/kekspose/protocol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testCluster = clusterReference{Namespace: "myproject", ClusterName: "my-cluster"}
+
+// logLines returns the logged lines without the time and the level.
+func logLines(output *bytes.Buffer) []string {
+	lines := strings.Split(strings.TrimSpace(output.String()), "\n")
+	for i, line := range lines {
+		if _, msg, found := strings.Cut(line, "msg="); found {
+			lines[i] = msg
+		}
+	}
+
+	return lines
+}
+
+func TestGroupMonitorClassicGroup(t *testing.T) {
+	var output bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&output, nil))
+	monitor := &groupMonitor{}
+	noTopics := func(protocol.UUID) (string, bool) { return "", false }
+	start := time.Now()
+
+	join := protocol.RequestHeader{APIKey: protocol.JoinGroup, APIVersion: 5, ClientID: "consumer-1"}
+	joinRequest := protocol.GroupRequest{Group: "my-group", ProtocolType: protocol.ConsumerProtocolType}
+	monitor.request(logger, testCluster, join, joinRequest, start)
+	monitor.response(logger, testCluster, join, joinRequest, protocol.GroupResponse{ErrorCode: 79, MemberID: "member-1"}, noTopics, start)
+	monitor.response(logger, testCluster, join, joinRequest, protocol.GroupResponse{Epoch: 1, Protocol: "range", Leader: "member-1", MemberID: "member-1", Members: []string{"member-1"}}, noTopics, start)
+
+	sync := protocol.RequestHeader{APIKey: protocol.SyncGroup, APIVersion: 3, ClientID: "consumer-1"}
+	syncRequest := protocol.GroupRequest{Group: "my-group", MemberID: "member-1", Epoch: 1}
+	assignment := []byte{0, 0, 0, 0, 0, 1, 0, 6, 'o', 'r', 'd', 'e', 'r', 's', 0, 0, 0, 2, 0, 0, 0, 1, 0, 0, 0, 0, 0xff, 0xff, 0xff, 0xff}
+	monitor.response(logger, testCluster, sync, syncRequest, protocol.GroupResponse{AssignmentData: assignment}, noTopics, start.Add(3*time.Second))
+
+	commit := protocol.RequestHeader{APIKey: protocol.OffsetCommit, APIVersion: 8, ClientID: "consumer-1"}
+	commitRequest := protocol.GroupRequest{Group: "my-group", MemberID: "member-1", Epoch: 1, Offsets: []protocol.CommittedOffset{
+		{Topic: "orders", Partition: 0, Offset: 42},
+		{Topic: "orders", Partition: 1, Offset: 17},
+	}}
+	monitor.response(logger, testCluster, commit, commitRequest, protocol.GroupResponse{Errors: []protocol.ResponseError{{Code: 22, Topic: "orders", Partition: 1}}}, noTopics, start)
+
+	leave := protocol.RequestHeader{APIKey: protocol.LeaveGroup, APIVersion: 3, ClientID: "consumer-1"}
+	monitor.request(logger, testCluster, leave, protocol.GroupRequest{Group: "my-group", Leaving: []string{"member-1"}}, start)
+
+	assert.Equal(t, []string{
+		`"Group rebalance started" group=my-group member="" clientId=consumer-1`,
+		`"Group generation changed" group=my-group generation=1 previousGeneration=0 protocol=range leader=member-1`,
+		`"Member joined the group" group=my-group member=member-1 clientId=consumer-1`,
+		`"Member assignment" group=my-group member=member-1 generation=1 assignment=orders-0,orders-1`,
+		`"Group rebalance completed" group=my-group generation=1 members=1 duration=3s`,
+		`"Offsets committed" group=my-group member=member-1 generation=1 offsets=orders-0:42`,
+		`"Member leaving the group" group=my-group member=member-1 clientId=consumer-1`,
+	}, logLines(&output))
+
+	state := monitor.groups[groupKey{cluster: testCluster, group: "my-group"}]
+	require.NotNil(t, state)
+	assert.Equal(t, 1, state.rebalances)
+	assert.Equal(t, 3*time.Second, state.rebalanceTime)
+	assert.Empty(t, state.members)
+}
+
+func TestGroupMonitorConsumerGroup(t *testing.T) {
+	var output bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&output, nil))
+	monitor := &groupMonitor{}
+	topicID := protocol.UUID{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16}
+	topics := func(id protocol.UUID) (string, bool) { return "orders", id == topicID }
+	start := time.Now()
+
+	heartbeat := protocol.RequestHeader{APIKey: protocol.ConsumerGroupHeartbeat, APIVersion: 0, ClientID: "consumer-1"}
+	joining := protocol.GroupRequest{Group: "my-group", MemberID: "member-1"}
+	monitor.request(logger, testCluster, heartbeat, joining, start)
+	monitor.response(logger, testCluster, heartbeat, joining, protocol.GroupResponse{MemberID: "member-1", Epoch: 1, Assigned: true, Assignment: []protocol.TopicPartitions{{TopicID: topicID, Partitions: []int32{1, 0}}}}, topics, start)
+
+	// The second member joins and the first member reaches the new epoch later
+	joining = protocol.GroupRequest{Group: "my-group", MemberID: "member-2"}
+	monitor.request(logger, testCluster, heartbeat, joining, start)
+	monitor.response(logger, testCluster, heartbeat, joining, protocol.GroupResponse{MemberID: "member-2", Epoch: 2}, topics, start.Add(time.Second))
+
+	stable := protocol.GroupRequest{Group: "my-group", MemberID: "member-1", Epoch: 1}
+	monitor.response(logger, testCluster, heartbeat, stable, protocol.GroupResponse{MemberID: "member-1", Epoch: 2, Assigned: true, Assignment: []protocol.TopicPartitions{{TopicID: topicID, Partitions: []int32{0}}}}, topics, start.Add(4*time.Second))
+
+	assert.Equal(t, []string{
+		`"Member joining the group" group=my-group member=member-1 instanceId="" clientId=consumer-1`,
+		`"Member joined the group" group=my-group member=member-1 clientId=consumer-1`,
+		`"Group epoch changed" group=my-group epoch=1 previousEpoch=0`,
+		`"Group rebalance started" group=my-group member=member-1`,
+		`"Member assignment" group=my-group member=member-1 epoch=1 assignment=orders-0,orders-1`,
+		`"Group rebalance completed" group=my-group epoch=1 members=1 duration=0s`,
+		`"Member joining the group" group=my-group member=member-2 instanceId="" clientId=consumer-1`,
+		`"Member joined the group" group=my-group member=member-2 clientId=consumer-1`,
+		`"Group epoch changed" group=my-group epoch=2 previousEpoch=1`,
+		`"Group rebalance started" group=my-group member=member-2`,
+		`"Member assignment" group=my-group member=member-1 epoch=2 assignment=orders-0`,
+		`"Group rebalance completed" group=my-group epoch=2 members=2 duration=3s`,
+	}, logLines(&output))
+}
+
+func TestInspectConnectionForGroups(t *testing.T) {
+	k := &Kekspose{MonitorGroups: true}
+	e := &exposure{clusterReference: testCluster, Keks: &keks2.Keks{}, PortMapping: map[int32]uint32{0: 50000}}
+
+	engine, inspector := k.inspectConnection(slog.Default(), e)
+	assert.NotNil(t, engine)
+	require.NotNil(t, inspector)
+	assert.Same(t, &k.groups, inspector.(*connectionInspector).groups)
+	assert.Equal(t, testCluster, inspector.(*connectionInspector).cluster)
+}
@@ -0,0 +1,326 @@
+/*
+Copyright © 2025 Jakub Scholz
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kekspose
+
+import (
+	"context"
+	"log/slog"
+	"maps"
+	"sync"
+
+	"github.com/scholzj/kekspose/pkg/kekspose/protocol"
+	"github.com/scholzj/kekspose/pkg/kekspose/proxiedforward"
+	"github.com/scholzj/proksy"
+)
+
+// inspectedRequestsLimit is the number of the latest requests remembered by the connection inspector.
+// The older requests are forgotten even when they did not get a response, such as the Produce requests
+// with acks=0.
+const inspectedRequestsLimit = 1024
+
+// topicRegistry remembers the names of the topics by their IDs, as found in the Metadata responses, so
+// that the requests using topic IDs can be matched by the topic names.
+type topicRegistry struct {
+	lock  sync.RWMutex
+	names map[protocol.UUID]string
+}
+
+func (t *topicRegistry) add(names map[protocol.UUID]string) {
+	if len(names) == 0 {
+		return
+	}
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.names == nil {
+		t.names = make(map[protocol.UUID]string, len(names))
+	}
+	maps.Copy(t.names, names)
+}
+
+func (t *topicRegistry) name(id protocol.UUID) (string, bool) {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	name, found := t.names[id]
+	return name, found
+}
+
+// inspectedRequest is a request remembered until its response arrives. The group is the consumer group
+// used by the request, if any. The groupRequest is the decoded request of a consumer group member when
+// the group activity is monitored.
+type inspectedRequest struct {
+	header       protocol.RequestHeader
+	group        string
+	groupRequest *protocol.GroupRequest
+	logged       bool
+}
+
+// connectionInspector decodes the requests and responses of one client connection. It decides which
+// RPCs are logged and remembers the requests, so that their responses can be decoded with the API
+// version of the request. It also logs the records of the Produce requests and Fetch responses when
+// they should be decoded, the error codes of the responses when errors is set, and the activity of the
+// consumer groups in the cluster when groups is set. The records are logged by a goroutine started with
+// the first records.
+type connectionInspector struct {
+	filter  rpcFilter
+	records recordOptions
+	errors  *errorStats
+	groups  *groupMonitor
+	cluster clusterReference
+	topics  *topicRegistry
+	logger  *slog.Logger
+
+	lock     sync.Mutex
+	requests map[int32]*inspectedRequest
+	order    []int32
+
+	recordWorker sync.Once
+	recordQueue  chan recordJob
+	recordDone   chan struct{}
+}
+
+// inspectConnection returns the proxy engine and the inspector for a new connection to a node of the
+// exposure. The connections are inspected only when some RPCs should not be logged, when the records
+// should be decoded, when the errors in the responses should be logged, or when the consumer groups are
+// monitored. Otherwise, no engine is returned and the connection uses the engine shared by all
+// connections to the node.
+func (k *Kekspose) inspectConnection(logger *slog.Logger, e *exposure) (*proksy.Engine, proxiedforward.Inspector) {
+	var engine *proksy.Engine
+	var inspector *connectionInspector
+
+	k.engines.read(func() {
+		filter := k.rpcFilter()
+		records := k.recordOptions()
+		if !filter.active() && !records.active() && !k.LogErrors && !k.MonitorGroups {
+			return
+		}
+
+		inspector = &connectionInspector{filter: filter, records: records, cluster: e.clusterReference, topics: &k.topicIDs, requests: make(map[int32]*inspectedRequest)}
+		if k.LogErrors {
+			inspector.errors = &k.errors
+		}
+		if k.MonitorGroups {
+			inspector.groups = &k.groups
+		}
+		inspector.logger = slog.New(&rpcLogHandler{Handler: logger.Handler(), inspector: inspector})
+		engine = k.newProxyEngine(inspector.logger, e.PortMapping)
+	})
+
+	if engine == nil {
+		return nil, nil
+	}
+
+	return engine, inspector
+}
+
+// FrameLimits returns how much of the frames the inspector needs, so that the whole frames are buffered
+// only when they are decoded. The client IDs are read from the request headers. The topics and groups
+// are read from the request bodies, and the topic IDs of the topics from the Metadata responses. The
+// records, the error codes, and the consumer groups need the whole frames. The records are decoded only
+// with the verbose logging, which can be enabled later, so their frames are kept regardless of it.
+func (c *connectionInspector) FrameLimits() (int, int) {
+	request, response := 0, 0
+
+	if len(c.filter.Topics) > 0 || len(c.filter.Groups) > 0 || c.records.active() || c.groups != nil {
+		request = proxiedforward.WholeFrames
+	}
+
+	if len(c.filter.Topics) > 0 || c.records.active() || c.errors != nil || c.groups != nil {
+		response = proxiedforward.WholeFrames
+	}
+
+	return request, response
+}
+
+// Request decides whether the RPC is logged. Requests which cannot be decoded are logged.
+func (c *connectionInspector) Request(frame []byte) {
+	header, body, err := protocol.ReadRequestHeader(frame)
+	if err != nil {
+		return
+	}
+
+	resources, _ := protocol.ReadRequestResources(header, body)
+	request := &inspectedRequest{header: header, logged: c.filter.matches(header, resources, c.topics.name)}
+	if len(resources.Groups) > 0 {
+		request.group = resources.Groups[0]
+	}
+
+	if request.logged && header.APIKey == protocol.Produce && c.decodesRecords() {
+		c.logProduceRecords(header, frame)
+	}
+
+	if c.groups != nil && request.logged && protocol.MonitorsGroup(header.APIKey) {
+		c.monitorGroupRequest(request, frame)
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if _, found := c.requests[header.CorrelationID]; !found {
+		c.order = append(c.order, header.CorrelationID)
+	}
+	c.requests[header.CorrelationID] = request
+
+	if len(c.order) > inspectedRequestsLimit {
+		delete(c.requests, c.order[0])
+		c.order = c.order[1:]
+	}
+}
+
+// Response learns the topic IDs from the Metadata responses, logs the records of the Fetch responses,
+// logs the error codes of the responses, and follows the activity of the consumer groups.
+func (c *connectionInspector) Response(frame []byte) {
+	if len(frame) < 4 {
+		return
+	}
+
+	request, found := c.request(protocol.NewReader(frame).Int32())
+	if !found {
+		return
+	}
+
+	header := request.header
+	if c.errors != nil && request.logged && protocol.ReportsErrors(header.APIKey) {
+		c.logResponseErrors(request, frame)
+	}
+
+	if request.groupRequest != nil {
+		c.monitorGroupResponse(request, frame)
+	}
+
+	switch {
+	case header.APIKey == protocol.Metadata:
+	case header.APIKey == protocol.Fetch && request.logged && c.decodesRecords():
+	default:
+		return
+	}
+
+	_, body, err := protocol.ReadResponseHeader(frame, header.APIKey, header.APIVersion)
+	if err != nil {
+		return
+	}
+
+	// Keep the topics and records decoded before an error
+	if header.APIKey == protocol.Metadata {
+		topics, _ := protocol.ReadMetadataTopicIDs(header.APIVersion, body)
+		c.topics.add(topics)
+	} else {
+		partitions, _ := protocol.ReadFetchRecords(header.APIVersion, body)
+		c.logRecords(header, partitions)
+	}
+}
+
+// Close waits for the records of the connection to be logged.
+func (c *connectionInspector) Close() {
+	c.closeRecords()
+}
+
+func (c *connectionInspector) request(correlationId int32) (*inspectedRequest, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	request, found := c.requests[correlationId]
+	return request, found
+}
+
+// logged returns false when the RPC with the correlation ID should not be logged. Unknown RPCs are
+// logged.
+func (c *connectionInspector) logged(correlationId int32) bool {
+	request, found := c.request(correlationId)
+	return !found || request.logged
+}
+
+// missingCorrelationIdWarning makes sure that the RPC log messages without a correlation ID are
+// reported only once.
+var missingCorrelationIdWarning sync.Once
+
+// rpcLogHandler drops the log records of the RPCs which should not be logged. The RPCs are identified
+// by the correlation ID attribute of the records (see correlationIdKey). While the RPCs are filtered,
+// the debug and trace records without a readable correlation ID are dropped as well, because they cannot
+// be told apart from the RPCs which should not be logged. The other records are passed as they are.
+type rpcLogHandler struct {
+	slog.Handler
+	inspector     *connectionInspector
+	correlationId *int32
+}
+
+func (h *rpcLogHandler) Handle(ctx context.Context, record slog.Record) error {
+	correlationId := h.correlationId
+	if correlationId == nil {
+		record.Attrs(func(attr slog.Attr) bool {
+			if id, ok := correlationIdAttr(attr); ok {
+				correlationId = &id
+				return false
+			}
+
+			return true
+		})
+	}
+
+	if correlationId == nil {
+		if record.Level <= slog.LevelDebug && h.inspector.filter.active() {
+			missingCorrelationIdWarning.Do(func() {
+				slog.Warn("The RPC log messages without a correlation ID cannot be filtered and are not logged", "attribute", correlationIdKey, "message", record.Message)
+			})
+
+			return nil
+		}
+	} else if !h.inspector.logged(*correlationId) {
+		return nil
+	}
+
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h *rpcLogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	handler := &rpcLogHandler{Handler: h.Handler.WithAttrs(attrs), inspector: h.inspector, correlationId: h.correlationId}
+	for _, attr := range attrs {
+		if id, ok := correlationIdAttr(attr); ok {
+			handler.correlationId = &id
+		}
+	}
+
+	return handler
+}
+
+func (h *rpcLogHandler) WithGroup(name string) slog.Handler {
+	return &rpcLogHandler{Handler: h.Handler.WithGroup(name), inspector: h.inspector, correlationId: h.correlationId}
+}
+
+// correlationIdAttr returns the correlation ID when the attribute contains it.
+func correlationIdAttr(attr slog.Attr) (int32, bool) {
+	if attr.Key != correlationIdKey {
+		return 0, false
+	}
+
+	value := attr.Value.Resolve()
+	switch value.Kind() {
+	case slog.KindInt64:
+		return int32(value.Int64()), true
+	case slog.KindUint64:
+		return int32(value.Uint64()), true
+	case slog.KindAny:
+		if id, ok := value.Any().(int32); ok {
+			return id, true
+		}
+	default:
+	}
+
+	return 0, false
+}
@@ -0,0 +1,143 @@
+package kekspose
+
+import (
+	"bytes"
+	"encoding/binary"
+	"log/slog"
+	"strings"
+	"testing"
+
+	keks2 "github.com/scholzj/kekspose/pkg/kekspose/keks"
+	"github.com/scholzj/kekspose/pkg/kekspose/protocol"
+	"github.com/scholzj/kekspose/pkg/kekspose/proxiedforward"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testRequest builds a non-flexible request frame with the given body.
+func testRequest(apiKey int16, apiVersion int16, correlationId int32, clientId string, body ...byte) []byte {
+	frame := binary.BigEndian.AppendUint16(nil, uint16(apiKey))
+	frame = binary.BigEndian.AppendUint16(frame, uint16(apiVersion))
+	frame = binary.BigEndian.AppendUint32(frame, uint32(correlationId))
+	frame = appendString(frame, clientId)
+	return append(frame, body...)
+}
+
+// appendString appends a non-compact string.
+func appendString(data []byte, s string) []byte {
+	data = binary.BigEndian.AppendUint16(data, uint16(len(s)))
+	return append(data, s...)
+}
+
+// metadataRequest builds a Metadata v4 request for the topics.
+func metadataRequest(correlationId int32, clientId string, topics ...string) []byte {
+	body := binary.BigEndian.AppendUint32(nil, uint32(len(topics)))
+	for _, topic := range topics {
+		body = appendString(body, topic)
+	}
+
+	return testRequest(protocol.Metadata, 4, correlationId, clientId, append(body, 1)...)
+}
+
+func TestConnectionInspectorFiltersLogs(t *testing.T) {
+	var output bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&output, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	inspector := &connectionInspector{filter: rpcFilter{Topics: []string{"orders"}}, topics: &topicRegistry{}, requests: make(map[int32]*inspectedRequest)}
+	rpcLogger := slog.New(&rpcLogHandler{Handler: logger.Handler(), inspector: inspector})
+
+	inspector.Request(metadataRequest(1, "my-client", "orders"))
+	inspector.Request(metadataRequest(2, "my-client", "payments"))
+
+	rpcLogger.Debug("-> request", "api", "Metadata", "correlationId", int32(1))
+	rpcLogger.Debug("-> request", "api", "Metadata", "correlationId", int32(2))
+	rpcLogger.With("correlationId", 2).Debug("<- response", "api", "Metadata")
+	rpcLogger.Debug("<- response", "api", "Metadata", "correlationId", 1)
+	// Unknown RPCs and other messages are logged
+	rpcLogger.Debug("-> request", "api", "Metadata", "correlationId", 3)
+	rpcLogger.Info("Connection closed")
+	// RPC messages without a readable correlation ID are not
+	rpcLogger.Debug("-> request", "api", "Metadata", "correlationID", 2)
+	rpcLogger.Debug("-> request", "api", "Metadata", "correlationId", "2")
+
+	lines := strings.Split(strings.TrimSpace(output.String()), "\n")
+	require.Len(t, lines, 4)
+	assert.Contains(t, lines[0], "correlationId=1")
+	assert.Contains(t, lines[1], "correlationId=1")
+	assert.Contains(t, lines[2], "correlationId=3")
+	assert.Contains(t, lines[3], "Connection closed")
+}
+
+func TestConnectionInspectorFrameLimits(t *testing.T) {
+	request, response := (&connectionInspector{filter: rpcFilter{ClientIDs: []string{"my-client"}}}).FrameLimits()
+	assert.Zero(t, request)
+	assert.Zero(t, response)
+
+	request, response = (&connectionInspector{filter: rpcFilter{Groups: []string{"my-group"}}}).FrameLimits()
+	assert.Equal(t, proxiedforward.WholeFrames, request)
+	assert.Zero(t, response)
+
+	request, response = (&connectionInspector{errors: &errorStats{}}).FrameLimits()
+	assert.Zero(t, request)
+	assert.Equal(t, proxiedforward.WholeFrames, response)
+
+	request, response = (&connectionInspector{records: recordOptions{Topics: []string{"orders"}}}).FrameLimits()
+	assert.Equal(t, proxiedforward.WholeFrames, request)
+	assert.Equal(t, proxiedforward.WholeFrames, response)
+}
+
+func TestConnectionInspectorForgetsOldRequests(t *testing.T) {
+	inspector := &connectionInspector{filter: rpcFilter{ClientIDs: []string{"other"}}, topics: &topicRegistry{}, requests: make(map[int32]*inspectedRequest)}
+
+	for i := range int32(inspectedRequestsLimit + 10) {
+		inspector.Request(metadataRequest(i, "my-client"))
+	}
+
+	assert.Len(t, inspector.requests, inspectedRequestsLimit)
+	assert.True(t, inspector.logged(0))
+	assert.False(t, inspector.logged(inspectedRequestsLimit+9))
+}
+
+func TestConnectionInspectorLearnsTopicIDs(t *testing.T) {
+	registry := &topicRegistry{}
+	inspector := &connectionInspector{filter: rpcFilter{Topics: []string{"orders"}}, topics: registry, requests: make(map[int32]*inspectedRequest)}
+	topicID := protocol.UUID{1, 2, 3}
+
+	// Flexible Metadata v12 request for all topics
+	inspector.Request(testRequest(protocol.Metadata, 12, 5, "my-client", 0, 0, 0, 0))
+
+	// Metadata v12 response with one topic and no brokers and partitions
+	response := binary.BigEndian.AppendUint32(nil, 5)
+	response = append(response, 0)                                     // Header tagged fields
+	response = append(response, 0, 0, 0, 0)                            // Throttle time
+	response = append(response, 1)                                     // No brokers
+	response = append(response, 0)                                     // Null cluster ID
+	response = append(response, 0, 0, 0, 0)                            // Controller ID
+	response = append(response, 2)                                     // One topic
+	response = append(response, 0, 0, 7, 'o', 'r', 'd', 'e', 'r', 's') // Error code and name
+	response = append(response, topicID[:]...)
+	response = append(response, 0, 1, 0, 0, 0, 0, 0) // Is internal, no partitions, operations, tags
+	response = append(response, 0)                   // Tagged fields
+
+	inspector.Response(response)
+
+	name, found := registry.name(topicID)
+	assert.True(t, found)
+	assert.Equal(t, "orders", name)
+}
+
+func TestInspectConnection(t *testing.T) {
+	k := &Kekspose{}
+	e := &exposure{Keks: &keks2.Keks{}, PortMapping: map[int32]uint32{0: 50000}}
+
+	// Without a filter, the connections use the shared engine
+	engine, inspector := k.inspectConnection(slog.Default(), e)
+	assert.Nil(t, engine)
+	assert.Nil(t, inspector)
+
+	k.LogGroups = []string{"my-group"}
+	engine, inspector = k.inspectConnection(slog.Default(), e)
+	assert.NotNil(t, engine)
+	require.NotNil(t, inspector)
+	assert.Equal(t, rpcFilter{Groups: []string{"my-group"}}, inspector.(*connectionInspector).filter)
+}
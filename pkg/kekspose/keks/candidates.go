@@ -0,0 +1,112 @@
+/*
+Copyright © 2025 Jakub Scholz
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package keks
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	strimziapi "github.com/scholzj/strimzi-go/pkg/apis/kafka.strimzi.io/v1"
+	strimziclient "github.com/scholzj/strimzi-go/pkg/client/clientset/versioned"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Candidate is a Kafka cluster that Keksposé could expose together with the listeners suitable for
+// it. It is used to offer the user a choice when the cluster or listener were not specified.
+type Candidate struct {
+	ClusterName string
+	Ready       bool
+	Listeners   []CandidateListener
+}
+
+// CandidateListener describes one listener of a candidate Kafka cluster.
+type CandidateListener struct {
+	Name           string
+	Port           int32
+	TLS            bool
+	Authentication string
+}
+
+// KafkaExists checks whether the Kafka cluster with the given name exists in the namespace.
+func KafkaExists(strimzi strimziclient.Interface, namespace string, clusterName string) (bool, error) {
+	_, err := strimzi.KafkaV1().Kafkas(namespace).Get(context.TODO(), clusterName, v1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return false, nil
+		}
+
+		//goland:noinspection GoErrorStringFormat
+		return false, fmt.Errorf("failed to get Kafka cluster %s in namespace %s: %w", clusterName, namespace, err)
+	}
+
+	return true, nil
+}
+
+// ListCandidates lists the Kafka clusters in the namespace which have at least one listener suitable
+// for Keksposé. The same rules as for the automatic listener selection apply, so TLS listeners are
+// included only when allowInsecureTLS is set. Unready clusters are included only with allowUnready.
+func ListCandidates(strimzi strimziclient.Interface, namespace string, allowUnready bool, allowInsecureTLS bool) ([]Candidate, error) {
+	kafkas, err := strimzi.KafkaV1().Kafkas(namespace).List(context.TODO(), v1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Kafka clusters in namespace %s: %w", namespace, err)
+	}
+
+	candidates := make([]Candidate, 0, len(kafkas.Items))
+	for _, kafka := range kafkas.Items {
+		ready := IsKafkaReady(&kafka)
+		if !ready && !allowUnready {
+			continue
+		}
+
+		if kafka.Spec == nil || kafka.Spec.Kafka == nil {
+			continue
+		}
+
+		listeners := make([]CandidateListener, 0, len(kafka.Spec.Kafka.Listeners))
+		for _, listener := range kafka.Spec.Kafka.Listeners {
+			if isSuitableListener(listener, allowInsecureTLS) {
+				listeners = append(listeners, CandidateListener{
+					Name:           listener.Name,
+					Port:           listener.Port,
+					TLS:            listener.Tls,
+					Authentication: listenerAuthentication(listener),
+				})
+			}
+		}
+
+		if len(listeners) > 0 {
+			candidates = append(candidates, Candidate{ClusterName: kafka.Name, Ready: ready, Listeners: listeners})
+		}
+	}
+
+	slices.SortFunc(candidates, func(a, b Candidate) int {
+		return strings.Compare(a.ClusterName, b.ClusterName)
+	})
+
+	return candidates, nil
+}
+
+func listenerAuthentication(listener strimziapi.GenericKafkaListener) string {
+	if listener.Authentication == nil || listener.Authentication.Type == "" {
+		return "none"
+	}
+
+	return string(listener.Authentication.Type)
+}
@@ -0,0 +1,79 @@
+package keks
+
+import (
+	"context"
+	"testing"
+
+	kafkav1 "github.com/scholzj/strimzi-go/pkg/apis/kafka.strimzi.io/v1"
+	"github.com/scholzj/strimzi-go/pkg/client/clientset/versioned/fake"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestListCandidates(t *testing.T) {
+	ready := &kafkav1.KafkaStatus{Conditions: []kafkav1.Condition{{Type: "Ready", Status: "True"}}}
+	kafkaB := &kafkav1.Kafka{
+		ObjectMeta: metav1.ObjectMeta{Name: "cluster-b", Namespace: "my-namespace"},
+		Spec: &kafkav1.KafkaSpec{Kafka: &kafkav1.KafkaClusterSpec{Listeners: []kafkav1.GenericKafkaListener{{
+			Name: "plain", Type: kafkav1.INTERNAL_KAFKALISTENERTYPE, Tls: false, Port: 9092,
+		}, {
+			Name: "tls", Type: kafkav1.INTERNAL_KAFKALISTENERTYPE, Tls: true, Port: 9093,
+			Authentication: &kafkav1.KafkaListenerAuthentication{Type: kafkav1.SCRAM_SHA_512_KAFKALISTENERAUTHENTICATIONTYPE},
+		}}}},
+		Status: ready,
+	}
+	kafkaA := &kafkav1.Kafka{
+		ObjectMeta: metav1.ObjectMeta{Name: "cluster-a", Namespace: "my-namespace"},
+		Spec: &kafkav1.KafkaSpec{Kafka: &kafkav1.KafkaClusterSpec{Listeners: []kafkav1.GenericKafkaListener{{
+			Name: "plain", Type: kafkav1.INTERNAL_KAFKALISTENERTYPE, Tls: false, Port: 9092,
+		}}}},
+		Status: ready,
+	}
+	unready := &kafkav1.Kafka{
+		ObjectMeta: metav1.ObjectMeta{Name: "cluster-c", Namespace: "my-namespace"},
+		Spec: &kafkav1.KafkaSpec{Kafka: &kafkav1.KafkaClusterSpec{Listeners: []kafkav1.GenericKafkaListener{{
+			Name: "plain", Type: kafkav1.INTERNAL_KAFKALISTENERTYPE, Tls: false, Port: 9092,
+		}}}},
+	}
+	tlsOnly := &kafkav1.Kafka{
+		ObjectMeta: metav1.ObjectMeta{Name: "cluster-d", Namespace: "my-namespace"},
+		Spec: &kafkav1.KafkaSpec{Kafka: &kafkav1.KafkaClusterSpec{Listeners: []kafkav1.GenericKafkaListener{{
+			Name: "tls", Type: kafkav1.INTERNAL_KAFKALISTENERTYPE, Tls: true, Port: 9093,
+		}}}},
+		Status: ready,
+	}
+
+	client := fake.NewSimpleClientset()
+	for _, kafka := range []*kafkav1.Kafka{kafkaB, kafkaA, unready, tlsOnly} {
+		_, err := client.KafkaV1().Kafkas("my-namespace").Create(context.TODO(), kafka, metav1.CreateOptions{})
+		assert.Nil(t, err)
+	}
+
+	candidates, err := ListCandidates(client, "my-namespace", false, false)
+	assert.Nil(t, err)
+	assert.Equal(t, []Candidate{
+		{ClusterName: "cluster-a", Ready: true, Listeners: []CandidateListener{{Name: "plain", Port: 9092, Authentication: "none"}}},
+		{ClusterName: "cluster-b", Ready: true, Listeners: []CandidateListener{{Name: "plain", Port: 9092, Authentication: "none"}}},
+	}, candidates)
+
+	candidates, err = ListCandidates(client, "my-namespace", true, true)
+	assert.Nil(t, err)
+	assert.Len(t, candidates, 4)
+	assert.Equal(t, CandidateListener{Name: "tls", Port: 9093, TLS: true, Authentication: "scram-sha-512"}, candidates[1].Listeners[1])
+	assert.False(t, candidates[2].Ready)
+	assert.Equal(t, "cluster-d", candidates[3].ClusterName)
+}
+
+func TestKafkaExists(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	_, err := client.KafkaV1().Kafkas("my-namespace").Create(context.TODO(), &kafkav1.Kafka{ObjectMeta: metav1.ObjectMeta{Name: "my-cluster", Namespace: "my-namespace"}}, metav1.CreateOptions{})
+	assert.Nil(t, err)
+
+	exists, err := KafkaExists(client, "my-namespace", "my-cluster")
+	assert.Nil(t, err)
+	assert.True(t, exists)
+
+	exists, err = KafkaExists(client, "my-namespace", "other-cluster")
+	assert.Nil(t, err)
+	assert.False(t, exists)
+}
@@ -0,0 +1,115 @@
+/*
+Copyright © 2025 Jakub Scholz
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package keks
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	strimziapi "github.com/scholzj/strimzi-go/pkg/apis/kafka.strimzi.io/v1"
+	strimziclient "github.com/scholzj/strimzi-go/pkg/client/clientset/versioned"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// CruiseControlRestPort is the port of the Cruise Control REST API in Strimzi-managed Kafka clusters.
+	CruiseControlRestPort uint32 = 9090
+	// CruiseControlAdminUsername is the name of the Cruise Control API user with the admin role created by Strimzi.
+	CruiseControlAdminUsername = "admin"
+	// cruiseControlAdminPasswordKey is the key of the admin password in the Cruise Control API Secret.
+	cruiseControlAdminPasswordKey = "cruise-control.apiAdminPassword"
+)
+
+// FindCruiseControl finds the Cruise Control instance of the Kafka cluster and picks its ready pod to
+// forward the REST API. It returns nil when Cruise Control is not enabled in the Kafka resource or
+// when it does not have any ready pod. When the API authentication is enabled, the admin credentials
+// are read from the Secret generated by Strimzi.
+func FindCruiseControl(strimzi strimziclient.Interface, kube kubernetes.Interface, namespace string, clusterName string, allowUnready bool) (*Endpoint, error) {
+	kafka, err := strimzi.KafkaV1().Kafkas(namespace).Get(context.TODO(), clusterName, v1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get Kafka cluster %s in namespace %s: %w", clusterName, namespace, err)
+	}
+
+	if kafka.Spec == nil || kafka.Spec.CruiseControl == nil {
+		return nil, nil
+	}
+
+	var conditions []strimziapi.Condition
+	if kafka.Status != nil {
+		conditions = kafka.Status.Conditions
+	}
+
+	// The forwarder terminates the TLS connection, so the local API always uses plain HTTP
+	name := clusterName + "-cruise-control"
+	endpoint, err := findEndpoint(kube, "CruiseControl", name, namespace, conditions, "strimzi.io/name="+name, CruiseControlRestPort, "http", allowUnready)
+	if err != nil || endpoint == nil {
+		return nil, err
+	}
+	endpoint.TLS = isConfigEnabled(kafka.Spec.CruiseControl.Config, "webserver.ssl.enable")
+
+	if isConfigEnabled(kafka.Spec.CruiseControl.Config, "webserver.security.enable") {
+		password, err := cruiseControlAdminPassword(kube, namespace, clusterName)
+		if err != nil {
+			return nil, err
+		}
+
+		endpoint.Username = CruiseControlAdminUsername
+		endpoint.Password = password
+		endpoint.PasswordSecret = cruiseControlAPISecretName(clusterName)
+		endpoint.PasswordKey = cruiseControlAdminPasswordKey
+	}
+
+	return endpoint, nil
+}
+
+// cruiseControlAdminPassword reads the password of the admin API user from the Secret generated by Strimzi.
+func cruiseControlAdminPassword(kube kubernetes.Interface, namespace string, clusterName string) (string, error) {
+	secretName := cruiseControlAPISecretName(clusterName)
+
+	secret, err := kube.CoreV1().Secrets(namespace).Get(context.TODO(), secretName, v1.GetOptions{})
+	if err != nil {
+		return "", fmt.Errorf("failed to get the Cruise Control API Secret %s in namespace %s: %w", secretName, namespace, err)
+	}
+
+	password, found := secret.Data[cruiseControlAdminPasswordKey]
+	if !found {
+		return "", fmt.Errorf("the Cruise Control API Secret %s in namespace %s does not contain the %s key", secretName, namespace, cruiseControlAdminPasswordKey)
+	}
+
+	return string(password), nil
+}
+
+// cruiseControlAPISecretName returns the name of the Cruise Control API Secret generated by Strimzi.
+func cruiseControlAPISecretName(clusterName string) string {
+	return clusterName + "-cruise-control-api"
+}
+
+// isConfigEnabled checks the boolean Cruise Control option. Strimzi enables both TLS and authentication
+// of the Cruise Control API unless they are disabled in the configuration.
+func isConfigEnabled(config strimziapi.MapStringObject, key string) bool {
+	switch value := config[key].(type) {
+	case bool:
+		return value
+	case string:
+		enabled, err := strconv.ParseBool(value)
+		return err != nil || enabled
+	default:
+		return true
+	}
+}
@@ -0,0 +1,99 @@
+package keks
+
+import (
+	"testing"
+
+	kafkav1 "github.com/scholzj/strimzi-go/pkg/apis/kafka.strimzi.io/v1"
+	strimzifake "github.com/scholzj/strimzi-go/pkg/client/clientset/versioned/fake"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestFindCruiseControl(t *testing.T) {
+	strimziclient := strimzifake.NewSimpleClientset(&kafkav1.Kafka{
+		ObjectMeta: metav1.ObjectMeta{Name: "my-cluster", Namespace: "my-namespace"},
+		Spec:       &kafkav1.KafkaSpec{CruiseControl: &kafkav1.CruiseControlSpec{}},
+		Status:     &kafkav1.KafkaStatus{Conditions: []kafkav1.Condition{{Type: "Ready", Status: "True"}}},
+	})
+	kubeclient := fake.NewSimpleClientset(
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "my-cluster-cruise-control-abc", Namespace: "my-namespace", Labels: map[string]string{"strimzi.io/name": "my-cluster-cruise-control"}},
+			Status:     corev1.PodStatus{Phase: corev1.PodRunning, Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}}},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "my-cluster-cruise-control-api", Namespace: "my-namespace"},
+			Data:       map[string][]byte{"cruise-control.apiAdminPassword": []byte("s3cr3t")},
+		},
+	)
+
+	endpoint, err := FindCruiseControl(strimziclient, kubeclient, "my-namespace", "my-cluster", false)
+
+	require.NoError(t, err)
+	assert.Equal(t, &Endpoint{
+		Kind:           "CruiseControl",
+		Name:           "my-cluster-cruise-control",
+		Namespace:      "my-namespace",
+		PodName:        "my-cluster-cruise-control-abc",
+		Port:           CruiseControlRestPort,
+		Scheme:         "http",
+		TLS:            true,
+		Username:       CruiseControlAdminUsername,
+		Password:       "s3cr3t",
+		PasswordSecret: "my-cluster-cruise-control-api",
+		PasswordKey:    "cruise-control.apiAdminPassword",
+	}, endpoint)
+}
+
+func TestFindCruiseControlWithoutSecurity(t *testing.T) {
+	strimziclient := strimzifake.NewSimpleClientset(&kafkav1.Kafka{
+		ObjectMeta: metav1.ObjectMeta{Name: "my-cluster", Namespace: "my-namespace"},
+		Spec: &kafkav1.KafkaSpec{CruiseControl: &kafkav1.CruiseControlSpec{Config: kafkav1.MapStringObject{
+			"webserver.ssl.enable":      false,
+			"webserver.security.enable": "false",
+		}}},
+		Status: &kafkav1.KafkaStatus{Conditions: []kafkav1.Condition{{Type: "Ready", Status: "True"}}},
+	})
+	kubeclient := fake.NewSimpleClientset(&corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "my-cluster-cruise-control-abc", Namespace: "my-namespace", Labels: map[string]string{"strimzi.io/name": "my-cluster-cruise-control"}},
+		Status:     corev1.PodStatus{Phase: corev1.PodRunning, Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}}},
+	})
+
+	endpoint, err := FindCruiseControl(strimziclient, kubeclient, "my-namespace", "my-cluster", false)
+
+	require.NoError(t, err)
+	require.NotNil(t, endpoint)
+	assert.False(t, endpoint.TLS)
+	assert.Empty(t, endpoint.Username)
+	assert.Empty(t, endpoint.Password)
+}
+
+func TestFindCruiseControlWhenNotEnabled(t *testing.T) {
+	strimziclient := strimzifake.NewSimpleClientset(&kafkav1.Kafka{
+		ObjectMeta: metav1.ObjectMeta{Name: "my-cluster", Namespace: "my-namespace"},
+		Spec:       &kafkav1.KafkaSpec{},
+	})
+
+	endpoint, err := FindCruiseControl(strimziclient, fake.NewSimpleClientset(), "my-namespace", "my-cluster", false)
+
+	require.NoError(t, err)
+	assert.Nil(t, endpoint)
+}
+
+func TestFindCruiseControlWithoutSecret(t *testing.T) {
+	strimziclient := strimzifake.NewSimpleClientset(&kafkav1.Kafka{
+		ObjectMeta: metav1.ObjectMeta{Name: "my-cluster", Namespace: "my-namespace"},
+		Spec:       &kafkav1.KafkaSpec{CruiseControl: &kafkav1.CruiseControlSpec{}},
+		Status:     &kafkav1.KafkaStatus{Conditions: []kafkav1.Condition{{Type: "Ready", Status: "True"}}},
+	})
+	kubeclient := fake.NewSimpleClientset(&corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "my-cluster-cruise-control-abc", Namespace: "my-namespace", Labels: map[string]string{"strimzi.io/name": "my-cluster-cruise-control"}},
+		Status:     corev1.PodStatus{Phase: corev1.PodRunning, Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}}},
+	})
+
+	_, err := FindCruiseControl(strimziclient, kubeclient, "my-namespace", "my-cluster", false)
+
+	require.ErrorContains(t, err, "failed to get the Cruise Control API Secret my-cluster-cruise-control-api in namespace my-namespace")
+}
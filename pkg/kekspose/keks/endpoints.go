@@ -0,0 +1,221 @@
+/*
+Copyright © 2025 Jakub Scholz
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package keks
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"strings"
+
+	strimziapi "github.com/scholzj/strimzi-go/pkg/apis/kafka.strimzi.io/v1"
+	strimziclient "github.com/scholzj/strimzi-go/pkg/client/clientset/versioned"
+	corev1 "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// KafkaConnectRestPort is the port of the Kafka Connect REST API in Strimzi-managed Connect clusters.
+	KafkaConnectRestPort uint32 = 8083
+	// KafkaBridgeDefaultHttpPort is the port of the Kafka Bridge HTTP API when it is not configured in the
+	// KafkaBridge resource.
+	KafkaBridgeDefaultHttpPort uint32 = 8080
+)
+
+// Endpoint is an HTTP API of a Strimzi component connected to the Kafka cluster. Unlike the Kafka
+// brokers, it is forwarded without the Kafka protocol proxy.
+type Endpoint struct {
+	Kind      string
+	Name      string
+	Namespace string
+	PodName   string
+	Port      uint32
+	// Scheme is the URL scheme used by the forwarded HTTP API (http or https).
+	Scheme string
+	// TLS indicates that the pod serves the API over TLS, which is terminated by the forwarder.
+	TLS bool
+	// Username and Password are the credentials for the API, when it uses authentication.
+	Username string
+	Password string
+	// PasswordSecret and PasswordKey identify the Secret with the password, so that it can be
+	// shown to the user without logging the password itself.
+	PasswordSecret string
+	PasswordKey    string
+}
+
+// FindKafkaConnects finds the Kafka Connect clusters using the Kafka cluster and picks a ready pod for
+// each of them to forward its REST API. Connect clusters without any ready pod are skipped.
+func FindKafkaConnects(strimzi strimziclient.Interface, kube kubernetes.Interface, namespace string, clusterName string, allowUnready bool) ([]Endpoint, error) {
+	connects, err := strimzi.KafkaV1().KafkaConnects(namespace).List(context.TODO(), v1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Kafka Connect clusters in namespace %s: %w", namespace, err)
+	}
+
+	endpoints := make([]Endpoint, 0)
+	for _, connect := range connects.Items {
+		if connect.Spec == nil || !targetsKafka(connect.Spec.BootstrapServers, namespace, clusterName) {
+			continue
+		}
+
+		var conditions []strimziapi.Condition
+		var labelSelector string
+		if connect.Status != nil {
+			conditions = connect.Status.Conditions
+			labelSelector = connect.Status.LabelSelector
+		}
+		if labelSelector == "" {
+			labelSelector = "strimzi.io/cluster=" + connect.Name + ",strimzi.io/kind=KafkaConnect"
+		}
+
+		endpoint, err := findEndpoint(kube, "KafkaConnect", connect.Name, namespace, conditions, labelSelector, KafkaConnectRestPort, "http", allowUnready)
+		if err != nil {
+			return nil, err
+		}
+
+		if endpoint != nil {
+			endpoints = append(endpoints, *endpoint)
+		}
+	}
+
+	return endpoints, nil
+}
+
+// FindKafkaBridges finds the Kafka Bridges using the Kafka cluster and picks a ready pod for each of
+// them to forward its HTTP API. Bridges without any ready pod are skipped.
+func FindKafkaBridges(strimzi strimziclient.Interface, kube kubernetes.Interface, namespace string, clusterName string, allowUnready bool) ([]Endpoint, error) {
+	bridges, err := strimzi.KafkaV1().KafkaBridges(namespace).List(context.TODO(), v1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Kafka Bridges in namespace %s: %w", namespace, err)
+	}
+
+	endpoints := make([]Endpoint, 0)
+	for _, bridge := range bridges.Items {
+		if bridge.Spec == nil || !targetsKafka(bridge.Spec.BootstrapServers, namespace, clusterName) {
+			continue
+		}
+
+		port := KafkaBridgeDefaultHttpPort
+		scheme := "http"
+		if bridge.Spec.Http != nil {
+			if bridge.Spec.Http.Port != 0 {
+				port = uint32(bridge.Spec.Http.Port)
+			}
+
+			if bridge.Spec.Http.Tls != nil {
+				scheme = "https"
+			}
+		}
+
+		var conditions []strimziapi.Condition
+		var labelSelector string
+		if bridge.Status != nil {
+			conditions = bridge.Status.Conditions
+			labelSelector = bridge.Status.LabelSelector
+		}
+		if labelSelector == "" {
+			labelSelector = "strimzi.io/cluster=" + bridge.Name + ",strimzi.io/kind=KafkaBridge"
+		}
+
+		endpoint, err := findEndpoint(kube, "KafkaBridge", bridge.Name, namespace, conditions, labelSelector, port, scheme, allowUnready)
+		if err != nil {
+			return nil, err
+		}
+
+		if endpoint != nil {
+			endpoints = append(endpoints, *endpoint)
+		}
+	}
+
+	return endpoints, nil
+}
+
+// findEndpoint checks the readiness of the component and finds a ready pod for it. It returns nil
+// when the component should be skipped.
+func findEndpoint(kube kubernetes.Interface, kind string, name string, namespace string, conditions []strimziapi.Condition, labelSelector string, port uint32, scheme string, allowUnready bool) (*Endpoint, error) {
+	if !isReady(conditions) {
+		if !allowUnready {
+			slog.Warn("Skipping component which is not ready", "kind", kind, "name", name, "namespace", namespace, "overrideFlag", "--allow-unready")
+			return nil, nil
+		}
+
+		slog.Warn("Component is not Ready, continuing because the readiness check was overridden", "kind", kind, "name", name, "namespace", namespace, "overrideFlag", "--allow-unready")
+	}
+
+	pods, err := kube.CoreV1().Pods(namespace).List(context.TODO(), v1.ListOptions{LabelSelector: labelSelector})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pods of %s %s in namespace %s: %w", kind, name, namespace, err)
+	}
+
+	for _, pod := range pods.Items {
+		if IsPodReady(&pod) {
+			slog.Info("Found component", "kind", kind, "name", name, "namespace", namespace, "podName", pod.Name, "port", port)
+			return &Endpoint{Kind: kind, Name: name, Namespace: namespace, PodName: pod.Name, Port: port, Scheme: scheme}, nil
+		}
+	}
+
+	slog.Warn("Skipping component without any ready pod", "kind", kind, "name", name, "namespace", namespace)
+	return nil, nil
+}
+
+// targetsKafka checks whether the bootstrap servers point to the bootstrap service of the Kafka
+// cluster. The service can be addressed by its short name or by its fully qualified name.
+func targetsKafka(bootstrapServers string, namespace string, clusterName string) bool {
+	for _, server := range strings.Split(bootstrapServers, ",") {
+		host := strings.TrimSpace(server)
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+
+		labels := strings.Split(host, ".")
+		if labels[0] != clusterName+"-kafka-bootstrap" {
+			continue
+		}
+
+		if len(labels) == 1 || labels[1] == namespace {
+			return true
+		}
+	}
+
+	return false
+}
+
+func isReady(conditions []strimziapi.Condition) bool {
+	for _, condition := range conditions {
+		if condition.Type == "Ready" && condition.Status == "True" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsPodReady checks whether the pod is running and has the Ready condition set to True.
+func IsPodReady(pod *corev1.Pod) bool {
+	if pod.Status.Phase != corev1.PodRunning {
+		return false
+	}
+
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+
+	return false
+}
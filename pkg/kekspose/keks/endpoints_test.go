@@ -0,0 +1,107 @@
+package keks
+
+import (
+	"testing"
+
+	kafkav1 "github.com/scholzj/strimzi-go/pkg/apis/kafka.strimzi.io/v1"
+	strimzifake "github.com/scholzj/strimzi-go/pkg/client/clientset/versioned/fake"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestTargetsKafka(t *testing.T) {
+	assert.True(t, targetsKafka("my-cluster-kafka-bootstrap:9092", "my-namespace", "my-cluster"))
+	assert.True(t, targetsKafka("my-cluster-kafka-bootstrap.my-namespace.svc:9093", "my-namespace", "my-cluster"))
+	assert.True(t, targetsKafka("other:9092, my-cluster-kafka-bootstrap.my-namespace.svc.cluster.local:9092", "my-namespace", "my-cluster"))
+	assert.False(t, targetsKafka("my-cluster-kafka-bootstrap.other-namespace.svc:9092", "my-namespace", "my-cluster"))
+	assert.False(t, targetsKafka("other-cluster-kafka-bootstrap:9092", "my-namespace", "my-cluster"))
+	assert.False(t, targetsKafka("", "my-namespace", "my-cluster"))
+}
+
+func TestFindKafkaConnects(t *testing.T) {
+	ready := &kafkav1.KafkaConnectStatus{Conditions: []kafkav1.Condition{{Type: "Ready", Status: "True"}}, LabelSelector: "strimzi.io/cluster=my-connect,strimzi.io/kind=KafkaConnect"}
+	strimziclient := strimzifake.NewSimpleClientset(
+		&kafkav1.KafkaConnect{
+			ObjectMeta: metav1.ObjectMeta{Name: "my-connect", Namespace: "my-namespace"},
+			Spec:       &kafkav1.KafkaConnectSpec{BootstrapServers: "my-cluster-kafka-bootstrap:9092"},
+			Status:     ready,
+		},
+		&kafkav1.KafkaConnect{
+			ObjectMeta: metav1.ObjectMeta{Name: "other-connect", Namespace: "my-namespace"},
+			Spec:       &kafkav1.KafkaConnectSpec{BootstrapServers: "other-cluster-kafka-bootstrap:9092"},
+			Status:     ready,
+		},
+		&kafkav1.KafkaConnect{
+			ObjectMeta: metav1.ObjectMeta{Name: "unready-connect", Namespace: "my-namespace"},
+			Spec:       &kafkav1.KafkaConnectSpec{BootstrapServers: "my-cluster-kafka-bootstrap:9092"},
+		},
+	)
+
+	labels := map[string]string{"strimzi.io/cluster": "my-connect", "strimzi.io/kind": "KafkaConnect"}
+	kubeclient := fake.NewSimpleClientset(
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "my-connect-connect-0", Namespace: "my-namespace", Labels: labels},
+			Status:     corev1.PodStatus{Phase: corev1.PodPending},
+		},
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "my-connect-connect-1", Namespace: "my-namespace", Labels: labels},
+			Status:     corev1.PodStatus{Phase: corev1.PodRunning, Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}}},
+		},
+	)
+
+	endpoints, err := FindKafkaConnects(strimziclient, kubeclient, "my-namespace", "my-cluster", false)
+
+	require.NoError(t, err)
+	assert.Equal(t, []Endpoint{{Kind: "KafkaConnect", Name: "my-connect", Namespace: "my-namespace", PodName: "my-connect-connect-1", Port: KafkaConnectRestPort, Scheme: "http"}}, endpoints)
+}
+
+func TestFindKafkaBridges(t *testing.T) {
+	ready := &kafkav1.KafkaBridgeStatus{Conditions: []kafkav1.Condition{{Type: "Ready", Status: "True"}}}
+	strimziclient := strimzifake.NewSimpleClientset(
+		&kafkav1.KafkaBridge{
+			ObjectMeta: metav1.ObjectMeta{Name: "my-bridge", Namespace: "my-namespace"},
+			Spec:       &kafkav1.KafkaBridgeSpec{BootstrapServers: "my-cluster-kafka-bootstrap:9092", Http: &kafkav1.KafkaBridgeHttpConfig{Port: 8088}},
+			Status:     ready,
+		},
+		&kafkav1.KafkaBridge{
+			ObjectMeta: metav1.ObjectMeta{Name: "default-bridge", Namespace: "my-namespace"},
+			Spec:       &kafkav1.KafkaBridgeSpec{BootstrapServers: "my-cluster-kafka-bootstrap:9092", Http: &kafkav1.KafkaBridgeHttpConfig{Tls: &kafkav1.KafkaBridgeHttpTls{}}},
+			Status:     ready,
+		},
+		&kafkav1.KafkaBridge{
+			ObjectMeta: metav1.ObjectMeta{Name: "unready-bridge", Namespace: "my-namespace"},
+			Spec:       &kafkav1.KafkaBridgeSpec{BootstrapServers: "my-cluster-kafka-bootstrap:9092"},
+		},
+	)
+
+	kubeclient := fake.NewSimpleClientset(
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "my-bridge-bridge-abc", Namespace: "my-namespace", Labels: map[string]string{"strimzi.io/cluster": "my-bridge", "strimzi.io/kind": "KafkaBridge"}},
+			Status:     corev1.PodStatus{Phase: corev1.PodRunning, Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}}},
+		},
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "default-bridge-bridge-def", Namespace: "my-namespace", Labels: map[string]string{"strimzi.io/cluster": "default-bridge", "strimzi.io/kind": "KafkaBridge"}},
+			Status:     corev1.PodStatus{Phase: corev1.PodRunning, Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}}},
+		},
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "unready-bridge-bridge-ghi", Namespace: "my-namespace", Labels: map[string]string{"strimzi.io/cluster": "unready-bridge", "strimzi.io/kind": "KafkaBridge"}},
+			Status:     corev1.PodStatus{Phase: corev1.PodRunning, Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}}},
+		},
+	)
+
+	endpoints, err := FindKafkaBridges(strimziclient, kubeclient, "my-namespace", "my-cluster", false)
+
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []Endpoint{
+		{Kind: "KafkaBridge", Name: "my-bridge", Namespace: "my-namespace", PodName: "my-bridge-bridge-abc", Port: 8088, Scheme: "http"},
+		{Kind: "KafkaBridge", Name: "default-bridge", Namespace: "my-namespace", PodName: "default-bridge-bridge-def", Port: KafkaBridgeDefaultHttpPort, Scheme: "https"},
+	}, endpoints)
+
+	endpoints, err = FindKafkaBridges(strimziclient, kubeclient, "my-namespace", "my-cluster", true)
+
+	require.NoError(t, err)
+	assert.Len(t, endpoints, 3)
+}
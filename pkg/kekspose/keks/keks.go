@@ -29,19 +29,37 @@ import (
 )
 
 type Keks struct {
-	Nodes        map[int32]string
-	Port         uint32
-	TLS          bool
-	ListenerName string
+	Nodes          map[int32]string
+	Port           uint32
+	TLS            bool
+	ListenerName   string
+	Authentication string
 }
 
 func BakeKeks(strimzi strimziclient.Interface, namespace string, clusterName string, listenerName string, allowUnready bool, allowInsecureTLS bool) (*Keks, error) {
+	var listenerNames []string
+	if listenerName != "" {
+		listenerNames = []string{listenerName}
+	}
+
+	kekses, err := BakeKeksForListeners(strimzi, namespace, clusterName, listenerNames, allowUnready, allowInsecureTLS)
+	if err != nil {
+		return nil, err
+	}
+
+	return kekses[0], nil
+}
+
+// BakeKeksForListeners finds the Kafka cluster and returns one Keks for each of the listeners. The
+// nodes are shared by all of them. When no listener names are given, the first suitable listener is
+// used.
+func BakeKeksForListeners(strimzi strimziclient.Interface, namespace string, clusterName string, listenerNames []string, allowUnready bool, allowInsecureTLS bool) ([]*Keks, error) {
 	kafka, err := findKafka(strimzi, namespace, clusterName, allowUnready)
 	if err != nil {
 		return nil, err
 	}
 
-	listener, err := findListener(kafka, listenerName, allowInsecureTLS)
+	listeners, err := findListeners(kafka, listenerNames, allowInsecureTLS)
 	if err != nil {
 		return nil, err
 	}
@@ -51,14 +69,18 @@ func BakeKeks(strimzi strimziclient.Interface, namespace string, clusterName str
 		return nil, err
 	}
 
-	keks := &Keks{
-		Port:         uint32(listener.Port),
-		Nodes:        nodes,
-		TLS:          listener.Tls,
-		ListenerName: listener.Name,
+	kekses := make([]*Keks, 0, len(listeners))
+	for _, listener := range listeners {
+		kekses = append(kekses, &Keks{
+			Port:           uint32(listener.Port),
+			Nodes:          nodes,
+			TLS:            listener.Tls,
+			ListenerName:   listener.Name,
+			Authentication: listenerAuthentication(*listener),
+		})
 	}
 
-	return keks, nil
+	return kekses, nil
 }
 
 func findKafka(strimzi strimziclient.Interface, namespace string, clusterName string, allowUnready bool) (*strimziapi.Kafka, error) {
@@ -73,7 +95,7 @@ func findKafka(strimzi strimziclient.Interface, namespace string, clusterName st
 		return nil, fmt.Errorf("failed to get Kafka cluster %s in namespace %s: %w", clusterName, namespace, err)
 	}
 
-	if !isKafkaReady(kafka) {
+	if !IsKafkaReady(kafka) {
 		if !allowUnready {
 			//goland:noinspection GoErrorStringFormat
 			return nil, fmt.Errorf("Kafka cluster %s in namespace %s was found, but it is not ready. Use --allow-unready to override this check", clusterName, namespace)
@@ -87,7 +109,8 @@ func findKafka(strimzi strimziclient.Interface, namespace string, clusterName st
 	return kafka, nil
 }
 
-func isKafkaReady(kafka *strimziapi.Kafka) bool {
+// IsKafkaReady checks whether the Kafka resource has the Ready condition set to True.
+func IsKafkaReady(kafka *strimziapi.Kafka) bool {
 	if kafka.Status != nil && kafka.Status.Conditions != nil && len(kafka.Status.Conditions) > 0 {
 		for _, condition := range kafka.Status.Conditions {
 			if condition.Type == "Ready" && condition.Status == "True" {
@@ -101,6 +124,35 @@ func isKafkaReady(kafka *strimziapi.Kafka) bool {
 	}
 }
 
+// findListeners finds the listener for each of the listener names. Without any listener names, it
+// finds the first suitable listener instead.
+func findListeners(kafka *strimziapi.Kafka, listenerNames []string, allowInsecureTLS bool) ([]*strimziapi.GenericKafkaListener, error) {
+	if len(listenerNames) == 0 {
+		listener, err := findListener(kafka, "", allowInsecureTLS)
+		if err != nil {
+			return nil, err
+		}
+
+		return []*strimziapi.GenericKafkaListener{listener}, nil
+	}
+
+	listeners := make([]*strimziapi.GenericKafkaListener, 0, len(listenerNames))
+	for _, listenerName := range listenerNames {
+		if slices.ContainsFunc(listeners, func(listener *strimziapi.GenericKafkaListener) bool { return listener.Name == listenerName }) {
+			continue
+		}
+
+		listener, err := findListener(kafka, listenerName, allowInsecureTLS)
+		if err != nil {
+			return nil, err
+		}
+
+		listeners = append(listeners, listener)
+	}
+
+	return listeners, nil
+}
+
 func findListener(kafka *strimziapi.Kafka, listenerName string, allowInsecureTLS bool) (*strimziapi.GenericKafkaListener, error) {
 	var listener *strimziapi.GenericKafkaListener
 	var err error
@@ -127,7 +179,7 @@ func findListener(kafka *strimziapi.Kafka, listenerName string, allowInsecureTLS
 
 func findFirstSuitableListener(kafka *strimziapi.Kafka, allowInsecureTLS bool) (*strimziapi.GenericKafkaListener, error) {
 	for _, listener := range kafka.Spec.Kafka.Listeners {
-		if !listener.Tls || allowInsecureTLS {
+		if isSuitableListener(listener, allowInsecureTLS) {
 			slog.Info("Found suitable listener", "listener", listener.Name, "tls", listener.Tls)
 			return &listener, nil
 		}
@@ -140,6 +192,10 @@ func findFirstSuitableListener(kafka *strimziapi.Kafka, allowInsecureTLS bool) (
 	return nil, fmt.Errorf("no Kafka listener without TLS encryption found. Use --allow-insecure-tls to allow TLS-encrypted listeners")
 }
 
+func isSuitableListener(listener strimziapi.GenericKafkaListener, allowInsecureTLS bool) bool {
+	return !listener.Tls || allowInsecureTLS
+}
+
 func findListenerByName(kafka *strimziapi.Kafka, listenerName string, allowInsecureTLS bool) (*strimziapi.GenericKafkaListener, error) {
 	for _, listener := range kafka.Spec.Kafka.Listeners {
 		if listener.Name == listenerName {
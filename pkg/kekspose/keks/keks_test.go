@@ -464,3 +464,44 @@ func TestAutoSelectTLSListenerWithAllowInsecureTLS(t *testing.T) {
 	assert.Equal(t, "tls-first", keks.ListenerName)
 	assert.Equal(t, uint32(9093), keks.Port)
 }
+
+func TestMultipleListeners(t *testing.T) {
+	kafka := &kafkav1.Kafka{
+		ObjectMeta: metav1.ObjectMeta{Name: "my-cluster", Namespace: "my-namespace"},
+		Spec: &kafkav1.KafkaSpec{Kafka: &kafkav1.KafkaClusterSpec{Version: "3.9.0", Listeners: []kafkav1.GenericKafkaListener{{
+			Name: "plain", Type: kafkav1.INTERNAL_KAFKALISTENERTYPE, Tls: false, Port: 9092,
+		}, {
+			Name: "scram", Type: kafkav1.INTERNAL_KAFKALISTENERTYPE, Tls: false, Port: 9094,
+			Authentication: &kafkav1.KafkaListenerAuthentication{Type: kafkav1.SCRAM_SHA_512_KAFKALISTENERAUTHENTICATIONTYPE},
+		}, {
+			Name: "tls", Type: kafkav1.INTERNAL_KAFKALISTENERTYPE, Tls: true, Port: 9093,
+		}}}},
+		Status: &kafkav1.KafkaStatus{Conditions: []kafkav1.Condition{{Type: "Ready", Status: "True"}}},
+	}
+	nodePool := &kafkav1.KafkaNodePool{
+		ObjectMeta: metav1.ObjectMeta{Name: "pool-a", Namespace: "my-namespace", Labels: map[string]string{"strimzi.io/cluster": "my-cluster"}},
+		Spec:       &kafkav1.KafkaNodePoolSpec{Replicas: 2, Roles: []kafkav1.ProcessRoles{kafkav1.BROKER_PROCESSROLES}},
+		Status:     &kafkav1.KafkaNodePoolStatus{NodeIds: []int32{0, 1}},
+	}
+	client := fake.NewSimpleClientset()
+	_, err := client.KafkaV1().Kafkas("my-namespace").Create(context.TODO(), kafka, metav1.CreateOptions{})
+	assert.Nil(t, err)
+	_, err = client.KafkaV1().KafkaNodePools("my-namespace").Create(context.TODO(), nodePool, metav1.CreateOptions{})
+	assert.Nil(t, err)
+
+	kekses, err := BakeKeksForListeners(client, "my-namespace", "my-cluster", []string{"scram", "plain", "scram"}, false, false)
+	assert.Nil(t, err)
+	assert.Len(t, kekses, 2)
+	assert.Equal(t, "scram", kekses[0].ListenerName)
+	assert.Equal(t, uint32(9094), kekses[0].Port)
+	assert.Equal(t, "scram-sha-512", kekses[0].Authentication)
+	assert.Equal(t, "plain", kekses[1].ListenerName)
+	assert.Equal(t, uint32(9092), kekses[1].Port)
+	assert.Equal(t, "none", kekses[1].Authentication)
+	assert.Equal(t, map[int32]string{0: "my-cluster-pool-a-0", 1: "my-cluster-pool-a-1"}, kekses[1].Nodes)
+
+	// Any unsuitable listener fails the whole lookup
+	kekses, err = BakeKeksForListeners(client, "my-namespace", "my-cluster", []string{"plain", "tls"}, false, false)
+	assert.Nil(t, kekses)
+	assert.Equal(t, "Kafka listener with name tls exists, but has unsupported configuration (TLS encryption is enabled). Use --allow-insecure-tls to allow it", err.Error())
+}
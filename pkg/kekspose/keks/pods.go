@@ -0,0 +1,135 @@
+/*
+Copyright © 2025 Jakub Scholz
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package keks
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// NodeIdFromOrdinal uses the ordinal suffix of the pod name (e.g. 2 for kafka-2) as the node ID.
+	NodeIdFromOrdinal = "ordinal"
+	// nodeIdFromAnnotation uses the value of the pod annotation (annotation:<key>) as the node ID.
+	nodeIdFromAnnotation = "annotation:"
+	// nodeIdFromLabel uses the value of the pod label (label:<key>) as the node ID.
+	nodeIdFromLabel = "label:"
+)
+
+// BakeKeksFromPods finds the Kafka brokers of a Kafka cluster not managed by Strimzi (e.g. installed
+// using a Helm chart or as a plain StatefulSet). The broker pods are selected using the label
+// selector and their node IDs are extracted using the node ID rule (ordinal, annotation:<key>, or
+// label:<key>). The listener on the port uses TLS when tls is set.
+func BakeKeksFromPods(kube kubernetes.Interface, namespace string, labelSelector string, port uint32, tls bool, nodeIdRule string, allowUnready bool) (*Keks, error) {
+	if err := ValidateNodeIdRule(nodeIdRule); err != nil {
+		return nil, err
+	}
+
+	pods, err := kube.CoreV1().Pods(namespace).List(context.TODO(), v1.ListOptions{LabelSelector: labelSelector})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pods with label selector %s in namespace %s: %w", labelSelector, namespace, err)
+	}
+
+	nodes := make(map[int32]string)
+	for _, pod := range pods.Items {
+		if !IsPodReady(&pod) {
+			if !allowUnready {
+				slog.Warn("Skipping Kafka pod which is not ready", "podName", pod.Name, "namespace", namespace, "overrideFlag", "--allow-unready")
+				continue
+			}
+
+			slog.Warn("Kafka pod is not Ready, continuing because the readiness check was overridden", "podName", pod.Name, "namespace", namespace, "overrideFlag", "--allow-unready")
+		}
+
+		nodeId, err := podNodeId(&pod, nodeIdRule)
+		if err != nil {
+			return nil, err
+		}
+
+		if other, found := nodes[nodeId]; found {
+			return nil, fmt.Errorf("pods %s and %s have the same node ID %d", other, pod.Name, nodeId)
+		}
+
+		nodes[nodeId] = pod.Name
+	}
+
+	if len(nodes) == 0 {
+		return nil, fmt.Errorf("no Kafka pods to expose were found with label selector %s in namespace %s", labelSelector, namespace)
+	}
+
+	slog.Info("Found Kafka nodes", "nodes", nodes)
+
+	// Without the Strimzi resources, there is no information about the listener authentication
+	return &Keks{
+		Nodes:          nodes,
+		Port:           port,
+		TLS:            tls,
+		Authentication: "unknown",
+	}, nil
+}
+
+// ValidateNodeIdRule checks that the node ID rule is one of the supported rules.
+func ValidateNodeIdRule(nodeIdRule string) error {
+	if nodeIdRule == NodeIdFromOrdinal {
+		return nil
+	}
+
+	for _, prefix := range []string{nodeIdFromAnnotation, nodeIdFromLabel} {
+		if key, found := strings.CutPrefix(nodeIdRule, prefix); found && key != "" {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("invalid node ID rule %q. Use ordinal, annotation:<key>, or label:<key>", nodeIdRule)
+}
+
+// podNodeId extracts the node ID of the pod using the node ID rule.
+func podNodeId(pod *corev1.Pod, nodeIdRule string) (int32, error) {
+	var value string
+
+	if key, found := strings.CutPrefix(nodeIdRule, nodeIdFromAnnotation); found {
+		value, found = pod.Annotations[key]
+		if !found {
+			return 0, fmt.Errorf("pod %s does not have the annotation %s with the node ID", pod.Name, key)
+		}
+	} else if key, found := strings.CutPrefix(nodeIdRule, nodeIdFromLabel); found {
+		value, found = pod.Labels[key]
+		if !found {
+			return 0, fmt.Errorf("pod %s does not have the label %s with the node ID", pod.Name, key)
+		}
+	} else {
+		index := strings.LastIndex(pod.Name, "-")
+		if index < 0 {
+			return 0, fmt.Errorf("pod name %s does not end with an ordinal number", pod.Name)
+		}
+		value = pod.Name[index+1:]
+	}
+
+	nodeId, err := strconv.ParseInt(value, 10, 32)
+	if err != nil || nodeId < 0 {
+		return 0, fmt.Errorf("failed to extract the node ID of pod %s from %q using rule %s", pod.Name, value, nodeIdRule)
+	}
+
+	return int32(nodeId), nil
+}
@@ -0,0 +1,67 @@
+package keks
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestBakeKeksFromPodsWithOrdinals(t *testing.T) {
+	kubeclient := fake.NewSimpleClientset(
+		kafkaPod("kafka-0", nil, corev1.ConditionTrue),
+		kafkaPod("kafka-1", nil, corev1.ConditionTrue),
+		kafkaPod("kafka-2", nil, corev1.ConditionFalse),
+	)
+
+	keks, err := BakeKeksFromPods(kubeclient, "my-namespace", "app.kubernetes.io/name=kafka", 9092, false, NodeIdFromOrdinal, false)
+
+	require.NoError(t, err)
+	assert.Equal(t, map[int32]string{0: "kafka-0", 1: "kafka-1"}, keks.Nodes)
+	assert.Equal(t, uint32(9092), keks.Port)
+	assert.False(t, keks.TLS)
+
+	keks, err = BakeKeksFromPods(kubeclient, "my-namespace", "app.kubernetes.io/name=kafka", 9092, false, NodeIdFromOrdinal, true)
+
+	require.NoError(t, err)
+	assert.Len(t, keks.Nodes, 3)
+}
+
+func TestBakeKeksFromPodsWithAnnotation(t *testing.T) {
+	kubeclient := fake.NewSimpleClientset(
+		kafkaPod("kafka-a", map[string]string{"example.com/broker-id": "100"}, corev1.ConditionTrue),
+		kafkaPod("kafka-b", map[string]string{"example.com/broker-id": "101"}, corev1.ConditionTrue),
+	)
+
+	keks, err := BakeKeksFromPods(kubeclient, "my-namespace", "app.kubernetes.io/name=kafka", 9094, true, "annotation:example.com/broker-id", false)
+
+	require.NoError(t, err)
+	assert.Equal(t, map[int32]string{100: "kafka-a", 101: "kafka-b"}, keks.Nodes)
+	assert.True(t, keks.TLS)
+
+	_, err = BakeKeksFromPods(kubeclient, "my-namespace", "app.kubernetes.io/name=kafka", 9094, false, "label:broker-id", false)
+	require.EqualError(t, err, "pod kafka-a does not have the label broker-id with the node ID")
+}
+
+func TestBakeKeksFromPodsWithoutPods(t *testing.T) {
+	_, err := BakeKeksFromPods(fake.NewSimpleClientset(), "my-namespace", "app.kubernetes.io/name=kafka", 9092, false, NodeIdFromOrdinal, false)
+	require.EqualError(t, err, "no Kafka pods to expose were found with label selector app.kubernetes.io/name=kafka in namespace my-namespace")
+}
+
+func TestValidateNodeIdRule(t *testing.T) {
+	assert.NoError(t, ValidateNodeIdRule("ordinal"))
+	assert.NoError(t, ValidateNodeIdRule("annotation:broker-id"))
+	assert.NoError(t, ValidateNodeIdRule("label:broker-id"))
+	assert.EqualError(t, ValidateNodeIdRule("label:"), `invalid node ID rule "label:". Use ordinal, annotation:<key>, or label:<key>`)
+	assert.Error(t, ValidateNodeIdRule("name"))
+}
+
+func kafkaPod(name string, annotations map[string]string, ready corev1.ConditionStatus) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "my-namespace", Labels: map[string]string{"app.kubernetes.io/name": "kafka"}, Annotations: annotations},
+		Status:     corev1.PodStatus{Phase: corev1.PodRunning, Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: ready}}},
+	}
+}
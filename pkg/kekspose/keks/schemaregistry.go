@@ -0,0 +1,60 @@
+/*
+Copyright © 2025 Jakub Scholz
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package keks
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// SchemaRegistryKind is the kind of the forwarded schema registry endpoint.
+	SchemaRegistryKind = "SchemaRegistry"
+	// SchemaRegistryDefaultPort is the default port of the schema registry API.
+	SchemaRegistryDefaultPort uint32 = 8081
+)
+
+// FindSchemaRegistry finds a ready pod of the schema registry selected by the label selector to forward
+// its API. The schema registry is not managed by Strimzi, so its readiness is decided only by its pods.
+// It returns nil when none of the selected pods is ready. The endpoint is named after the
+// app.kubernetes.io/name label of the pod, or after the pod when it does not have it.
+func FindSchemaRegistry(kube kubernetes.Interface, namespace string, labelSelector string, port uint32) (*Endpoint, error) {
+	pods, err := kube.CoreV1().Pods(namespace).List(context.TODO(), v1.ListOptions{LabelSelector: labelSelector})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the schema registry pods in namespace %s: %w", namespace, err)
+	}
+
+	for _, pod := range pods.Items {
+		if !IsPodReady(&pod) {
+			continue
+		}
+
+		name := pod.Labels["app.kubernetes.io/name"]
+		if name == "" {
+			name = pod.Name
+		}
+
+		slog.Info("Found component", "kind", SchemaRegistryKind, "name", name, "namespace", namespace, "podName", pod.Name, "port", port)
+		return &Endpoint{Kind: SchemaRegistryKind, Name: name, Namespace: namespace, PodName: pod.Name, Port: port, Scheme: "http"}, nil
+	}
+
+	return nil, nil
+}
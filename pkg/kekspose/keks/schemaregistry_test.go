@@ -0,0 +1,48 @@
+package keks
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestFindSchemaRegistry(t *testing.T) {
+	kubeclient := fake.NewSimpleClientset(
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "apicurio-registry-0", Namespace: "my-namespace", Labels: map[string]string{"app": "registry"}},
+			Status:     corev1.PodStatus{Phase: corev1.PodPending},
+		},
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "apicurio-registry-1", Namespace: "my-namespace", Labels: map[string]string{"app": "registry", "app.kubernetes.io/name": "apicurio-registry"}},
+			Status:     corev1.PodStatus{Phase: corev1.PodRunning, Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}}},
+		},
+	)
+
+	endpoint, err := FindSchemaRegistry(kubeclient, "my-namespace", "app=registry", 8080)
+
+	require.NoError(t, err)
+	assert.Equal(t, &Endpoint{
+		Kind:      SchemaRegistryKind,
+		Name:      "apicurio-registry",
+		Namespace: "my-namespace",
+		PodName:   "apicurio-registry-1",
+		Port:      8080,
+		Scheme:    "http",
+	}, endpoint)
+}
+
+func TestFindSchemaRegistryWithoutReadyPods(t *testing.T) {
+	kubeclient := fake.NewSimpleClientset(&corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "schema-registry-0", Namespace: "my-namespace", Labels: map[string]string{"app": "schema-registry"}},
+		Status:     corev1.PodStatus{Phase: corev1.PodPending},
+	})
+
+	endpoint, err := FindSchemaRegistry(kubeclient, "my-namespace", "app=schema-registry", SchemaRegistryDefaultPort)
+
+	require.NoError(t, err)
+	assert.Nil(t, endpoint)
+}
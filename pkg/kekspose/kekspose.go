@@ -29,8 +29,13 @@ import (
 	"strings"
 	"sync"
 	"syscall"
+	"time"
 
 	keks2 "github.com/scholzj/kekspose/pkg/kekspose/keks"
+	"github.com/scholzj/kekspose/pkg/kekspose/proxiedforward"
+	"github.com/scholzj/kekspose/pkg/kekspose/records"
+	"github.com/scholzj/kekspose/pkg/kekspose/schemaregistry"
+	"github.com/scholzj/kekspose/pkg/kekspose/session"
 	"github.com/scholzj/proksy"
 	"github.com/scholzj/proksy/filter"
 	strimzi "github.com/scholzj/strimzi-go/pkg/client/clientset/versioned"
@@ -41,11 +46,15 @@ import (
 )
 
 type Kekspose struct {
-	KubeConfigPath   string
-	Context          string
-	Namespace        string
-	ClusterName      string
-	ListenerName     string
+	KubeConfigPath string
+	Context        string
+	Namespace      string
+	// ClusterNames are the names of the Kafka clusters to expose. Each name can be qualified with
+	// its namespace (<namespace>/<name>); names without namespace use the Namespace field.
+	ClusterNames []string
+	// ListenerNames are the names of the listeners to expose. Each listener gets its own range of
+	// local ports. When empty, the first suitable listener is exposed.
+	ListenerNames    []string
 	StartingPort     uint32
 	AllowUnready     bool
 	AllowInsecureTLS bool
@@ -54,14 +63,116 @@ type Kekspose struct {
 	// BodyAPIKeys restricts decoding+logging of full message bodies to these Kafka API keys. Empty
 	// means decode bodies for every logged API (the default behaviour).
 	BodyAPIKeys []int16
+	// LogClientIDs, LogTopics, and LogGroups restrict RPC logging to the requests from the matching
+	// client IDs, using the matching topics, or using the matching consumer groups. They are lists of
+	// patterns with the * and ? wildcards. Empty means no restriction.
+	LogClientIDs []string
+	LogTopics    []string
+	LogGroups    []string
+	// RecordTopics decodes and logs the records produced to and fetched from the topics matching these
+	// patterns. The keys, values, and headers are rendered in RecordFormat and truncated to
+	// RecordMaxBytes. Empty disables decoding the records.
+	RecordTopics   []string
+	RecordFormat   records.Format
+	RecordMaxBytes int
+	// Redact selects the data redacted in the RPC logs (values, headers, or configs). The SASL
+	// authentication bytes are always redacted.
+	Redact []string
+	// LogErrors logs the error codes in the responses at the warning level, and summarizes them by
+	// the API and the error code at the end of the session.
+	LogErrors bool
+	// MonitorGroups logs the members joining and leaving the consumer groups, their generations,
+	// assignments, rebalances, and committed offsets, and summarizes the groups at the end of the session.
+	MonitorGroups bool
+	// SchemaRegistryURL is the URL of the schema registry used to decode the keys and values of the
+	// logged records which use the Confluent wire format. Alternatively, SchemaRegistrySelector selects
+	// the schema registry pods in the namespace of the first exposed Kafka cluster, and their
+	// SchemaRegistryPort is forwarded.
+	SchemaRegistryURL      string
+	SchemaRegistrySelector string
+	SchemaRegistryPort     uint32
+	// AutoPort moves the port mapping to the next block of free local ports when some of the ports
+	// starting from StartingPort are already in use.
+	AutoPort bool
+	// Interactive lets the user pick the Kafka cluster and listener from the terminal when the
+	// configured Kafka cluster does not exist.
+	Interactive bool
+	// CommandLine returns the command line equivalent to the current one, but with the given cluster
+	// and listener names. It is logged after the interactive selection when set.
+	CommandLine func(clusterNames []string, listenerNames []string) string
+	// KafkaConnect forwards the REST API of the Kafka Connect clusters connected to the exposed Kafka
+	// clusters as well.
+	KafkaConnect bool
+	// KafkaBridge forwards the HTTP API of the Kafka Bridges connected to the exposed Kafka clusters
+	// as well.
+	KafkaBridge bool
+	// CruiseControl forwards the Cruise Control REST API of the exposed Kafka clusters as well.
+	CruiseControl bool
+	// CruiseControlAuth adds the Cruise Control API credentials to the forwarded requests, so they
+	// do not need to be provided by the user.
+	CruiseControlAuth bool
+	// PodSelector is the label selector of the broker pods of a Kafka cluster not managed by Strimzi.
+	// When set, the brokers are discovered from the pods instead of the Strimzi resources.
+	PodSelector string
+	// PodPort is the container port of the Kafka listener used with PodSelector.
+	PodPort uint32
+	// PodTLS connects to the Kafka listener of the pods selected by PodSelector using TLS. Like the TLS
+	// listeners of the Strimzi-managed clusters, it requires AllowInsecureTLS.
+	PodTLS bool
+	// NodeIdRule defines how the node IDs are extracted from the pods selected by PodSelector
+	// (ordinal, annotation:<key>, or label:<key>).
+	NodeIdRule string
+	// DiscoverNodes verifies the nodes found in the node pools against the brokers returned by the
+	// Kafka cluster in the Metadata response and uses the discovered ones.
+	DiscoverNodes bool
+	// WaitTimeout is how long to wait for the pods to become ready before starting the port forwarding.
+	// Zero disables the readiness check.
+	WaitTimeout time.Duration
+	// PartialStart starts the port forwarding for the ready pods right away and for the other pods once
+	// they become ready.
+	PartialStart bool
+	// LazyConnect opens the local ports right away, but connects to the pods only when the first
+	// client connects. The connection is closed again after IdleTimeout without any clients.
+	LazyConnect bool
+	IdleTimeout time.Duration
+	// Transport is the transport used for the port forwarding connections to the Kubernetes API
+	// server (auto, websocket, or spdy).
+	Transport string
+	// TLSHandshakeTimeout limits the TLS handshake with the brokers when using --allow-insecure-tls.
+	TLSHandshakeTimeout time.Duration
+	// ConnectionIdleTimeout closes the client connections without any traffic for this long. Zero
+	// keeps them open.
+	ConnectionIdleTimeout time.Duration
+	// ReadTimeout closes the client connections when the pod does not respond for this long. Zero
+	// waits forever.
+	ReadTimeout time.Duration
+	// DrainTimeout is how long to wait on shutdown for the requests in flight to complete. Zero closes
+	// the connections right away.
+	DrainTimeout time.Duration
+	// SessionName is the name of the session when running in the background. Its state file is written
+	// to SessionDir once the port forwarding is ready and removed again on shutdown.
+	SessionName string
+	SessionDir  string
+	// AdminAddress is the address of the local admin API. The admin API is disabled when it is empty.
+	// Its token is written to AdminTokenFile.
+	AdminAddress   string
+	AdminTokenFile string
+
+	engines  proxyEngines
+	topicIDs topicRegistry
+	errors   errorStats
+	groups   groupMonitor
+	schemas  *schemaregistry.Client
 }
 
 func (k *Kekspose) ExposeKafka() error {
 	k.resolveKubeConfigPath()
 	clientConfig := k.newClientConfig()
 
-	if err := k.resolveNamespace(); err != nil {
-		return fmt.Errorf("failed to determine the namespace: %w", err)
+	if k.needsDefaultNamespace() {
+		if err := k.resolveNamespace(); err != nil {
+			return fmt.Errorf("failed to determine the namespace: %w", err)
+		}
 	}
 
 	kubeconfig, err := clientConfig.ClientConfig()
@@ -82,51 +193,168 @@ func (k *Kekspose) ExposeKafka() error {
 		return fmt.Errorf("failed to create Strimzi client: %w", err)
 	}
 
-	// Get Kafka cluster details
-	keks, err := keks2.BakeKeks(strimziclient, k.Namespace, k.ClusterName, k.ListenerName, k.AllowUnready, k.AllowInsecureTLS)
+	if k.Interactive && len(k.ClusterNames) == 1 && k.PodSelector == "" {
+		if err := k.pickKafka(strimziclient, os.Stdin, os.Stdout); err != nil {
+			return fmt.Errorf("failed to select the Kafka cluster: %w", err)
+		}
+	}
+
+	// Get Kafka cluster details and prepare the port mappings
+	var exposures []*exposure
+	if k.PodSelector != "" {
+		exposures, err = k.bakePodExposure(kubeclient)
+	} else {
+		var discover func(clusterReference, []*keks2.Keks) error
+		if k.DiscoverNodes {
+			discover = func(reference clusterReference, kekses []*keks2.Keks) error {
+				return k.discoverNodes(kubeconfig, kubeclient, reference, kekses)
+			}
+		}
+
+		exposures, err = k.bakeExposures(strimziclient, discover)
+	}
 	if err != nil {
-		return fmt.Errorf("failed to find the Kafka cluster with a suitable listener: %w", err)
+		return err
 	}
-	if keks.TLS {
-		slog.Warn("Using TLS upstream with certificate verification disabled", "listenerName", keks.ListenerName, "overrideFlag", "--allow-insecure-tls")
+
+	// Find the HTTP APIs of the connected components
+	endpoints, err := k.bakeEndpoints(strimziclient, kubeclient, exposures)
+	if err != nil {
+		return err
 	}
 
-	ctx, stopSignals := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
-	defer stopSignals()
+	if err := k.connectSchemaRegistry(endpoints); err != nil {
+		return err
+	}
 
-	errors := make(chan error, len(keks.Nodes))
+	signals, stopSignals := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stopSignals()
+	// The session can also be stopped through the admin API
+	ctx, shutdown := context.WithCancel(signals)
+	defer shutdown()
 
-	// Prepare the mapping
-	portMapping := k.preparePortMapping(keks)
+	// The logging options can be changed at runtime with SIGUSR1 and SIGUSR2
+	k.handleLoggingSignals(ctx)
 
 	// Prepare forwarders
-	portForwarders := k.preparePortForwarders(kubeconfig, kubeclient, keks, portMapping)
+	portForwarders := make([]*PortForwarder, 0)
+	for _, e := range exposures {
+		portForwarders = append(portForwarders, k.preparePortForwarders(kubeconfig, kubeclient, e, len(exposures) > 1)...)
+	}
+	authProxies := make([]*authProxy, 0)
+	for _, e := range endpoints {
+		// HTTP APIs are forwarded without the Kafka proxy, so there is no node ID and no engine
+		portForwarders = append(portForwarders, NewPortForwarder(kubeconfig, kubeclient, e.Namespace, e.PodName, -1, e.ForwardPort, e.Port, e.TLS, nil))
+
+		if e.injectsAuth() {
+			proxy, err := newAuthProxy(e.LocalPort, e.ForwardPort, e.Username, e.Password)
+			if err != nil {
+				for _, proxy := range authProxies {
+					proxy.Close()
+				}
+				return fmt.Errorf("failed to start the authentication proxy for %s %s: %w", e.Kind, e.Name, err)
+			}
+			authProxies = append(authProxies, proxy)
+		}
+	}
+
+	// Ports of different listeners and components running in the same pod share the connection
+	portForwarders = groupByPod(portForwarders)
+
+	for _, pf := range portForwarders {
+		k.configurePortForwarder(pf)
+	}
+
+	// The forwarders started later by reloading the discovery report their errors here as well, so
+	// they do not block on it once the shutdown started
+	errors := make(chan error, len(portForwarders)+len(authProxies)+1)
+
+	live := &liveSession{
+		k:             k,
+		ctx:           ctx,
+		kubeconfig:    kubeconfig,
+		kubeclient:    kubeclient,
+		strimziclient: strimziclient,
+		startedAt:     time.Now().UTC(),
+		exposures:     exposures,
+		endpoints:     endpoints,
+		shutdown:      shutdown,
+	}
+	live.addForwarders(portForwarders)
+
+	var admin *adminServer
 
 	var stopOnce sync.Once
 	stopPortForwarders := func() {
 		stopOnce.Do(func() {
-			for _, pf := range portForwarders {
-				id := pf.NodeId
-				slog.Info("Stopping port forwarding between localhost and Kubernetes", "localPort", portMapping[id], "podName", keks.Nodes[id], "remotePort", keks.Port, "namespace", k.Namespace)
-				close(pf.Stop)
+			live.stopAll()
+
+			for _, proxy := range authProxies {
+				proxy.Close()
+			}
+
+			if admin != nil {
+				admin.Close()
 			}
 		})
 	}
 
+	live.start = func(pf *PortForwarder) {
+		if !live.markRunning(pf) {
+			// The forwarder was removed by reloading the discovery while waiting for its pod
+			return
+		}
+
+		for _, port := range pf.ForwardedPorts() {
+			slog.Info("Starting port forwarding between localhost and Kubernetes", "localPort", port.LocalPort, "podName", pf.PodName, "remotePort", port.RemotePort, "namespace", pf.Namespace)
+		}
+
+		go func() {
+			if err := pf.ForwardPorts(); err != nil {
+				select {
+				case errors <- err:
+				case <-ctx.Done():
+				}
+			}
+		}()
+	}
+
+	// Wait for the pods
+	pending, err := k.waitForPods(ctx, kubeclient, portForwarders)
+	if err != nil {
+		stopPortForwarders()
+
+		if ctx.Err() != nil {
+			slog.Info("Received shutdown signal while waiting for the pods, shutting down")
+			return nil
+		}
+
+		return fmt.Errorf("failed waiting for the pods: %w", err)
+	}
+
 	// Start forwarders
+	started := make([]*PortForwarder, 0, len(portForwarders))
 	for _, pf := range portForwarders {
-		id := pf.NodeId
-		slog.Info("Starting port forwarding between localhost and Kubernetes", "localPort", portMapping[id], "podName", keks.Nodes[id], "remotePort", keks.Port, "namespace", k.Namespace)
+		if !slices.Contains(pending, pf) {
+			live.start(pf)
+			started = append(started, pf)
+		}
+	}
 
-		go func(pf *PortForwarder) {
-			if err := pf.ForwardPorts(); err != nil {
+	if len(pending) > 0 {
+		go k.startWhenReady(ctx, kubeclient, pending, live.start)
+	}
+
+	for _, proxy := range authProxies {
+		go func(proxy *authProxy) {
+			if err := proxy.Serve(); err != nil {
 				errors <- err
 			}
-		}(pf)
+		}(proxy)
 	}
 
 	// Wait for forwarders readiness
-	for _, pf := range portForwarders {
+	for _, pf := range started {
 		select {
 		case <-pf.Ready:
 		case err := <-errors:
@@ -136,34 +364,114 @@ func (k *Kekspose) ExposeKafka() error {
 	}
 
 	slog.Info("Port forwarding is ready")
-	slog.Info("Use the following address to access the Kafka cluster", "address", k.bootstrapAddress(portMapping))
-	slog.Info("Press Ctrl+C to stop port forwarding")
+	for _, e := range exposures {
+		slog.Info("Use the following address to access the Kafka cluster", "clusterName", e.Name(), "namespace", e.Namespace, "listenerName", e.Keks.ListenerName, "address", k.bootstrapAddress(e.PortMapping))
+	}
+	for _, e := range endpoints {
+		slog.Info("Use the following URL to access the HTTP API", "kind", e.Kind, "name", e.Name, "namespace", e.Namespace, "url", e.URL())
+
+		if e.injectsAuth() {
+			slog.Info("The credentials are added to the requests automatically", "kind", e.Kind, "name", e.Name, "username", e.Username)
+		} else if e.Username != "" {
+			// The password is not logged, as the logs might end up in the session log file or elsewhere
+			slog.Info("Use the following credentials to access the HTTP API or use --cruise-control-auth to add them automatically", "kind", e.Kind, "name", e.Name, "username", e.Username, "passwordSecret", e.PasswordSecret, "passwordKey", e.PasswordKey)
+		}
+	}
+
+	if k.AdminAddress != "" {
+		tokenFile := k.AdminTokenFile
+		if tokenFile == "" {
+			if tokenFile, err = k.defaultAdminTokenFile(); err != nil {
+				stopPortForwarders()
+				return err
+			}
+		}
+
+		// Assigned before the first use by stopPortForwarders, which runs on this goroutine
+		admin, err = newAdminServer(k.AdminAddress, tokenFile, live)
+		if err != nil {
+			stopPortForwarders()
+			return err
+		}
+		live.adminAddress = admin.URL()
+		live.adminTokenFile = tokenFile
+
+		go func() {
+			if err := admin.Serve(); err != nil {
+				errors <- err
+			}
+		}()
+
+		slog.Info("The admin API is available", "url", admin.URL(), "tokenFile", tokenFile)
+	}
+
+	if k.SessionName != "" {
+		if err := live.saveSession(); err != nil {
+			stopPortForwarders()
+			return err
+		}
+		defer func() {
+			if err := session.Remove(k.SessionDir, k.SessionName); err != nil {
+				slog.Warn("Failed to remove the session state", "session", k.SessionName, "error", err)
+			}
+		}()
+
+		slog.Info("Running in the background. Use kekspose stop to stop port forwarding", "session", k.SessionName)
+	} else {
+		slog.Info("Press Ctrl+C to stop port forwarding")
+	}
 
 	// Wait for shutdown
 	select {
 	case <-ctx.Done():
 		slog.Info("Received shutdown signal, stopping port forwarding")
+
+		// The second signal skips the draining
+		stopSignals()
+		force, stopForce := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		k.drainPortForwarders(force, live.runningForwarders())
+		stopForce()
+
 		stopPortForwarders()
+		k.logErrorSummary()
+		k.logGroupSummary()
 		slog.Info("Shutting down")
 		return nil
 	case err := <-errors:
 		stopPortForwarders()
+		k.logErrorSummary()
+		k.logGroupSummary()
 		return fmt.Errorf("failed forwarding ports: %w", err)
 	}
 }
 
+// configurePortForwarder applies the connection options to the forwarder.
+func (k *Kekspose) configurePortForwarder(pf *PortForwarder) {
+	pf.LazyConnect = k.LazyConnect
+	pf.IdleTimeout = k.IdleTimeout
+	pf.Transport = k.Transport
+	pf.Timeouts = proxiedforward.Timeouts{TLSHandshake: k.TLSHandshakeTimeout, Idle: k.ConnectionIdleTimeout, Read: k.ReadTimeout}
+}
+
 func (k *Kekspose) resolveKubeConfigPath() {
 	if k.KubeConfigPath == "" {
-		if os.Getenv("KUBECONFIG") != "" {
-			k.KubeConfigPath = os.Getenv("KUBECONFIG")
-			slog.Info("Found kubeconfig", "kubeconfig", k.KubeConfigPath)
-		} else if home := homedir.HomeDir(); home != "" {
-			k.KubeConfigPath = filepath.Join(home, ".kube", "config")
+		k.KubeConfigPath = defaultKubeConfigPath()
+		if k.KubeConfigPath != "" {
 			slog.Info("Found kubeconfig", "kubeconfig", k.KubeConfigPath)
 		}
 	}
 }
 
+func defaultKubeConfigPath() string {
+	if os.Getenv("KUBECONFIG") != "" {
+		return os.Getenv("KUBECONFIG")
+	} else if home := homedir.HomeDir(); home != "" {
+		return filepath.Join(home, ".kube", "config")
+	}
+
+	return ""
+}
+
 func (k *Kekspose) newClientConfig() clientcmd.ClientConfig {
 	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
 
@@ -220,24 +528,34 @@ func (k *Kekspose) resolveNamespace() error {
 	return nil
 }
 
-func (k *Kekspose) preparePortMapping(keks *keks2.Keks) map[int32]uint32 {
-	portMapping := make(map[int32]uint32)
-	nextPort := k.StartingPort
+func (k *Kekspose) preparePortForwarders(kubeconfig *rest.Config, kubeclient *kubernetes.Clientset, e *exposure, multipleExposures bool) []*PortForwarder {
+	return k.prepareNodeForwarders(kubeconfig, kubeclient, e, sortedNodeIDs(e.Keks.Nodes), multipleExposures)
+}
 
-	for _, nodeId := range sortedNodeIDs(keks.Nodes) {
-		portMapping[nodeId] = nextPort
-		nextPort++
-	}
+// prepareNodeForwarders prepares the port forwarders for the given nodes of the exposure.
+func (k *Kekspose) prepareNodeForwarders(kubeconfig *rest.Config, kubeclient *kubernetes.Clientset, e *exposure, nodeIds []int32, multipleExposures bool) []*PortForwarder {
+	portForwarders := make([]*PortForwarder, 0, len(nodeIds))
 
-	return portMapping
-}
+	for _, nodeId := range nodeIds {
+		node := e.Keks.Nodes[nodeId]
 
-func (k *Kekspose) preparePortForwarders(kubeconfig *rest.Config, kubeclient *kubernetes.Clientset, keks *keks2.Keks, portMapping map[int32]uint32) []*PortForwarder {
-	portForwarders := make([]*PortForwarder, 0, len(keks.Nodes))
+		logger := slog.Default().With("node", nodeId)
+		if multipleExposures {
+			// Node IDs are unique only within a cluster and listener, so both are needed to tell the log lines apart
+			logger = slog.Default().With("cluster", e.Name(), "listener", e.Keks.ListenerName, "node", nodeId)
+		}
 
-	for _, nodeId := range sortedNodeIDs(keks.Nodes) {
-		node := keks.Nodes[nodeId]
-		portForwarder := NewPortForwarder(kubeconfig, kubeclient, k.Namespace, node, nodeId, portMapping[nodeId], keks.Port, keks.TLS, k.newProxyEngine(nodeId, portMapping))
+		// The engine is rebuilt when the logging options or the port mapping change at runtime
+		engine := k.engines.add(func() *proksy.Engine {
+			return k.newProxyEngine(logger, e.PortMapping)
+		})
+
+		portForwarder := NewPortForwarder(kubeconfig, kubeclient, e.Namespace, node, nodeId, e.PortMapping[nodeId], e.Keks.Port, e.Keks.TLS, engine.Load())
+		portForwarder.ProxyFunc = engine.Load
+		portForwarder.engine = engine
+		portForwarder.InspectFunc = func() (*proksy.Engine, proxiedforward.Inspector) {
+			return k.inspectConnection(logger, e)
+		}
 		portForwarders = append(portForwarders, portForwarder)
 	}
 
@@ -247,8 +565,10 @@ func (k *Kekspose) preparePortForwarders(kubeconfig *rest.Config, kubeclient *ku
 // newProxyEngine builds the proksy engine used to proxy one broker connection. Every broker shares
 // the same behaviour - log each RPC, and rewrite advertised broker addresses to localhost plus the
 // forwarded port for that node - so the engine is configured identically per node, differing only in
-// a node-scoped logger that tags log lines with the broker's node ID.
-func (k *Kekspose) newProxyEngine(nodeId int32, portMapping map[int32]uint32) *proksy.Engine {
+// a node-scoped logger that tags log lines with the broker's node ID. The port mapping is the one of
+// the broker's own cluster, so the advertised addresses never point to another exposed cluster. The
+// sensitive data in the logged message bodies are redacted.
+func (k *Kekspose) newProxyEngine(logger *slog.Logger, portMapping map[int32]uint32) *proksy.Engine {
 	resolve := func(id int32) (host string, port int32, ok bool) {
 		mapped, found := portMapping[id]
 		return "localhost", int32(mapped), found
@@ -269,7 +589,7 @@ func (k *Kekspose) newProxyEngine(nodeId int32, portMapping map[int32]uint32) *p
 	return proksy.NewEngine(
 		filter.DebugLog(debugOpts...),
 		filter.HostRewrite(resolve),
-	).WithLogger(slog.Default().With("node", nodeId))
+	).WithLogger(slog.New(&redactingHandler{Handler: logger.Handler(), redaction: k.redaction()}))
 }
 
 func (k *Kekspose) bootstrapAddress(portMapping map[int32]uint32) string {
@@ -0,0 +1,152 @@
+/*
+Copyright © 2025 Jakub Scholz
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kekspose
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/scholzj/kekspose/pkg/kekspose/session"
+	strimzi "github.com/scholzj/strimzi-go/pkg/client/clientset/versioned"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+// liveSession is the state of the running port forwarding. It is shared by the shutdown, the reload
+// of the discovery, and the admin API, so the forwarders can be added and removed at runtime.
+type liveSession struct {
+	k             *Kekspose
+	ctx           context.Context
+	kubeconfig    *rest.Config
+	kubeclient    *kubernetes.Clientset
+	strimziclient strimzi.Interface
+	startedAt     time.Time
+	// exposures are read and changed with the lock of the proxy engines held, because the engines
+	// use their port mappings
+	exposures []*exposure
+	endpoints []*endpointExposure
+	// start starts the port forwarding of a forwarder and shutdown triggers the graceful shutdown
+	start    func(*PortForwarder)
+	shutdown context.CancelFunc
+	// adminAddress and adminTokenFile describe the admin API, when it is enabled
+	adminAddress   string
+	adminTokenFile string
+
+	lock       sync.Mutex
+	forwarders []*PortForwarder
+	running    []*PortForwarder
+	stopped    bool
+
+	// reloadLock makes sure that only one reload runs at a time
+	reloadLock sync.Mutex
+}
+
+// allForwarders returns all forwarders, including those waiting for their pods.
+func (s *liveSession) allForwarders() []*PortForwarder {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return slices.Clone(s.forwarders)
+}
+
+// runningForwarders returns the forwarders which were started.
+func (s *liveSession) runningForwarders() []*PortForwarder {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return slices.Clone(s.running)
+}
+
+// addForwarders registers new forwarders. It returns false when the session is already stopped.
+func (s *liveSession) addForwarders(forwarders []*PortForwarder) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.stopped {
+		return false
+	}
+
+	s.forwarders = append(s.forwarders, forwarders...)
+	return true
+}
+
+// markRunning records that the forwarder is being started. It returns false when the forwarder was
+// stopped in the meantime.
+func (s *liveSession) markRunning(pf *PortForwarder) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.stopped || !slices.Contains(s.forwarders, pf) {
+		return false
+	}
+
+	s.running = append(s.running, pf)
+	return true
+}
+
+// stopForwarder stops a single forwarder and forgets it.
+func (s *liveSession) stopForwarder(pf *PortForwarder) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.stopped || !slices.Contains(s.forwarders, pf) {
+		return
+	}
+
+	s.forwarders = slices.DeleteFunc(s.forwarders, func(other *PortForwarder) bool { return other == pf })
+	s.running = slices.DeleteFunc(s.running, func(other *PortForwarder) bool { return other == pf })
+	logStopping(pf)
+	close(pf.Stop)
+}
+
+// stopAll stops all forwarders. It can be called multiple times.
+func (s *liveSession) stopAll() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.stopped {
+		return
+	}
+
+	s.stopped = true
+	for _, pf := range s.forwarders {
+		logStopping(pf)
+		close(pf.Stop)
+	}
+}
+
+// saveSession writes the state file of the session running in the background.
+func (s *liveSession) saveSession() error {
+	var state *session.Session
+	s.k.engines.read(func() {
+		state = s.k.newSession(s.exposures, s.endpoints)
+	})
+	state.StartedAt = s.startedAt
+	state.AdminAddress = s.adminAddress
+	state.AdminTokenFile = s.adminTokenFile
+
+	return session.Save(s.k.SessionDir, state)
+}
+
+func logStopping(pf *PortForwarder) {
+	for _, port := range pf.ForwardedPorts() {
+		slog.Info("Stopping port forwarding between localhost and Kubernetes", "localPort", port.LocalPort, "podName", pf.PodName, "remotePort", port.RemotePort, "namespace", pf.Namespace)
+	}
+}
@@ -0,0 +1,113 @@
+/*
+Copyright © 2025 Jakub Scholz
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kekspose
+
+import (
+	"log/slog"
+	"slices"
+	"sync/atomic"
+
+	"github.com/scholzj/proksy/filter"
+)
+
+// correlationIdKey is the attribute with the correlation ID in the log messages of the RPCs. The same
+// key is used by filter.DebugLog, which offers no way to skip some RPCs other than by their API keys.
+// So the RPCs are filtered by dropping the log messages with the correlation IDs of the RPCs which
+// should not be logged. The messages about the records and the errors of the RPCs use the key as well,
+// so that they are filtered the same way. When the RPC messages stop carrying the key, they are dropped
+// instead of being logged unfiltered (see rpcLogHandler).
+const correlationIdKey = "correlationId"
+
+// MaxVerbosity is the highest verbosity. It logs the decoded bodies of the Kafka messages.
+const MaxVerbosity = 2
+
+var verbosity atomic.Int32
+
+// SetVerbosity sets the level of the default logger. Verbosity 0 logs only the informational messages,
+// 1 adds the debug messages including the forwarded Kafka RPCs, and 2 adds the decoded message bodies.
+// It can be changed at any time and applies to the open connections as well.
+func SetVerbosity(verbose int) {
+	verbose = min(max(verbose, 0), MaxVerbosity)
+	verbosity.Store(int32(verbose))
+	slog.SetLogLoggerLevel(verbosityLevel(verbose))
+}
+
+// Verbosity returns the current verbosity.
+func Verbosity() int {
+	return int(verbosity.Load())
+}
+
+func verbosityLevel(verbose int) slog.Level {
+	switch verbose {
+	case 0:
+		return slog.LevelInfo
+	case 1:
+		return slog.LevelDebug
+	default:
+		return filter.TraceLevel
+	}
+}
+
+// loggingOptions are the logging options which can be changed at runtime.
+type loggingOptions struct {
+	Verbosity   int
+	LogAPIKeys  []int16
+	BodyAPIKeys []int16
+	Filter      rpcFilter
+}
+
+// loggingOptions returns the current logging options.
+func (k *Kekspose) loggingOptions() loggingOptions {
+	var options loggingOptions
+	k.engines.read(func() {
+		options = loggingOptions{Verbosity: Verbosity(), LogAPIKeys: slices.Clone(k.LogAPIKeys), BodyAPIKeys: slices.Clone(k.BodyAPIKeys), Filter: k.rpcFilter()}
+	})
+
+	return options
+}
+
+// loggedRPCsNote tells the user that the open connections keep logging the RPCs selected when they were
+// opened.
+const loggedRPCsNote = "The verbosity applies to all connections right away. The logged RPCs (apis, bodyApis, clientIds, topics, and groups) apply only to new connections, the open connections keep the selection they were opened with."
+
+// setLoggingOptions changes the logging options at runtime. The verbosity applies right away. The
+// selection of the logged RPCs applies to the new connections, because the engines of all forwarded
+// ports are rebuilt with it while the open connections keep their engine.
+func (k *Kekspose) setLoggingOptions(options loggingOptions) {
+	k.engines.update(func() {
+		k.LogAPIKeys = options.LogAPIKeys
+		k.BodyAPIKeys = options.BodyAPIKeys
+		k.LogClientIDs = options.Filter.ClientIDs
+		k.LogTopics = options.Filter.Topics
+		k.LogGroups = options.Filter.Groups
+	})
+	SetVerbosity(options.Verbosity)
+
+	slog.Info("Changed the logging options, the logged RPCs change only for the new connections", "verbosity", Verbosity(), "apis", apiNames(options.LogAPIKeys), "bodyApis", apiNames(options.BodyAPIKeys), "clientIds", options.Filter.ClientIDs, "topics", options.Filter.Topics, "groups", options.Filter.Groups)
+}
+
+// rpcFilter returns the filter of the logged RPCs. It has to be called with the lock of the engines
+// held.
+func (k *Kekspose) rpcFilter() rpcFilter {
+	return rpcFilter{ClientIDs: slices.Clone(k.LogClientIDs), Topics: slices.Clone(k.LogTopics), Groups: slices.Clone(k.LogGroups)}
+}
+
+// nextVerbosity returns the verbosity following the given one. After the highest verbosity, it starts
+// again from 0.
+func nextVerbosity(verbose int) int {
+	return (verbose + 1) % (MaxVerbosity + 1)
+}
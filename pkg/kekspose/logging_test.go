@@ -0,0 +1,62 @@
+package kekspose
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/scholzj/proksy"
+	"github.com/scholzj/proksy/filter"
+	"github.com/stretchr/testify/assert"
+)
+
+// restoreVerbosity restores the verbosity of the default logger changed by the test.
+func restoreVerbosity(t *testing.T) {
+	t.Helper()
+
+	previous := Verbosity()
+	SetVerbosity(0)
+	t.Cleanup(func() { SetVerbosity(previous) })
+}
+
+func TestSetVerbosity(t *testing.T) {
+	restoreVerbosity(t)
+
+	SetVerbosity(1)
+	assert.Equal(t, 1, Verbosity())
+	assert.True(t, slog.Default().Enabled(context.Background(), slog.LevelDebug))
+	assert.False(t, slog.Default().Enabled(context.Background(), slog.LevelDebug-1))
+
+	// -vvv is the same as -vv
+	SetVerbosity(3)
+	assert.Equal(t, MaxVerbosity, Verbosity())
+	assert.True(t, slog.Default().Enabled(context.Background(), filter.TraceLevel))
+
+	SetVerbosity(-1)
+	assert.Equal(t, 0, Verbosity())
+	assert.False(t, slog.Default().Enabled(context.Background(), slog.LevelDebug))
+}
+
+func TestNextVerbosity(t *testing.T) {
+	assert.Equal(t, 1, nextVerbosity(0))
+	assert.Equal(t, 2, nextVerbosity(1))
+	assert.Equal(t, 0, nextVerbosity(2))
+}
+
+func TestSetLoggingOptions(t *testing.T) {
+	restoreVerbosity(t)
+
+	k := &Kekspose{LogAPIKeys: []int16{0}}
+	first := k.engines.add(func() *proksy.Engine { return proksy.NewEngine() })
+	second := k.engines.add(func() *proksy.Engine { return proksy.NewEngine() })
+	before := k.engines.current.Load()
+
+	k.setLoggingOptions(loggingOptions{Verbosity: 1, LogAPIKeys: []int16{3}, BodyAPIKeys: []int16{3}})
+
+	assert.Equal(t, loggingOptions{Verbosity: 1, LogAPIKeys: []int16{3}, BodyAPIKeys: []int16{3}}, k.loggingOptions())
+	// All engines are replaced at once
+	assert.NotSame(t, before, k.engines.current.Load())
+	assert.Len(t, *k.engines.current.Load(), 2)
+	assert.NotNil(t, first.Load())
+	assert.NotNil(t, second.Load())
+}
@@ -0,0 +1,55 @@
+//go:build !windows
+
+/*
+Copyright © 2025 Jakub Scholz
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kekspose
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// handleLoggingSignals changes the logging options when receiving the signals until ctx is done. SIGUSR1
+// cycles through the verbosity levels and SIGUSR2 restores the logging options Keksposé started with.
+// The signals are registered right away, so their default action no longer terminates Keksposé.
+func (k *Kekspose) handleLoggingSignals(ctx context.Context) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGUSR1, syscall.SIGUSR2)
+	initial := k.loggingOptions()
+
+	go func() {
+		defer signal.Stop(signals)
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case sig := <-signals:
+				if sig == syscall.SIGUSR2 {
+					k.setLoggingOptions(initial)
+					continue
+				}
+
+				options := k.loggingOptions()
+				options.Verbosity = nextVerbosity(options.Verbosity)
+				k.setLoggingOptions(options)
+			}
+		}
+	}()
+}
@@ -0,0 +1,37 @@
+//go:build !windows
+
+package kekspose
+
+import (
+	"context"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandleLoggingSignals(t *testing.T) {
+	restoreVerbosity(t)
+
+	k := &Kekspose{LogAPIKeys: []int16{3}}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	k.handleLoggingSignals(ctx)
+
+	assert.NoError(t, syscall.Kill(syscall.Getpid(), syscall.SIGUSR1))
+	assert.Eventually(t, func() bool { return Verbosity() == 1 }, 5*time.Second, 10*time.Millisecond)
+
+	assert.NoError(t, syscall.Kill(syscall.Getpid(), syscall.SIGUSR1))
+	assert.Eventually(t, func() bool { return Verbosity() == 2 }, 5*time.Second, 10*time.Millisecond)
+
+	k.setLoggingOptions(loggingOptions{Verbosity: 2})
+
+	// SIGUSR2 restores the initial options
+	assert.NoError(t, syscall.Kill(syscall.Getpid(), syscall.SIGUSR2))
+	assert.Eventually(t, func() bool {
+		options := k.loggingOptions()
+		return options.Verbosity == 0 && len(options.LogAPIKeys) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+}
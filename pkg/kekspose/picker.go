@@ -0,0 +1,157 @@
+/*
+Copyright © 2025 Jakub Scholz
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kekspose
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+
+	keks2 "github.com/scholzj/kekspose/pkg/kekspose/keks"
+	strimzi "github.com/scholzj/strimzi-go/pkg/client/clientset/versioned"
+)
+
+// DefaultStartingPort is the port used for the first forwarded broker when no other port is configured.
+const DefaultStartingPort uint32 = 50000
+
+// pickKafka asks the user to choose the Kafka cluster when the configured cluster does not exist. It
+// is used only in the interactive mode with a single configured cluster. Only the clusters with all the
+// requested listeners are offered, so that the chosen cluster is exposed with exactly the listeners the
+// user asked for. Once the choice is made, it logs the equivalent command line so that the next run can skip
+// the interactive selection.
+func (k *Kekspose) pickKafka(strimziclient strimzi.Interface, in io.Reader, out io.Writer) error {
+	clusterName := k.ClusterNames[0]
+
+	exists, err := keks2.KafkaExists(strimziclient, k.Namespace, clusterName)
+	if err != nil {
+		return err
+	}
+
+	if exists {
+		return nil
+	}
+
+	candidates, err := keks2.ListCandidates(strimziclient, k.Namespace, k.AllowUnready, k.AllowInsecureTLS)
+	if err != nil {
+		return err
+	}
+
+	choices := make([]keks2.Candidate, 0)
+	labels := make([]string, 0)
+	for _, candidate := range candidates {
+		candidate.Listeners = slices.DeleteFunc(candidate.Listeners, func(listener keks2.CandidateListener) bool {
+			return len(k.ListenerNames) > 0 && !slices.Contains(k.ListenerNames, listener.Name)
+		})
+
+		if len(candidate.Listeners) > 0 && len(missingListeners(candidate, k.ListenerNames)) == 0 {
+			choices = append(choices, candidate)
+			labels = append(labels, candidateLabel(candidate))
+		}
+	}
+
+	if len(choices) == 0 && len(k.ListenerNames) > 0 {
+		//goland:noinspection GoErrorStringFormat
+		return fmt.Errorf("Kafka cluster %s in namespace %s was not found and no other Kafka cluster with all the requested listeners (%s) exists", clusterName, k.Namespace, strings.Join(k.ListenerNames, ", "))
+	} else if len(choices) == 0 {
+		//goland:noinspection GoErrorStringFormat
+		return fmt.Errorf("Kafka cluster %s in namespace %s was not found and no other Kafka cluster with a suitable listener exists", clusterName, k.Namespace)
+	}
+
+	_, _ = fmt.Fprintf(out, "Kafka cluster %s was not found in namespace %s.\n", clusterName, k.Namespace)
+
+	var choice keks2.Candidate
+	if len(choices) == 1 {
+		choice = choices[0]
+		_, _ = fmt.Fprintf(out, "Using the only available option: %s\n", labels[0])
+	} else {
+		index, err := promptForChoice(in, out, labels)
+		if err != nil {
+			return err
+		}
+		choice = choices[index]
+	}
+
+	k.ClusterNames = []string{choice.ClusterName}
+
+	if k.CommandLine != nil {
+		slog.Info("Use the following command to skip the interactive selection next time", "command", k.CommandLine(k.ClusterNames, k.ListenerNames))
+	}
+
+	return nil
+}
+
+// missingListeners returns the requested listeners which the candidate does not have.
+func missingListeners(candidate keks2.Candidate, listenerNames []string) []string {
+	return slices.DeleteFunc(slices.Clone(listenerNames), func(name string) bool {
+		return slices.ContainsFunc(candidate.Listeners, func(listener keks2.CandidateListener) bool {
+			return listener.Name == name
+		})
+	})
+}
+
+func candidateLabel(candidate keks2.Candidate) string {
+	listeners := make([]string, 0, len(candidate.Listeners))
+	for _, listener := range candidate.Listeners {
+		details := []string{fmt.Sprintf("port %d", listener.Port), "authentication " + listener.Authentication}
+		if listener.TLS {
+			details = append(details, "TLS")
+		}
+
+		listeners = append(listeners, fmt.Sprintf("%s (%s)", listener.Name, strings.Join(details, ", ")))
+	}
+
+	label := candidate.ClusterName
+	if !candidate.Ready {
+		label += " (not ready)"
+	}
+
+	return label + ": " + strings.Join(listeners, ", ")
+}
+
+// promptForChoice prints the numbered options and reads the user's selection. Invalid input is
+// reported and the question repeated until a valid option is selected or the input ends.
+func promptForChoice(in io.Reader, out io.Writer, labels []string) (int, error) {
+	_, _ = fmt.Fprintln(out, "Available Kafka clusters and their listeners:")
+	for i, label := range labels {
+		_, _ = fmt.Fprintf(out, "  [%d] %s\n", i+1, label)
+	}
+
+	scanner := bufio.NewScanner(in)
+	for {
+		_, _ = fmt.Fprintf(out, "Select the Kafka cluster to expose [1-%d]: ", len(labels))
+
+		if !scanner.Scan() {
+			if err := scanner.Err(); err != nil {
+				return 0, fmt.Errorf("failed to read the selection: %w", err)
+			}
+
+			return 0, fmt.Errorf("no Kafka cluster was selected")
+		}
+
+		selection, err := strconv.Atoi(strings.TrimSpace(scanner.Text()))
+		if err != nil || selection < 1 || selection > len(labels) {
+			_, _ = fmt.Fprintf(out, "Invalid selection %q\n", strings.TrimSpace(scanner.Text()))
+			continue
+		}
+
+		return selection - 1, nil
+	}
+}
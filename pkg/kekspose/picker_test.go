@@ -0,0 +1,112 @@
+package kekspose
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	kafkav1 "github.com/scholzj/strimzi-go/pkg/apis/kafka.strimzi.io/v1"
+	"github.com/scholzj/strimzi-go/pkg/client/clientset/versioned/fake"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestPickKafkaPromptsForCluster(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	createReadyKafka(t, client, "cluster-a", kafkav1.GenericKafkaListener{Name: "plain", Port: 9092})
+	createReadyKafka(t, client, "cluster-b", kafkav1.GenericKafkaListener{Name: "plain", Port: 9092}, kafkav1.GenericKafkaListener{Name: "scram", Port: 9094})
+
+	var out bytes.Buffer
+	k := Kekspose{Namespace: "my-namespace", ClusterNames: []string{"my-cluster"}, StartingPort: DefaultStartingPort}
+	err := k.pickKafka(client, strings.NewReader("x\n7\n2\n"), &out)
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"cluster-b"}, k.ClusterNames)
+	assert.Empty(t, k.ListenerNames)
+	assert.Contains(t, out.String(), "Kafka cluster my-cluster was not found in namespace my-namespace.")
+	assert.Contains(t, out.String(), "  [1] cluster-a: plain (port 9092, authentication none)")
+	assert.Contains(t, out.String(), "  [2] cluster-b: plain (port 9092, authentication none), scram (port 9094, authentication none)")
+	assert.Equal(t, 2, strings.Count(out.String(), "Invalid selection"))
+}
+
+func TestPickKafkaKeepsExistingCluster(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	createReadyKafka(t, client, "my-cluster", kafkav1.GenericKafkaListener{Name: "plain", Port: 9092})
+	createReadyKafka(t, client, "cluster-b", kafkav1.GenericKafkaListener{Name: "plain", Port: 9092})
+
+	var out bytes.Buffer
+	k := Kekspose{Namespace: "my-namespace", ClusterNames: []string{"my-cluster"}}
+	err := k.pickKafka(client, strings.NewReader(""), &out)
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"my-cluster"}, k.ClusterNames)
+	assert.Empty(t, k.ListenerNames)
+	assert.Empty(t, out.String())
+}
+
+func TestPickKafkaSelectsOnlyOption(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	createReadyKafka(t, client, "cluster-a", kafkav1.GenericKafkaListener{Name: "plain", Port: 9092}, kafkav1.GenericKafkaListener{Name: "tls", Port: 9093, Tls: true})
+
+	var out bytes.Buffer
+	k := Kekspose{Namespace: "my-namespace", ClusterNames: []string{"my-cluster"}}
+	err := k.pickKafka(client, strings.NewReader(""), &out)
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"cluster-a"}, k.ClusterNames)
+	assert.Empty(t, k.ListenerNames)
+}
+
+func TestPickKafkaKeepsRequestedListeners(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	createReadyKafka(t, client, "cluster-a", kafkav1.GenericKafkaListener{Name: "plain", Port: 9092})
+	createReadyKafka(t, client, "cluster-b", kafkav1.GenericKafkaListener{Name: "plain", Port: 9092}, kafkav1.GenericKafkaListener{Name: "scram", Port: 9094})
+	createReadyKafka(t, client, "cluster-c", kafkav1.GenericKafkaListener{Name: "external", Port: 9095})
+
+	var out bytes.Buffer
+	k := Kekspose{Namespace: "my-namespace", ClusterNames: []string{"my-cluster"}, ListenerNames: []string{"scram", "plain"}}
+	err := k.pickKafka(client, strings.NewReader(""), &out)
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"cluster-b"}, k.ClusterNames)
+	assert.Equal(t, []string{"scram", "plain"}, k.ListenerNames)
+	assert.Contains(t, out.String(), "Using the only available option: cluster-b")
+	assert.NotContains(t, out.String(), "cluster-a")
+	assert.NotContains(t, out.String(), "cluster-c")
+}
+
+func TestPickKafkaFailsWithoutRequestedListeners(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	createReadyKafka(t, client, "cluster-a", kafkav1.GenericKafkaListener{Name: "plain", Port: 9092})
+	createReadyKafka(t, client, "cluster-b", kafkav1.GenericKafkaListener{Name: "plain", Port: 9092}, kafkav1.GenericKafkaListener{Name: "scram", Port: 9094})
+
+	k := Kekspose{Namespace: "my-namespace", ClusterNames: []string{"my-cluster"}, ListenerNames: []string{"scram", "missing"}}
+	err := k.pickKafka(client, strings.NewReader("1\n"), &bytes.Buffer{})
+
+	require.EqualError(t, err, "Kafka cluster my-cluster in namespace my-namespace was not found and no other Kafka cluster with all the requested listeners (scram, missing) exists")
+	assert.Equal(t, []string{"my-cluster"}, k.ClusterNames)
+}
+
+func TestPickKafkaFailsWithoutCandidates(t *testing.T) {
+	client := fake.NewSimpleClientset()
+
+	k := Kekspose{Namespace: "my-namespace", ClusterNames: []string{"my-cluster"}}
+	err := k.pickKafka(client, strings.NewReader(""), &bytes.Buffer{})
+
+	require.EqualError(t, err, "Kafka cluster my-cluster in namespace my-namespace was not found and no other Kafka cluster with a suitable listener exists")
+}
+
+func createReadyKafka(t *testing.T, client *fake.Clientset, name string, listeners ...kafkav1.GenericKafkaListener) {
+	t.Helper()
+
+	kafka := &kafkav1.Kafka{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "my-namespace"},
+		Spec:       &kafkav1.KafkaSpec{Kafka: &kafkav1.KafkaClusterSpec{Listeners: listeners}},
+		Status:     &kafkav1.KafkaStatus{Conditions: []kafkav1.Condition{{Type: "Ready", Status: "True"}}},
+	}
+
+	_, err := client.KafkaV1().Kafkas("my-namespace").Create(context.TODO(), kafka, metav1.CreateOptions{})
+	require.NoError(t, err)
+}
@@ -19,54 +19,131 @@ package kekspose
 import (
 	"fmt"
 	"log/slog"
+	"math"
 	"net/http"
 	"net/url"
+	"time"
 
 	"github.com/scholzj/kekspose/pkg/kekspose/proxiedforward"
 	"github.com/scholzj/proksy"
+	"k8s.io/apimachinery/pkg/util/httpstream"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/portforward"
 	"k8s.io/client-go/transport/spdy"
 )
 
-type PortForwarder struct {
-	KubeConfig *rest.Config
-	URL        *url.URL
+// Transports used for the port forwarding connections to the Kubernetes API server
+const (
+	// TransportAuto uses WebSockets and falls back to SPDY when the API server does not support them.
+	TransportAuto = "auto"
+	// TransportWebSocket tunnels the port forwarding protocol through WebSockets.
+	TransportWebSocket = "websocket"
+	// TransportSPDY uses the SPDY upgrade, which is supported also by the older Kubernetes versions.
+	TransportSPDY = "spdy"
+)
+
+// ForwardedPort is one local port forwarded to the pod with its own TLS setting and proxy engine.
+type ForwardedPort struct {
 	NodeId     int32
-	Ports      []string
+	LocalPort  uint32
+	RemotePort uint32
 	UseTLS     bool
 	Proxy      *proksy.Engine
+	// ProxyFunc, when set, provides the proxy engine for each new connection instead of Proxy, so that
+	// the engine can be replaced at runtime.
+	ProxyFunc func() *proksy.Engine
+	// InspectFunc, when set, provides the engine and the inspector of each new connection. See
+	// proxiedforward.ProxiedPort.Inspect.
+	InspectFunc func() (*proksy.Engine, proxiedforward.Inspector)
+	Stats       *proxiedforward.Stats
+	// engine is the rebuildable engine behind ProxyFunc, which is removed when the port stops being
+	// forwarded.
+	engine *proxyEngine
+}
+
+type PortForwarder struct {
+	// ForwardedPort is the main port of the forwarder.
+	ForwardedPort
+	KubeConfig *rest.Config
+	URL        *url.URL
+	Namespace  string
+	PodName    string
 	Ready      chan struct{}
 	Stop       chan struct{}
+	// Drain stops accepting new connections and closes the open connections once their requests got a
+	// response. Done is closed when the forwarding finished.
+	Drain chan struct{}
+	Done  chan struct{}
+	// AdditionalPorts are forwarded to the same pod over the same connection as the main port.
+	AdditionalPorts []ForwardedPort
+	// LazyConnect dials the connection to the pod only on the first local connection and closes it
+	// again after IdleTimeout without any local connections.
+	LazyConnect bool
+	IdleTimeout time.Duration
+	// Transport is the transport used for the connection to the API server (auto, websocket, or spdy).
+	// Empty means SPDY.
+	Transport string
+	// Timeouts are the time limits of the connections proxied by the forwarder.
+	Timeouts proxiedforward.Timeouts
 }
 
 func NewPortForwarder(kubeConfig *rest.Config, kubeClient *kubernetes.Clientset, namespace string, podName string, nodeId int32, localPort uint32, remotePort uint32, useTLS bool, proxy *proksy.Engine) *PortForwarder {
 	return &PortForwarder{
+		ForwardedPort: ForwardedPort{
+			NodeId:     nodeId,
+			LocalPort:  localPort,
+			RemotePort: remotePort,
+			UseTLS:     useTLS,
+			Proxy:      proxy,
+			Stats:      &proxiedforward.Stats{},
+		},
 		KubeConfig: kubeConfig,
 		URL:        kubeClient.CoreV1().RESTClient().Post().Resource("pods").Namespace(namespace).Name(podName).SubResource("portforward").URL(),
-		NodeId:     nodeId,
-		Ports:      []string{fmt.Sprintf("%d:%d", localPort, remotePort)},
-		UseTLS:     useTLS,
-		Proxy:      proxy,
+		Namespace:  namespace,
+		PodName:    podName,
 		Ready:      make(chan struct{}),
 		Stop:       make(chan struct{}),
+		Drain:      make(chan struct{}),
+		Done:       make(chan struct{}),
 	}
 }
 
 func (pf *PortForwarder) ForwardPorts() error {
-	transport, upgrader, err := spdy.RoundTripperFor(pf.KubeConfig)
+	if pf.Done != nil {
+		defer close(pf.Done)
+	}
+
+	dialer, err := pf.newDialer()
 	if err != nil {
-		slog.Error("Failed to create round tripper", "error", err)
+		slog.Error("Failed to create dialer", "error", err)
 		return err
 	}
 
-	dialer := spdy.NewDialer(upgrader, &http.Client{Transport: transport}, http.MethodPost, pf.URL)
-	fw, err := proxiedforward.New(dialer, pf.Ports, pf.Stop, pf.Ready, pf.UseTLS, pf.Proxy)
+	ports := make([]proxiedforward.ProxiedPort, 0, len(pf.AdditionalPorts)+1)
+	for _, port := range pf.ForwardedPorts() {
+		if err := ValidatePort(port.LocalPort); err != nil {
+			return fmt.Errorf("invalid local port of node %d: %w", port.NodeId, err)
+		}
+		if err := ValidatePort(port.RemotePort); err != nil {
+			return fmt.Errorf("invalid remote port of node %d: %w", port.NodeId, err)
+		}
+
+		ports = append(ports, proxiedforward.ProxiedPort{Local: uint16(port.LocalPort), Remote: uint16(port.RemotePort), UseTLS: port.UseTLS, Engine: port.Proxy, EngineFunc: port.ProxyFunc, Inspect: port.InspectFunc, Stats: port.Stats})
+	}
+
+	fw, err := proxiedforward.NewForPorts(dialer, ports, pf.Stop, pf.Ready)
 	if err != nil {
 		slog.Error("Failed to create port forwarder", "error", err)
 		return err
 	}
 
+	if pf.LazyConnect {
+		fw.WithLazyConnect(pf.IdleTimeout)
+	}
+
+	fw.WithTimeouts(pf.Timeouts).WithDrain(pf.Drain)
+
 	if err := fw.ForwardPorts(); err != nil {
 		slog.Error("Failed to forward port", "error", err)
 		return err
@@ -74,3 +151,85 @@ func (pf *PortForwarder) ForwardPorts() error {
 
 	return nil
 }
+
+// ForwardedPorts returns the main port followed by the additional ports of the forwarder.
+func (pf *PortForwarder) ForwardedPorts() []ForwardedPort {
+	return append([]ForwardedPort{pf.ForwardedPort}, pf.AdditionalPorts...)
+}
+
+// groupByPod merges the forwarders targeting the same pod, so that all their ports share a single
+// connection to the pod instead of opening one connection per port. The order of the forwarders is
+// kept.
+func groupByPod(forwarders []*PortForwarder) []*PortForwarder {
+	grouped := make([]*PortForwarder, 0, len(forwarders))
+	byPod := make(map[string]*PortForwarder, len(forwarders))
+
+	for _, pf := range forwarders {
+		key := pf.Namespace + "/" + pf.PodName
+
+		if existing, found := byPod[key]; found {
+			existing.AdditionalPorts = append(existing.AdditionalPorts, pf.ForwardedPorts()...)
+			continue
+		}
+
+		byPod[key] = pf
+		grouped = append(grouped, pf)
+	}
+
+	return grouped
+}
+
+// newDialer creates the dialer for the configured transport. In the auto mode, the WebSocket dialer is
+// used first and the SPDY dialer is used only when the WebSocket upgrade fails (e.g. with older API
+// servers or proxies which do not support it), the same way as kubectl does it.
+func (pf *PortForwarder) newDialer() (httpstream.Dialer, error) {
+	if pf.Transport == TransportWebSocket || pf.Transport == TransportAuto {
+		websocketDialer, err := portforward.NewSPDYOverWebsocketDialer(pf.URL, pf.KubeConfig)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create WebSocket dialer: %w", err)
+		}
+
+		if pf.Transport == TransportWebSocket {
+			return websocketDialer, nil
+		}
+
+		spdyDialer, err := pf.newSPDYDialer()
+		if err != nil {
+			return nil, err
+		}
+
+		return portforward.NewFallbackDialer(websocketDialer, spdyDialer, func(err error) bool {
+			return httpstream.IsUpgradeFailure(err) || httpstream.IsHTTPSProxyError(err)
+		}), nil
+	}
+
+	return pf.newSPDYDialer()
+}
+
+func (pf *PortForwarder) newSPDYDialer() (httpstream.Dialer, error) {
+	transport, upgrader, err := spdy.RoundTripperFor(pf.KubeConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create round tripper: %w", err)
+	}
+
+	return spdy.NewDialer(upgrader, &http.Client{Transport: transport}, http.MethodPost, pf.URL), nil
+}
+
+// ValidatePort checks that the port is between 1 and 65535, so that it can be forwarded.
+func ValidatePort(port uint32) error {
+	if port < 1 || port > math.MaxUint16 {
+		return fmt.Errorf("port %d is out of the range 1-65535", port)
+	}
+
+	return nil
+}
+
+// ValidateTransport checks that the transport is one of the supported transports.
+func ValidateTransport(transport string) error {
+	switch transport {
+	case TransportAuto, TransportWebSocket, TransportSPDY:
+		return nil
+	default:
+		return fmt.Errorf("unknown transport %q. Use auto, websocket, or spdy", transport)
+	}
+}
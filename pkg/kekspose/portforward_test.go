@@ -0,0 +1,83 @@
+package kekspose
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/portforward"
+)
+
+func TestValidateTransport(t *testing.T) {
+	assert.NoError(t, ValidateTransport(TransportAuto))
+	assert.NoError(t, ValidateTransport(TransportWebSocket))
+	assert.NoError(t, ValidateTransport(TransportSPDY))
+	assert.EqualError(t, ValidateTransport("http2"), `unknown transport "http2". Use auto, websocket, or spdy`)
+}
+
+func TestValidatePort(t *testing.T) {
+	assert.NoError(t, ValidatePort(1))
+	assert.NoError(t, ValidatePort(65535))
+	assert.EqualError(t, ValidatePort(0), "port 0 is out of the range 1-65535")
+	assert.EqualError(t, ValidatePort(70000), "port 70000 is out of the range 1-65535")
+}
+
+func TestForwardPortsRejectsInvalidPorts(t *testing.T) {
+	pf := &PortForwarder{
+		KubeConfig:    &rest.Config{Host: "https://localhost:6443"},
+		URL:           &url.URL{Scheme: "https", Host: "localhost:6443", Path: "/api/v1/namespaces/my-namespace/pods/my-pod/portforward"},
+		Transport:     TransportSPDY,
+		ForwardedPort: ForwardedPort{LocalPort: 50000, RemotePort: 70000},
+	}
+
+	assert.EqualError(t, pf.ForwardPorts(), "invalid remote port of node 0: port 70000 is out of the range 1-65535")
+}
+
+func TestNewDialer(t *testing.T) {
+	pf := &PortForwarder{
+		KubeConfig: &rest.Config{Host: "https://localhost:6443"},
+		URL:        &url.URL{Scheme: "https", Host: "localhost:6443", Path: "/api/v1/namespaces/my-namespace/pods/my-pod/portforward"},
+	}
+
+	pf.Transport = TransportAuto
+	dialer, err := pf.newDialer()
+	require.NoError(t, err)
+	assert.IsType(t, &portforward.FallbackDialer{}, dialer)
+
+	pf.Transport = TransportWebSocket
+	dialer, err = pf.newDialer()
+	require.NoError(t, err)
+	assert.NotNil(t, dialer)
+	_, isFallback := dialer.(*portforward.FallbackDialer)
+	assert.False(t, isFallback)
+
+	pf.Transport = TransportSPDY
+	dialer, err = pf.newDialer()
+	require.NoError(t, err)
+	assert.NotNil(t, dialer)
+	_, isFallback = dialer.(*portforward.FallbackDialer)
+	assert.False(t, isFallback)
+}
+
+func TestGroupByPod(t *testing.T) {
+	first := &PortForwarder{Namespace: "my-namespace", PodName: "my-cluster-broker-0", ForwardedPort: ForwardedPort{NodeId: 0, LocalPort: 50000, RemotePort: 9092}}
+	second := &PortForwarder{Namespace: "my-namespace", PodName: "my-cluster-broker-1", ForwardedPort: ForwardedPort{NodeId: 1, LocalPort: 50001, RemotePort: 9092}}
+	third := &PortForwarder{Namespace: "my-namespace", PodName: "my-cluster-broker-0", ForwardedPort: ForwardedPort{NodeId: 0, LocalPort: 50002, RemotePort: 9093, UseTLS: true}}
+	other := &PortForwarder{Namespace: "other-namespace", PodName: "my-cluster-broker-0", ForwardedPort: ForwardedPort{NodeId: 0, LocalPort: 50003, RemotePort: 9092}}
+
+	grouped := groupByPod([]*PortForwarder{first, second, third, other})
+	require.Len(t, grouped, 3)
+	assert.Same(t, first, grouped[0])
+	assert.Same(t, second, grouped[1])
+	assert.Same(t, other, grouped[2])
+
+	ports := grouped[0].ForwardedPorts()
+	require.Len(t, ports, 2)
+	assert.Equal(t, ForwardedPort{NodeId: 0, LocalPort: 50000, RemotePort: 9092}, ports[0])
+	assert.Equal(t, ForwardedPort{NodeId: 0, LocalPort: 50002, RemotePort: 9093, UseTLS: true}, ports[1])
+
+	assert.Len(t, grouped[1].ForwardedPorts(), 1)
+	assert.Len(t, grouped[2].ForwardedPorts(), 1)
+}
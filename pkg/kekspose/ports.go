@@ -0,0 +1,220 @@
+/*
+Copyright © 2025 Jakub Scholz
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kekspose
+
+import (
+	"cmp"
+	"fmt"
+	"log/slog"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+
+	keks2 "github.com/scholzj/kekspose/pkg/kekspose/keks"
+)
+
+// resolvePortMapping prepares the port mapping starting from the given port and verifies that all
+// its local ports are free before any forwarder is started. When some ports are taken, it either fails
+// with the list of conflicting ports or, with AutoPort enabled, moves the whole mapping to the next
+// block of free ports.
+func (k *Kekspose) resolvePortMapping(keks *keks2.Keks, startingPort uint32) (map[int32]uint32, error) {
+	if err := checkPortBlock(startingPort, len(keks.Nodes)); err != nil {
+		return nil, err
+	}
+
+	portMapping := preparePortMapping(keks, startingPort)
+
+	unavailable := unavailablePorts(portMapping)
+	if len(unavailable) > 0 {
+		if !k.AutoPort {
+			return nil, fmt.Errorf("local port(s) %s are already in use. Use --starting-port to select different ports or --auto-port to find free ports automatically", formatPorts(unavailable))
+		}
+
+		freePort, err := findFreePortBlock(startingPort+1, len(portMapping))
+		if err != nil {
+			return nil, err
+		}
+
+		slog.Warn("Local ports are already in use, using the next free block of ports", "unavailablePorts", formatPorts(unavailable), "startingPort", freePort)
+		portMapping = preparePortMapping(keks, freePort)
+	}
+
+	for _, nodeId := range sortedNodeIDs(portMapping) {
+		slog.Info("Port mapping", "node", nodeId, "podName", keks.Nodes[nodeId], "localPort", portMapping[nodeId])
+	}
+
+	return portMapping, nil
+}
+
+// resolvePort verifies that a single local port is free. When it is taken, it either fails or, with
+// AutoPort enabled, moves to the next free port.
+func (k *Kekspose) resolvePort(port uint32) (uint32, error) {
+	if err := checkPortBlock(port, 1); err != nil {
+		return 0, err
+	}
+
+	if err := checkPortAvailable(port); err == nil {
+		return port, nil
+	}
+
+	if !k.AutoPort {
+		return 0, fmt.Errorf("local port(s) %s are already in use. Use --starting-port to select different ports or --auto-port to find free ports automatically", formatPorts([]uint32{port}))
+	}
+
+	freePort, err := findFreePortBlock(port+1, 1)
+	if err != nil {
+		return 0, err
+	}
+
+	slog.Warn("Local port is already in use, using the next free port", "unavailablePort", port, "localPort", freePort)
+	return freePort, nil
+}
+
+// ephemeralPort asks the operating system for a free local port. It is used for internal forwarding
+// which is not used by the user directly. The operating system picks the port only for the IPv4
+// loopback address, so it is retried until it is free on all localhost addresses.
+func ephemeralPort() (uint32, error) {
+	var lastErr error
+
+	for range 10 {
+		listener, err := net.Listen("tcp4", "127.0.0.1:0")
+		if err != nil {
+			return 0, err
+		}
+
+		port := uint32(listener.Addr().(*net.TCPAddr).Port)
+		if err := listener.Close(); err != nil {
+			return 0, err
+		}
+
+		if lastErr = checkPortAvailable(port); lastErr == nil {
+			return port, nil
+		}
+	}
+
+	return 0, fmt.Errorf("failed to find a free local port: %w", lastErr)
+}
+
+func preparePortMapping(keks *keks2.Keks, startingPort uint32) map[int32]uint32 {
+	portMapping := make(map[int32]uint32)
+	nextPort := startingPort
+
+	for _, nodeId := range sortedNodeIDs(keks.Nodes) {
+		portMapping[nodeId] = nextPort
+		nextPort++
+	}
+
+	return portMapping
+}
+
+// unavailablePorts returns the local ports from the mapping that cannot be listened on.
+func unavailablePorts(portMapping map[int32]uint32) []uint32 {
+	var unavailable []uint32
+
+	for _, nodeId := range sortedNodeIDs(portMapping) {
+		if err := checkPortAvailable(portMapping[nodeId]); err != nil {
+			unavailable = append(unavailable, portMapping[nodeId])
+		}
+	}
+
+	return unavailable
+}
+
+// checkPortBlock verifies that the block of count consecutive ports starting from the given port fits
+// into the range of the valid ports.
+func checkPortBlock(start uint32, count int) error {
+	if uint64(start)+uint64(max(count, 1))-1 > math.MaxUint16 {
+		return fmt.Errorf("the %d local port(s) starting from port %d do not fit below port %d. Use --starting-port to select lower ports", max(count, 1), start, math.MaxUint16+1)
+	}
+
+	return nil
+}
+
+// findFreePortBlock finds the first block of count consecutive free ports starting from the given port.
+func findFreePortBlock(start uint32, count int) (uint32, error) {
+	for port := start; port+uint32(count)-1 <= math.MaxUint16; {
+		free := true
+
+		for offset := uint32(0); offset < uint32(count); offset++ {
+			if err := checkPortAvailable(port + offset); err != nil {
+				// The block cannot start before the port which is in use
+				port += offset + 1
+				free = false
+				break
+			}
+		}
+
+		if free {
+			return port, nil
+		}
+	}
+
+	return 0, fmt.Errorf("failed to find %d consecutive free local ports starting from port %d", count, start)
+}
+
+// localhostAddresses are the addresses the port forwarders listen on for localhost.
+var localhostAddresses = []struct{ network, address string }{
+	{"tcp4", "127.0.0.1"},
+	{"tcp6", "::1"},
+}
+
+// checkPortAvailable checks whether the local port can be used for forwarding by briefly listening on
+// it. The port forwarders listen on both the IPv4 and the IPv6 loopback addresses, so the port has to
+// be free on both. Only a loopback address which cannot be listened on at all, for example because
+// IPv6 is disabled, is skipped.
+func checkPortAvailable(port uint32) error {
+	var firstErr error
+	usable := 0
+
+	for _, a := range localhostAddresses {
+		if err := listenBriefly(a.network, a.address, port); err != nil {
+			if listenBriefly(a.network, a.address, 0) == nil {
+				return err
+			}
+
+			firstErr = cmp.Or(firstErr, err)
+			continue
+		}
+
+		usable++
+	}
+
+	if usable == 0 {
+		return firstErr
+	}
+
+	return nil
+}
+
+func listenBriefly(network string, address string, port uint32) error {
+	listener, err := net.Listen(network, net.JoinHostPort(address, strconv.FormatUint(uint64(port), 10)))
+	if err != nil {
+		return err
+	}
+
+	return listener.Close()
+}
+
+func formatPorts(ports []uint32) string {
+	formatted := make([]string, 0, len(ports))
+	for _, port := range ports {
+		formatted = append(formatted, strconv.FormatUint(uint64(port), 10))
+	}
+
+	return strings.Join(formatted, ", ")
+}
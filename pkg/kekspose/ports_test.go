@@ -0,0 +1,93 @@
+package kekspose
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"testing"
+
+	keks2 "github.com/scholzj/kekspose/pkg/kekspose/keks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolvePortMappingFailsOnUsedPorts(t *testing.T) {
+	busyPort := occupyPort(t)
+
+	k := Kekspose{}
+	portMapping, err := k.resolvePortMapping(&keks2.Keks{Nodes: map[int32]string{0: "pod-0"}}, busyPort)
+
+	assert.Nil(t, portMapping)
+	require.EqualError(t, err, "local port(s) "+strconv.FormatUint(uint64(busyPort), 10)+" are already in use. Use --starting-port to select different ports or --auto-port to find free ports automatically")
+}
+
+func TestResolvePortMappingWithAutoPort(t *testing.T) {
+	busyPort := occupyPort(t)
+
+	k := Kekspose{AutoPort: true}
+	portMapping, err := k.resolvePortMapping(&keks2.Keks{Nodes: map[int32]string{0: "pod-0", 1: "pod-1"}}, busyPort)
+
+	require.NoError(t, err)
+	assert.Greater(t, portMapping[0], busyPort)
+	assert.Equal(t, portMapping[0]+1, portMapping[1])
+}
+
+func TestResolvePortMappingFailsAboveThePortRange(t *testing.T) {
+	k := Kekspose{AutoPort: true}
+	portMapping, err := k.resolvePortMapping(&keks2.Keks{Nodes: map[int32]string{0: "pod-0", 1: "pod-1", 2: "pod-2"}}, 65534)
+
+	assert.Nil(t, portMapping)
+	require.EqualError(t, err, "the 3 local port(s) starting from port 65534 do not fit below port 65536. Use --starting-port to select lower ports")
+}
+
+func TestFindFreePortBlockSkipsUsedPorts(t *testing.T) {
+	busyPort := occupyPort(t)
+
+	port, err := findFreePortBlock(busyPort, 1)
+
+	require.NoError(t, err)
+	assert.Greater(t, port, busyPort)
+	require.NoError(t, checkPortAvailable(port))
+}
+
+func TestFindFreePortBlockFailsAtTheEndOfPortRange(t *testing.T) {
+	_, err := findFreePortBlock(65535, 2)
+
+	require.EqualError(t, err, "failed to find 2 consecutive free local ports starting from port 65535")
+}
+
+func occupyPort(t *testing.T) uint32 {
+	t.Helper()
+
+	listener, err := net.Listen("tcp4", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = listener.Close()
+	})
+
+	return uint32(listener.Addr().(*net.TCPAddr).Port)
+}
+
+func TestCheckPortAvailableOnIPv6(t *testing.T) {
+	listener, err := net.Listen("tcp6", "[::1]:0")
+	if err != nil {
+		t.Skip("IPv6 loopback address is not available")
+	}
+	defer listener.Close()
+
+	port := uint32(listener.Addr().(*net.TCPAddr).Port)
+	assert.Error(t, checkPortAvailable(port))
+}
+
+func TestResolvePort(t *testing.T) {
+	port := occupyPort(t)
+
+	k := Kekspose{}
+	_, err := k.resolvePort(port)
+	require.EqualError(t, err, fmt.Sprintf("local port(s) %d are already in use. Use --starting-port to select different ports or --auto-port to find free ports automatically", port))
+
+	k = Kekspose{AutoPort: true}
+	resolved, err := k.resolvePort(port)
+	require.NoError(t, err)
+	assert.Greater(t, resolved, port)
+}
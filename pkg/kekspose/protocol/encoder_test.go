@@ -0,0 +1,95 @@
+package protocol
+
+import (
+	"encoding/binary"
+)
+
+// encoder builds the Kafka protocol messages for the tests.
+type encoder struct {
+	data     []byte
+	flexible bool
+}
+
+func (e *encoder) int8(v int8) *encoder {
+	e.data = append(e.data, byte(v))
+	return e
+}
+
+func (e *encoder) int16(v int16) *encoder {
+	e.data = binary.BigEndian.AppendUint16(e.data, uint16(v))
+	return e
+}
+
+func (e *encoder) int32(v int32) *encoder {
+	e.data = binary.BigEndian.AppendUint32(e.data, uint32(v))
+	return e
+}
+
+func (e *encoder) int64(v int64) *encoder {
+	e.data = binary.BigEndian.AppendUint64(e.data, uint64(v))
+	return e
+}
+
+func (e *encoder) uvarint(v uint64) *encoder {
+	e.data = binary.AppendUvarint(e.data, v)
+	return e
+}
+
+func (e *encoder) varint(v int64) *encoder {
+	e.data = binary.AppendVarint(e.data, v)
+	return e
+}
+
+func (e *encoder) uuid(v UUID) *encoder {
+	e.data = append(e.data, v[:]...)
+	return e
+}
+
+// length writes the length of a string (short) or of bytes and arrays.
+func (e *encoder) length(n int, short bool) *encoder {
+	switch {
+	case e.flexible:
+		return e.uvarint(uint64(n + 1))
+	case short:
+		return e.int16(int16(n))
+	default:
+		return e.int32(int32(n))
+	}
+}
+
+func (e *encoder) string(v string) *encoder {
+	e.length(len(v), true)
+	e.data = append(e.data, v...)
+	return e
+}
+
+func (e *encoder) nullString() *encoder {
+	return e.length(-1, true)
+}
+
+func (e *encoder) bytes(v []byte) *encoder {
+	e.length(len(v), false)
+	e.data = append(e.data, v...)
+	return e
+}
+
+func (e *encoder) array(n int) *encoder {
+	return e.length(n, false)
+}
+
+// tags writes empty tagged fields in the flexible versions.
+func (e *encoder) tags() *encoder {
+	if e.flexible {
+		e.uvarint(0)
+	}
+	return e
+}
+
+// requestHeader writes the request header. The client ID always uses the non-compact string.
+func (e *encoder) requestHeader(apiKey int16, apiVersion int16, correlationId int32, clientId string) *encoder {
+	e.int16(apiKey).int16(apiVersion).int32(correlationId)
+	e.data = binary.BigEndian.AppendUint16(e.data, uint16(len(clientId)))
+	e.data = append(e.data, clientId...)
+	e.flexible = Flexible(apiKey, apiVersion)
+	return e.tags()
+}
@@ -0,0 +1,412 @@
+/*
+Copyright © 2025 Jakub Scholz
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package protocol
+
+import "strconv"
+
+// errorNames are the names of the Kafka error codes.
+var errorNames = []string{
+	"NONE", "OFFSET_OUT_OF_RANGE", "CORRUPT_MESSAGE", "UNKNOWN_TOPIC_OR_PARTITION", "INVALID_FETCH_SIZE",
+	"LEADER_NOT_AVAILABLE", "NOT_LEADER_OR_FOLLOWER", "REQUEST_TIMED_OUT", "BROKER_NOT_AVAILABLE",
+	"REPLICA_NOT_AVAILABLE", "MESSAGE_TOO_LARGE", "STALE_CONTROLLER_EPOCH", "OFFSET_METADATA_TOO_LARGE",
+	"NETWORK_EXCEPTION", "COORDINATOR_LOAD_IN_PROGRESS", "COORDINATOR_NOT_AVAILABLE", "NOT_COORDINATOR",
+	"INVALID_TOPIC_EXCEPTION", "RECORD_LIST_TOO_LARGE", "NOT_ENOUGH_REPLICAS", "NOT_ENOUGH_REPLICAS_AFTER_APPEND",
+	"INVALID_REQUIRED_ACKS", "ILLEGAL_GENERATION", "INCONSISTENT_GROUP_PROTOCOL", "INVALID_GROUP_ID",
+	"UNKNOWN_MEMBER_ID", "INVALID_SESSION_TIMEOUT", "REBALANCE_IN_PROGRESS", "INVALID_COMMIT_OFFSET_SIZE",
+	"TOPIC_AUTHORIZATION_FAILED", "GROUP_AUTHORIZATION_FAILED", "CLUSTER_AUTHORIZATION_FAILED",
+	"INVALID_TIMESTAMP", "UNSUPPORTED_SASL_MECHANISM", "ILLEGAL_SASL_STATE", "UNSUPPORTED_VERSION",
+	"TOPIC_ALREADY_EXISTS", "INVALID_PARTITIONS", "INVALID_REPLICATION_FACTOR", "INVALID_REPLICA_ASSIGNMENT",
+	"INVALID_CONFIG", "NOT_CONTROLLER", "INVALID_REQUEST", "UNSUPPORTED_FOR_MESSAGE_FORMAT", "POLICY_VIOLATION",
+	"OUT_OF_ORDER_SEQUENCE_NUMBER", "DUPLICATE_SEQUENCE_NUMBER", "INVALID_PRODUCER_EPOCH", "INVALID_TXN_STATE",
+	"INVALID_PRODUCER_ID_MAPPING", "INVALID_TRANSACTION_TIMEOUT", "CONCURRENT_TRANSACTIONS",
+	"TRANSACTION_COORDINATOR_FENCED", "TRANSACTIONAL_ID_AUTHORIZATION_FAILED", "SECURITY_DISABLED",
+	"OPERATION_NOT_ATTEMPTED", "KAFKA_STORAGE_ERROR", "LOG_DIR_NOT_FOUND", "SASL_AUTHENTICATION_FAILED",
+	"UNKNOWN_PRODUCER_ID", "REASSIGNMENT_IN_PROGRESS", "DELEGATION_TOKEN_AUTH_DISABLED",
+	"DELEGATION_TOKEN_NOT_FOUND", "DELEGATION_TOKEN_OWNER_MISMATCH", "DELEGATION_TOKEN_REQUEST_NOT_ALLOWED",
+	"DELEGATION_TOKEN_AUTHORIZATION_FAILED", "DELEGATION_TOKEN_EXPIRED", "INVALID_PRINCIPAL_TYPE",
+	"NON_EMPTY_GROUP", "GROUP_ID_NOT_FOUND", "FETCH_SESSION_ID_NOT_FOUND", "INVALID_FETCH_SESSION_EPOCH",
+	"LISTENER_NOT_FOUND", "TOPIC_DELETION_DISABLED", "FENCED_LEADER_EPOCH", "UNKNOWN_LEADER_EPOCH",
+	"UNSUPPORTED_COMPRESSION_TYPE", "STALE_BROKER_EPOCH", "OFFSET_NOT_AVAILABLE", "MEMBER_ID_REQUIRED",
+	"PREFERRED_LEADER_NOT_AVAILABLE", "GROUP_MAX_SIZE_REACHED", "FENCED_INSTANCE_ID",
+	"ELIGIBLE_LEADERS_NOT_AVAILABLE", "ELECTION_NOT_NEEDED", "NO_REASSIGNMENT_IN_PROGRESS",
+	"GROUP_SUBSCRIBED_TO_TOPIC", "INVALID_RECORD", "UNSTABLE_OFFSET_COMMIT", "THROTTLING_QUOTA_EXCEEDED",
+	"PRODUCER_FENCED", "RESOURCE_NOT_FOUND", "DUPLICATE_RESOURCE", "UNACCEPTABLE_CREDENTIAL",
+	"INCONSISTENT_VOTER_SET", "INVALID_UPDATE_VERSION", "FEATURE_UPDATE_FAILED",
+	"PRINCIPAL_DESERIALIZATION_FAILURE", "SNAPSHOT_NOT_FOUND", "POSITION_OUT_OF_RANGE", "UNKNOWN_TOPIC_ID",
+	"DUPLICATE_BROKER_REGISTRATION", "BROKER_ID_NOT_REGISTERED", "INCONSISTENT_TOPIC_ID",
+	"INCONSISTENT_CLUSTER_ID", "TRANSACTIONAL_ID_NOT_FOUND", "FETCH_SESSION_TOPIC_ID_ERROR",
+	"INELIGIBLE_REPLICA", "NEW_LEADER_ELECTED", "OFFSET_MOVED_TO_TIERED_STORAGE", "FENCED_MEMBER_EPOCH",
+	"UNRELEASED_INSTANCE_ID", "UNSUPPORTED_ASSIGNOR", "STALE_MEMBER_EPOCH", "MISMATCHED_ENDPOINT_TYPE",
+	"UNSUPPORTED_ENDPOINT_TYPE", "UNKNOWN_CONTROLLER_ID", "UNKNOWN_SUBSCRIPTION_ID", "TELEMETRY_TOO_LARGE",
+	"INVALID_REGISTRATION", "TRANSACTION_ABORTABLE",
+}
+
+// ErrorName returns the name of the Kafka error code.
+func ErrorName(code int16) string {
+	if code == -1 {
+		return "UNKNOWN_SERVER_ERROR"
+	}
+
+	if code >= 0 && int(code) < len(errorNames) {
+		return errorNames[code]
+	}
+
+	return "UNKNOWN(" + strconv.Itoa(int(code)) + ")"
+}
+
+// ResponseError is a non-zero error code in a response. The errors of the topics and partitions have
+// the topic name or, in the versions using topic IDs, the topic ID. The partition is -1 for the errors
+// which are not about a partition.
+type ResponseError struct {
+	Code      int16
+	Topic     string
+	TopicID   UUID
+	Partition int32
+	Group     string
+}
+
+// responseErrors collects the non-zero error codes of a response.
+type responseErrors []ResponseError
+
+func (e *responseErrors) add(r *Reader, code int16, topic string, id UUID, partition int32) {
+	if code != 0 && r.Err() == nil {
+		*e = append(*e, ResponseError{Code: code, Topic: topic, TopicID: id, Partition: partition})
+	}
+}
+
+func (e *responseErrors) addGroup(r *Reader, code int16, group string) {
+	if code != 0 && r.Err() == nil {
+		*e = append(*e, ResponseError{Code: code, Partition: -1, Group: group})
+	}
+}
+
+// ReportsErrors returns true when ReadResponseErrors decodes the errors of the API.
+func ReportsErrors(apiKey int16) bool {
+	switch apiKey {
+	case Produce, Fetch, ListOffsets, Metadata, OffsetCommit, OffsetFetch, FindCoordinator, JoinGroup,
+		Heartbeat, LeaveGroup, SyncGroup, SaslHandshake, APIVersions, CreateTopics, DeleteTopics,
+		InitProducerID, AddOffsetsToTxn, EndTxn, SaslAuthenticate, DeleteGroups, ConsumerGroupHeartbeat:
+		return true
+	default:
+		return false
+	}
+}
+
+// ReadResponseErrors decodes the non-zero error codes of the response to the request, including the
+// errors of its topics and partitions. The APIs not known to this package return no errors. When the
+// response is malformed, the errors decoded before the error are returned together with the error. The
+// group is set for the errors of the group APIs when the request names the group.
+func ReadResponseErrors(h RequestHeader, group string, r *Reader) ([]ResponseError, error) {
+	var errs responseErrors
+	v := h.APIVersion
+	flexible := h.Flexible()
+
+	switch h.APIKey {
+	case Produce:
+		readProduceErrors(r, v, flexible, &errs)
+	case Fetch:
+		readFetchErrors(r, v, flexible, &errs)
+	case ListOffsets:
+		if v >= 2 {
+			r.Int32() // Throttle time
+		}
+		for i, n := 0, r.ArrayLength(flexible); i < n; i++ {
+			topic := r.String(flexible)
+			for j, partitions := 0, r.ArrayLength(flexible); j < partitions; j++ {
+				partition := r.Int32()
+				errs.add(r, r.Int16(), topic, UUID{}, partition)
+				if v == 0 {
+					r.Skip(8 * max(r.ArrayLength(flexible), 0)) // Old style offsets
+				} else {
+					r.Int64() // Timestamp
+					r.Int64() // Offset
+				}
+				if v >= 4 {
+					r.Int32() // Leader epoch
+				}
+				r.TaggedFields(flexible)
+			}
+			r.TaggedFields(flexible)
+		}
+	case Metadata:
+		readMetadataErrors(r, v, flexible, &errs)
+	case OffsetCommit:
+		if v >= 3 {
+			r.Int32() // Throttle time
+		}
+		for i, n := 0, r.ArrayLength(flexible); i < n; i++ {
+			topic, id := readTopicOrID(r, flexible, v >= 10)
+			for j, partitions := 0, r.ArrayLength(flexible); j < partitions; j++ {
+				partition := r.Int32()
+				errs.add(r, r.Int16(), topic, id, partition)
+				r.TaggedFields(flexible)
+			}
+			r.TaggedFields(flexible)
+		}
+	case OffsetFetch:
+		readOffsetFetchErrors(r, v, flexible, group, &errs)
+	case FindCoordinator:
+		if v >= 1 {
+			r.Int32() // Throttle time
+		}
+		if v <= 3 {
+			errs.addGroup(r, r.Int16(), group)
+		} else {
+			for i, n := 0, r.ArrayLength(flexible); i < n; i++ {
+				key := r.String(flexible)
+				r.Int32()                  // Node ID
+				r.String(flexible)         // Host
+				r.Int32()                  // Port
+				code := r.Int16()          // Error code
+				r.NullableString(flexible) // Error message
+				r.TaggedFields(flexible)
+				errs.addGroup(r, code, key)
+			}
+		}
+	case JoinGroup, SyncGroup:
+		// The throttle time was added in JoinGroup v2 and SyncGroup v1
+		if v >= 2 || h.APIKey == SyncGroup && v >= 1 {
+			r.Int32() // Throttle time
+		}
+		errs.addGroup(r, r.Int16(), group)
+	case Heartbeat, LeaveGroup, DeleteGroups, ConsumerGroupHeartbeat, InitProducerID, AddOffsetsToTxn, EndTxn:
+		// All versions of these APIs have the throttle time, except for Heartbeat and LeaveGroup v0
+		if v >= 1 || h.APIKey != Heartbeat && h.APIKey != LeaveGroup {
+			r.Int32() // Throttle time
+		}
+		if h.APIKey == DeleteGroups {
+			for i, n := 0, r.ArrayLength(flexible); i < n; i++ {
+				id := r.String(flexible)
+				errs.addGroup(r, r.Int16(), id)
+				r.TaggedFields(flexible)
+			}
+		} else {
+			errs.addGroup(r, r.Int16(), group)
+		}
+	case SaslHandshake, APIVersions, SaslAuthenticate:
+		errs.add(r, r.Int16(), "", UUID{}, -1)
+	case CreateTopics:
+		readCreateTopicsErrors(r, v, flexible, &errs)
+	case DeleteTopics:
+		if v >= 1 {
+			r.Int32() // Throttle time
+		}
+		for i, n := 0, r.ArrayLength(flexible); i < n; i++ {
+			name, _ := r.NullableString(flexible)
+			var id UUID
+			if v >= 6 {
+				id = r.UUID()
+			}
+			errs.add(r, r.Int16(), name, id, -1)
+			if v >= 5 {
+				r.NullableString(flexible) // Error message
+			}
+			r.TaggedFields(flexible)
+		}
+	}
+
+	return errs, r.Err()
+}
+
+func readProduceErrors(r *Reader, v int16, flexible bool, errs *responseErrors) {
+	for i, n := 0, r.ArrayLength(flexible); i < n; i++ {
+		topic, id := readTopicOrID(r, flexible, v >= 13)
+		for j, partitions := 0, r.ArrayLength(flexible); j < partitions; j++ {
+			partition := r.Int32()
+			errs.add(r, r.Int16(), topic, id, partition)
+			r.Int64() // Base offset
+			if v >= 2 {
+				r.Int64() // Log append time
+			}
+			if v >= 5 {
+				r.Int64() // Log start offset
+			}
+			if v >= 8 {
+				for k, records := 0, r.ArrayLength(flexible); k < records; k++ {
+					r.Int32()                  // Batch index
+					r.NullableString(flexible) // Batch index error message
+					r.TaggedFields(flexible)
+				}
+				r.NullableString(flexible) // Error message
+			}
+			r.TaggedFields(flexible)
+		}
+		r.TaggedFields(flexible)
+	}
+}
+
+func readFetchErrors(r *Reader, v int16, flexible bool, errs *responseErrors) {
+	if v >= 1 {
+		r.Int32() // Throttle time
+	}
+	if v >= 7 {
+		errs.add(r, r.Int16(), "", UUID{}, -1)
+		r.Int32() // Session ID
+	}
+
+	for i, n := 0, r.ArrayLength(flexible); i < n; i++ {
+		topic, id := readTopicOrID(r, flexible, v >= 13)
+		for j, partitions := 0, r.ArrayLength(flexible); j < partitions; j++ {
+			partition := r.Int32()
+			errs.add(r, r.Int16(), topic, id, partition)
+			r.Int64() // High watermark
+			if v >= 4 {
+				r.Int64() // Last stable offset
+			}
+			if v >= 5 {
+				r.Int64() // Log start offset
+			}
+			if v >= 4 {
+				for k, aborted := 0, r.ArrayLength(flexible); k < aborted; k++ {
+					r.Int64() // Producer ID
+					r.Int64() // First offset
+					r.TaggedFields(flexible)
+				}
+			}
+			if v >= 11 {
+				r.Int32() // Preferred read replica
+			}
+			r.Bytes(flexible) // Records
+			r.TaggedFields(flexible)
+		}
+		r.TaggedFields(flexible)
+	}
+}
+
+func readMetadataErrors(r *Reader, v int16, flexible bool, errs *responseErrors) {
+	if v >= 3 {
+		r.Int32() // Throttle time
+	}
+	for i, n := 0, r.ArrayLength(flexible); i < n; i++ {
+		r.Int32()          // Node ID
+		r.String(flexible) // Host
+		r.Int32()          // Port
+		if v >= 1 {
+			r.NullableString(flexible) // Rack
+		}
+		r.TaggedFields(flexible)
+	}
+	if v >= 2 {
+		r.NullableString(flexible) // Cluster ID
+	}
+	if v >= 1 {
+		r.Int32() // Controller ID
+	}
+
+	for i, n := 0, r.ArrayLength(flexible); i < n; i++ {
+		code := r.Int16()
+		topic, _ := r.NullableString(flexible)
+		var id UUID
+		if v >= 10 {
+			id = r.UUID()
+		}
+		if v >= 1 {
+			r.Bool() // Is internal
+		}
+		errs.add(r, code, topic, id, -1)
+
+		for j, partitions := 0, r.ArrayLength(flexible); j < partitions; j++ {
+			code := r.Int16()
+			partition := r.Int32()
+			errs.add(r, code, topic, id, partition)
+			r.Int32() // Leader ID
+			if v >= 7 {
+				r.Int32() // Leader epoch
+			}
+			r.SkipInt32Array(flexible) // Replicas
+			r.SkipInt32Array(flexible) // In-sync replicas
+			if v >= 5 {
+				r.SkipInt32Array(flexible) // Offline replicas
+			}
+			r.TaggedFields(flexible)
+		}
+		if v >= 8 {
+			r.Int32() // Topic authorized operations
+		}
+		r.TaggedFields(flexible)
+	}
+}
+
+func readOffsetFetchErrors(r *Reader, v int16, flexible bool, group string, errs *responseErrors) {
+	if v >= 3 {
+		r.Int32() // Throttle time
+	}
+
+	// The versions 8 and newer fetch the offsets of multiple groups
+	groups := 1
+	if v >= 8 {
+		groups = r.ArrayLength(flexible)
+	}
+
+	for g := 0; g < groups; g++ {
+		if v >= 8 {
+			group = r.String(flexible)
+		}
+
+		for i, n := 0, r.ArrayLength(flexible); i < n; i++ {
+			topic, id := readTopicOrID(r, flexible, v >= 10)
+			for j, partitions := 0, r.ArrayLength(flexible); j < partitions; j++ {
+				partition := r.Int32()
+				r.Int64() // Committed offset
+				if v >= 5 {
+					r.Int32() // Committed leader epoch
+				}
+				r.NullableString(flexible) // Metadata
+				errs.add(r, r.Int16(), topic, id, partition)
+				r.TaggedFields(flexible)
+			}
+			r.TaggedFields(flexible)
+		}
+
+		if v >= 2 {
+			errs.addGroup(r, r.Int16(), group)
+		}
+		if v >= 8 {
+			r.TaggedFields(flexible)
+		}
+	}
+}
+
+func readCreateTopicsErrors(r *Reader, v int16, flexible bool, errs *responseErrors) {
+	if v >= 2 {
+		r.Int32() // Throttle time
+	}
+
+	for i, n := 0, r.ArrayLength(flexible); i < n; i++ {
+		topic := r.String(flexible)
+		if v >= 7 {
+			r.UUID() // Topic ID
+		}
+		errs.add(r, r.Int16(), topic, UUID{}, -1)
+		if v >= 1 {
+			r.NullableString(flexible) // Error message
+		}
+		if v >= 5 {
+			r.Int32() // Number of partitions
+			r.Int16() // Replication factor
+			for j, configs := 0, r.ArrayLength(flexible); j < configs; j++ {
+				r.String(flexible)         // Name
+				r.NullableString(flexible) // Value
+				r.Bool()                   // Read only
+				r.Int8()                   // Config source
+				r.Bool()                   // Is sensitive
+				r.TaggedFields(flexible)
+			}
+		}
+		r.TaggedFields(flexible)
+	}
+}
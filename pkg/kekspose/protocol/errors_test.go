@@ -0,0 +1,124 @@
+package protocol
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func readErrors(t *testing.T, apiKey int16, apiVersion int16, group string, e *encoder) []ResponseError {
+	t.Helper()
+
+	errs, err := ReadResponseErrors(RequestHeader{APIKey: apiKey, APIVersion: apiVersion}, group, NewReader(e.data))
+	require.NoError(t, err)
+
+	return errs
+}
+
+func TestErrorName(t *testing.T) {
+	assert.Equal(t, "UNKNOWN_SERVER_ERROR", ErrorName(-1))
+	assert.Equal(t, "NONE", ErrorName(0))
+	assert.Equal(t, "NOT_LEADER_OR_FOLLOWER", ErrorName(6))
+	assert.Equal(t, "TOPIC_AUTHORIZATION_FAILED", ErrorName(29))
+	assert.Equal(t, "UNKNOWN_TOPIC_ID", ErrorName(100))
+	assert.Equal(t, "TRANSACTION_ABORTABLE", ErrorName(120))
+	assert.Equal(t, "UNKNOWN(1000)", ErrorName(1000))
+}
+
+func TestReadProduceErrors(t *testing.T) {
+	e := &encoder{}
+	e.array(1).string("orders").array(2)
+	e.int32(0).int16(0).int64(10).int64(-1).int64(0)
+	e.int32(1).int16(6).int64(-1).int64(-1).int64(0)
+	e.int32(0)
+
+	errs := readErrors(t, Produce, 7, "", e)
+	assert.Equal(t, []ResponseError{{Code: 6, Topic: "orders", Partition: 1}}, errs)
+
+	e = &encoder{flexible: true}
+	e.array(1).uuid(testTopicID).array(1)
+	e.int32(2).int16(100).int64(-1).int64(-1).int64(0).array(0).nullString().tags().tags()
+	e.int32(0).tags()
+
+	errs = readErrors(t, Produce, 13, "", e)
+	assert.Equal(t, []ResponseError{{Code: 100, TopicID: testTopicID, Partition: 2}}, errs)
+}
+
+func TestReadFetchErrors(t *testing.T) {
+	e := &encoder{}
+	e.int32(0).int16(70).int32(0).array(1).string("orders").array(1)
+	e.int32(3).int16(1).int64(-1).int64(-1).int64(-1).array(-1).int32(-1).bytes(nil)
+
+	errs := readErrors(t, Fetch, 11, "", e)
+	assert.Equal(t, []ResponseError{{Code: 70, Partition: -1}, {Code: 1, Topic: "orders", Partition: 3}}, errs)
+}
+
+func TestReadMetadataErrors(t *testing.T) {
+	e := &encoder{flexible: true}
+	e.int32(0)
+	e.array(1).int32(0).string("localhost").int32(9092).nullString().tags()
+	e.string("my-cluster").int32(0)
+	e.array(2)
+	e.int16(3).string("missing").uuid(UUID{}).int8(0).array(0).int32(0).tags()
+	e.int16(0).string("orders").uuid(testTopicID).int8(0)
+	e.array(1).int16(5).int32(0).int32(-1).int32(5).array(0).array(0).array(0).tags()
+	e.int32(0).tags()
+	e.tags()
+
+	errs := readErrors(t, Metadata, 12, "", e)
+	assert.Equal(t, []ResponseError{
+		{Code: 3, Topic: "missing", Partition: -1},
+		{Code: 5, Topic: "orders", TopicID: testTopicID, Partition: 0},
+	}, errs)
+}
+
+func TestReadGroupErrors(t *testing.T) {
+	e := &encoder{flexible: true}
+	e.int32(0).int16(27).tags()
+	assert.Equal(t, []ResponseError{{Code: 27, Partition: -1, Group: "my-group"}}, readErrors(t, Heartbeat, 4, "my-group", e))
+
+	e = &encoder{}
+	e.int16(25)
+	assert.Equal(t, []ResponseError{{Code: 25, Partition: -1, Group: "my-group"}}, readErrors(t, LeaveGroup, 0, "my-group", e))
+
+	e = &encoder{}
+	e.int32(0).int16(22).bytes(nil)
+	assert.Equal(t, []ResponseError{{Code: 22, Partition: -1, Group: "my-group"}}, readErrors(t, SyncGroup, 1, "my-group", e))
+
+	e = &encoder{flexible: true}
+	e.int32(0).array(2).string("my-group").array(0).int16(0).tags().string("other-group").array(0).int16(69).tags()
+	assert.Equal(t, []ResponseError{{Code: 69, Partition: -1, Group: "other-group"}}, readErrors(t, OffsetFetch, 8, "", e))
+
+	e = &encoder{flexible: true}
+	e.int32(0).array(1).string("my-group").int32(1).string("localhost").int32(9092).int16(15).nullString().tags()
+	assert.Equal(t, []ResponseError{{Code: 15, Partition: -1, Group: "my-group"}}, readErrors(t, FindCoordinator, 4, "", e))
+
+	e = &encoder{flexible: true}
+	e.int32(0).array(1).string("orders").array(1).int32(0).int16(25).tags().tags()
+	assert.Equal(t, []ResponseError{{Code: 25, Topic: "orders", Partition: 0}}, readErrors(t, OffsetCommit, 8, "my-group", e))
+}
+
+func TestReadOtherErrors(t *testing.T) {
+	e := &encoder{}
+	e.int16(58).nullString()
+	assert.Equal(t, []ResponseError{{Code: 58, Partition: -1}}, readErrors(t, SaslAuthenticate, 1, "", e))
+
+	e = &encoder{flexible: true}
+	e.int32(0).array(1).string("orders").uuid(UUID{}).int16(36).nullString().int32(-1).int16(-1).array(-1).tags()
+	assert.Equal(t, []ResponseError{{Code: 36, Topic: "orders", Partition: -1}}, readErrors(t, CreateTopics, 7, "", e))
+
+	// The APIs which are not decoded have no errors
+	assert.Empty(t, readErrors(t, DescribeGroups, 5, "", &encoder{}))
+	assert.False(t, ReportsErrors(DescribeGroups))
+	assert.True(t, ReportsErrors(Produce))
+}
+
+func TestReadMalformedErrors(t *testing.T) {
+	e := &encoder{}
+	e.array(2).string("orders").array(1).int32(0).int16(6).int64(-1)
+
+	errs, err := ReadResponseErrors(RequestHeader{APIKey: Produce, APIVersion: 3}, "", NewReader(e.data))
+	assert.ErrorIs(t, err, ErrMalformed)
+	assert.Equal(t, []ResponseError{{Code: 6, Topic: "orders", Partition: 0}}, errs)
+}
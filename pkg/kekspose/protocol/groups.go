@@ -0,0 +1,286 @@
+/*
+Copyright © 2025 Jakub Scholz
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package protocol
+
+// ConsumerProtocolType is the protocol type of the classic consumer groups. Only their assignments can
+// be decoded with ReadConsumerAssignment.
+const ConsumerProtocolType = "consumer"
+
+// TopicPartitions are the partitions of one topic. The newer versions of some APIs identify the topic
+// by its ID instead of its name.
+type TopicPartitions struct {
+	Topic      string
+	TopicID    UUID
+	Partitions []int32
+}
+
+// CommittedOffset is the offset of a partition committed by the OffsetCommit request.
+type CommittedOffset struct {
+	Topic     string
+	TopicID   UUID
+	Partition int32
+	Offset    int64
+}
+
+// GroupRequest is a request of a consumer group member. The epoch is the generation ID of the classic
+// group APIs or the member epoch of the ConsumerGroupHeartbeat requests. Leaving are the members leaving
+// the group with the LeaveGroup request.
+type GroupRequest struct {
+	Group        string
+	MemberID     string
+	InstanceID   string
+	Epoch        int32
+	ProtocolType string
+	Leaving      []string
+	Offsets      []CommittedOffset
+}
+
+// GroupResponse is the response to a request of a consumer group member. The epoch is the generation ID
+// of the JoinGroup responses or the member epoch of the ConsumerGroupHeartbeat responses. Only the
+// leader of a classic group gets the members in the JoinGroup response. The SyncGroup responses have
+// the encoded assignment in AssignmentData, while the ConsumerGroupHeartbeat responses have the decoded
+// Assignment when Assigned is set. Errors are the errors of the partitions in the OffsetCommit
+// responses.
+type GroupResponse struct {
+	ErrorCode      int16
+	MemberID       string
+	Epoch          int32
+	Protocol       string
+	Leader         string
+	Members        []string
+	AssignmentData []byte
+	Assignment     []TopicPartitions
+	Assigned       bool
+	Errors         []ResponseError
+}
+
+// MonitorsGroup returns true when ReadGroupRequest and ReadGroupResponse decode the API.
+func MonitorsGroup(apiKey int16) bool {
+	switch apiKey {
+	case JoinGroup, SyncGroup, Heartbeat, LeaveGroup, OffsetCommit, ConsumerGroupHeartbeat:
+		return true
+	default:
+		return false
+	}
+}
+
+// ReadGroupRequest decodes the consumer group request. The APIs not known to this package return an
+// empty request. When the request is malformed, the fields decoded before the error are returned
+// together with the error.
+func ReadGroupRequest(h RequestHeader, r *Reader) (GroupRequest, error) {
+	req := GroupRequest{}
+	v := h.APIVersion
+	flexible := h.Flexible()
+
+	switch h.APIKey {
+	case JoinGroup:
+		req.Group = r.String(flexible)
+		r.Int32() // Session timeout
+		if v >= 1 {
+			r.Int32() // Rebalance timeout
+		}
+		req.MemberID = r.String(flexible)
+		if v >= 5 {
+			req.InstanceID = r.String(flexible)
+		}
+		req.ProtocolType = r.String(flexible)
+	case SyncGroup, Heartbeat:
+		req.Group = r.String(flexible)
+		req.Epoch = r.Int32()
+		req.MemberID = r.String(flexible)
+		if v >= 3 {
+			req.InstanceID = r.String(flexible)
+		}
+	case LeaveGroup:
+		req.Group = r.String(flexible)
+		if v <= 2 {
+			req.MemberID = r.String(flexible)
+			req.Leaving = append(req.Leaving, req.MemberID)
+			break
+		}
+
+		for i, n := 0, r.ArrayLength(flexible); i < n; i++ {
+			member := r.String(flexible)
+			instance := r.String(flexible)
+			if v >= 5 {
+				r.NullableString(flexible) // Reason
+			}
+			r.TaggedFields(flexible)
+
+			// The static members can leave using only their group instance ID
+			if member == "" {
+				member = instance
+			}
+			if r.Err() == nil {
+				req.Leaving = append(req.Leaving, member)
+			}
+		}
+	case OffsetCommit:
+		readGroupOffsetCommit(r, v, flexible, &req)
+	case ConsumerGroupHeartbeat:
+		req.Group = r.String(flexible)
+		req.MemberID = r.String(flexible)
+		req.Epoch = r.Int32()
+		req.InstanceID = r.String(flexible)
+	}
+
+	return req, r.Err()
+}
+
+func readGroupOffsetCommit(r *Reader, v int16, flexible bool, req *GroupRequest) {
+	req.Group = r.String(flexible)
+	req.Epoch = -1
+	if v >= 1 {
+		req.Epoch = r.Int32()
+		req.MemberID = r.String(flexible)
+	}
+	if v >= 7 {
+		req.InstanceID = r.String(flexible)
+	}
+	if v >= 2 && v <= 4 {
+		r.Int64() // Retention time
+	}
+
+	for i, n := 0, r.ArrayLength(flexible); i < n; i++ {
+		topic, id := readTopicOrID(r, flexible, v >= 10)
+		for j, partitions := 0, r.ArrayLength(flexible); j < partitions; j++ {
+			offset := CommittedOffset{Topic: topic, TopicID: id, Partition: r.Int32(), Offset: r.Int64()}
+			if v >= 6 {
+				r.Int32() // Committed leader epoch
+			}
+			if v == 1 {
+				r.Int64() // Commit timestamp
+			}
+			r.NullableString(flexible) // Committed metadata
+			r.TaggedFields(flexible)
+
+			if r.Err() == nil {
+				req.Offsets = append(req.Offsets, offset)
+			}
+		}
+		r.TaggedFields(flexible)
+	}
+}
+
+// ReadGroupResponse decodes the response to the consumer group request. The APIs not known to this
+// package return an empty response. When the response is malformed, the fields decoded before the error
+// are returned together with the error.
+func ReadGroupResponse(h RequestHeader, r *Reader) (GroupResponse, error) {
+	resp := GroupResponse{}
+	v := h.APIVersion
+	flexible := h.Flexible()
+
+	switch h.APIKey {
+	case JoinGroup:
+		readGroupJoin(r, v, flexible, &resp)
+	case SyncGroup:
+		if v >= 1 {
+			r.Int32() // Throttle time
+		}
+		resp.ErrorCode = r.Int16()
+		if v >= 5 {
+			r.NullableString(flexible) // Protocol type
+			resp.Protocol, _ = r.NullableString(flexible)
+		}
+		resp.AssignmentData = r.Bytes(flexible)
+	case Heartbeat, LeaveGroup:
+		if v >= 1 {
+			r.Int32() // Throttle time
+		}
+		resp.ErrorCode = r.Int16()
+	case OffsetCommit:
+		// The errors of the partitions are the same as the ones logged with --log-errors
+		errs, _ := ReadResponseErrors(h, "", r)
+		resp.Errors = errs
+	case ConsumerGroupHeartbeat:
+		r.Int32() // Throttle time
+		resp.ErrorCode = r.Int16()
+		r.NullableString(flexible) // Error message
+		resp.MemberID = r.String(flexible)
+		resp.Epoch = r.Int32()
+		r.Int32() // Heartbeat interval
+
+		// The assignment is a nullable structure, which is present only when it changes
+		if r.Int8() >= 0 {
+			resp.Assignment = readTopicPartitions(r, flexible, true)
+			r.TaggedFields(flexible)
+			resp.Assigned = r.Err() == nil
+		}
+	}
+
+	return resp, r.Err()
+}
+
+func readGroupJoin(r *Reader, v int16, flexible bool, resp *GroupResponse) {
+	if v >= 2 {
+		r.Int32() // Throttle time
+	}
+	resp.ErrorCode = r.Int16()
+	resp.Epoch = r.Int32()
+	if v >= 7 {
+		r.NullableString(flexible) // Protocol type
+	}
+	resp.Protocol, _ = r.NullableString(flexible)
+	resp.Leader = r.String(flexible)
+	if v >= 9 {
+		r.Bool() // Skip assignment
+	}
+	resp.MemberID = r.String(flexible)
+
+	for i, n := 0, r.ArrayLength(flexible); i < n; i++ {
+		member := r.String(flexible)
+		if v >= 5 {
+			r.NullableString(flexible) // Group instance ID
+		}
+		r.Bytes(flexible) // Metadata
+		r.TaggedFields(flexible)
+
+		if r.Err() == nil {
+			resp.Members = append(resp.Members, member)
+		}
+	}
+}
+
+// readTopicPartitions reads an array of topics with an array of their partitions.
+func readTopicPartitions(r *Reader, flexible bool, byID bool) []TopicPartitions {
+	var topics []TopicPartitions
+	for i, n := 0, r.ArrayLength(flexible); i < n; i++ {
+		topic := TopicPartitions{}
+		topic.Topic, topic.TopicID = readTopicOrID(r, flexible, byID)
+		for j, partitions := 0, r.ArrayLength(flexible); j < partitions; j++ {
+			topic.Partitions = append(topic.Partitions, r.Int32())
+		}
+		r.TaggedFields(flexible)
+
+		if r.Err() == nil {
+			topics = append(topics, topic)
+		}
+	}
+
+	return topics
+}
+
+// ReadConsumerAssignment decodes the assignment of a classic consumer group member, as sent by the
+// group leader in the SyncGroup request and received by the member in the SyncGroup response. The user
+// data of the assignment are skipped.
+func ReadConsumerAssignment(data []byte) ([]TopicPartitions, error) {
+	r := NewReader(data)
+	r.Int16() // Version
+
+	topics := readTopicPartitions(r, false, false)
+	return topics, r.Err()
+}
@@ -0,0 +1,120 @@
+package protocol
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func readGroupRequest(t *testing.T, apiKey int16, apiVersion int16, e *encoder) GroupRequest {
+	t.Helper()
+
+	req, err := ReadGroupRequest(RequestHeader{APIKey: apiKey, APIVersion: apiVersion}, NewReader(e.data))
+	require.NoError(t, err)
+
+	return req
+}
+
+func readGroupResponse(t *testing.T, apiKey int16, apiVersion int16, e *encoder) GroupResponse {
+	t.Helper()
+
+	resp, err := ReadGroupResponse(RequestHeader{APIKey: apiKey, APIVersion: apiVersion}, NewReader(e.data))
+	require.NoError(t, err)
+
+	return resp
+}
+
+func TestReadClassicGroupRequests(t *testing.T) {
+	e := &encoder{flexible: true}
+	e.string("my-group").int32(45000).int32(300000).string("member-1").string("instance-1").string("consumer")
+	e.array(1).string("range").bytes([]byte{0, 1}).tags().nullString().tags()
+	assert.Equal(t, GroupRequest{Group: "my-group", MemberID: "member-1", InstanceID: "instance-1", ProtocolType: "consumer"}, readGroupRequest(t, JoinGroup, 8, e))
+
+	e = &encoder{}
+	e.string("my-group").int32(3).string("member-1").array(0)
+	assert.Equal(t, GroupRequest{Group: "my-group", MemberID: "member-1", Epoch: 3}, readGroupRequest(t, SyncGroup, 2, e))
+
+	e = &encoder{}
+	e.string("my-group").string("member-1")
+	assert.Equal(t, GroupRequest{Group: "my-group", MemberID: "member-1", Leaving: []string{"member-1"}}, readGroupRequest(t, LeaveGroup, 1, e))
+
+	e = &encoder{flexible: true}
+	e.string("my-group").array(2)
+	e.string("member-1").nullString().string("shutdown").tags()
+	e.string("").string("instance-2").nullString().tags()
+	e.tags()
+	assert.Equal(t, GroupRequest{Group: "my-group", Leaving: []string{"member-1", "instance-2"}}, readGroupRequest(t, LeaveGroup, 5, e))
+}
+
+func TestReadOffsetCommitRequest(t *testing.T) {
+	e := &encoder{}
+	e.string("my-group").int32(4).string("member-1").int64(-1)
+	e.array(1).string("orders").array(2)
+	e.int32(0).int64(42).nullString()
+	e.int32(1).int64(17).string("metadata")
+
+	assert.Equal(t, GroupRequest{Group: "my-group", MemberID: "member-1", Epoch: 4, Offsets: []CommittedOffset{
+		{Topic: "orders", Partition: 0, Offset: 42},
+		{Topic: "orders", Partition: 1, Offset: 17},
+	}}, readGroupRequest(t, OffsetCommit, 2, e))
+
+	e = &encoder{flexible: true}
+	e.string("my-group").int32(7).string("member-1").nullString()
+	e.array(1).uuid(testTopicID).array(1).int32(3).int64(100).int32(5).nullString().tags().tags()
+	e.tags()
+
+	assert.Equal(t, GroupRequest{Group: "my-group", MemberID: "member-1", Epoch: 7, Offsets: []CommittedOffset{
+		{TopicID: testTopicID, Partition: 3, Offset: 100},
+	}}, readGroupRequest(t, OffsetCommit, 10, e))
+}
+
+func TestReadClassicGroupResponses(t *testing.T) {
+	e := &encoder{flexible: true}
+	e.int32(0).int16(0).int32(5).string("consumer").string("range").string("member-1").int8(0).string("member-1")
+	e.array(2).string("member-1").nullString().bytes(nil).tags().string("member-2").string("instance-2").bytes(nil).tags()
+	e.tags()
+
+	assert.Equal(t, GroupResponse{Epoch: 5, Protocol: "range", Leader: "member-1", MemberID: "member-1", Members: []string{"member-1", "member-2"}}, readGroupResponse(t, JoinGroup, 9, e))
+
+	e = &encoder{}
+	e.int32(0).int16(0).bytes([]byte{0, 0, 0, 0, 0, 1, 0, 6, 'o', 'r', 'd', 'e', 'r', 's', 0, 0, 0, 2, 0, 0, 0, 0, 0, 0, 0, 2, 0xff, 0xff, 0xff, 0xff})
+	resp := readGroupResponse(t, SyncGroup, 3, e)
+	assert.Equal(t, int16(0), resp.ErrorCode)
+
+	assignment, err := ReadConsumerAssignment(resp.AssignmentData)
+	require.NoError(t, err)
+	assert.Equal(t, []TopicPartitions{{Topic: "orders", Partitions: []int32{0, 2}}}, assignment)
+
+	e = &encoder{}
+	e.int32(0).int16(27)
+	assert.Equal(t, GroupResponse{ErrorCode: 27}, readGroupResponse(t, Heartbeat, 2, e))
+
+	e = &encoder{}
+	e.int32(0).array(1).string("orders").array(2).int32(0).int16(0).int32(1).int16(22)
+	assert.Equal(t, GroupResponse{Errors: []ResponseError{{Code: 22, Topic: "orders", Partition: 1}}}, readGroupResponse(t, OffsetCommit, 3, e))
+}
+
+func TestReadConsumerGroupHeartbeat(t *testing.T) {
+	e := &encoder{flexible: true}
+	e.string("my-group").string("member-1").int32(0).nullString().nullString().int32(300000)
+	e.array(1).string("orders").nullString().array(-1).tags()
+	assert.Equal(t, GroupRequest{Group: "my-group", MemberID: "member-1"}, readGroupRequest(t, ConsumerGroupHeartbeat, 0, e))
+
+	e = &encoder{flexible: true}
+	e.int32(0).int16(0).nullString().string("member-1").int32(12).int32(5000)
+	e.int8(1).array(1).uuid(testTopicID).array(2).int32(0).int32(1).tags().tags()
+	e.tags()
+
+	assert.Equal(t, GroupResponse{MemberID: "member-1", Epoch: 12, Assigned: true, Assignment: []TopicPartitions{
+		{TopicID: testTopicID, Partitions: []int32{0, 1}},
+	}}, readGroupResponse(t, ConsumerGroupHeartbeat, 0, e))
+
+	// The assignment is present only when it changes
+	e = &encoder{flexible: true}
+	e.int32(0).int16(0).nullString().string("member-1").int32(12).int32(5000).int8(-1).tags()
+	assert.Equal(t, GroupResponse{MemberID: "member-1", Epoch: 12}, readGroupResponse(t, ConsumerGroupHeartbeat, 0, e))
+
+	assert.True(t, MonitorsGroup(ConsumerGroupHeartbeat))
+	assert.False(t, MonitorsGroup(DescribeGroups))
+}
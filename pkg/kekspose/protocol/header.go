@@ -0,0 +1,163 @@
+/*
+Copyright © 2025 Jakub Scholz
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package protocol
+
+// The API keys of the Kafka APIs decoded by this package or redacted by Keksposé.
+const (
+	Produce                 int16 = 0
+	Fetch                   int16 = 1
+	ListOffsets             int16 = 2
+	Metadata                int16 = 3
+	OffsetCommit            int16 = 8
+	OffsetFetch             int16 = 9
+	FindCoordinator         int16 = 10
+	JoinGroup               int16 = 11
+	Heartbeat               int16 = 12
+	LeaveGroup              int16 = 13
+	SyncGroup               int16 = 14
+	DescribeGroups          int16 = 15
+	SaslHandshake           int16 = 17
+	APIVersions             int16 = 18
+	CreateTopics            int16 = 19
+	DeleteTopics            int16 = 20
+	DeleteRecords           int16 = 21
+	InitProducerID          int16 = 22
+	OffsetForLeaderEpoch    int16 = 23
+	AddPartitionsToTxn      int16 = 24
+	AddOffsetsToTxn         int16 = 25
+	EndTxn                  int16 = 26
+	TxnOffsetCommit         int16 = 28
+	DescribeConfigs         int16 = 32
+	AlterConfigs            int16 = 33
+	SaslAuthenticate        int16 = 36
+	CreatePartitions        int16 = 37
+	DeleteGroups            int16 = 42
+	IncrementalAlterConfigs int16 = 44
+	OffsetDelete            int16 = 47
+	DescribeProducers       int16 = 61
+	ConsumerGroupHeartbeat  int16 = 68
+	ConsumerGroupDescribe   int16 = 69
+	DescribeTopicPartitions int16 = 75
+	ShareGroupHeartbeat     int16 = 76
+)
+
+// firstFlexibleVersions are the first versions of the APIs using the flexible encoding with the compact
+// types and the tagged fields. The APIs which are not listed use it in all versions. The value -1 means
+// that the API never uses it.
+var firstFlexibleVersions = map[int16]int16{
+	0:  9,  // Produce
+	1:  12, // Fetch
+	2:  6,  // ListOffsets
+	3:  9,  // Metadata
+	4:  4,  // LeaderAndIsr
+	5:  2,  // StopReplica
+	6:  6,  // UpdateMetadata
+	7:  3,  // ControlledShutdown
+	8:  8,  // OffsetCommit
+	9:  6,  // OffsetFetch
+	10: 3,  // FindCoordinator
+	11: 6,  // JoinGroup
+	12: 4,  // Heartbeat
+	13: 4,  // LeaveGroup
+	14: 4,  // SyncGroup
+	15: 5,  // DescribeGroups
+	16: 3,  // ListGroups
+	17: -1, // SaslHandshake
+	18: 3,  // ApiVersions
+	19: 5,  // CreateTopics
+	20: 4,  // DeleteTopics
+	21: 2,  // DeleteRecords
+	22: 2,  // InitProducerId
+	23: 4,  // OffsetForLeaderEpoch
+	24: 3,  // AddPartitionsToTxn
+	25: 3,  // AddOffsetsToTxn
+	26: 3,  // EndTxn
+	27: 1,  // WriteTxnMarkers
+	28: 3,  // TxnOffsetCommit
+	29: 2,  // DescribeAcls
+	30: 2,  // CreateAcls
+	31: 2,  // DeleteAcls
+	32: 4,  // DescribeConfigs
+	33: 2,  // AlterConfigs
+	34: 2,  // AlterReplicaLogDirs
+	35: 2,  // DescribeLogDirs
+	36: 2,  // SaslAuthenticate
+	37: 2,  // CreatePartitions
+	38: 2,  // CreateDelegationToken
+	39: 2,  // RenewDelegationToken
+	40: 2,  // ExpireDelegationToken
+	41: 2,  // DescribeDelegationToken
+	42: 2,  // DeleteGroups
+	43: 2,  // ElectLeaders
+	44: 1,  // IncrementalAlterConfigs
+	47: -1, // OffsetDelete
+	48: 1,  // DescribeClientQuotas
+	49: 1,  // AlterClientQuotas
+	53: 1,  // BeginQuorumEpoch
+	54: 1,  // EndQuorumEpoch
+}
+
+// Flexible returns true when the version of the API uses the flexible encoding.
+func Flexible(apiKey int16, apiVersion int16) bool {
+	first, found := firstFlexibleVersions[apiKey]
+	if !found {
+		return true
+	}
+
+	return first >= 0 && apiVersion >= first
+}
+
+// RequestHeader is the header of a request.
+type RequestHeader struct {
+	APIKey        int16
+	APIVersion    int16
+	CorrelationID int32
+	ClientID      string
+}
+
+// Flexible returns true when the request uses the flexible encoding.
+func (h RequestHeader) Flexible() bool {
+	return Flexible(h.APIKey, h.APIVersion)
+}
+
+// ReadRequestHeader decodes the header of the request frame. It returns the reader of the request body
+// following the header.
+func ReadRequestHeader(frame []byte) (RequestHeader, *Reader, error) {
+	r := NewReader(frame)
+	h := RequestHeader{APIKey: r.Int16(), APIVersion: r.Int16(), CorrelationID: r.Int32()}
+
+	// The client ID uses the non-compact string even in the flexible header
+	h.ClientID = r.String(false)
+	r.TaggedFields(h.Flexible())
+
+	return h, r, r.Err()
+}
+
+// ReadResponseHeader decodes the header of the response frame to a request with the given API key and
+// version. It returns the correlation ID and the reader of the response body following the header.
+func ReadResponseHeader(frame []byte, apiKey int16, apiVersion int16) (int32, *Reader, error) {
+	r := NewReader(frame)
+	correlationId := r.Int32()
+
+	// The ApiVersions responses never use the flexible header, so that the clients can read them
+	// before knowing the supported versions
+	if apiKey != APIVersions {
+		r.TaggedFields(Flexible(apiKey, apiVersion))
+	}
+
+	return correlationId, r, r.Err()
+}
@@ -0,0 +1,112 @@
+/*
+Copyright © 2025 Jakub Scholz
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package protocol
+
+import "encoding/binary"
+
+// BrokersRequestVersion is the version of the Metadata request encoded by AppendBrokersRequest. Version 4
+// is the oldest version supported by both the old Kafka versions and Kafka 4.x.
+const BrokersRequestVersion int16 = 4
+
+// Broker is a broker of the Kafka cluster as described in the Metadata response.
+type Broker struct {
+	NodeID int32
+	Host   string
+	Port   int32
+}
+
+// AppendBrokersRequest appends the size-delimited Metadata request without any topics, which returns
+// only the brokers of the Kafka cluster. It uses the BrokersRequestVersion of the request.
+func AppendBrokersRequest(data []byte, correlationID int32, clientID string) []byte {
+	data = binary.BigEndian.AppendUint32(data, uint32(15+len(clientID)))
+	data = binary.BigEndian.AppendUint16(data, uint16(Metadata))
+	data = binary.BigEndian.AppendUint16(data, uint16(BrokersRequestVersion))
+	data = binary.BigEndian.AppendUint32(data, uint32(correlationID))
+	data = binary.BigEndian.AppendUint16(data, uint16(len(clientID)))
+	data = append(data, clientID...)
+	data = binary.BigEndian.AppendUint32(data, 0) // Empty topics array means no topics
+	return append(data, 0)                        // Allow auto topic creation
+}
+
+// ReadMetadataBrokers decodes the brokers from the Metadata response.
+func ReadMetadataBrokers(apiVersion int16, r *Reader) ([]Broker, error) {
+	if apiVersion >= 3 {
+		r.Int32() // Throttle time
+	}
+
+	brokers := readMetadataBrokers(r, apiVersion, Flexible(Metadata, apiVersion))
+	return brokers, r.Err()
+}
+
+func readMetadataBrokers(r *Reader, v int16, flexible bool) []Broker {
+	brokers := make([]Broker, 0)
+	for i, n := 0, r.ArrayLength(flexible); i < n; i++ {
+		broker := Broker{NodeID: r.Int32(), Host: r.String(flexible), Port: r.Int32()}
+		if v >= 1 {
+			r.NullableString(flexible) // Rack
+		}
+		r.TaggedFields(flexible)
+
+		if r.Err() == nil {
+			brokers = append(brokers, broker)
+		}
+	}
+
+	return brokers
+}
+
+// ReadMetadataTopicIDs decodes the IDs and names of the topics from the Metadata response. Only the
+// versions 10 and newer contain the topic IDs.
+func ReadMetadataTopicIDs(apiVersion int16, r *Reader) (map[UUID]string, error) {
+	v := apiVersion
+	flexible := Flexible(Metadata, v)
+	topics := make(map[UUID]string)
+
+	if v < 10 {
+		return topics, nil
+	}
+
+	r.Int32() // Throttle time
+	readMetadataBrokers(r, v, flexible)
+	r.NullableString(flexible) // Cluster ID
+	r.Int32()                  // Controller ID
+
+	for i, n := 0, r.ArrayLength(flexible); i < n; i++ {
+		r.Int16() // Error code
+		name, named := r.NullableString(flexible)
+		id := r.UUID()
+		r.Bool() // Is internal
+		for j, partitions := 0, r.ArrayLength(flexible); j < partitions; j++ {
+			r.Int16() // Error code
+			r.Int32() // Partition
+			r.Int32() // Leader ID
+			r.Int32() // Leader epoch
+			r.SkipInt32Array(flexible)
+			r.SkipInt32Array(flexible)
+			r.SkipInt32Array(flexible)
+			r.TaggedFields(flexible)
+		}
+		r.Int32() // Topic authorized operations
+		r.TaggedFields(flexible)
+
+		if named && id != (UUID{}) && r.Err() == nil {
+			topics[id] = name
+		}
+	}
+
+	return topics, r.Err()
+}
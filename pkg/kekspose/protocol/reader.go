@@ -0,0 +1,252 @@
+/*
+Copyright © 2025 Jakub Scholz
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package protocol decodes the parts of the Kafka protocol messages which Keksposé inspects, such as
+// the request headers, the topics and groups used by the requests, and the error codes of the
+// responses. It decodes only the fields it needs and skips the rest. The only request it encodes is the
+// Metadata request used to discover the brokers.
+package protocol
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"math"
+)
+
+// ErrMalformed is returned when a message is truncated or cannot be decoded.
+var ErrMalformed = errors.New("malformed Kafka message")
+
+// UUID is a topic ID.
+type UUID [16]byte
+
+// String formats the UUID the same way as Kafka does.
+func (u UUID) String() string {
+	return base64.RawURLEncoding.EncodeToString(u[:])
+}
+
+// Reader decodes the Kafka protocol types. Once the data runs out or a value is invalid, it returns
+// zero values and Err returns ErrMalformed.
+type Reader struct {
+	data   []byte
+	failed bool
+}
+
+// NewReader creates a reader of the data.
+func NewReader(data []byte) *Reader {
+	return &Reader{data: data}
+}
+
+// Err returns ErrMalformed when the data could not be decoded.
+func (r *Reader) Err() error {
+	if r.failed {
+		return ErrMalformed
+	}
+
+	return nil
+}
+
+// Remaining returns the number of bytes not read yet.
+func (r *Reader) Remaining() int {
+	return len(r.data)
+}
+
+func (r *Reader) take(n int) []byte {
+	if r.failed || n < 0 || len(r.data) < n {
+		r.fail()
+		return nil
+	}
+
+	v := r.data[:n:n]
+	r.data = r.data[n:]
+	return v
+}
+
+func (r *Reader) fail() {
+	r.failed = true
+	r.data = nil
+}
+
+// Skip skips n bytes.
+func (r *Reader) Skip(n int) {
+	r.take(n)
+}
+
+func (r *Reader) Int8() int8 {
+	if v := r.take(1); v != nil {
+		return int8(v[0])
+	}
+
+	return 0
+}
+
+func (r *Reader) Bool() bool {
+	return r.Int8() != 0
+}
+
+func (r *Reader) Int16() int16 {
+	if v := r.take(2); v != nil {
+		return int16(binary.BigEndian.Uint16(v))
+	}
+
+	return 0
+}
+
+func (r *Reader) Int32() int32 {
+	if v := r.take(4); v != nil {
+		return int32(binary.BigEndian.Uint32(v))
+	}
+
+	return 0
+}
+
+func (r *Reader) Int64() int64 {
+	if v := r.take(8); v != nil {
+		return int64(binary.BigEndian.Uint64(v))
+	}
+
+	return 0
+}
+
+func (r *Reader) UUID() UUID {
+	var u UUID
+	copy(u[:], r.take(16))
+	return u
+}
+
+// Uvarint reads an unsigned varint, used by the compact types and the tagged fields.
+func (r *Reader) Uvarint() uint64 {
+	if r.failed {
+		return 0
+	}
+
+	v, n := binary.Uvarint(r.data)
+	if n <= 0 {
+		r.fail()
+		return 0
+	}
+
+	r.data = r.data[n:]
+	return v
+}
+
+// Varint reads a zigzag-encoded signed varint, used by the records.
+func (r *Reader) Varint() int64 {
+	if r.failed {
+		return 0
+	}
+
+	v, n := binary.Varint(r.data)
+	if n <= 0 {
+		r.fail()
+		return 0
+	}
+
+	r.data = r.data[n:]
+	return v
+}
+
+// VarintBytes reads bytes with a signed varint length, used by the records. Null is returned as nil.
+// The returned slice shares the data of the reader.
+func (r *Reader) VarintBytes() []byte {
+	n := r.Varint()
+	if n < 0 || r.failed {
+		return nil
+	}
+
+	if n > int64(len(r.data)) {
+		r.fail()
+		return nil
+	}
+
+	return r.take(int(n))
+}
+
+// length reads the length of a string, bytes, or array. The compact types store the length plus one
+// as an unsigned varint. Null is returned as -1.
+func (r *Reader) length(compact bool, long bool) int {
+	if compact {
+		v := r.Uvarint()
+		if v > math.MaxInt32 {
+			r.fail()
+			return 0
+		}
+
+		return int(v) - 1
+	}
+
+	if long {
+		return int(r.Int32())
+	}
+
+	return int(r.Int16())
+}
+
+// NullableString reads a string which can be null. The flexible versions use the compact string.
+func (r *Reader) NullableString(flexible bool) (string, bool) {
+	n := r.length(flexible, false)
+	if n < 0 || r.failed {
+		return "", false
+	}
+
+	return string(r.take(n)), !r.failed
+}
+
+// String reads a string. Null is returned as an empty string.
+func (r *Reader) String(flexible bool) string {
+	s, _ := r.NullableString(flexible)
+	return s
+}
+
+// Bytes reads bytes which can be null. The returned slice shares the data of the reader.
+func (r *Reader) Bytes(flexible bool) []byte {
+	n := r.length(flexible, true)
+	if n < 0 || r.failed {
+		return nil
+	}
+
+	return r.take(n)
+}
+
+// ArrayLength reads the length of an array. Null arrays return -1. The length is checked against the
+// remaining data, so that a malformed length cannot cause huge allocations.
+func (r *Reader) ArrayLength(flexible bool) int {
+	n := r.length(flexible, true)
+	if n > len(r.data) {
+		r.fail()
+		return 0
+	}
+
+	return n
+}
+
+// SkipInt32Array skips an array of int32 values.
+func (r *Reader) SkipInt32Array(flexible bool) {
+	r.Skip(4 * max(r.ArrayLength(flexible), 0))
+}
+
+// TaggedFields skips the tagged fields, which are present only in the flexible versions.
+func (r *Reader) TaggedFields(flexible bool) {
+	if !flexible {
+		return
+	}
+
+	count := r.Uvarint()
+	for i := uint64(0); i < count && !r.failed; i++ {
+		r.Uvarint()
+		r.Skip(int(min(r.Uvarint(), math.MaxInt32)))
+	}
+}
@@ -0,0 +1,115 @@
+/*
+Copyright © 2025 Jakub Scholz
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package protocol
+
+// PartitionRecords are the records of one partition in a Produce request or a Fetch response. The
+// records are kept encoded in record batches.
+type PartitionRecords struct {
+	Topic     string
+	TopicID   UUID
+	Partition int32
+	Records   []byte
+}
+
+// ReadProduceRecords decodes the records of the partitions from the Produce request. The versions 13
+// and newer identify the topics only by their IDs.
+func ReadProduceRecords(apiVersion int16, r *Reader) ([]PartitionRecords, error) {
+	v := apiVersion
+	flexible := Flexible(Produce, v)
+	var partitions []PartitionRecords
+
+	if v >= 3 {
+		r.NullableString(flexible) // Transactional ID
+	}
+	r.Int16() // Acks
+	r.Int32() // Timeout
+
+	for i, n := 0, r.ArrayLength(flexible); i < n; i++ {
+		topic, id := readTopicOrID(r, flexible, v >= 13)
+		for j, m := 0, r.ArrayLength(flexible); j < m; j++ {
+			partition := r.Int32()
+			records := r.Bytes(flexible)
+			r.TaggedFields(flexible)
+
+			if len(records) > 0 && r.Err() == nil {
+				partitions = append(partitions, PartitionRecords{Topic: topic, TopicID: id, Partition: partition, Records: records})
+			}
+		}
+		r.TaggedFields(flexible)
+	}
+
+	return partitions, r.Err()
+}
+
+// ReadFetchRecords decodes the records of the partitions from the Fetch response. The versions 13 and
+// newer identify the topics only by their IDs.
+func ReadFetchRecords(apiVersion int16, r *Reader) ([]PartitionRecords, error) {
+	v := apiVersion
+	flexible := Flexible(Fetch, v)
+	var partitions []PartitionRecords
+
+	if v >= 1 {
+		r.Int32() // Throttle time
+	}
+	if v >= 7 {
+		r.Int16() // Error code
+		r.Int32() // Session ID
+	}
+
+	for i, n := 0, r.ArrayLength(flexible); i < n; i++ {
+		topic, id := readTopicOrID(r, flexible, v >= 13)
+		for j, m := 0, r.ArrayLength(flexible); j < m; j++ {
+			partition := r.Int32()
+			r.Int16() // Error code
+			r.Int64() // High watermark
+			if v >= 4 {
+				r.Int64() // Last stable offset
+			}
+			if v >= 5 {
+				r.Int64() // Log start offset
+			}
+			if v >= 4 {
+				for k, aborted := 0, r.ArrayLength(flexible); k < aborted; k++ {
+					r.Int64() // Producer ID
+					r.Int64() // First offset
+					r.TaggedFields(flexible)
+				}
+			}
+			if v >= 11 {
+				r.Int32() // Preferred read replica
+			}
+			records := r.Bytes(flexible)
+			r.TaggedFields(flexible)
+
+			if len(records) > 0 && r.Err() == nil {
+				partitions = append(partitions, PartitionRecords{Topic: topic, TopicID: id, Partition: partition, Records: records})
+			}
+		}
+		r.TaggedFields(flexible)
+	}
+
+	return partitions, r.Err()
+}
+
+// readTopicOrID reads the topic name, or the topic ID when the version uses the IDs.
+func readTopicOrID(r *Reader, flexible bool, byID bool) (string, UUID) {
+	if byID {
+		return "", r.UUID()
+	}
+
+	return r.String(flexible), UUID{}
+}
@@ -0,0 +1,72 @@
+package protocol
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadProduceRecords(t *testing.T) {
+	for _, version := range []int16{3, 9} {
+		e := new(encoder).requestHeader(Produce, version, 1, "my-producer")
+		e.nullString().int16(-1).int32(30000)
+		e.array(2)
+		e.string("orders").array(2).int32(0).bytes([]byte{1, 2, 3}).tags().int32(1).bytes(nil).tags().tags()
+		e.string("payments").array(1).int32(4).bytes([]byte{4}).tags().tags()
+
+		h, r, err := ReadRequestHeader(e.data)
+		require.NoError(t, err)
+
+		partitions, err := ReadProduceRecords(h.APIVersion, r)
+		require.NoError(t, err)
+		assert.Equal(t, []PartitionRecords{
+			{Topic: "orders", Partition: 0, Records: []byte{1, 2, 3}},
+			{Topic: "payments", Partition: 4, Records: []byte{4}},
+		}, partitions, "version %d", version)
+	}
+
+	e := new(encoder).requestHeader(Produce, 13, 1, "my-producer")
+	e.nullString().int16(-1).int32(30000)
+	e.array(1).uuid(testTopicID).array(1).int32(2).bytes([]byte{1}).tags().tags()
+
+	h, r, err := ReadRequestHeader(e.data)
+	require.NoError(t, err)
+
+	partitions, err := ReadProduceRecords(h.APIVersion, r)
+	require.NoError(t, err)
+	assert.Equal(t, []PartitionRecords{{TopicID: testTopicID, Partition: 2, Records: []byte{1}}}, partitions)
+}
+
+func TestReadFetchRecords(t *testing.T) {
+	e := new(encoder)
+	e.int32(0).int16(0).int32(123)
+	e.array(1).string("orders").array(2)
+	e.int32(0).int16(0).int64(10).int64(10).int64(0).array(1).int64(1000).int64(5).bytes([]byte{1, 2})
+	e.int32(1).int16(0).int64(10).int64(10).int64(0).array(-1).bytes(nil)
+
+	partitions, err := ReadFetchRecords(7, NewReader(e.data))
+	require.NoError(t, err)
+	assert.Equal(t, []PartitionRecords{{Topic: "orders", Partition: 0, Records: []byte{1, 2}}}, partitions)
+
+	e = &encoder{flexible: true}
+	e.int32(0).int16(0).int32(123)
+	e.array(1).uuid(testTopicID).array(1)
+	e.int32(3).int16(0).int64(10).int64(10).int64(0).array(1).int64(1000).int64(5).tags().int32(-1).bytes([]byte{7}).tags()
+	e.tags().tags()
+
+	partitions, err = ReadFetchRecords(13, NewReader(e.data))
+	require.NoError(t, err)
+	assert.Equal(t, []PartitionRecords{{TopicID: testTopicID, Partition: 3, Records: []byte{7}}}, partitions)
+
+	// The partitions decoded before the truncation are returned
+	e = new(encoder)
+	e.int32(0).int16(0).int32(123)
+	e.array(1).string("orders").array(2)
+	e.int32(0).int16(0).int64(10).int64(10).int64(0).array(0).bytes([]byte{1, 2})
+	e.int32(1).int16(0).int64(10).int64(10).int64(0).array(0).bytes([]byte{3, 4})
+
+	partitions, err = ReadFetchRecords(7, NewReader(e.data[:len(e.data)-1]))
+	assert.ErrorIs(t, err, ErrMalformed)
+	assert.Equal(t, []PartitionRecords{{Topic: "orders", Partition: 0, Records: []byte{1, 2}}}, partitions)
+}
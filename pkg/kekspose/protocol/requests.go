@@ -0,0 +1,356 @@
+/*
+Copyright © 2025 Jakub Scholz
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package protocol
+
+// Resources are the topics and the groups used by a request. The newer versions of some APIs identify
+// the topics by their IDs instead of their names.
+type Resources struct {
+	Topics   []string
+	TopicIDs []UUID
+	Groups   []string
+}
+
+// topic reads the topic name or, in the versions using topic IDs, the topic ID.
+func (res *Resources) topic(r *Reader, flexible bool, byID bool) {
+	if byID {
+		if id := r.UUID(); r.Err() == nil {
+			res.TopicIDs = append(res.TopicIDs, id)
+		}
+	} else if name := r.String(flexible); r.Err() == nil {
+		res.Topics = append(res.Topics, name)
+	}
+}
+
+// topicOrID adds the topic by its name when it has one and by its ID otherwise.
+func (res *Resources) topicOrID(r *Reader, name string, named bool, id UUID) {
+	switch {
+	case r.Err() != nil:
+	case named:
+		res.Topics = append(res.Topics, name)
+	default:
+		res.TopicIDs = append(res.TopicIDs, id)
+	}
+}
+
+// group reads the group ID.
+func (res *Resources) group(r *Reader, flexible bool) {
+	if group := r.String(flexible); r.Err() == nil {
+		res.Groups = append(res.Groups, group)
+	}
+}
+
+// ReadRequestResources decodes the topics and the groups used by the request. The APIs without topics
+// and groups, and the APIs not known to this package, return no resources. When the request is
+// malformed, the resources decoded before the error are returned together with the error.
+func ReadRequestResources(h RequestHeader, r *Reader) (Resources, error) {
+	res := Resources{}
+	v := h.APIVersion
+	flexible := h.Flexible()
+
+	switch h.APIKey {
+	case Produce:
+		readProduceRequest(r, v, flexible, &res)
+	case Fetch:
+		readFetchRequest(r, v, flexible, &res)
+	case ListOffsets:
+		r.Int32() // Replica ID
+		if v >= 2 {
+			r.Int8() // Isolation level
+		}
+		readTopics(r, flexible, &res, func() {
+			r.Int32() // Partition
+			if v >= 4 {
+				r.Int32() // Current leader epoch
+			}
+			r.Int64() // Timestamp
+			if v == 0 {
+				r.Int32() // Max number of offsets
+			}
+		})
+	case Metadata:
+		for i, n := 0, r.ArrayLength(flexible); i < n; i++ {
+			if v >= 10 {
+				id := r.UUID()
+				name, named := r.NullableString(flexible)
+				res.topicOrID(r, name, named, id)
+			} else {
+				res.topic(r, flexible, false)
+			}
+			r.TaggedFields(flexible)
+		}
+	case OffsetCommit:
+		readOffsetCommitRequest(r, v, flexible, &res)
+	case OffsetFetch:
+		readOffsetFetchRequest(r, v, flexible, &res)
+	case FindCoordinator:
+		keyType := int8(0)
+		if v <= 3 {
+			key := r.String(flexible)
+			if v >= 1 {
+				keyType = r.Int8()
+			}
+			if keyType == 0 && r.Err() == nil {
+				res.Groups = append(res.Groups, key)
+			}
+		} else {
+			keyType = r.Int8()
+			for i, n := 0, r.ArrayLength(flexible); i < n; i++ {
+				key := r.String(flexible)
+				if keyType == 0 && r.Err() == nil {
+					res.Groups = append(res.Groups, key)
+				}
+			}
+		}
+	case JoinGroup, Heartbeat, LeaveGroup, SyncGroup, ConsumerGroupHeartbeat, ShareGroupHeartbeat:
+		res.group(r, flexible)
+	case DescribeGroups, DeleteGroups, ConsumerGroupDescribe:
+		for i, n := 0, r.ArrayLength(flexible); i < n; i++ {
+			res.group(r, flexible)
+		}
+	case OffsetDelete:
+		res.group(r, flexible)
+		readTopics(r, flexible, &res, func() {
+			r.Int32() // Partition
+		})
+	case TxnOffsetCommit:
+		r.String(flexible) // Transactional ID
+		res.group(r, flexible)
+		r.Int64() // Producer ID
+		r.Int16() // Producer epoch
+		if v >= 3 {
+			r.Int32()                  // Generation ID
+			r.String(flexible)         // Member ID
+			r.NullableString(flexible) // Group instance ID
+		}
+		readTopics(r, flexible, &res, func() {
+			r.Int32() // Partition
+			r.Int64() // Committed offset
+			if v >= 2 {
+				r.Int32() // Committed leader epoch
+			}
+			r.NullableString(flexible) // Committed metadata
+		})
+	case AddOffsetsToTxn:
+		r.String(flexible) // Transactional ID
+		r.Int64()          // Producer ID
+		r.Int16()          // Producer epoch
+		res.group(r, flexible)
+	case AddPartitionsToTxn:
+		if v <= 3 {
+			r.String(flexible) // Transactional ID
+			r.Int64()          // Producer ID
+			r.Int16()          // Producer epoch
+			for i, n := 0, r.ArrayLength(flexible); i < n; i++ {
+				res.topic(r, flexible, false)
+				r.SkipInt32Array(flexible)
+				r.TaggedFields(flexible)
+			}
+		}
+	case CreateTopics:
+		for i, n := 0, r.ArrayLength(flexible); i < n; i++ {
+			res.topic(r, flexible, false)
+			r.Int32() // Number of partitions
+			r.Int16() // Replication factor
+			for j, assignments := 0, r.ArrayLength(flexible); j < assignments; j++ {
+				r.Int32() // Partition
+				r.SkipInt32Array(flexible)
+				r.TaggedFields(flexible)
+			}
+			for j, configs := 0, r.ArrayLength(flexible); j < configs; j++ {
+				r.String(flexible)         // Name
+				r.NullableString(flexible) // Value
+				r.TaggedFields(flexible)
+			}
+			r.TaggedFields(flexible)
+		}
+	case DeleteTopics:
+		for i, n := 0, r.ArrayLength(flexible); i < n; i++ {
+			if v >= 6 {
+				name, named := r.NullableString(flexible)
+				res.topicOrID(r, name, named, r.UUID())
+				r.TaggedFields(flexible)
+			} else {
+				res.topic(r, flexible, false)
+			}
+		}
+	case DeleteRecords:
+		readTopics(r, flexible, &res, func() {
+			r.Int32() // Partition
+			r.Int64() // Offset
+		})
+	case OffsetForLeaderEpoch:
+		if v >= 3 {
+			r.Int32() // Replica ID
+		}
+		readTopics(r, flexible, &res, func() {
+			r.Int32() // Partition
+			if v >= 2 {
+				r.Int32() // Current leader epoch
+			}
+			r.Int32() // Leader epoch
+		})
+	case CreatePartitions:
+		for i, n := 0, r.ArrayLength(flexible); i < n; i++ {
+			res.topic(r, flexible, false)
+			r.Int32() // Count
+			for j, assignments := 0, r.ArrayLength(flexible); j < assignments; j++ {
+				r.SkipInt32Array(flexible)
+				r.TaggedFields(flexible)
+			}
+			r.TaggedFields(flexible)
+		}
+	case DescribeProducers:
+		for i, n := 0, r.ArrayLength(flexible); i < n; i++ {
+			res.topic(r, flexible, false)
+			r.SkipInt32Array(flexible)
+			r.TaggedFields(flexible)
+		}
+	case DescribeTopicPartitions:
+		for i, n := 0, r.ArrayLength(flexible); i < n; i++ {
+			res.topic(r, flexible, false)
+			r.TaggedFields(flexible)
+		}
+	}
+
+	return res, r.Err()
+}
+
+// readTopics reads the common array of topics with an array of partitions. The partition function reads
+// the fields of one partition without its tagged fields.
+func readTopics(r *Reader, flexible bool, res *Resources, partition func()) {
+	for i, n := 0, r.ArrayLength(flexible); i < n; i++ {
+		res.topic(r, flexible, false)
+		for j, partitions := 0, r.ArrayLength(flexible); j < partitions; j++ {
+			partition()
+			r.TaggedFields(flexible)
+		}
+		r.TaggedFields(flexible)
+	}
+}
+
+func readProduceRequest(r *Reader, v int16, flexible bool, res *Resources) {
+	if v >= 3 {
+		r.NullableString(flexible) // Transactional ID
+	}
+	r.Int16() // Acks
+	r.Int32() // Timeout
+
+	for i, n := 0, r.ArrayLength(flexible); i < n; i++ {
+		res.topic(r, flexible, v >= 13)
+		for j, partitions := 0, r.ArrayLength(flexible); j < partitions; j++ {
+			r.Int32()         // Partition
+			r.Bytes(flexible) // Records
+			r.TaggedFields(flexible)
+		}
+		r.TaggedFields(flexible)
+	}
+}
+
+func readFetchRequest(r *Reader, v int16, flexible bool, res *Resources) {
+	if v <= 14 {
+		r.Int32() // Replica ID
+	}
+	r.Int32() // Max wait
+	r.Int32() // Min bytes
+	if v >= 3 {
+		r.Int32() // Max bytes
+	}
+	if v >= 4 {
+		r.Int8() // Isolation level
+	}
+	if v >= 7 {
+		r.Int32() // Session ID
+		r.Int32() // Session epoch
+	}
+
+	for i, n := 0, r.ArrayLength(flexible); i < n; i++ {
+		res.topic(r, flexible, v >= 13)
+		for j, partitions := 0, r.ArrayLength(flexible); j < partitions; j++ {
+			r.Int32() // Partition
+			if v >= 9 {
+				r.Int32() // Current leader epoch
+			}
+			r.Int64() // Fetch offset
+			if v >= 12 {
+				r.Int32() // Last fetched epoch
+			}
+			if v >= 5 {
+				r.Int64() // Log start offset
+			}
+			r.Int32() // Partition max bytes
+			r.TaggedFields(flexible)
+		}
+		r.TaggedFields(flexible)
+	}
+}
+
+func readOffsetCommitRequest(r *Reader, v int16, flexible bool, res *Resources) {
+	res.group(r, flexible)
+	if v >= 1 {
+		r.Int32()          // Generation ID or member epoch
+		r.String(flexible) // Member ID
+	}
+	if v >= 7 {
+		r.NullableString(flexible) // Group instance ID
+	}
+	if v >= 2 && v <= 4 {
+		r.Int64() // Retention time
+	}
+
+	for i, n := 0, r.ArrayLength(flexible); i < n; i++ {
+		res.topic(r, flexible, v >= 10)
+		for j, partitions := 0, r.ArrayLength(flexible); j < partitions; j++ {
+			r.Int32() // Partition
+			r.Int64() // Committed offset
+			if v >= 6 {
+				r.Int32() // Committed leader epoch
+			}
+			if v == 1 {
+				r.Int64() // Commit timestamp
+			}
+			r.NullableString(flexible) // Committed metadata
+			r.TaggedFields(flexible)
+		}
+		r.TaggedFields(flexible)
+	}
+}
+
+func readOffsetFetchRequest(r *Reader, v int16, flexible bool, res *Resources) {
+	if v <= 7 {
+		res.group(r, flexible)
+		for i, n := 0, r.ArrayLength(flexible); i < n; i++ {
+			res.topic(r, flexible, false)
+			r.SkipInt32Array(flexible)
+			r.TaggedFields(flexible)
+		}
+		return
+	}
+
+	for i, n := 0, r.ArrayLength(flexible); i < n; i++ {
+		res.group(r, flexible)
+		if v >= 9 {
+			r.NullableString(flexible) // Member ID
+			r.Int32()                  // Member epoch
+		}
+		for j, topics := 0, r.ArrayLength(flexible); j < topics; j++ {
+			res.topic(r, flexible, v >= 10)
+			r.SkipInt32Array(flexible)
+			r.TaggedFields(flexible)
+		}
+		r.TaggedFields(flexible)
+	}
+}
@@ -0,0 +1,197 @@
+package protocol
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testTopicID = UUID{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16}
+
+func readResources(t *testing.T, frame []byte) (RequestHeader, Resources) {
+	t.Helper()
+
+	h, r, err := ReadRequestHeader(frame)
+	require.NoError(t, err)
+
+	res, err := ReadRequestResources(h, r)
+	require.NoError(t, err)
+
+	return h, res
+}
+
+func TestReadRequestHeader(t *testing.T) {
+	h, r, err := ReadRequestHeader(new(encoder).requestHeader(Metadata, 12, 42, "my-client").int8(7).data)
+	require.NoError(t, err)
+	assert.Equal(t, RequestHeader{APIKey: Metadata, APIVersion: 12, CorrelationID: 42, ClientID: "my-client"}, h)
+	assert.True(t, h.Flexible())
+	assert.Equal(t, int8(7), r.Int8())
+
+	h, _, err = ReadRequestHeader(new(encoder).requestHeader(Metadata, 8, 43, "").data)
+	require.NoError(t, err)
+	assert.False(t, h.Flexible())
+	assert.Equal(t, int32(43), h.CorrelationID)
+
+	_, _, err = ReadRequestHeader([]byte{0, 3, 0})
+	assert.ErrorIs(t, err, ErrMalformed)
+}
+
+func TestFlexible(t *testing.T) {
+	assert.False(t, Flexible(Produce, 8))
+	assert.True(t, Flexible(Produce, 9))
+	assert.False(t, Flexible(OffsetDelete, 0))
+	assert.True(t, Flexible(ConsumerGroupHeartbeat, 0))
+}
+
+func TestReadProduceResources(t *testing.T) {
+	for _, version := range []int16{3, 9} {
+		e := new(encoder).requestHeader(Produce, version, 1, "my-producer")
+		e.nullString().int16(-1).int32(30000)
+		e.array(2)
+		e.string("orders").array(1).int32(0).bytes([]byte{1, 2, 3}).tags().tags()
+		e.string("payments").array(2).int32(0).bytes(nil).tags().int32(1).bytes([]byte{4}).tags().tags()
+
+		h, res := readResources(t, e.data)
+		assert.Equal(t, "my-producer", h.ClientID)
+		assert.Equal(t, []string{"orders", "payments"}, res.Topics, "version %d", version)
+	}
+
+	e := new(encoder).requestHeader(Produce, 13, 1, "my-producer")
+	e.nullString().int16(-1).int32(30000)
+	e.array(1).uuid(testTopicID).array(1).int32(0).bytes([]byte{1}).tags().tags()
+
+	_, res := readResources(t, e.data)
+	assert.Empty(t, res.Topics)
+	assert.Equal(t, []UUID{testTopicID}, res.TopicIDs)
+}
+
+func TestReadFetchResources(t *testing.T) {
+	e := new(encoder).requestHeader(Fetch, 11, 1, "my-consumer")
+	e.int32(-1).int32(500).int32(1).int32(1024).int8(0).int32(0).int32(-1)
+	e.array(1).string("orders").array(1).int32(0).int32(5).int64(100).int64(0).int32(1024)
+	e.array(0).string("")
+
+	_, res := readResources(t, e.data)
+	assert.Equal(t, []string{"orders"}, res.Topics)
+
+	e = new(encoder).requestHeader(Fetch, 15, 1, "my-consumer")
+	e.int32(500).int32(1).int32(1024).int8(0).int32(0).int32(-1)
+	e.array(1).uuid(testTopicID).array(1).int32(0).int32(5).int64(100).int32(5).int64(0).int32(1024).tags().tags()
+
+	_, res = readResources(t, e.data)
+	assert.Equal(t, []UUID{testTopicID}, res.TopicIDs)
+}
+
+func TestReadMetadataResources(t *testing.T) {
+	e := new(encoder).requestHeader(Metadata, 12, 1, "my-client")
+	e.array(2).uuid(UUID{}).string("orders").tags().uuid(testTopicID).nullString().tags()
+
+	_, res := readResources(t, e.data)
+	assert.Equal(t, []string{"orders"}, res.Topics)
+	assert.Equal(t, []UUID{testTopicID}, res.TopicIDs)
+
+	// Null array requests all topics
+	e = new(encoder).requestHeader(Metadata, 12, 1, "my-client")
+	e.array(-1)
+
+	_, res = readResources(t, e.data)
+	assert.Empty(t, res.Topics)
+}
+
+func TestReadGroupResources(t *testing.T) {
+	e := new(encoder).requestHeader(OffsetCommit, 8, 1, "my-consumer")
+	e.string("my-group").int32(3).string("member-1").nullString()
+	e.array(1).string("orders").array(1).int32(0).int64(42).int32(5).nullString().tags().tags()
+
+	_, res := readResources(t, e.data)
+	assert.Equal(t, []string{"my-group"}, res.Groups)
+	assert.Equal(t, []string{"orders"}, res.Topics)
+
+	e = new(encoder).requestHeader(OffsetFetch, 8, 1, "my-consumer")
+	e.array(2)
+	e.string("group-a").array(1).string("orders").array(1).int32(0).tags().tags()
+	e.string("group-b").array(-1).tags()
+
+	_, res = readResources(t, e.data)
+	assert.Equal(t, []string{"group-a", "group-b"}, res.Groups)
+	assert.Equal(t, []string{"orders"}, res.Topics)
+
+	e = new(encoder).requestHeader(FindCoordinator, 4, 1, "my-consumer")
+	e.int8(0).array(1).string("my-group")
+
+	_, res = readResources(t, e.data)
+	assert.Equal(t, []string{"my-group"}, res.Groups)
+
+	// Transaction coordinators are not groups
+	e = new(encoder).requestHeader(FindCoordinator, 4, 1, "my-producer")
+	e.int8(1).array(1).string("my-transaction")
+
+	_, res = readResources(t, e.data)
+	assert.Empty(t, res.Groups)
+
+	e = new(encoder).requestHeader(JoinGroup, 9, 1, "my-consumer")
+	e.string("my-group").int32(45000)
+
+	_, res = readResources(t, e.data)
+	assert.Equal(t, []string{"my-group"}, res.Groups)
+}
+
+func TestReadMalformedResources(t *testing.T) {
+	e := new(encoder).requestHeader(Produce, 9, 1, "my-producer")
+	e.nullString().int16(-1).int32(30000)
+	e.array(2).string("orders").array(1).int32(0).bytes([]byte{1, 2, 3}).tags().tags()
+
+	h, r, err := ReadRequestHeader(e.data)
+	require.NoError(t, err)
+
+	res, err := ReadRequestResources(h, r)
+	assert.ErrorIs(t, err, ErrMalformed)
+	assert.Equal(t, []string{"orders"}, res.Topics)
+
+	// Array lengths larger than the message are rejected
+	e = new(encoder).requestHeader(DescribeGroups, 5, 1, "my-client")
+	e.array(1 << 30)
+
+	h, r, err = ReadRequestHeader(e.data)
+	require.NoError(t, err)
+	_, err = ReadRequestResources(h, r)
+	assert.ErrorIs(t, err, ErrMalformed)
+}
+
+func TestReadMetadataTopicIDs(t *testing.T) {
+	e := &encoder{flexible: true}
+	e.int32(0)
+	e.array(1).int32(0).string("localhost").int32(9092).nullString().tags()
+	e.string("my-cluster").int32(0)
+	e.array(2)
+	e.int16(0).string("orders").uuid(testTopicID).int8(0)
+	e.array(1).int16(0).int32(0).int32(0).int32(5).array(1).int32(0).array(1).int32(0).array(0).tags()
+	e.int32(-2147483648).tags()
+	e.int16(3).string("missing").uuid(UUID{}).int8(0).array(0).int32(0).tags()
+	e.tags()
+
+	topics, err := ReadMetadataTopicIDs(12, NewReader(e.data))
+	require.NoError(t, err)
+	assert.Equal(t, map[UUID]string{testTopicID: "orders"}, topics)
+	assert.Equal(t, "AQIDBAUGBwgJCgsMDQ4PEA", testTopicID.String())
+}
+
+func TestBrokersRequestAndResponse(t *testing.T) {
+	request := AppendBrokersRequest(nil, 7, "kekspose")
+	assert.Equal(t, []byte{0, 0, 0, 23, 0, 3, 0, 4, 0, 0, 0, 7, 0, 8, 'k', 'e', 'k', 's', 'p', 'o', 's', 'e', 0, 0, 0, 0, 0}, request)
+
+	header, _, err := ReadRequestHeader(request[4:])
+	require.NoError(t, err)
+	assert.Equal(t, RequestHeader{APIKey: Metadata, APIVersion: BrokersRequestVersion, CorrelationID: 7, ClientID: "kekspose"}, header)
+
+	e := &encoder{}
+	e.int32(0).array(2)
+	e.int32(0).string("broker-0").int32(9092).nullString()
+	e.int32(1).string("broker-1").int32(9093).string("zone-a")
+	e.string("my-cluster").int32(0).array(0)
+
+	brokers, err := ReadMetadataBrokers(BrokersRequestVersion, NewReader(e.data))
+	require.NoError(t, err)
+	assert.Equal(t, []Broker{{NodeID: 0, Host: "broker-0", Port: 9092}, {NodeID: 1, Host: "broker-1", Port: 9093}}, brokers)
+}
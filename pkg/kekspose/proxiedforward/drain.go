@@ -0,0 +1,234 @@
+/*
+Copyright © 2025 Jakub Scholz
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package proxiedforward
+
+import (
+	"encoding/binary"
+	"io"
+	"sync"
+
+	"github.com/scholzj/kekspose/pkg/kekspose/protocol"
+)
+
+const (
+	// requestPrefixLimit is the number of bytes of each request kept for reading its header. It is
+	// enough for the header and the acks field of the Produce requests with any reasonable client ID.
+	requestPrefixLimit = 512
+	// responsePrefixLimit covers the correlation ID at the beginning of each response.
+	responsePrefixLimit = 4
+	// maxKeptPrefix is the largest buffer kept for reading the next frames.
+	maxKeptPrefix = 1024 * 1024
+)
+
+// pending tracks a set of keys, such as the correlation IDs of the requests without a response, and
+// signals when the set becomes empty.
+type pending[K comparable] struct {
+	lock  sync.Mutex
+	keys  map[K]struct{}
+	empty chan struct{}
+}
+
+func newPending[K comparable]() *pending[K] {
+	return &pending[K]{keys: make(map[K]struct{})}
+}
+
+func (p *pending[K]) add(key K) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.keys[key] = struct{}{}
+}
+
+func (p *pending[K]) done(key K) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	delete(p.keys, key)
+	if len(p.keys) == 0 && p.empty != nil {
+		close(p.empty)
+		p.empty = nil
+	}
+}
+
+// idle returns a channel which is closed once there are no pending keys.
+func (p *pending[K]) idle() <-chan struct{} {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if len(p.keys) == 0 {
+		closed := make(chan struct{})
+		close(closed)
+		return closed
+	}
+
+	if p.empty == nil {
+		p.empty = make(chan struct{})
+	}
+	return p.empty
+}
+
+// frameParser splits a stream of Kafka protocol frames and passes the beginning of each frame (up to
+// the prefix limit) to the callback. Frames with an invalid size stop the parsing.
+type frameParser struct {
+	limit    int
+	onPrefix func([]byte)
+
+	size      [4]byte
+	sizeRead  int
+	remaining int
+	want      int
+	prefix    []byte
+	broken    bool
+}
+
+func (f *frameParser) feed(p []byte) {
+	for len(p) > 0 && !f.broken {
+		if f.sizeRead < 4 {
+			n := copy(f.size[f.sizeRead:], p)
+			f.sizeRead += n
+			p = p[n:]
+
+			if f.sizeRead < 4 {
+				return
+			}
+
+			size := int32(binary.BigEndian.Uint32(f.size[:]))
+			if size < 0 {
+				f.broken = true
+				return
+			}
+
+			f.remaining = int(size)
+			f.want = min(f.remaining, f.limit)
+			f.prefix = f.prefix[:0]
+			if f.want == 0 {
+				f.onPrefix(nil)
+			}
+		}
+
+		n := min(len(p), f.remaining)
+		if missing := f.want - len(f.prefix); missing > 0 {
+			f.prefix = append(f.prefix, p[:min(n, missing)]...)
+			if len(f.prefix) == f.want {
+				f.onPrefix(f.prefix)
+
+				// Do not keep the buffer of an unusually large frame for the whole connection
+				if cap(f.prefix) > maxKeptPrefix {
+					f.prefix = nil
+				}
+			}
+		}
+
+		f.remaining -= n
+		p = p[n:]
+		if f.remaining == 0 {
+			f.sizeRead = 0
+		}
+	}
+}
+
+// requestTap reads the requests sent by the client and registers the correlation IDs of the requests
+// which expect a response. When there is an inspector, it passes it the requests as well.
+type requestTap struct {
+	io.ReadWriteCloser
+	parser frameParser
+}
+
+func newRequestTap(conn io.ReadWriteCloser, requests *pending[int32], inspector Inspector) *requestTap {
+	limit, _ := frameLimits(inspector)
+
+	return &requestTap{
+		ReadWriteCloser: conn,
+		parser: frameParser{limit: limit, onPrefix: func(prefix []byte) {
+			if correlationId, ok := expectsResponse(prefix); ok {
+				requests.add(correlationId)
+			}
+
+			if inspector != nil {
+				inspector.Request(prefix)
+			}
+		}},
+	}
+}
+
+func (r *requestTap) Read(p []byte) (int, error) {
+	n, err := r.ReadWriteCloser.Read(p)
+	r.parser.feed(p[:n])
+	return n, err
+}
+
+// responseTap reads the responses sent by the broker and marks their requests as completed. When there
+// is an inspector, it passes it the responses as well.
+type responseTap struct {
+	io.ReadWriteCloser
+	parser frameParser
+}
+
+func newResponseTap(conn io.ReadWriteCloser, requests *pending[int32], inspector Inspector) *responseTap {
+	_, limit := frameLimits(inspector)
+
+	return &responseTap{
+		ReadWriteCloser: conn,
+		parser: frameParser{limit: limit, onPrefix: func(prefix []byte) {
+			if inspector != nil {
+				// Passed before completing the request, so that the inspector sees the response before
+				// the connection can be closed by draining
+				inspector.Response(prefix)
+			}
+
+			if len(prefix) >= 4 {
+				requests.done(int32(binary.BigEndian.Uint32(prefix)))
+			}
+		}},
+	}
+}
+
+func (r *responseTap) Read(p []byte) (int, error) {
+	n, err := r.ReadWriteCloser.Read(p)
+	r.parser.feed(p[:n])
+	return n, err
+}
+
+// expectsResponse reads the correlation ID from the request header and checks whether the broker
+// responds to the request. Only the Produce requests with acks=0 have no response. When the request
+// cannot be decoded, it is assumed to have a response.
+func expectsResponse(request []byte) (int32, bool) {
+	r := protocol.NewReader(request)
+	apiKey := r.Int16()
+	apiVersion := r.Int16()
+	correlationId := r.Int32()
+
+	if r.Err() != nil {
+		return 0, false
+	}
+
+	if apiKey != protocol.Produce {
+		return correlationId, true
+	}
+
+	flexible := protocol.Flexible(apiKey, apiVersion)
+
+	// The client ID uses the non-compact string even in the flexible header
+	r.NullableString(false)
+	r.TaggedFields(flexible)
+	if apiVersion >= 3 {
+		r.NullableString(flexible) // Transactional ID
+	}
+
+	acks := r.Int16()
+	return correlationId, r.Err() != nil || acks != 0
+}
@@ -0,0 +1,204 @@
+package proxiedforward
+
+import (
+	"encoding/binary"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/scholzj/kekspose/pkg/kekspose/protocol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPendingIdle(t *testing.T) {
+	p := newPending[int32]()
+
+	select {
+	case <-p.idle():
+	default:
+		t.Fatal("empty set should be idle")
+	}
+
+	p.add(1)
+	p.add(2)
+	idle := p.idle()
+
+	p.done(1)
+	select {
+	case <-idle:
+		t.Fatal("set with a pending key should not be idle")
+	default:
+	}
+
+	// Unknown keys are ignored
+	p.done(3)
+	p.done(2)
+	select {
+	case <-idle:
+	default:
+		t.Fatal("set should be idle after all keys are done")
+	}
+}
+
+func TestFrameParserSplitsFrames(t *testing.T) {
+	prefixes := make([][]byte, 0)
+	parser := frameParser{limit: 4, onPrefix: func(prefix []byte) {
+		prefixes = append(prefixes, append([]byte(nil), prefix...))
+	}}
+
+	stream := append(frame([]byte("abcdefgh")), frame([]byte("xy"))...)
+	stream = append(stream, frame(nil)...)
+
+	// Feed the stream byte by byte to split the frames and their sizes
+	for _, b := range stream {
+		parser.feed([]byte{b})
+	}
+
+	require.Len(t, prefixes, 3)
+	assert.Equal(t, []byte("abcd"), prefixes[0])
+	assert.Equal(t, []byte("xy"), prefixes[1])
+	assert.Empty(t, prefixes[2])
+
+	// The same stream in a single chunk
+	prefixes = prefixes[:0]
+	parser.feed(stream)
+	require.Len(t, prefixes, 3)
+	assert.Equal(t, []byte("abcd"), prefixes[0])
+}
+
+func TestFrameParserStopsOnInvalidSize(t *testing.T) {
+	calls := 0
+	parser := frameParser{limit: 4, onPrefix: func([]byte) { calls++ }}
+
+	parser.feed([]byte{0xff, 0xff, 0xff, 0xff, 0, 0, 0, 1, 0})
+	assert.True(t, parser.broken)
+	assert.Equal(t, 0, calls)
+}
+
+func TestExpectsResponse(t *testing.T) {
+	// Metadata v4
+	correlationId, ok := expectsResponse(requestHeader(3, 4, 7, "my-client"))
+	assert.True(t, ok)
+	assert.Equal(t, int32(7), correlationId)
+
+	// Produce v3 with acks=1 and acks=0
+	assert.True(t, responseExpected(expectsResponse(produceRequest(3, 8, "my-client", 1))))
+	assert.False(t, responseExpected(expectsResponse(produceRequest(3, 8, "my-client", 0))))
+
+	// Flexible Produce v9 with acks=-1 and acks=0
+	assert.True(t, responseExpected(expectsResponse(produceRequest(9, 9, "my-client", -1))))
+	assert.False(t, responseExpected(expectsResponse(produceRequest(9, 9, "my-client", 0))))
+
+	// Truncated Produce requests are assumed to have a response
+	request := produceRequest(3, 10, "my-client", 0)
+	correlationId, ok = expectsResponse(request[:len(request)-1])
+	assert.True(t, ok)
+	assert.Equal(t, int32(10), correlationId)
+}
+
+func TestTapsTrackRequestsInFlight(t *testing.T) {
+	client, local := net.Pipe()
+	defer client.Close()
+	remote, server := net.Pipe()
+	defer server.Close()
+
+	requests := newPending[int32]()
+	requestReader := newRequestTap(local, requests, nil)
+	responseReader := newResponseTap(remote, requests, nil)
+
+	go func() {
+		_, _ = client.Write(frame(requestHeader(3, 4, 1, "my-client")))
+		_, _ = client.Write(frame(produceRequest(3, 2, "my-client", 0)))
+	}()
+
+	buf := make([]byte, 1024)
+	for range 2 {
+		_, err := requestReader.Read(buf)
+		require.NoError(t, err)
+	}
+
+	// Only the Metadata request expects a response
+	idle := requests.idle()
+	select {
+	case <-idle:
+		t.Fatal("the Metadata request should be in flight")
+	default:
+	}
+
+	go func() {
+		response := make([]byte, 4)
+		binary.BigEndian.PutUint32(response, 1)
+		_, _ = server.Write(frame(response))
+	}()
+
+	_, err := responseReader.Read(buf)
+	require.NoError(t, err)
+
+	select {
+	case <-idle:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the requests should be completed")
+	}
+}
+
+func TestDrainStopsListeningAndReturns(t *testing.T) {
+	stopChan := make(chan struct{})
+	defer close(stopChan)
+	drainChan := make(chan struct{})
+	readyChan := make(chan struct{})
+
+	pf, err := New(&testDialer{}, []string{"0:9092"}, stopChan, readyChan, false, nil)
+	require.NoError(t, err)
+	pf.WithDrain(drainChan)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- pf.ForwardPorts()
+	}()
+	<-readyChan
+
+	ports, err := pf.GetPorts()
+	require.NoError(t, err)
+	address := net.JoinHostPort("127.0.0.1", strconv.Itoa(int(ports[0].Local)))
+
+	close(drainChan)
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the forwarder was not drained")
+	}
+
+	_, err = net.Dial("tcp", address)
+	assert.Error(t, err)
+}
+
+func frame(data []byte) []byte {
+	return append(binary.BigEndian.AppendUint32(nil, uint32(len(data))), data...)
+}
+
+func requestHeader(apiKey int16, apiVersion int16, correlationId int32, clientId string) []byte {
+	header := binary.BigEndian.AppendUint16(nil, uint16(apiKey))
+	header = binary.BigEndian.AppendUint16(header, uint16(apiVersion))
+	header = binary.BigEndian.AppendUint32(header, uint32(correlationId))
+	header = binary.BigEndian.AppendUint16(header, uint16(len(clientId)))
+	return append(header, clientId...)
+}
+
+func produceRequest(apiVersion int16, correlationId int32, clientId string, acks int16) []byte {
+	request := requestHeader(protocol.Produce, apiVersion, correlationId, clientId)
+	if apiVersion >= 9 {
+		// Empty tagged fields of the header and null compact transactional ID
+		request = append(request, 0, 0)
+	} else {
+		// Null transactional ID
+		request = binary.BigEndian.AppendUint16(request, 0xffff)
+	}
+	return binary.BigEndian.AppendUint16(request, uint16(acks))
+}
+
+func responseExpected(_ int32, ok bool) bool {
+	return ok
+}
@@ -0,0 +1,72 @@
+/*
+Copyright © 2025 Jakub Scholz
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package proxiedforward
+
+import "github.com/scholzj/proksy"
+
+// inspectedFrameLimit is the size limit of the frames passed to the inspectors. The larger frames are
+// passed truncated to it.
+const inspectedFrameLimit = 16 * 1024 * 1024
+
+// WholeFrames is the frame limit of the inspectors which need the whole frames (see FrameLimiter).
+const WholeFrames = inspectedFrameLimit
+
+// Inspector observes the Kafka protocol frames of one proxied connection. The frames are passed without
+// the size prefix, before the proxy engine handles them. They are valid only during the call, so the
+// inspector has to copy the data it keeps. Requests and responses are passed from different
+// goroutines.
+type Inspector interface {
+	Request(frame []byte)
+	Response(frame []byte)
+	// Close is called once the connection is closed.
+	Close()
+}
+
+// FrameLimiter is implemented by the inspectors which need only the beginning of the frames. Only up to
+// the returned number of bytes of each request and response is buffered and passed to the inspector.
+// The requests are always passed with their header and the responses with their correlation ID. The
+// inspectors which do not implement it get the whole frames.
+type FrameLimiter interface {
+	FrameLimits() (request int, response int)
+}
+
+// frameLimits returns the number of bytes of the requests and responses passed to the inspector.
+func frameLimits(inspector Inspector) (int, int) {
+	if inspector == nil {
+		return requestPrefixLimit, responsePrefixLimit
+	}
+
+	limiter, ok := inspector.(FrameLimiter)
+	if !ok {
+		return inspectedFrameLimit, inspectedFrameLimit
+	}
+
+	request, response := limiter.FrameLimits()
+	return min(max(request, requestPrefixLimit), inspectedFrameLimit), min(max(response, responsePrefixLimit), inspectedFrameLimit)
+}
+
+// connection returns the proxy engine and the inspector for a new connection. Without an inspector,
+// the engine used by all connections is returned.
+func (p ProxiedPort) connection() (*proksy.Engine, Inspector) {
+	if p.Inspect != nil {
+		if engine, inspector := p.Inspect(); engine != nil {
+			return engine, inspector
+		}
+	}
+
+	return p.engine(), nil
+}
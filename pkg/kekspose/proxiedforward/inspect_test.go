@@ -0,0 +1,159 @@
+package proxiedforward
+
+import (
+	"encoding/binary"
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/scholzj/proksy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recordingInspector struct {
+	lock      sync.Mutex
+	requests  [][]byte
+	responses [][]byte
+	closed    bool
+}
+
+func (i *recordingInspector) Request(frame []byte) {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	i.requests = append(i.requests, append([]byte(nil), frame...))
+}
+
+func (i *recordingInspector) Response(frame []byte) {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	i.responses = append(i.responses, append([]byte(nil), frame...))
+}
+
+func (i *recordingInspector) Close() {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	i.closed = true
+}
+
+func TestTapsPassFramesToInspector(t *testing.T) {
+	client, local := net.Pipe()
+	defer client.Close()
+	remote, server := net.Pipe()
+	defer server.Close()
+
+	inspector := &recordingInspector{}
+	requests := newPending[int32]()
+	requestReader := newRequestTap(local, requests, inspector)
+	responseReader := newResponseTap(remote, requests, inspector)
+
+	// The whole request is passed, not only the prefix used for tracking the requests
+	request := append(requestHeader(3, 4, 1, "my-client"), make([]byte, 2*requestPrefixLimit)...)
+	go func() {
+		_, _ = client.Write(frame(request))
+	}()
+
+	buf := make([]byte, 4096)
+	read := 0
+	for read < len(request)+4 {
+		n, err := requestReader.Read(buf)
+		require.NoError(t, err)
+		read += n
+	}
+
+	response := binary.BigEndian.AppendUint32(nil, 1)
+	response = append(response, "body"...)
+	go func() {
+		_, _ = server.Write(frame(response))
+	}()
+
+	_, err := responseReader.Read(buf)
+	require.NoError(t, err)
+
+	assert.Equal(t, [][]byte{request}, inspector.requests)
+	assert.Equal(t, [][]byte{response}, inspector.responses)
+}
+
+// prefixInspector needs only the headers of the frames.
+type prefixInspector struct {
+	recordingInspector
+}
+
+func (i *prefixInspector) FrameLimits() (int, int) {
+	return 0, 0
+}
+
+func TestTapsPassPrefixesToFrameLimiter(t *testing.T) {
+	client, local := net.Pipe()
+	defer client.Close()
+	remote, server := net.Pipe()
+	defer server.Close()
+
+	inspector := &prefixInspector{}
+	requests := newPending[int32]()
+	requestReader := newRequestTap(local, requests, inspector)
+	responseReader := newResponseTap(remote, requests, inspector)
+
+	request := append(requestHeader(3, 4, 1, "my-client"), make([]byte, 2*requestPrefixLimit)...)
+	go func() {
+		_, _ = client.Write(frame(request))
+	}()
+
+	buf := make([]byte, 4096)
+	read := 0
+	for read < len(request)+4 {
+		n, err := requestReader.Read(buf)
+		require.NoError(t, err)
+		read += n
+	}
+
+	response := binary.BigEndian.AppendUint32(nil, 1)
+	response = append(response, "body"...)
+	go func() {
+		_, _ = server.Write(frame(response))
+	}()
+
+	_, err := responseReader.Read(buf)
+	require.NoError(t, err)
+
+	assert.Equal(t, [][]byte{request[:requestPrefixLimit]}, inspector.requests)
+	assert.Equal(t, [][]byte{response[:responsePrefixLimit]}, inspector.responses)
+}
+
+func TestFrameLimits(t *testing.T) {
+	request, response := frameLimits(nil)
+	assert.Equal(t, requestPrefixLimit, request)
+	assert.Equal(t, responsePrefixLimit, response)
+
+	request, response = frameLimits(&recordingInspector{})
+	assert.Equal(t, inspectedFrameLimit, request)
+	assert.Equal(t, inspectedFrameLimit, response)
+
+	request, response = frameLimits(&prefixInspector{})
+	assert.Equal(t, requestPrefixLimit, request)
+	assert.Equal(t, responsePrefixLimit, response)
+}
+
+func TestProxiedPortConnection(t *testing.T) {
+	shared := proksy.NewEngine()
+	port := ProxiedPort{Engine: shared}
+
+	engine, inspector := port.connection()
+	assert.Same(t, shared, engine)
+	assert.Nil(t, inspector)
+
+	// Without an engine from Inspect, the shared engine is used
+	port.Inspect = func() (*proksy.Engine, Inspector) { return nil, nil }
+	engine, inspector = port.connection()
+	assert.Same(t, shared, engine)
+	assert.Nil(t, inspector)
+
+	own := &recordingInspector{}
+	port.Inspect = func() (*proksy.Engine, Inspector) { return proksy.NewEngine(), own }
+	engine, inspector = port.connection()
+	assert.NotNil(t, engine)
+	assert.Same(t, own, inspector)
+}
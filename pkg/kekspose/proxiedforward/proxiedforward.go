@@ -30,10 +30,12 @@ import (
 	"log/slog"
 	"net"
 	"net/http"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/scholzj/proksy"
 	v1 "k8s.io/api/core/v1"
@@ -57,11 +59,9 @@ var (
 // ProxiedForwarder knows how to listen for local connections and forward them to
 // a remote pod via an upgraded HTTP request.
 type ProxiedForwarder struct {
-	engine    *proksy.Engine
 	addresses []listenAddress
 	ports     []ProxiedPort
 	stopChan  <-chan struct{}
-	useTLS    bool
 
 	dialer        httpstream.Dialer
 	streamConn    httpstream.Connection
@@ -69,12 +69,52 @@ type ProxiedForwarder struct {
 	Ready         chan struct{}
 	requestIDLock sync.Mutex
 	requestID     int
+
+	// In the lazy mode, the stream connection is dialed on the first local connection and closed
+	// again after all local connections were closed for idleTimeout.
+	lazy        bool
+	idleTimeout time.Duration
+	connLock    sync.Mutex
+	activeConns int
+	idleTimer   *time.Timer
+
+	timeouts Timeouts
+
+	// Closing drainChan stops accepting new connections and closes the open connections once they
+	// have no requests waiting for a response.
+	drainChan <-chan struct{}
+	conns     *pending[net.Conn]
+	closeOnce sync.Once
 }
 
-// ProxiedPort contains a Local:Remote port pairing.
+// ProxiedPort contains a Local:Remote port pairing. Each port has its own TLS setting and proxy
+// engine, so that ports with different protocols can share the same connection to the pod. Without
+// the engine, the data are copied as-is.
 type ProxiedPort struct {
 	Local  uint16
 	Remote uint16
+	UseTLS bool
+	Engine *proksy.Engine
+	// EngineFunc, when set, provides the engine for each new connection instead of Engine. It allows
+	// replacing the engine at runtime without affecting the open connections. When it returns nil, for
+	// example because the port is being removed, Engine is used.
+	EngineFunc func() *proksy.Engine
+	// Stats, when set, counts the connections and the data transferred through the port.
+	Stats *Stats
+	// Inspect, when set, is called for each new connection. When it returns an engine, the connection
+	// uses it instead of the shared engine and the inspector observes its requests and responses.
+	Inspect func() (*proksy.Engine, Inspector)
+}
+
+// engine returns the proxy engine for a new connection.
+func (p ProxiedPort) engine() *proksy.Engine {
+	if p.EngineFunc != nil {
+		if engine := p.EngineFunc(); engine != nil {
+			return engine
+		}
+	}
+
+	return p.Engine
 }
 
 /*
@@ -123,7 +163,7 @@ func parsePorts(ports []string) ([]ProxiedPort, error) {
 			return nil, fmt.Errorf("remote port must be > 0")
 		}
 
-		forwards = append(forwards, ProxiedPort{uint16(localPort), uint16(remotePort)})
+		forwards = append(forwards, ProxiedPort{Local: uint16(localPort), Remote: uint16(remotePort)})
 	}
 
 	return forwards, nil
@@ -189,15 +229,60 @@ func NewOnAddresses(dialer httpstream.Dialer, addresses []string, ports []string
 	if err != nil {
 		return nil, err
 	}
+	for i := range parsedPorts {
+		parsedPorts[i].UseTLS = useTLS
+		parsedPorts[i].Engine = engine
+	}
+	return newForwarder(dialer, parsedAddresses, parsedPorts, stopChan, readyChan), nil
+}
+
+// NewForPorts creates a new ProxiedForwarder with localhost listen addresses for multiple ports
+// forwarded over a single connection to the pod. Each port uses its own TLS setting and engine.
+func NewForPorts(dialer httpstream.Dialer, ports []ProxiedPort, stopChan <-chan struct{}, readyChan chan struct{}) (*ProxiedForwarder, error) {
+	if len(ports) == 0 {
+		return nil, errors.New("you must specify at least 1 port")
+	}
+	parsedAddresses, err := parseAddresses([]string{"localhost"})
+	if err != nil {
+		return nil, err
+	}
+	return newForwarder(dialer, parsedAddresses, slices.Clone(ports), stopChan, readyChan), nil
+}
+
+func newForwarder(dialer httpstream.Dialer, addresses []listenAddress, ports []ProxiedPort, stopChan <-chan struct{}, readyChan chan struct{}) *ProxiedForwarder {
 	return &ProxiedForwarder{
 		dialer:    dialer,
-		addresses: parsedAddresses,
-		ports:     parsedPorts,
+		addresses: addresses,
+		ports:     ports,
 		stopChan:  stopChan,
 		Ready:     readyChan,
-		useTLS:    useTLS,
-		engine:    engine,
-	}, nil
+		conns:     newPending[net.Conn](),
+	}
+}
+
+// WithLazyConnect enables the lazy mode. The local listeners are opened right away, but the
+// connection to the pod is dialed only when the first local connection is accepted. It is closed
+// again when there are no local connections for idleTimeout. Zero idleTimeout keeps the connection
+// open once it is dialed.
+func (pf *ProxiedForwarder) WithLazyConnect(idleTimeout time.Duration) *ProxiedForwarder {
+	pf.lazy = true
+	pf.idleTimeout = idleTimeout
+	return pf
+}
+
+// WithTimeouts sets the time limits of the proxied connections.
+func (pf *ProxiedForwarder) WithTimeouts(timeouts Timeouts) *ProxiedForwarder {
+	pf.timeouts = timeouts
+	return pf
+}
+
+// WithDrain enables draining the connections when drainChan is closed. The forwarder stops accepting
+// new connections and closes the open connections once all their Kafka requests got a response.
+// Connections without the proxy engine are closed right away, as their requests are not tracked.
+// ForwardPorts returns when all connections are closed or when stopChan is closed.
+func (pf *ProxiedForwarder) WithDrain(drainChan <-chan struct{}) *ProxiedForwarder {
+	pf.drainChan = drainChan
+	return pf
 }
 
 // ForwardPorts formats and executes a port forwarding request. The connection will remain
@@ -205,6 +290,11 @@ func NewOnAddresses(dialer httpstream.Dialer, addresses []string, ports []string
 func (pf *ProxiedForwarder) ForwardPorts() error {
 	defer pf.Close()
 
+	if pf.lazy {
+		defer pf.closeStreamConn()
+		return pf.forward()
+	}
+
 	var err error
 	var protocol string
 	pf.streamConn, protocol, err = pf.dialer.Dial(PortForwardProtocolV1Name)
@@ -245,16 +335,121 @@ func (pf *ProxiedForwarder) forward() error {
 		close(pf.Ready)
 	}
 
-	// wait for interrupt or conn closure
+	// In the lazy mode, a lost connection is dialed again on the next local connection
+	var closeChan <-chan bool
+	if !pf.lazy {
+		closeChan = pf.streamConn.CloseChan()
+	}
+
+	// wait for interrupt, drain or conn closure
 	select {
 	case <-pf.stopChan:
-	case <-pf.streamConn.CloseChan():
+	case <-pf.drainChan:
+		pf.drain()
+	case <-closeChan:
 		return ErrLostConnectionToPod
 	}
 
 	return nil
 }
 
+// drain stops accepting new connections and waits until the open connections are closed.
+func (pf *ProxiedForwarder) drain() {
+	slog.Debug("Draining connections")
+	pf.Close()
+
+	select {
+	case <-pf.conns.idle():
+		slog.Debug("All connections were drained")
+	case <-pf.stopChan:
+	}
+}
+
+// acquireStreamConn returns the connection to the pod for a new local connection. In the lazy mode,
+// it dials the connection when there is none yet or when the previous one was closed.
+func (pf *ProxiedForwarder) acquireStreamConn() (httpstream.Connection, error) {
+	if !pf.lazy {
+		return pf.streamConn, nil
+	}
+
+	pf.connLock.Lock()
+	defer pf.connLock.Unlock()
+
+	if pf.idleTimer != nil {
+		pf.idleTimer.Stop()
+		pf.idleTimer = nil
+	}
+
+	if pf.streamConn != nil {
+		select {
+		case <-pf.streamConn.CloseChan():
+			pf.streamConn = nil
+		default:
+		}
+	}
+
+	if pf.streamConn == nil {
+		slog.Debug("Dialing the connection to the pod")
+
+		streamConn, protocol, err := pf.dialer.Dial(PortForwardProtocolV1Name)
+		if err != nil {
+			return nil, fmt.Errorf("error upgrading connection: %s", err)
+		}
+		if protocol != PortForwardProtocolV1Name {
+			streamConn.Close()
+			return nil, fmt.Errorf("unable to negotiate protocol: client supports %q, server returned %q", PortForwardProtocolV1Name, protocol)
+		}
+
+		pf.streamConn = streamConn
+	}
+
+	pf.activeConns++
+	return pf.streamConn, nil
+}
+
+// releaseStreamConn is called when a local connection is closed. In the lazy mode, it closes the
+// connection to the pod once it is idle for idleTimeout.
+func (pf *ProxiedForwarder) releaseStreamConn() {
+	if !pf.lazy {
+		return
+	}
+
+	pf.connLock.Lock()
+	defer pf.connLock.Unlock()
+
+	pf.activeConns--
+	if pf.activeConns > 0 || pf.idleTimeout <= 0 {
+		return
+	}
+
+	pf.idleTimer = time.AfterFunc(pf.idleTimeout, func() {
+		pf.connLock.Lock()
+		defer pf.connLock.Unlock()
+
+		if pf.activeConns == 0 && pf.streamConn != nil {
+			slog.Debug("Closing the idle connection to the pod")
+			pf.streamConn.Close()
+			pf.streamConn = nil
+		}
+	})
+}
+
+// closeStreamConn closes the connection to the pod dialed in the lazy mode.
+func (pf *ProxiedForwarder) closeStreamConn() {
+	pf.connLock.Lock()
+	defer pf.connLock.Unlock()
+
+	if pf.idleTimer != nil {
+		pf.idleTimer.Stop()
+		pf.idleTimer = nil
+	}
+
+	if pf.streamConn != nil {
+		pf.streamConn.Close()
+		pf.streamConn = nil
+	}
+}
+
 // listenOnPort delegates listener creation and waits for connections on requested bind addresses.
 // An error is raised based on address groups (default and localhost) and their failure modes
 func (pf *ProxiedForwarder) listenOnPort(port *ProxiedPort) error {
@@ -315,9 +510,15 @@ func (pf *ProxiedForwarder) getListener(protocol string, hostname string, port *
 // waitForConnection waits for new connections to listener and handles them in
 // the background.
 func (pf *ProxiedForwarder) waitForConnection(listener net.Listener, port ProxiedPort) {
+	// In the lazy mode, the stream connection comes and goes, so only closing the listener stops waiting
+	var closeChan <-chan bool
+	if !pf.lazy {
+		closeChan = pf.streamConn.CloseChan()
+	}
+
 	for {
 		select {
-		case <-pf.streamConn.CloseChan():
+		case <-closeChan:
 			return
 		default:
 			conn, err := listener.Accept()
@@ -328,7 +529,11 @@ func (pf *ProxiedForwarder) waitForConnection(listener net.Listener, port Proxie
 				}
 				return
 			}
-			go pf.handleConnection(conn, port)
+			pf.conns.add(conn)
+			go func() {
+				defer pf.conns.done(conn)
+				pf.handleConnection(conn, port)
+			}()
 		}
 	}
 }
@@ -346,8 +551,18 @@ func (pf *ProxiedForwarder) nextRequestID() int {
 func (pf *ProxiedForwarder) handleConnection(conn net.Conn, port ProxiedPort) {
 	defer conn.Close()
 
+	conn, untrack := port.Stats.track(conn)
+	defer untrack()
+
 	slog.Info("Handling connection", "localPort", port.Local)
 
+	streamConn, err := pf.acquireStreamConn()
+	if err != nil {
+		runtime.HandleError(fmt.Errorf("error connecting to the pod for port %d -> %d: %v", port.Local, port.Remote, err))
+		return
+	}
+	defer pf.releaseStreamConn()
+
 	requestID := pf.nextRequestID()
 
 	// create error stream
@@ -355,14 +570,14 @@ func (pf *ProxiedForwarder) handleConnection(conn net.Conn, port ProxiedPort) {
 	headers.Set(v1.StreamType, v1.StreamTypeError)
 	headers.Set(v1.PortHeader, fmt.Sprintf("%d", port.Remote))
 	headers.Set(v1.PortForwardRequestIDHeader, strconv.Itoa(requestID))
-	errorStream, err := pf.streamConn.CreateStream(headers)
+	errorStream, err := streamConn.CreateStream(headers)
 	if err != nil {
 		runtime.HandleError(fmt.Errorf("error creating error stream for port %d -> %d: %v", port.Local, port.Remote, err))
 		return
 	}
 	// we're not writing to this stream
 	errorStream.Close()
-	defer pf.streamConn.RemoveStreams(errorStream)
+	defer streamConn.RemoveStreams(errorStream)
 
 	errorChan := make(chan error)
 	go func() {
@@ -378,32 +593,50 @@ func (pf *ProxiedForwarder) handleConnection(conn net.Conn, port ProxiedPort) {
 
 	// create data stream
 	headers.Set(v1.StreamType, v1.StreamTypeData)
-	dataStream, err := pf.streamConn.CreateStream(headers)
+	dataStream, err := streamConn.CreateStream(headers)
 	if err != nil {
 		runtime.HandleError(fmt.Errorf("error creating forwarding stream for port %d -> %d: %v", port.Local, port.Remote, err))
 		return
 	}
-	defer pf.streamConn.RemoveStreams(dataStream)
+	defer streamConn.RemoveStreams(dataStream)
 
-	brokerConn, err := establishBrokerConn(dataStream, pf.useTLS)
+	brokerConn, err := establishBrokerConn(dataStream, port.UseTLS, pf.timeouts.tlsHandshake())
 	if err != nil {
 		runtime.HandleError(fmt.Errorf("error establishing TLS for port %d -> %d: %v", port.Local, port.Remote, err))
 		return
 	}
+	brokerConn = withResponseTimeout(brokerConn, pf.timeouts.Read)
 
 	// Proxy the connection. Engine.Proxy blocks until both directions are torn down (an EOF or error
 	// on either side cancels the other), so it replaces the old goroutine + shutdown-channel dance.
 	// Tie its lifetime to the forwarder's stop signal so a shutdown unblocks an idle connection.
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
+	// When draining, wait for the responses to the requests in flight before closing the connection
+	requests := newPending[int32]()
 	go func() {
 		select {
 		case <-pf.stopChan:
 			cancel()
+		case <-pf.drainChan:
+			select {
+			case <-requests.idle():
+			case <-pf.stopChan:
+			case <-ctx.Done():
+			}
+			cancel()
 		case <-ctx.Done():
 		}
 	}()
-	_ = pf.engine.Proxy(ctx, conn, brokerConn)
+	localConn, remoteConn := watchIdle(ctx, cancel, pf.timeouts.Idle, conn, brokerConn)
+	if engine, inspector := port.connection(); engine != nil {
+		_ = engine.Proxy(ctx, newRequestTap(localConn, requests, inspector), newResponseTap(remoteConn, requests, inspector))
+		if inspector != nil {
+			inspector.Close()
+		}
+	} else {
+		copyConnection(ctx, localConn, remoteConn)
+	}
 
 	// reset dataStream to discard any unsent data, preventing port forwarding from being blocked.
 	// we must reset dataStream before waiting on errorChan, otherwise,
@@ -414,32 +647,71 @@ func (pf *ProxiedForwarder) handleConnection(conn net.Conn, port ProxiedPort) {
 	err = <-errorChan
 	if err != nil {
 		runtime.HandleError(err)
-		pf.streamConn.Close()
+		streamConn.Close()
 	}
 }
 
-func establishBrokerConn(dataStream httpstream.Stream, useTLS bool) (io.ReadWriteCloser, error) {
-	brokerConn := io.ReadWriteCloser(dataStream)
+// copyConnection copies the data between the local connection and the remote stream as-is. It is used
+// for protocols other than Kafka (such as HTTP APIs), where no proxy engine is configured. It returns
+// when the remote side finishes, when copying from the local side fails, or when ctx is cancelled.
+func copyConnection(ctx context.Context, conn net.Conn, remote io.ReadWriteCloser) {
+	localError := make(chan struct{})
+	remoteDone := make(chan struct{})
+
+	go func() {
+		// Copy from the remote side to the local port.
+		if _, err := io.Copy(conn, remote); err != nil && !strings.Contains(err.Error(), networkClosedError) {
+			runtime.HandleError(fmt.Errorf("error copying from remote stream to local connection: %v", err))
+		}
+
+		close(remoteDone)
+	}()
+
+	go func() {
+		// Inform the server we're not sending any more data after the copy unblocks.
+		defer remote.Close()
+
+		// Copy from the local port to the remote side.
+		if _, err := io.Copy(remote, conn); err != nil && !strings.Contains(err.Error(), networkClosedError) {
+			runtime.HandleError(fmt.Errorf("error copying from local connection to remote stream: %v", err))
+			close(localError)
+		}
+	}()
+
+	select {
+	case <-remoteDone:
+	case <-localError:
+	case <-ctx.Done():
+	}
+}
+
+func establishBrokerConn(dataStream httpstream.Stream, useTLS bool, handshakeTimeout time.Duration) (net.Conn, error) {
+	brokerConn := newStreamConn(dataStream)
 	if !useTLS {
 		return brokerConn, nil
 	}
 
-	tlsConn := tls.Client(newStreamConn(dataStream), &tls.Config{InsecureSkipVerify: true})
+	// The deadline resets the stream when the handshake gets stuck, so it cannot block forever
+	tlsConn := tls.Client(brokerConn, &tls.Config{InsecureSkipVerify: true})
+	_ = tlsConn.SetDeadline(time.Now().Add(handshakeTimeout))
 	if err := tlsConn.Handshake(); err != nil {
 		return nil, err
 	}
+	_ = tlsConn.SetDeadline(time.Time{})
 
 	return tlsConn, nil
 }
 
 // Close stops all listeners of ProxiedForwarder.
 func (pf *ProxiedForwarder) Close() {
-	// stop all listeners
-	for _, l := range pf.listeners {
-		if err := l.Close(); err != nil {
-			runtime.HandleError(fmt.Errorf("error closing listener: %v", err))
+	pf.closeOnce.Do(func() {
+		// stop all listeners
+		for _, l := range pf.listeners {
+			if err := l.Close(); err != nil {
+				runtime.HandleError(fmt.Errorf("error closing listener: %v", err))
+			}
 		}
-	}
+	})
 }
 
 // GetPorts will return the ports that were forwarded; this can be used to
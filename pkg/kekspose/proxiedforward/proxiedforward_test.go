@@ -1,6 +1,7 @@
 package proxiedforward
 
 import (
+	"context"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/tls"
@@ -10,6 +11,9 @@ import (
 	"math/big"
 	"net"
 	"net/http"
+	"os"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -29,9 +33,9 @@ func TestNewStreamConnWrapsStream(t *testing.T) {
 	assert.Equal(t, "stream", conn.LocalAddr().Network())
 	assert.Equal(t, "9092", conn.LocalAddr().String())
 	assert.Equal(t, "9092", conn.RemoteAddr().String())
-	require.NoError(t, conn.SetDeadline(time.Now()))
-	require.NoError(t, conn.SetReadDeadline(time.Now()))
-	require.NoError(t, conn.SetWriteDeadline(time.Now()))
+	require.NoError(t, conn.SetDeadline(time.Now().Add(time.Minute)))
+	require.NoError(t, conn.SetReadDeadline(time.Time{}))
+	require.NoError(t, conn.SetWriteDeadline(time.Time{}))
 
 	go func() {
 		_, _ = right.Write([]byte("ping"))
@@ -43,15 +47,65 @@ func TestNewStreamConnWrapsStream(t *testing.T) {
 	assert.Equal(t, []byte("ping"), buf)
 }
 
-func TestEstablishBrokerConnWithoutTLSReturnsStream(t *testing.T) {
+func TestStreamConnReadDeadlineResetsStream(t *testing.T) {
+	left, right := net.Pipe()
+	defer right.Close()
+
+	stream := &testStream{Conn: left, headers: http.Header{"Port": []string{"9092"}}}
+	conn := newStreamConn(stream)
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(50*time.Millisecond)))
+
+	_, err := conn.Read(make([]byte, 4))
+	require.ErrorIs(t, err, os.ErrDeadlineExceeded)
+	assert.True(t, stream.wasReset())
+
+	// The stream was reset, so extending the deadline does not bring it back
+	require.NoError(t, conn.SetDeadline(time.Time{}))
+	_, err = conn.Write([]byte("ping"))
+	require.ErrorIs(t, err, os.ErrDeadlineExceeded)
+}
+
+func TestStreamConnPastDeadlineResetsStreamImmediately(t *testing.T) {
+	left, right := net.Pipe()
+	defer right.Close()
+
+	stream := &testStream{Conn: left, headers: http.Header{"Port": []string{"9092"}}}
+	conn := newStreamConn(stream)
+	require.NoError(t, conn.SetWriteDeadline(time.Now().Add(-time.Second)))
+	assert.True(t, stream.wasReset())
+
+	_, err := conn.Write([]byte("ping"))
+	require.ErrorIs(t, err, os.ErrDeadlineExceeded)
+}
+
+func TestStreamConnIgnoresTimerOfChangedDeadline(t *testing.T) {
 	left, right := net.Pipe()
 	defer left.Close()
 	defer right.Close()
 
 	stream := &testStream{Conn: left, headers: http.Header{"Port": []string{"9092"}}}
-	conn, err := establishBrokerConn(stream, false)
+	conn := newStreamConn(stream).(*streamConn)
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(time.Minute)))
+	generation := conn.read.generation
+
+	// The timer fired, but the deadline was cleared before the timer got the lock
+	require.NoError(t, conn.SetReadDeadline(time.Time{}))
+	conn.expire(&conn.read, generation)
+	assert.False(t, stream.wasReset())
+
+	conn.expire(&conn.read, conn.read.generation)
+	assert.True(t, stream.wasReset())
+}
+
+func TestEstablishBrokerConnWithoutTLSWrapsStream(t *testing.T) {
+	left, right := net.Pipe()
+	defer left.Close()
+	defer right.Close()
+
+	stream := &testStream{Conn: left, headers: http.Header{"Port": []string{"9092"}}}
+	conn, err := establishBrokerConn(stream, false, time.Second)
 	require.NoError(t, err)
-	require.Same(t, stream, conn)
+	require.IsType(t, &streamConn{}, conn)
 
 	go func() {
 		_, _ = right.Write([]byte("pong"))
@@ -94,7 +148,7 @@ func TestEstablishBrokerConnWithTLS(t *testing.T) {
 	}()
 
 	stream := &testStream{Conn: left, headers: http.Header{"Port": []string{"9093"}}}
-	conn, err := establishBrokerConn(stream, true)
+	conn, err := establishBrokerConn(stream, true, 5*time.Second)
 	require.NoError(t, err)
 
 	_, err = conn.Write([]byte("ping"))
@@ -107,13 +161,148 @@ func TestEstablishBrokerConnWithTLS(t *testing.T) {
 	require.NoError(t, <-serverErr)
 }
 
+func TestEstablishBrokerConnWithTLSHandshakeTimeout(t *testing.T) {
+	left, right := net.Pipe()
+	defer right.Close()
+
+	// Consume the ClientHello without ever answering it
+	go func() {
+		_, _ = io.Copy(io.Discard, right)
+	}()
+
+	stream := &testStream{Conn: left, headers: http.Header{"Port": []string{"9093"}}}
+	start := time.Now()
+	_, err := establishBrokerConn(stream, true, 100*time.Millisecond)
+	require.Error(t, err)
+	assert.Less(t, time.Since(start), 5*time.Second)
+	assert.True(t, stream.wasReset())
+}
+
+func TestWatchIdleCancelsIdleConnection(t *testing.T) {
+	client, local := net.Pipe()
+	defer client.Close()
+	remote, server := net.Pipe()
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	localConn, remoteConn := watchIdle(ctx, cancel, 200*time.Millisecond, local, remote)
+
+	done := make(chan struct{})
+	go func() {
+		copyConnection(ctx, localConn, remoteConn)
+		close(done)
+	}()
+
+	// Activity keeps the connection open past the idle timeout
+	for i := 0; i < 4; i++ {
+		_, err := client.Write([]byte("ping"))
+		require.NoError(t, err)
+
+		buf := make([]byte, 4)
+		_, err = io.ReadFull(server, buf)
+		require.NoError(t, err)
+
+		time.Sleep(100 * time.Millisecond)
+	}
+	require.NoError(t, ctx.Err())
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("idle connection was not closed")
+	}
+	assert.ErrorIs(t, ctx.Err(), context.Canceled)
+}
+
+func TestWatchIdleDisabled(t *testing.T) {
+	local, remote := net.Pipe()
+	defer local.Close()
+	defer remote.Close()
+
+	localConn, remoteConn := watchIdle(context.Background(), func() {}, 0, local, remote)
+	assert.Same(t, local, localConn)
+	assert.Same(t, remote, remoteConn)
+}
+
+func TestResponseTimeout(t *testing.T) {
+	left, right := net.Pipe()
+	defer right.Close()
+
+	stream := &testStream{Conn: left, headers: http.Header{"Port": []string{"9092"}}}
+	conn := withResponseTimeout(newStreamConn(stream), 200*time.Millisecond)
+
+	go func() {
+		buf := make([]byte, 4)
+		// Answer the first request, but not the second one
+		_, _ = io.ReadFull(right, buf)
+		_, _ = right.Write([]byte("pong"))
+		_, _ = io.ReadFull(right, buf)
+	}()
+
+	// Without any request, waiting for the pod is not limited
+	time.Sleep(300 * time.Millisecond)
+
+	_, err := conn.Write([]byte("ping"))
+	require.NoError(t, err)
+	buf := make([]byte, 4)
+	_, err = io.ReadFull(conn, buf)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("pong"), buf)
+
+	_, err = conn.Write([]byte("ping"))
+	require.NoError(t, err)
+	_, err = conn.Read(buf)
+	require.ErrorIs(t, err, os.ErrDeadlineExceeded)
+}
+
+func TestCopyConnectionForwardsBothDirections(t *testing.T) {
+	client, local := net.Pipe()
+	defer client.Close()
+	remote, server := net.Pipe()
+	defer server.Close()
+
+	done := make(chan struct{})
+	go func() {
+		copyConnection(context.Background(), local, remote)
+		close(done)
+	}()
+
+	_, err := client.Write([]byte("GET /"))
+	require.NoError(t, err)
+
+	buf := make([]byte, 5)
+	_, err = io.ReadFull(server, buf)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("GET /"), buf)
+
+	_, err = server.Write([]byte("200 OK"))
+	require.NoError(t, err)
+
+	buf = make([]byte, 6)
+	_, err = io.ReadFull(client, buf)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("200 OK"), buf)
+
+	// Closing the remote side finishes the forwarding
+	require.NoError(t, server.Close())
+	<-done
+}
+
 type testStream struct {
 	net.Conn
 	headers http.Header
+	reset   atomic.Bool
 }
 
 func (t *testStream) Reset() error {
-	return nil
+	t.reset.Store(true)
+	return t.Conn.Close()
+}
+
+func (t *testStream) wasReset() bool {
+	return t.reset.Load()
 }
 
 func (t *testStream) Headers() http.Header {
@@ -150,3 +339,65 @@ func generateTestCertificate(t *testing.T) tls.Certificate {
 }
 
 var _ httpstream.Stream = (*testStream)(nil)
+
+func TestLazyConnectDialsOnDemandAndClosesIdleConnection(t *testing.T) {
+	dialer := &testDialer{}
+	pf, err := New(dialer, []string{"0:9092"}, make(chan struct{}), nil, false, nil)
+	require.NoError(t, err)
+	pf.WithLazyConnect(20 * time.Millisecond)
+
+	first, err := pf.acquireStreamConn()
+	require.NoError(t, err)
+	second, err := pf.acquireStreamConn()
+	require.NoError(t, err)
+	assert.Same(t, first, second)
+	assert.Equal(t, 1, dialer.dials)
+
+	pf.releaseStreamConn()
+	pf.releaseStreamConn()
+
+	select {
+	case <-first.CloseChan():
+	case <-time.After(5 * time.Second):
+		t.Fatal("the idle connection was not closed")
+	}
+
+	third, err := pf.acquireStreamConn()
+	require.NoError(t, err)
+	assert.NotSame(t, first, third)
+	assert.Equal(t, 2, dialer.dials)
+
+	pf.closeStreamConn()
+	<-third.CloseChan()
+}
+
+type testDialer struct {
+	dials int
+}
+
+func (d *testDialer) Dial(...string) (httpstream.Connection, string, error) {
+	d.dials++
+	return &testConnection{closeChan: make(chan bool)}, PortForwardProtocolV1Name, nil
+}
+
+type testConnection struct {
+	closeOnce sync.Once
+	closeChan chan bool
+}
+
+func (c *testConnection) CreateStream(http.Header) (httpstream.Stream, error) {
+	return nil, io.ErrClosedPipe
+}
+
+func (c *testConnection) Close() error {
+	c.closeOnce.Do(func() { close(c.closeChan) })
+	return nil
+}
+
+func (c *testConnection) CloseChan() <-chan bool {
+	return c.closeChan
+}
+
+func (c *testConnection) SetIdleTimeout(time.Duration) {}
+
+func (c *testConnection) RemoveStreams(...httpstream.Stream) {}
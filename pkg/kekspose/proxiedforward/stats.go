@@ -0,0 +1,80 @@
+/*
+Copyright © 2025 Jakub Scholz
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package proxiedforward
+
+import (
+	"net"
+	"sync/atomic"
+)
+
+// Stats counts the connections and the transferred data of a forwarded port.
+type Stats struct {
+	activeConnections atomic.Int64
+	totalConnections  atomic.Int64
+	bytesSent         atomic.Int64
+	bytesReceived     atomic.Int64
+}
+
+// StatsSnapshot is a point-in-time copy of the Stats. Bytes sent are the bytes sent by the clients to
+// the pod and bytes received are the bytes sent by the pod to the clients.
+type StatsSnapshot struct {
+	ActiveConnections int64 `json:"activeConnections"`
+	TotalConnections  int64 `json:"totalConnections"`
+	BytesSent         int64 `json:"bytesSent"`
+	BytesReceived     int64 `json:"bytesReceived"`
+}
+
+// Snapshot returns the current values of the counters.
+func (s *Stats) Snapshot() StatsSnapshot {
+	return StatsSnapshot{
+		ActiveConnections: s.activeConnections.Load(),
+		TotalConnections:  s.totalConnections.Load(),
+		BytesSent:         s.bytesSent.Load(),
+		BytesReceived:     s.bytesReceived.Load(),
+	}
+}
+
+// track counts the connection and wraps it to count the transferred data. The returned function must
+// be called when the connection is closed.
+func (s *Stats) track(conn net.Conn) (net.Conn, func()) {
+	if s == nil {
+		return conn, func() {}
+	}
+
+	s.totalConnections.Add(1)
+	s.activeConnections.Add(1)
+	return &countingConn{Conn: conn, stats: s}, func() { s.activeConnections.Add(-1) }
+}
+
+// countingConn counts the data read from the local client as sent and the data written to it as
+// received.
+type countingConn struct {
+	net.Conn
+	stats *Stats
+}
+
+func (c *countingConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	c.stats.bytesSent.Add(int64(n))
+	return n, err
+}
+
+func (c *countingConn) Write(p []byte) (int, error) {
+	n, err := c.Conn.Write(p)
+	c.stats.bytesReceived.Add(int64(n))
+	return n, err
+}
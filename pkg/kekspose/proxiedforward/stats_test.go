@@ -0,0 +1,48 @@
+package proxiedforward
+
+import (
+	"io"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatsTrack(t *testing.T) {
+	stats := &Stats{}
+
+	local, remote := net.Pipe()
+	defer remote.Close()
+
+	conn, untrack := stats.track(local)
+	assert.Equal(t, StatsSnapshot{ActiveConnections: 1, TotalConnections: 1}, stats.Snapshot())
+
+	go func() {
+		_, _ = remote.Write([]byte("request"))
+		_, _ = io.ReadFull(remote, make([]byte, 3))
+	}()
+
+	_, err := io.ReadFull(conn, make([]byte, 7))
+	require.NoError(t, err)
+	_, err = conn.Write([]byte("ack"))
+	require.NoError(t, err)
+
+	_ = conn.Close()
+	untrack()
+
+	assert.Equal(t, StatsSnapshot{ActiveConnections: 0, TotalConnections: 1, BytesSent: 7, BytesReceived: 3}, stats.Snapshot())
+}
+
+func TestStatsTrackNil(t *testing.T) {
+	var stats *Stats
+
+	local, remote := net.Pipe()
+	defer remote.Close()
+	defer local.Close()
+
+	conn, untrack := stats.track(local)
+	untrack()
+
+	assert.Same(t, local, conn)
+}
@@ -3,13 +3,31 @@ package proxiedforward
 import (
 	"net"
 	"net/http"
+	"os"
+	"sync"
 	"time"
 
 	"k8s.io/apimachinery/pkg/util/httpstream"
 )
 
+// streamConn wraps the port-forward stream as a net.Conn. The stream cannot interrupt a blocked read
+// or write, so an expired deadline resets the stream instead. The reset is permanent: once a deadline
+// expires, all further reads and writes fail with os.ErrDeadlineExceeded even when the deadline is
+// extended again.
 type streamConn struct {
 	stream httpstream.Stream
+
+	lock    sync.Mutex
+	read    deadline
+	write   deadline
+	expired bool
+}
+
+// deadline is the timer of the read or write deadline. The generation changes every time the deadline
+// is set, so that a timer which fired just before the deadline was changed does not reset the stream.
+type deadline struct {
+	timer      *time.Timer
+	generation uint64
 }
 
 func newStreamConn(stream httpstream.Stream) net.Conn {
@@ -17,11 +35,27 @@ func newStreamConn(stream httpstream.Stream) net.Conn {
 }
 
 func (s *streamConn) Read(p []byte) (int, error) {
-	return s.stream.Read(p)
+	if s.isExpired() {
+		return 0, os.ErrDeadlineExceeded
+	}
+
+	n, err := s.stream.Read(p)
+	if err != nil && s.isExpired() {
+		err = os.ErrDeadlineExceeded
+	}
+	return n, err
 }
 
 func (s *streamConn) Write(p []byte) (int, error) {
-	return s.stream.Write(p)
+	if s.isExpired() {
+		return 0, os.ErrDeadlineExceeded
+	}
+
+	n, err := s.stream.Write(p)
+	if err != nil && s.isExpired() {
+		err = os.ErrDeadlineExceeded
+	}
+	return n, err
 }
 
 func (s *streamConn) Close() error {
@@ -36,18 +70,84 @@ func (s *streamConn) RemoteAddr() net.Addr {
 	return streamAddr{s.stream.Headers()}
 }
 
-func (s *streamConn) SetDeadline(time.Time) error {
+func (s *streamConn) SetDeadline(t time.Time) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.armDeadline(&s.read, t)
+	s.armDeadline(&s.write, t)
 	return nil
 }
 
-func (s *streamConn) SetReadDeadline(time.Time) error {
+func (s *streamConn) SetReadDeadline(t time.Time) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.armDeadline(&s.read, t)
 	return nil
 }
 
-func (s *streamConn) SetWriteDeadline(time.Time) error {
+func (s *streamConn) SetWriteDeadline(t time.Time) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.armDeadline(&s.write, t)
 	return nil
 }
 
+// armDeadline replaces the timer of the previous deadline with a timer resetting the stream at the
+// new deadline. The zero time disables the deadline. It must be called with the lock held.
+func (s *streamConn) armDeadline(d *deadline, t time.Time) {
+	d.generation++
+	if d.timer != nil {
+		d.timer.Stop()
+		d.timer = nil
+	}
+
+	if t.IsZero() || s.expired {
+		return
+	}
+
+	if until := time.Until(t); until > 0 {
+		generation := d.generation
+		d.timer = time.AfterFunc(until, func() {
+			s.expire(d, generation)
+		})
+		return
+	}
+
+	s.expireLocked()
+}
+
+// expire resets the stream when the deadline of the timer is still the current one. Stopping the timer
+// does not help once its function is waiting for the lock, so the generation is checked instead.
+func (s *streamConn) expire(d *deadline, generation uint64) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if d.generation != generation {
+		return
+	}
+
+	s.expireLocked()
+}
+
+func (s *streamConn) expireLocked() {
+	if s.expired {
+		return
+	}
+
+	s.expired = true
+	_ = s.stream.Reset()
+}
+
+func (s *streamConn) isExpired() bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.expired
+}
+
 type streamAddr struct {
 	headers http.Header
 }
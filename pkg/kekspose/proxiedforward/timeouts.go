@@ -0,0 +1,149 @@
+/*
+Copyright © 2025 Jakub Scholz
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package proxiedforward
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultTLSHandshakeTimeout is the time limit for the TLS handshake with the broker when no other
+// limit is configured.
+const DefaultTLSHandshakeTimeout = 10 * time.Second
+
+// Timeouts configures the time limits of the proxied connections. Zero disables the limit, except for
+// TLSHandshake which falls back to DefaultTLSHandshakeTimeout.
+type Timeouts struct {
+	// TLSHandshake limits the duration of the TLS handshake with the broker.
+	TLSHandshake time.Duration
+	// Idle closes the proxied connection when no data flowed in either direction for this long.
+	Idle time.Duration
+	// Read closes the proxied connection when the pod does not send anything for this long after the
+	// client sent some data to it.
+	Read time.Duration
+}
+
+func (t Timeouts) tlsHandshake() time.Duration {
+	if t.TLSHandshake <= 0 {
+		return DefaultTLSHandshakeTimeout
+	}
+
+	return t.TLSHandshake
+}
+
+// activityConn records the time of the last successful read or write on the connection.
+type activityConn struct {
+	net.Conn
+	last *atomic.Int64
+}
+
+func (a *activityConn) Read(p []byte) (int, error) {
+	n, err := a.Conn.Read(p)
+	if n > 0 {
+		a.last.Store(time.Now().UnixNano())
+	}
+	return n, err
+}
+
+func (a *activityConn) Write(p []byte) (int, error) {
+	n, err := a.Conn.Write(p)
+	if n > 0 {
+		a.last.Store(time.Now().UnixNano())
+	}
+	return n, err
+}
+
+// watchIdle wraps both sides of the proxied connection to track their activity and cancels the
+// connection once neither side sent any data for the idle timeout. It returns the connections unchanged
+// when the timeout is disabled.
+func watchIdle(ctx context.Context, cancel context.CancelFunc, idle time.Duration, local net.Conn, remote net.Conn) (net.Conn, net.Conn) {
+	if idle <= 0 {
+		return local, remote
+	}
+
+	last := &atomic.Int64{}
+	last.Store(time.Now().UnixNano())
+
+	go func() {
+		timer := time.NewTimer(idle)
+		defer timer.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-timer.C:
+				if remaining := idle - time.Since(time.Unix(0, last.Load())); remaining > 0 {
+					timer.Reset(remaining)
+					continue
+				}
+
+				slog.Info("Closing idle connection", "localAddress", local.LocalAddr(), "idleTimeout", idle)
+				cancel()
+				return
+			}
+		}
+	}()
+
+	return &activityConn{Conn: local, last: last}, &activityConn{Conn: remote, last: last}
+}
+
+// responseTimeoutConn limits how long the pod can stay silent after the client sent some data to it. A
+// write arms the read deadline unless it is already armed, and any data read from the pod disarms it.
+type responseTimeoutConn struct {
+	net.Conn
+	timeout time.Duration
+
+	lock  sync.Mutex
+	armed bool
+}
+
+func withResponseTimeout(conn net.Conn, timeout time.Duration) net.Conn {
+	if timeout <= 0 {
+		return conn
+	}
+
+	return &responseTimeoutConn{Conn: conn, timeout: timeout}
+}
+
+func (r *responseTimeoutConn) Read(p []byte) (int, error) {
+	n, err := r.Conn.Read(p)
+	if n > 0 {
+		r.lock.Lock()
+		if r.armed {
+			r.armed = false
+			_ = r.Conn.SetReadDeadline(time.Time{})
+		}
+		r.lock.Unlock()
+	}
+	return n, err
+}
+
+func (r *responseTimeoutConn) Write(p []byte) (int, error) {
+	r.lock.Lock()
+	if !r.armed {
+		r.armed = true
+		_ = r.Conn.SetReadDeadline(time.Now().Add(r.timeout))
+	}
+	r.lock.Unlock()
+
+	return r.Conn.Write(p)
+}
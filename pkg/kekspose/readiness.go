@@ -0,0 +1,153 @@
+/*
+Copyright © 2025 Jakub Scholz
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kekspose
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	keks2 "github.com/scholzj/kekspose/pkg/kekspose/keks"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// podReadinessInterval is how often the readiness of the pods is checked while waiting for them.
+var podReadinessInterval = 2 * time.Second
+
+// waitForPods waits until the pods of all the forwarders are running and ready, because forwarding
+// to other pods fails. With PartialStart, it does not wait and returns the forwarders with pods which
+// are not ready yet instead, so that they can be started later by startWhenReady. Without WaitTimeout
+// and PartialStart, the readiness is not checked at all.
+func (k *Kekspose) waitForPods(ctx context.Context, kubeclient kubernetes.Interface, forwarders []*PortForwarder) ([]*PortForwarder, error) {
+	if k.WaitTimeout <= 0 && !k.PartialStart {
+		return nil, nil
+	}
+
+	deadline := time.Now().Add(k.WaitTimeout)
+	reported := ""
+
+	for {
+		pending, err := notReadyForwarders(kubeclient, forwarders)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(pending) == 0 {
+			return nil, nil
+		}
+
+		pods := podNames(pending)
+
+		if k.PartialStart {
+			slog.Warn("Some pods are not ready yet, the port forwarding for them will start once they are ready", "pods", pods)
+			return pending, nil
+		}
+
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("pods %s did not become ready within %s. Use --wait-timeout to wait longer or --partial-start to start without them", pods, k.WaitTimeout)
+		}
+
+		if pods != reported {
+			slog.Info("Waiting for pods to become ready", "pods", pods, "timeout", k.WaitTimeout)
+			reported = pods
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(podReadinessInterval):
+		}
+	}
+}
+
+// startWhenReady starts the forwarders once their pods become ready. It runs until all of them are
+// started or until ctx is done. Pods which are not ready within WaitTimeout, when it is set, are
+// reported, but it keeps waiting for them.
+func (k *Kekspose) startWhenReady(ctx context.Context, kubeclient kubernetes.Interface, pending []*PortForwarder, start func(*PortForwarder)) {
+	deadline := time.Now().Add(k.WaitTimeout)
+	reportedTimeout := false
+
+	for len(pending) > 0 {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(podReadinessInterval):
+		}
+
+		notReady, err := notReadyForwarders(kubeclient, pending)
+		if err != nil {
+			slog.Warn("Failed to check the readiness of the pods", "error", err)
+			continue
+		}
+
+		for _, pf := range pending {
+			if !slices.Contains(notReady, pf) {
+				slog.Info("Pod is ready, starting the port forwarding", "podName", pf.PodName, "namespace", pf.Namespace)
+				start(pf)
+			}
+		}
+		pending = notReady
+
+		if len(pending) > 0 && !reportedTimeout && k.WaitTimeout > 0 && time.Now().After(deadline) {
+			slog.Warn("Pods did not become ready in time, still waiting for them", "pods", podNames(pending), "timeout", k.WaitTimeout)
+			reportedTimeout = true
+		}
+	}
+}
+
+// notReadyForwarders returns the forwarders with pods which are missing or are not running and ready.
+func notReadyForwarders(kubeclient kubernetes.Interface, forwarders []*PortForwarder) ([]*PortForwarder, error) {
+	readiness := make(map[string]bool)
+	notReady := make([]*PortForwarder, 0)
+
+	for _, pf := range forwarders {
+		key := pf.Namespace + "/" + pf.PodName
+
+		ready, checked := readiness[key]
+		if !checked {
+			pod, err := kubeclient.CoreV1().Pods(pf.Namespace).Get(context.TODO(), pf.PodName, v1.GetOptions{})
+			if err != nil && !apierrors.IsNotFound(err) {
+				return nil, fmt.Errorf("failed to get pod %s in namespace %s: %w", pf.PodName, pf.Namespace, err)
+			}
+
+			ready = err == nil && keks2.IsPodReady(pod)
+			readiness[key] = ready
+		}
+
+		if !ready {
+			notReady = append(notReady, pf)
+		}
+	}
+
+	return notReady, nil
+}
+
+func podNames(forwarders []*PortForwarder) string {
+	names := make([]string, 0, len(forwarders))
+	for _, pf := range forwarders {
+		if !slices.Contains(names, pf.PodName) {
+			names = append(names, pf.PodName)
+		}
+	}
+
+	return strings.Join(names, ", ")
+}
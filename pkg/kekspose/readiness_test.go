@@ -0,0 +1,102 @@
+package kekspose
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestNotReadyForwarders(t *testing.T) {
+	kubeclient := fake.NewSimpleClientset(
+		brokerPod("my-cluster-pool-a-0", corev1.PodRunning, corev1.ConditionTrue),
+		brokerPod("my-cluster-pool-a-1", corev1.PodRunning, corev1.ConditionFalse),
+		brokerPod("my-cluster-pool-a-2", corev1.PodPending, corev1.ConditionTrue),
+	)
+
+	forwarders := testForwarders("my-cluster-pool-a-0", "my-cluster-pool-a-1", "my-cluster-pool-a-2", "my-cluster-pool-a-3")
+	notReady, err := notReadyForwarders(kubeclient, forwarders)
+
+	require.NoError(t, err)
+	assert.Equal(t, forwarders[1:], notReady)
+	assert.Equal(t, "my-cluster-pool-a-1, my-cluster-pool-a-2, my-cluster-pool-a-3", podNames(notReady))
+}
+
+func TestWaitForPodsWaitsUntilReady(t *testing.T) {
+	podReadinessInterval = 10 * time.Millisecond
+	defer func() { podReadinessInterval = 2 * time.Second }()
+
+	kubeclient := fake.NewSimpleClientset(brokerPod("my-cluster-pool-a-0", corev1.PodRunning, corev1.ConditionFalse))
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		_, _ = kubeclient.CoreV1().Pods("my-namespace").Update(context.TODO(), brokerPod("my-cluster-pool-a-0", corev1.PodRunning, corev1.ConditionTrue), metav1.UpdateOptions{})
+	}()
+
+	k := Kekspose{WaitTimeout: 10 * time.Second}
+	pending, err := k.waitForPods(context.Background(), kubeclient, testForwarders("my-cluster-pool-a-0"))
+
+	require.NoError(t, err)
+	assert.Empty(t, pending)
+}
+
+func TestWaitForPodsTimesOut(t *testing.T) {
+	podReadinessInterval = 10 * time.Millisecond
+	defer func() { podReadinessInterval = 2 * time.Second }()
+
+	kubeclient := fake.NewSimpleClientset(brokerPod("my-cluster-pool-a-0", corev1.PodRunning, corev1.ConditionFalse))
+
+	k := Kekspose{WaitTimeout: 50 * time.Millisecond}
+	_, err := k.waitForPods(context.Background(), kubeclient, testForwarders("my-cluster-pool-a-0"))
+
+	require.EqualError(t, err, "pods my-cluster-pool-a-0 did not become ready within 50ms. Use --wait-timeout to wait longer or --partial-start to start without them")
+}
+
+func TestWaitForPodsWithPartialStart(t *testing.T) {
+	podReadinessInterval = 10 * time.Millisecond
+	defer func() { podReadinessInterval = 2 * time.Second }()
+
+	kubeclient := fake.NewSimpleClientset(
+		brokerPod("my-cluster-pool-a-0", corev1.PodRunning, corev1.ConditionTrue),
+		brokerPod("my-cluster-pool-a-1", corev1.PodRunning, corev1.ConditionFalse),
+	)
+	forwarders := testForwarders("my-cluster-pool-a-0", "my-cluster-pool-a-1")
+
+	k := Kekspose{PartialStart: true}
+	pending, err := k.waitForPods(context.Background(), kubeclient, forwarders)
+
+	require.NoError(t, err)
+	assert.Equal(t, forwarders[1:], pending)
+
+	// Without --partial-start, the readiness is not checked by default
+	unchecked, err := (&Kekspose{}).waitForPods(context.Background(), kubeclient, forwarders)
+
+	require.NoError(t, err)
+	assert.Empty(t, unchecked)
+
+	started := make(chan *PortForwarder, 1)
+	go k.startWhenReady(context.Background(), kubeclient, pending, func(pf *PortForwarder) { started <- pf })
+
+	_, err = kubeclient.CoreV1().Pods("my-namespace").Update(context.TODO(), brokerPod("my-cluster-pool-a-1", corev1.PodRunning, corev1.ConditionTrue), metav1.UpdateOptions{})
+	require.NoError(t, err)
+
+	select {
+	case pf := <-started:
+		assert.Equal(t, forwarders[1], pf)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the port forwarding was not started")
+	}
+}
+
+func testForwarders(podNames ...string) []*PortForwarder {
+	forwarders := make([]*PortForwarder, 0, len(podNames))
+	for _, podName := range podNames {
+		forwarders = append(forwarders, &PortForwarder{Namespace: "my-namespace", PodName: podName})
+	}
+
+	return forwarders
+}
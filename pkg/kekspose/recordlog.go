@@ -0,0 +1,241 @@
+/*
+Copyright © 2025 Jakub Scholz
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kekspose
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"slices"
+
+	"github.com/scholzj/go-kafka-protocol/messages"
+	keks2 "github.com/scholzj/kekspose/pkg/kekspose/keks"
+	"github.com/scholzj/kekspose/pkg/kekspose/protocol"
+	"github.com/scholzj/kekspose/pkg/kekspose/records"
+	"github.com/scholzj/kekspose/pkg/kekspose/schemaregistry"
+)
+
+// DefaultRecordMaxBytes is the default length of the rendered keys, values, and headers of the logged
+// records.
+const DefaultRecordMaxBytes = 256
+
+// recordQueueSize is the number of the Produce requests and Fetch responses of one connection queued for
+// logging their records. When the queue is full, the records are not logged, so that a slow schema
+// registry does not slow down the clients.
+const recordQueueSize = 64
+
+// recordOptions select the topics whose records are decoded and logged, and how the records are
+// rendered. The keys and values using the Confluent wire format are decoded with the schemas from the
+// schema registry when there is one. The redacted values and headers are replaced with their sizes.
+type recordOptions struct {
+	Topics    []string
+	Format    records.Format
+	MaxBytes  int
+	Schemas   *schemaregistry.Client
+	Redaction redaction
+}
+
+// active returns true when the records of some topics are decoded.
+func (o recordOptions) active() bool {
+	return len(o.Topics) > 0
+}
+
+// recordOptions returns the options of decoding the records.
+func (k *Kekspose) recordOptions() recordOptions {
+	return recordOptions{Topics: slices.Clone(k.RecordTopics), Format: k.RecordFormat, MaxBytes: k.RecordMaxBytes, Schemas: k.schemas, Redaction: k.redaction()}
+}
+
+// connectSchemaRegistry creates the client of the schema registry used to decode the logged records. The
+// schema registry is either forwarded from its pod or at the configured URL.
+func (k *Kekspose) connectSchemaRegistry(endpoints []*endpointExposure) error {
+	registryURL := k.SchemaRegistryURL
+	for _, e := range endpoints {
+		if e.Kind == keks2.SchemaRegistryKind {
+			registryURL = e.URL()
+		}
+	}
+
+	if registryURL == "" {
+		return nil
+	}
+
+	schemas, err := schemaregistry.NewClient(registryURL)
+	if err != nil {
+		return err
+	}
+
+	k.schemas = schemas
+	return nil
+}
+
+// recordJob are the records of one Produce request or Fetch response queued for logging.
+type recordJob struct {
+	header     protocol.RequestHeader
+	partitions []protocol.PartitionRecords
+}
+
+// decodesRecords returns true when the records should be decoded. The records are logged at the debug
+// level, so they are not decoded when it is disabled.
+func (c *connectionInspector) decodesRecords() bool {
+	return c.records.active() && c.logger.Enabled(context.Background(), slog.LevelDebug)
+}
+
+// logProduceRecords logs the records of the Produce request.
+func (c *connectionInspector) logProduceRecords(header protocol.RequestHeader, frame []byte) {
+	_, body, err := protocol.ReadRequestHeader(frame)
+	if err != nil {
+		return
+	}
+
+	// Keep the records decoded before an error
+	partitions, _ := protocol.ReadProduceRecords(header.APIVersion, body)
+	c.logRecords(header, partitions)
+}
+
+// logRecords queues the records of the partitions of the matching topics for logging. The topics used
+// by their IDs are skipped until their names are learned from a Metadata response. The records are
+// decoded and logged in the background, because looking up their schemas can take a while.
+func (c *connectionInspector) logRecords(header protocol.RequestHeader, partitions []protocol.PartitionRecords) {
+	job := recordJob{header: header}
+	for _, partition := range partitions {
+		if partition.Topic == "" {
+			name, found := c.topics.name(partition.TopicID)
+			if !found {
+				continue
+			}
+			partition.Topic = name
+		}
+
+		if !matchesAny(c.records.Topics, partition.Topic) {
+			continue
+		}
+
+		// The frame is valid only during the call of the inspector
+		partition.Records = bytes.Clone(partition.Records)
+		job.partitions = append(job.partitions, partition)
+	}
+
+	if len(job.partitions) == 0 {
+		return
+	}
+
+	c.recordWorker.Do(func() {
+		c.recordQueue = make(chan recordJob, recordQueueSize)
+		c.recordDone = make(chan struct{})
+		go c.logQueuedRecords()
+	})
+
+	select {
+	case c.recordQueue <- job:
+	default:
+		c.logger.Debug("Too many records queued for logging, skipping the records", "api", messages.Name(header.APIKey), correlationIdKey, header.CorrelationID)
+	}
+}
+
+// logQueuedRecords decodes and logs the queued records until the queue is closed.
+func (c *connectionInspector) logQueuedRecords() {
+	defer close(c.recordDone)
+
+	for job := range c.recordQueue {
+		for _, partition := range job.partitions {
+			c.logPartitionRecords(job.header, partition)
+		}
+	}
+}
+
+// closeRecords waits for the queued records to be logged.
+func (c *connectionInspector) closeRecords() {
+	c.recordWorker.Do(func() {})
+
+	if c.recordQueue != nil {
+		close(c.recordQueue)
+		<-c.recordDone
+	}
+}
+
+// logPartitionRecords decodes and logs the records of the partition. The control records, which mark
+// the ends of the transactions, are not logged.
+func (c *connectionInspector) logPartitionRecords(header protocol.RequestHeader, partition protocol.PartitionRecords) {
+	logger := c.logger.With("api", messages.Name(header.APIKey), correlationIdKey, header.CorrelationID, "topic", partition.Topic, "partition", partition.Partition)
+	batches, err := records.ReadBatches(partition.Records)
+	for _, batch := range batches {
+		if batch.Control {
+			continue
+		}
+
+		for _, record := range batch.Records {
+			c.logRecord(logger, header.APIKey == protocol.Fetch, batch, record)
+		}
+	}
+
+	if err != nil {
+		logger.Debug("Failed to decode the records", "error", err)
+	}
+}
+
+// logRecord logs a decoded record. The offsets are known only for the fetched records, because the
+// broker assigns them after the records are produced.
+func (c *connectionInspector) logRecord(logger *slog.Logger, fetched bool, batch records.Batch, record records.Record) {
+	attrs := make([]any, 0, 16)
+	if fetched {
+		attrs = append(attrs, "offset", record.Offset)
+	}
+	attrs = append(attrs, "timestamp", record.Timestamp.UTC(), "compression", batch.Compression.String())
+	attrs = c.appendDecoded(attrs, "key", record.Key)
+	if c.records.Redaction.Values {
+		attrs = append(attrs, "value", redactedBytes(record.Value))
+	} else {
+		attrs = c.appendDecoded(attrs, "value", record.Value)
+	}
+
+	if len(record.Headers) > 0 {
+		headers := make([]string, 0, len(record.Headers))
+		for _, h := range record.Headers {
+			if c.records.Redaction.Headers {
+				headers = append(headers, h.Key+"="+redactedBytes(h.Value))
+			} else {
+				headers = append(headers, h.Key+"="+c.render(h.Value))
+			}
+		}
+		attrs = append(attrs, "headers", headers)
+	}
+
+	logger.Debug("Decoded record", attrs...)
+}
+
+// appendDecoded appends the rendered key or value of a record. When it uses the Confluent wire format,
+// it is decoded with its schema and rendered as JSON, and its schema ID is appended as well. When the
+// decoding fails, the error is appended and the data is rendered as it is.
+func (c *connectionInspector) appendDecoded(attrs []any, name string, data []byte) []any {
+	id, ok := schemaregistry.SchemaID(data)
+	if c.records.Schemas == nil || !ok {
+		return append(attrs, name, c.render(data))
+	}
+
+	attrs = append(attrs, name+"SchemaId", id)
+
+	decoded, err := c.records.Schemas.Decode(context.Background(), data)
+	if err != nil {
+		return append(attrs, name, c.render(data), name+"SchemaError", err.Error())
+	}
+
+	return append(attrs, name, records.Render(decoded, records.FormatJSON, c.records.MaxBytes))
+}
+
+func (c *connectionInspector) render(data []byte) string {
+	return records.Render(data, c.records.Format, c.records.MaxBytes)
+}
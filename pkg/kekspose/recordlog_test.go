@@ -0,0 +1,211 @@
+package kekspose
+
+import (
+	"bytes"
+	"encoding/binary"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	keks2 "github.com/scholzj/kekspose/pkg/kekspose/keks"
+	"github.com/scholzj/kekspose/pkg/kekspose/protocol"
+	"github.com/scholzj/kekspose/pkg/kekspose/records"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testRecordBatch builds an uncompressed batch with one record with a trace header.
+func testRecordBatch(offset int64, key string, value string) []byte {
+	record := []byte{0}                     // Attributes
+	record = binary.AppendVarint(record, 0) // Timestamp delta
+	record = binary.AppendVarint(record, 0) // Offset delta
+	record = binary.AppendVarint(record, int64(len(key)))
+	record = append(record, key...)
+	record = binary.AppendVarint(record, int64(len(value)))
+	record = append(record, value...)
+	record = binary.AppendVarint(record, 1) // Headers
+	record = binary.AppendVarint(record, 5)
+	record = append(record, "trace"...)
+	record = binary.AppendVarint(record, 3)
+	record = append(record, "abc"...)
+
+	batch := binary.BigEndian.AppendUint32(nil, 0)                                    // Partition leader epoch
+	batch = append(batch, 2, 0, 0, 0, 0, 0, 0)                                        // Magic, CRC, and attributes
+	batch = binary.BigEndian.AppendUint32(batch, 0)                                   // Last offset delta
+	batch = binary.BigEndian.AppendUint64(batch, 1700000000000)                       // Base timestamp
+	batch = binary.BigEndian.AppendUint64(batch, 1700000000000)                       // Max timestamp
+	batch = append(batch, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff) // Producer ID and epoch
+	batch = binary.BigEndian.AppendUint32(batch, 0)                                   // Base sequence
+	batch = binary.BigEndian.AppendUint32(batch, 1)                                   // Records
+	batch = binary.AppendVarint(batch, int64(len(record)))
+	batch = append(batch, record...)
+
+	data := binary.BigEndian.AppendUint64(nil, uint64(offset))
+	data = binary.BigEndian.AppendUint32(data, uint32(len(batch)))
+	return append(data, batch...)
+}
+
+// appendRecords appends the records of a partition as non-compact bytes.
+func appendRecords(data []byte, partition int32, batch []byte) []byte {
+	data = binary.BigEndian.AppendUint32(data, uint32(partition))
+	data = binary.BigEndian.AppendUint32(data, uint32(len(batch)))
+	return append(data, batch...)
+}
+
+// produceRequest builds a Produce v3 request with one partition of each topic.
+func produceRequest(correlationId int32, topics map[string][]byte) []byte {
+	body := []byte{0xff, 0xff, 0, 1, 0, 0, 0x75, 0x30} // Null transactional ID, acks, and timeout
+	body = binary.BigEndian.AppendUint32(body, uint32(len(topics)))
+	for topic, batch := range topics {
+		body = appendString(body, topic)
+		body = binary.BigEndian.AppendUint32(body, 1)
+		body = appendRecords(body, 0, batch)
+	}
+
+	return testRequest(protocol.Produce, 3, correlationId, "my-producer", body...)
+}
+
+// fetchResponse builds a Fetch v4 response with one partition of the topic.
+func fetchResponse(correlationId int32, topic string, batch []byte) []byte {
+	response := binary.BigEndian.AppendUint32(nil, uint32(correlationId))
+	response = append(response, 0, 0, 0, 0, 0, 0, 0, 1) // Throttle time and one topic
+	response = appendString(response, topic)
+	response = append(response, 0, 0, 0, 1) // One partition
+
+	partition := []byte{0, 0}                                // Error code
+	partition = binary.BigEndian.AppendUint64(partition, 50) // High watermark
+	partition = binary.BigEndian.AppendUint64(partition, 50) // Last stable offset
+	partition = append(partition, 0xff, 0xff, 0xff, 0xff)    // Null aborted transactions
+	response = binary.BigEndian.AppendUint32(response, 3)    // Partition
+	response = append(response, partition...)
+	response = binary.BigEndian.AppendUint32(response, uint32(len(batch)))
+	return append(response, batch...)
+}
+
+func newRecordInspector(output *bytes.Buffer, filter rpcFilter) *connectionInspector {
+	inspector := &connectionInspector{
+		filter:   filter,
+		records:  recordOptions{Topics: []string{"orders*"}, Format: records.FormatAuto, MaxBytes: 10},
+		topics:   &topicRegistry{},
+		requests: make(map[int32]*inspectedRequest),
+	}
+	handler := slog.NewTextHandler(output, &slog.HandlerOptions{Level: slog.LevelDebug})
+	inspector.logger = slog.New(&rpcLogHandler{Handler: handler, inspector: inspector})
+
+	return inspector
+}
+
+func TestConnectionInspectorLogsProducedRecords(t *testing.T) {
+	var output bytes.Buffer
+	inspector := newRecordInspector(&output, rpcFilter{})
+
+	inspector.Request(produceRequest(7, map[string][]byte{
+		"orders":   testRecordBatch(0, "order-1", `{"amount": 100}`),
+		"payments": testRecordBatch(0, "payment-1", "paid"),
+	}))
+	inspector.Close()
+
+	lines := strings.Split(strings.TrimSpace(output.String()), "\n")
+	require.Len(t, lines, 1)
+	assert.Contains(t, lines[0], `msg="Decoded record" api=Produce correlationId=7 topic=orders partition=0`)
+	assert.Contains(t, lines[0], "timestamp=2023-11-14T22:13:20.000Z compression=none key=order-1")
+	assert.Contains(t, lines[0], `value="{\"amount\":... (15 bytes)" headers="[trace=abc]"`)
+	assert.NotContains(t, lines[0], "offset=")
+}
+
+func TestConnectionInspectorLogsFetchedRecords(t *testing.T) {
+	var output bytes.Buffer
+	inspector := newRecordInspector(&output, rpcFilter{ClientIDs: []string{"my-consumer"}})
+
+	inspector.Request(testRequest(protocol.Fetch, 4, 8, "my-consumer"))
+	inspector.Response(fetchResponse(8, "orders", testRecordBatch(42, "order-1", "created")))
+
+	// The records of the RPCs which are not logged are not logged either
+	inspector.Request(testRequest(protocol.Fetch, 4, 9, "other-consumer"))
+	inspector.Response(fetchResponse(9, "orders", testRecordBatch(43, "order-2", "created")))
+	inspector.Close()
+
+	lines := strings.Split(strings.TrimSpace(output.String()), "\n")
+	require.Len(t, lines, 1)
+	assert.Contains(t, lines[0], "api=Fetch correlationId=8 topic=orders partition=3 offset=42")
+	assert.Contains(t, lines[0], "key=order-1 value=created")
+}
+
+func TestConnectionInspectorLogsMalformedRecords(t *testing.T) {
+	var output bytes.Buffer
+	inspector := newRecordInspector(&output, rpcFilter{})
+
+	batch := testRecordBatch(0, "order-1", "created")
+	batch[16] = 1 // Magic
+
+	inspector.Request(produceRequest(7, map[string][]byte{"orders": batch}))
+	inspector.Close()
+	assert.Contains(t, output.String(), `msg="Failed to decode the records" api=Produce correlationId=7 topic=orders partition=0 error="record batches with magic 1 are not supported"`)
+}
+
+func TestInspectConnectionForRecords(t *testing.T) {
+	k := &Kekspose{RecordTopics: []string{"orders"}, RecordFormat: records.FormatHex, RecordMaxBytes: 16}
+	e := &exposure{Keks: &keks2.Keks{}, PortMapping: map[int32]uint32{0: 50000}}
+
+	engine, inspector := k.inspectConnection(slog.Default(), e)
+	assert.NotNil(t, engine)
+	require.NotNil(t, inspector)
+	assert.False(t, inspector.(*connectionInspector).filter.active())
+	assert.Equal(t, recordOptions{Topics: []string{"orders"}, Format: records.FormatHex, MaxBytes: 16}, inspector.(*connectionInspector).records)
+}
+
+func TestConnectionInspectorSkipsRecordsWithoutDebug(t *testing.T) {
+	var output bytes.Buffer
+	inspector := newRecordInspector(&output, rpcFilter{})
+	inspector.logger = slog.New(&rpcLogHandler{Handler: slog.NewTextHandler(&output, nil), inspector: inspector})
+
+	assert.False(t, inspector.decodesRecords())
+	inspector.Request(produceRequest(7, map[string][]byte{"orders": testRecordBatch(0, "order-1", "created")}))
+	inspector.Close()
+	assert.Empty(t, output.String())
+}
+
+func TestConnectionInspectorDecodesRecordsWithSchemas(t *testing.T) {
+	registry := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/schemas/ids/1" {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"error_code":40403,"message":"Schema not found"}`))
+			return
+		}
+
+		_, _ = w.Write([]byte(`{"schema":"{\"type\":\"record\",\"name\":\"Order\",\"fields\":[{\"name\":\"amount\",\"type\":\"long\"}]}"}`))
+	}))
+	defer registry.Close()
+
+	k := &Kekspose{SchemaRegistryURL: registry.URL}
+	require.NoError(t, k.connectSchemaRegistry(nil))
+
+	var output bytes.Buffer
+	inspector := newRecordInspector(&output, rpcFilter{})
+	inspector.records.MaxBytes = 0
+	inspector.records.Schemas = k.schemas
+
+	inspector.Request(produceRequest(7, map[string][]byte{"orders": testRecordBatch(0, "order-1", "\x00\x00\x00\x00\x01\xc8\x01")}))
+	inspector.Request(produceRequest(8, map[string][]byte{"orders": testRecordBatch(0, "order-2", "\x00\x00\x00\x00\x02\xc8\x01")}))
+	inspector.Close()
+
+	lines := strings.Split(strings.TrimSpace(output.String()), "\n")
+	require.Len(t, lines, 2)
+	assert.Contains(t, lines[0], `key=order-1 valueSchemaId=1 value="{\"amount\":100}"`)
+	assert.Contains(t, lines[1], `key=order-2 valueSchemaId=2 value=0000000002c801 valueSchemaError="failed to get schema 2: schema registry responded with 404 Not Found: Schema not found"`)
+}
+
+func TestConnectSchemaRegistry(t *testing.T) {
+	k := &Kekspose{}
+	require.NoError(t, k.connectSchemaRegistry(nil))
+	assert.Nil(t, k.schemas)
+
+	k = &Kekspose{SchemaRegistrySelector: "app=registry"}
+	require.NoError(t, k.connectSchemaRegistry([]*endpointExposure{{Endpoint: keks2.Endpoint{Kind: keks2.SchemaRegistryKind, Scheme: "http"}, LocalPort: 50010}}))
+	assert.NotNil(t, k.schemas)
+
+	k = &Kekspose{SchemaRegistryURL: "localhost:8081"}
+	assert.ErrorContains(t, k.connectSchemaRegistry(nil), "use an http or https URL")
+}
@@ -0,0 +1,161 @@
+/*
+Copyright © 2025 Jakub Scholz
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package records decodes the Kafka record batches from the Produce requests and the Fetch responses,
+// including the compressed ones, and renders the keys, values, and headers of the records for logging.
+package records
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/scholzj/kekspose/pkg/kekspose/protocol"
+)
+
+const (
+	// batchOverhead is the size of the record batch fields before the records, including the base
+	// offset and the batch length
+	batchOverhead = 61
+
+	// batchHeader is the size of the record batch fields following the batch length
+	batchHeader = batchOverhead - 12
+)
+
+// Batch is a decoded record batch.
+type Batch struct {
+	BaseOffset    int64
+	Compression   Compression
+	LogAppendTime bool
+	Transactional bool
+	Control       bool
+	ProducerID    int64
+	ProducerEpoch int16
+	Records       []Record
+}
+
+// Record is a record of a batch. The key and the values share the data of the batch.
+type Record struct {
+	Offset    int64
+	Timestamp time.Time
+	Key       []byte
+	Value     []byte
+	Headers   []Header
+}
+
+// Header is a record header.
+type Header struct {
+	Key   string
+	Value []byte
+}
+
+// ReadBatches decodes the record batches. The Fetch responses can end with a partial batch, so an
+// incomplete batch at the end is ignored. When a batch cannot be decoded, the batches decoded before
+// it are returned together with the error.
+func ReadBatches(data []byte) ([]Batch, error) {
+	var batches []Batch
+
+	for len(data) >= batchOverhead {
+		r := protocol.NewReader(data)
+		baseOffset := r.Int64()
+		length := int(r.Int32())
+
+		if length < batchHeader {
+			return batches, protocol.ErrMalformed
+		}
+		if length > len(data)-12 {
+			break
+		}
+
+		batch, err := readBatch(baseOffset, data[12:12+length])
+		if err != nil {
+			return batches, err
+		}
+
+		batches = append(batches, batch)
+		data = data[12+length:]
+	}
+
+	return batches, nil
+}
+
+// readBatch decodes one batch following its length.
+func readBatch(baseOffset int64, data []byte) (Batch, error) {
+	r := protocol.NewReader(data)
+	r.Int32() // Partition leader epoch
+	if magic := r.Int8(); magic != 2 {
+		return Batch{}, fmt.Errorf("record batches with magic %d are not supported", magic)
+	}
+	r.Int32() // CRC
+
+	attributes := r.Int16()
+	batch := Batch{
+		BaseOffset:    baseOffset,
+		Compression:   Compression(attributes & 0x07),
+		LogAppendTime: attributes&0x08 != 0,
+		Transactional: attributes&0x10 != 0,
+		Control:       attributes&0x20 != 0,
+	}
+
+	r.Int32() // Last offset delta
+	baseTimestamp := r.Int64()
+	maxTimestamp := r.Int64()
+	batch.ProducerID = r.Int64()
+	batch.ProducerEpoch = r.Int16()
+	r.Int32() // Base sequence
+	count := int(r.Int32())
+
+	records, err := decompress(batch.Compression, data[batchHeader:])
+	if err != nil {
+		return Batch{}, err
+	}
+
+	r = protocol.NewReader(records)
+	batch.Records = make([]Record, 0, min(max(count, 0), len(records)))
+	for i := 0; i < count; i++ {
+		record, err := readRecord(protocol.NewReader(r.VarintBytes()), baseOffset, baseTimestamp)
+		if err != nil || r.Err() != nil {
+			return Batch{}, protocol.ErrMalformed
+		}
+
+		if batch.LogAppendTime {
+			record.Timestamp = time.UnixMilli(maxTimestamp)
+		}
+		batch.Records = append(batch.Records, record)
+	}
+
+	return batch, nil
+}
+
+// readRecord decodes a record.
+func readRecord(r *protocol.Reader, baseOffset int64, baseTimestamp int64) (Record, error) {
+	r.Int8() // Attributes
+	record := Record{Timestamp: time.UnixMilli(baseTimestamp + r.Varint())}
+	record.Offset = baseOffset + r.Varint()
+	record.Key = r.VarintBytes()
+	record.Value = r.VarintBytes()
+
+	headers := int(r.Varint())
+	if headers > r.Remaining() {
+		return Record{}, protocol.ErrMalformed
+	}
+
+	for i := 0; i < headers; i++ {
+		key := string(r.VarintBytes())
+		record.Headers = append(record.Headers, Header{Key: key, Value: r.VarintBytes()})
+	}
+
+	return record, r.Err()
+}
@@ -0,0 +1,153 @@
+package records
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testTimestamp = time.UnixMilli(1700000000000)
+
+func appendVarintBytes(data []byte, v []byte) []byte {
+	if v == nil {
+		return binary.AppendVarint(data, -1)
+	}
+
+	data = binary.AppendVarint(data, int64(len(v)))
+	return append(data, v...)
+}
+
+// testBatch encodes the records into a batch. The offsets and timestamps of the records are encoded as
+// deltas from the first record.
+func testBatch(t *testing.T, attributes int16, records ...Record) []byte {
+	t.Helper()
+
+	var payload []byte
+	for _, record := range records {
+		var r []byte
+		r = append(r, 0)
+		r = binary.AppendVarint(r, record.Timestamp.UnixMilli()-records[0].Timestamp.UnixMilli())
+		r = binary.AppendVarint(r, record.Offset-records[0].Offset)
+		r = appendVarintBytes(r, record.Key)
+		r = appendVarintBytes(r, record.Value)
+		r = binary.AppendVarint(r, int64(len(record.Headers)))
+		for _, header := range record.Headers {
+			r = appendVarintBytes(r, []byte(header.Key))
+			r = appendVarintBytes(r, header.Value)
+		}
+
+		payload = binary.AppendVarint(payload, int64(len(r)))
+		payload = append(payload, r...)
+	}
+
+	if Compression(attributes&0x07) == CompressionGzip {
+		var compressed bytes.Buffer
+		w := gzip.NewWriter(&compressed)
+		_, _ = w.Write(payload)
+		require.NoError(t, w.Close())
+		payload = compressed.Bytes()
+	}
+
+	header := binary.BigEndian.AppendUint32(nil, 0) // Partition leader epoch
+	header = append(header, 2)
+	header = binary.BigEndian.AppendUint32(header, 0) // CRC
+	header = binary.BigEndian.AppendUint16(header, uint16(attributes))
+	header = binary.BigEndian.AppendUint32(header, uint32(len(records)-1))
+	header = binary.BigEndian.AppendUint64(header, uint64(records[0].Timestamp.UnixMilli()))
+	header = binary.BigEndian.AppendUint64(header, uint64(records[len(records)-1].Timestamp.UnixMilli()))
+	header = binary.BigEndian.AppendUint64(header, 1000) // Producer ID
+	header = binary.BigEndian.AppendUint16(header, 3)
+	header = binary.BigEndian.AppendUint32(header, 0) // Base sequence
+	header = binary.BigEndian.AppendUint32(header, uint32(len(records)))
+
+	batch := binary.BigEndian.AppendUint64(nil, uint64(records[0].Offset))
+	batch = binary.BigEndian.AppendUint32(batch, uint32(len(header)+len(payload)))
+	batch = append(batch, header...)
+	return append(batch, payload...)
+}
+
+func testRecords() []Record {
+	return []Record{
+		{Offset: 100, Timestamp: testTimestamp, Key: []byte("key-1"), Value: []byte(`{"id":1}`), Headers: []Header{{Key: "trace", Value: []byte("abc")}}},
+		{Offset: 101, Timestamp: testTimestamp.Add(time.Second), Value: []byte("second")},
+	}
+}
+
+func TestReadBatches(t *testing.T) {
+	data := append(testBatch(t, 0, testRecords()...), testBatch(t, int16(CompressionGzip)|0x10, testRecords()[1])...)
+
+	batches, err := ReadBatches(data)
+	require.NoError(t, err)
+	require.Len(t, batches, 2)
+
+	assert.Equal(t, int64(100), batches[0].BaseOffset)
+	assert.Equal(t, CompressionNone, batches[0].Compression)
+	assert.False(t, batches[0].Transactional)
+	assert.Equal(t, int64(1000), batches[0].ProducerID)
+	assert.Equal(t, int16(3), batches[0].ProducerEpoch)
+	require.Len(t, batches[0].Records, 2)
+	assert.Equal(t, testRecords()[0].Key, batches[0].Records[0].Key)
+	assert.Equal(t, testRecords()[0].Value, batches[0].Records[0].Value)
+	assert.Equal(t, testRecords()[0].Headers, batches[0].Records[0].Headers)
+	assert.True(t, testTimestamp.Equal(batches[0].Records[0].Timestamp))
+	assert.Nil(t, batches[0].Records[1].Key)
+	assert.Equal(t, int64(101), batches[0].Records[1].Offset)
+	assert.True(t, testTimestamp.Add(time.Second).Equal(batches[0].Records[1].Timestamp))
+
+	assert.Equal(t, int64(101), batches[1].BaseOffset)
+	assert.Equal(t, CompressionGzip, batches[1].Compression)
+	assert.True(t, batches[1].Transactional)
+	require.Len(t, batches[1].Records, 1)
+	assert.Equal(t, []byte("second"), batches[1].Records[0].Value)
+}
+
+func TestReadBatchesLogAppendTime(t *testing.T) {
+	records := testRecords()
+	records[1].Timestamp = testTimestamp.Add(time.Minute)
+
+	batches, err := ReadBatches(testBatch(t, 0x08, records...))
+	require.NoError(t, err)
+	require.Len(t, batches, 1)
+	assert.True(t, batches[0].LogAppendTime)
+	assert.True(t, testTimestamp.Add(time.Minute).Equal(batches[0].Records[0].Timestamp))
+}
+
+func TestReadBatchesPartial(t *testing.T) {
+	first := testBatch(t, 0, testRecords()...)
+	data := append(bytes.Clone(first), testBatch(t, 0, testRecords()...)...)
+
+	// The Fetch responses can end with a partial batch
+	batches, err := ReadBatches(data[:len(data)-5])
+	require.NoError(t, err)
+	assert.Len(t, batches, 1)
+
+	batches, err = ReadBatches(data[:len(first)+20])
+	require.NoError(t, err)
+	assert.Len(t, batches, 1)
+}
+
+func TestReadBatchesMalformed(t *testing.T) {
+	data := testBatch(t, 0, testRecords()...)
+
+	legacy := bytes.Clone(data)
+	legacy[16] = 1
+	_, err := ReadBatches(legacy)
+	assert.ErrorContains(t, err, "record batches with magic 1 are not supported")
+
+	// More records than the batch contains
+	truncated := bytes.Clone(data)
+	truncated[60] = 3
+	_, err = ReadBatches(truncated)
+	assert.Error(t, err)
+
+	compressed := bytes.Clone(data)
+	compressed[22] = byte(CompressionZstd)
+	batches, err := ReadBatches(append(testBatch(t, 0, testRecords()...), compressed...))
+	assert.ErrorContains(t, err, "failed to decompress the zstd records")
+	assert.Len(t, batches, 1)
+}
@@ -0,0 +1,188 @@
+/*
+Copyright © 2025 Jakub Scholz
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package records
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/klauspost/compress/snappy"
+	"github.com/klauspost/compress/zstd"
+	"github.com/pierrec/lz4/v4"
+)
+
+// maxDecompressedSize limits the size of the decompressed records of one batch, so that a malformed or
+// malicious batch cannot exhaust the memory.
+const maxDecompressedSize = 64 << 20
+
+var errTooLarge = errors.New("decompressed records exceed the size limit")
+
+// snappyXerialMagic starts the snappy data framed by the Java clients with the snappy-java library.
+var snappyXerialMagic = []byte{0x82, 'S', 'N', 'A', 'P', 'P', 'Y', 0}
+
+var errSnappyCorrupted = errors.New("corrupted snappy data")
+
+// Compression is the compression codec of the records in a batch.
+type Compression int8
+
+const (
+	CompressionNone Compression = iota
+	CompressionGzip
+	CompressionSnappy
+	CompressionLZ4
+	CompressionZstd
+)
+
+func (c Compression) String() string {
+	switch c {
+	case CompressionNone:
+		return "none"
+	case CompressionGzip:
+		return "gzip"
+	case CompressionSnappy:
+		return "snappy"
+	case CompressionLZ4:
+		return "lz4"
+	case CompressionZstd:
+		return "zstd"
+	default:
+		return fmt.Sprintf("unknown(%d)", int8(c))
+	}
+}
+
+// decompress decompresses the records of a batch.
+func decompress(compression Compression, data []byte) ([]byte, error) {
+	var out []byte
+	var err error
+
+	switch compression {
+	case CompressionNone:
+		return data, nil
+	case CompressionGzip:
+		out, err = decompressGzip(data, maxDecompressedSize)
+	case CompressionSnappy:
+		out, err = decompressSnappy(data, maxDecompressedSize)
+	case CompressionLZ4:
+		out, err = decompressLZ4(data, maxDecompressedSize)
+	case CompressionZstd:
+		out, err = decompressZstd(data, maxDecompressedSize)
+	default:
+		return nil, fmt.Errorf("unsupported compression %s", compression)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress the %s records: %w", compression, err)
+	}
+
+	return out, nil
+}
+
+func decompressGzip(src []byte, limit int) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(src))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	return readLimited(r, limit)
+}
+
+func decompressLZ4(src []byte, limit int) ([]byte, error) {
+	return readLimited(lz4.NewReader(bytes.NewReader(src)), limit)
+}
+
+// decompressZstd decompresses the zstd frames. Kafka does not use dictionaries.
+func decompressZstd(src []byte, limit int) ([]byte, error) {
+	r, err := zstd.NewReader(bytes.NewReader(src), zstd.WithDecoderConcurrency(1))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	return readLimited(r, limit)
+}
+
+// decompressSnappy decompresses the snappy data. The Java clients split it into blocks with the xerial
+// framing, while the other clients use a single raw block.
+func decompressSnappy(src []byte, limit int) ([]byte, error) {
+	if !bytes.HasPrefix(src, snappyXerialMagic) {
+		return decodeSnappyBlock(nil, src, limit)
+	}
+
+	// Magic, version, and compatible version
+	if len(src) < 16 {
+		return nil, errSnappyCorrupted
+	}
+	src = src[16:]
+
+	var out []byte
+	for len(src) > 0 {
+		if len(src) < 4 {
+			return nil, errSnappyCorrupted
+		}
+
+		size := int(binary.BigEndian.Uint32(src))
+		src = src[4:]
+		if size > len(src) {
+			return nil, errSnappyCorrupted
+		}
+
+		var err error
+		if out, err = decodeSnappyBlock(out, src[:size], limit); err != nil {
+			return nil, err
+		}
+		src = src[size:]
+	}
+
+	return out, nil
+}
+
+// decodeSnappyBlock decodes a raw snappy block and appends it to the output.
+func decodeSnappyBlock(out []byte, src []byte, limit int) ([]byte, error) {
+	length, err := snappy.DecodedLen(src)
+	if err != nil {
+		return nil, err
+	}
+	if length > limit-len(out) {
+		return nil, errTooLarge
+	}
+
+	block, err := snappy.Decode(nil, src)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(out, block...), nil
+}
+
+// readLimited reads the decompressed data, but at most the limit.
+func readLimited(r io.Reader, limit int) ([]byte, error) {
+	out, err := io.ReadAll(io.LimitReader(r, int64(limit)+1))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(out) > limit {
+		return nil, errTooLarge
+	}
+
+	return out, nil
+}
@@ -0,0 +1,126 @@
+package records
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/klauspost/compress/snappy"
+	"github.com/klauspost/compress/zstd"
+	"github.com/pierrec/lz4/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testData returns the data used in the compression tests: JSON lines, followed by random bytes, and the
+// same JSON lines again.
+func testData() []byte {
+	var lines bytes.Buffer
+	for i := 0; i < 800; i++ {
+		_, _ = fmt.Fprintf(&lines, "{\"id\":%d,\"name\":\"record-%d\",\"value\":%d}\n", i, i%97, i*i%1009)
+	}
+
+	data := bytes.Clone(lines.Bytes())
+	x := uint32(1)
+	for i := 0; i < 2000; i++ {
+		x = (x*1103515245 + 12345) & 0x7fffffff
+		data = append(data, byte(x>>16))
+	}
+
+	return append(data, lines.Bytes()...)
+}
+
+// compress compresses the test data with the writer.
+func compress(t *testing.T, newWriter func(w io.Writer) io.WriteCloser) []byte {
+	t.Helper()
+
+	var compressed bytes.Buffer
+	w := newWriter(&compressed)
+	_, err := w.Write(testData())
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	return compressed.Bytes()
+}
+
+func zstdTestData(t *testing.T) []byte {
+	return compress(t, func(w io.Writer) io.WriteCloser {
+		zw, err := zstd.NewWriter(w)
+		require.NoError(t, err)
+		return zw
+	})
+}
+
+func lz4TestData(t *testing.T) []byte {
+	return compress(t, func(w io.Writer) io.WriteCloser {
+		return lz4.NewWriter(w)
+	})
+}
+
+func TestDecompressZstd(t *testing.T) {
+	out, err := decompress(CompressionZstd, zstdTestData(t))
+	require.NoError(t, err)
+	assert.Equal(t, testData(), out)
+}
+
+func TestDecompressLZ4(t *testing.T) {
+	out, err := decompress(CompressionLZ4, lz4TestData(t))
+	require.NoError(t, err)
+	assert.Equal(t, testData(), out)
+}
+
+func TestDecompressGzip(t *testing.T) {
+	out, err := decompress(CompressionGzip, compress(t, func(w io.Writer) io.WriteCloser {
+		return gzip.NewWriter(w)
+	}))
+	require.NoError(t, err)
+	assert.Equal(t, testData(), out)
+}
+
+var testSnappyBlock = snappy.Encode(nil, []byte("abcabcabcabcd"))
+
+func TestDecompressSnappy(t *testing.T) {
+	out, err := decompress(CompressionSnappy, testSnappyBlock)
+	require.NoError(t, err)
+	assert.Equal(t, "abcabcabcabcd", string(out))
+
+	framed := append(bytes.Clone(snappyXerialMagic), 0, 0, 0, 1, 0, 0, 0, 1)
+	for i := 0; i < 2; i++ {
+		framed = append(framed, 0, 0, 0, byte(len(testSnappyBlock)))
+		framed = append(framed, testSnappyBlock...)
+	}
+
+	out, err = decompress(CompressionSnappy, framed)
+	require.NoError(t, err)
+	assert.Equal(t, "abcabcabcabcdabcabcabcabcd", string(out))
+}
+
+func TestDecompressMalformed(t *testing.T) {
+	data := zstdTestData(t)
+
+	_, err := decompress(CompressionZstd, data[:len(data)/2])
+	assert.Error(t, err)
+
+	_, err = decompress(CompressionLZ4, lz4TestData(t)[:100])
+	assert.Error(t, err)
+
+	// Copy from before the start of the data
+	_, err = decompress(CompressionSnappy, []byte{13, 0x08, 'a', 'b', 'c', 0x15, 9, 0x00, 'd'})
+	assert.Error(t, err)
+
+	_, err = decompress(Compression(6), []byte{1})
+	assert.ErrorContains(t, err, "unsupported compression unknown(6)")
+}
+
+func TestDecompressLimit(t *testing.T) {
+	_, err := decompressZstd(zstdTestData(t), 1000)
+	assert.ErrorIs(t, err, errTooLarge)
+
+	_, err = decompressLZ4(lz4TestData(t), 1000)
+	assert.ErrorIs(t, err, errTooLarge)
+
+	_, err = decompressSnappy(testSnappyBlock, 10)
+	assert.ErrorIs(t, err, errTooLarge)
+}
@@ -0,0 +1,139 @@
+/*
+Copyright © 2025 Jakub Scholz
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package records
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Format is the format used to render the keys, values, and headers of the records.
+type Format string
+
+const (
+	// FormatAuto renders JSON objects and arrays as compact JSON, other printable UTF-8 data as text, and
+	// the rest as hex.
+	FormatAuto Format = "auto"
+	// FormatText renders the data as UTF-8 text. Invalid UTF-8 sequences are replaced.
+	FormatText Format = "text"
+	// FormatJSON renders valid JSON as compact JSON and the rest the same way as FormatAuto.
+	FormatJSON Format = "json"
+	// FormatHex renders the data as hex.
+	FormatHex Format = "hex"
+)
+
+// ParseFormat returns the format with the given name.
+func ParseFormat(name string) (Format, error) {
+	switch format := Format(strings.ToLower(name)); format {
+	case FormatAuto, FormatText, FormatJSON, FormatHex:
+		return format, nil
+	default:
+		return "", fmt.Errorf("unknown format %q (use auto, text, json, or hex)", name)
+	}
+}
+
+// Render renders the data in the format. When maxBytes is positive, the rendered data is truncated to
+// about maxBytes bytes and suffixed with the original size. Null is rendered as <null>.
+func Render(data []byte, format Format, maxBytes int) string {
+	if data == nil {
+		return "<null>"
+	}
+
+	switch format {
+	case FormatText:
+		return truncate(strings.ToValidUTF8(string(data), "\uFFFD"), maxBytes, len(data))
+	case FormatHex:
+		return renderHex(data, maxBytes)
+	case FormatJSON:
+		if compact, ok := compactJSON(data, false); ok {
+			return truncate(compact, maxBytes, len(data))
+		}
+	default:
+		if compact, ok := compactJSON(data, true); ok {
+			return truncate(compact, maxBytes, len(data))
+		}
+	}
+
+	if printable(data) {
+		return truncate(string(data), maxBytes, len(data))
+	}
+
+	return renderHex(data, maxBytes)
+}
+
+// compactJSON returns the data as compact JSON when it is valid JSON. With structured, only the objects
+// and arrays are accepted, so that numbers or words are not mistaken for JSON.
+func compactJSON(data []byte, structured bool) (string, bool) {
+	trimmed := bytes.TrimSpace(data)
+	if structured && (len(trimmed) == 0 || (trimmed[0] != '{' && trimmed[0] != '[')) {
+		return "", false
+	}
+
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, trimmed); err != nil {
+		return "", false
+	}
+
+	return compact.String(), true
+}
+
+// printable returns true when the data is valid UTF-8 without control characters other than
+// whitespace.
+func printable(data []byte) bool {
+	if !utf8.Valid(data) {
+		return false
+	}
+
+	for _, r := range string(data) {
+		if !unicode.IsPrint(r) && !unicode.IsSpace(r) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func renderHex(data []byte, maxBytes int) string {
+	if maxBytes > 0 && len(data) > maxBytes {
+		return hex.EncodeToString(data[:maxBytes]) + truncatedSuffix(len(data))
+	}
+
+	return hex.EncodeToString(data)
+}
+
+// truncate cuts the rendered data to at most maxBytes bytes without splitting a UTF-8 character.
+func truncate(rendered string, maxBytes int, size int) string {
+	if maxBytes <= 0 || len(rendered) <= maxBytes {
+		return rendered
+	}
+
+	cut := maxBytes
+	for cut > 0 && !utf8.RuneStart(rendered[cut]) {
+		cut--
+	}
+
+	return rendered[:cut] + truncatedSuffix(size)
+}
+
+func truncatedSuffix(size int) string {
+	return fmt.Sprintf("... (%d bytes)", size)
+}
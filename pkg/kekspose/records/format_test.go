@@ -0,0 +1,49 @@
+package records
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseFormat(t *testing.T) {
+	format, err := ParseFormat("JSON")
+	require.NoError(t, err)
+	assert.Equal(t, FormatJSON, format)
+
+	_, err = ParseFormat("avro")
+	assert.ErrorContains(t, err, `unknown format "avro"`)
+}
+
+func TestRender(t *testing.T) {
+	json := []byte("{ \"id\": 1,\n \"name\": \"kafka\" }")
+	binary := []byte{0, 1, 0xfe, 0xff}
+
+	assert.Equal(t, "<null>", Render(nil, FormatAuto, 0))
+	assert.Equal(t, "", Render([]byte{}, FormatAuto, 0))
+
+	assert.Equal(t, `{"id":1,"name":"kafka"}`, Render(json, FormatAuto, 0))
+	assert.Equal(t, "hello", Render([]byte("hello"), FormatAuto, 0))
+	assert.Equal(t, "42", Render([]byte("42"), FormatAuto, 0))
+	assert.Equal(t, "0001feff", Render(binary, FormatAuto, 0))
+
+	assert.Equal(t, `{"id":1,"name":"kafka"}`, Render(json, FormatJSON, 0))
+	assert.Equal(t, "hello", Render([]byte("hello"), FormatJSON, 0))
+	assert.Equal(t, "0001feff", Render(binary, FormatJSON, 0))
+
+	assert.Equal(t, string(json), Render(json, FormatText, 0))
+	assert.Equal(t, "\x00\x01�", Render(binary, FormatText, 0))
+
+	assert.Equal(t, "68656c6c6f", Render([]byte("hello"), FormatHex, 0))
+}
+
+func TestRenderTruncated(t *testing.T) {
+	assert.Equal(t, "hel... (5 bytes)", Render([]byte("hello"), FormatAuto, 3))
+	assert.Equal(t, "hello", Render([]byte("hello"), FormatAuto, 5))
+	assert.Equal(t, `{"id... (12 bytes)`, Render([]byte(`{ "id": 1 }`+"\n"), FormatAuto, 4))
+	assert.Equal(t, "6865... (5 bytes)", Render([]byte("hello"), FormatHex, 2))
+
+	// Multibyte characters are not split
+	assert.Equal(t, "keks... (6 bytes)", Render([]byte("keksé"), FormatText, 5))
+}
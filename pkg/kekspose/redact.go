@@ -0,0 +1,392 @@
+/*
+Copyright © 2025 Jakub Scholz
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kekspose
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/scholzj/go-kafka-protocol/messages"
+	"github.com/scholzj/kekspose/pkg/kekspose/protocol"
+)
+
+// The data which can be redacted in the RPC logs
+const (
+	// RedactValues redacts the values of the records
+	RedactValues = "values"
+	// RedactHeaders redacts the values of the record headers
+	RedactHeaders = "headers"
+	// RedactConfigs redacts the values of the sensitive configuration options, such as passwords and
+	// JAAS configurations, in the DescribeConfigs, AlterConfigs, and IncrementalAlterConfigs RPCs
+	RedactConfigs = "configs"
+)
+
+// redacted replaces the redacted data in the logs.
+const redacted = "[REDACTED]"
+
+// maxRedactedDepth limits how deep the redaction looks into the logged message bodies.
+const maxRedactedDepth = 32
+
+// sensitiveConfigs are the parts of the names of the sensitive configuration options.
+var sensitiveConfigs = []string{"password", "secret", "jaas", "credential", "token", "keystore.key"}
+
+// summaryAttrs are the attributes of the RPC log records which do not contain the message bodies. The
+// other attributes of the SaslAuthenticate RPCs are redacted.
+var summaryAttrs = []string{"cluster", "listener", "node", "api", "apiKey", "apiVersion", correlationIdKey, "clientId", "bodySize", "error"}
+
+// redactedAPIKeys are the APIs whose log records can contain redacted data.
+var redactedAPIKeys = []int16{protocol.Produce, protocol.Fetch, protocol.DescribeConfigs, protocol.AlterConfigs, protocol.SaslAuthenticate, protocol.IncrementalAlterConfigs}
+
+// redaction selects the data redacted in the RPC logs. The SASL authentication bytes are always
+// redacted.
+type redaction struct {
+	Values  bool
+	Headers bool
+	Configs bool
+}
+
+// ValidateRedaction checks the names of the data redacted in the RPC logs.
+func ValidateRedaction(names []string) error {
+	for _, name := range names {
+		switch name {
+		case RedactValues, RedactHeaders, RedactConfigs:
+		default:
+			return fmt.Errorf("unknown data %q (use %s, %s, or %s)", name, RedactValues, RedactHeaders, RedactConfigs)
+		}
+	}
+
+	return nil
+}
+
+// redaction returns the data redacted in the RPC logs.
+func (k *Kekspose) redaction() redaction {
+	return redaction{
+		Values:  slices.Contains(k.Redact, RedactValues),
+		Headers: slices.Contains(k.Redact, RedactHeaders),
+		Configs: slices.Contains(k.Redact, RedactConfigs),
+	}
+}
+
+// records returns true when the records in the message bodies are redacted. The record batches contain
+// both the values and the headers, so they are redacted as a whole.
+func (r redaction) records() bool {
+	return r.Values || r.Headers
+}
+
+// redactedBytes returns the replacement of the redacted data, which keeps their size.
+func redactedBytes(data []byte) string {
+	if data == nil {
+		return "<null>"
+	}
+
+	return redacted + " (" + strconv.Itoa(len(data)) + " bytes)"
+}
+
+// sensitiveConfig returns true when the configuration option contains secrets.
+func sensitiveConfig(name string) bool {
+	name = strings.ToLower(name)
+	for _, sensitive := range sensitiveConfigs {
+		if strings.Contains(name, sensitive) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// unclassifiedRecordWarning makes sure that the RPC log messages without a recognised API are reported
+// only once.
+var unclassifiedRecordWarning sync.Once
+
+// redactingHandler redacts the sensitive data in the message bodies logged by the proxy engine. The
+// RPCs are identified by the apiKey or api attributes of the log records. The redaction fails closed:
+// the debug and trace records without a recognised API keep only their summary, because they might
+// contain the SASL credentials, and the attributes of the redacted APIs which are not decoded message
+// bodies are redacted as a whole.
+type redactingHandler struct {
+	slog.Handler
+	redaction redaction
+	apiKey    *int16
+}
+
+func (h *redactingHandler) Handle(ctx context.Context, record slog.Record) error {
+	apiKey := h.apiKey
+	if apiKey == nil {
+		record.Attrs(func(attr slog.Attr) bool {
+			if key, ok := apiKeyAttr(attr); ok {
+				apiKey = &key
+				return false
+			}
+
+			return true
+		})
+	}
+
+	if apiKey == nil {
+		if record.Level > slog.LevelDebug {
+			return h.Handler.Handle(ctx, record)
+		}
+
+		unclassifiedRecordWarning.Do(func() {
+			slog.Warn("The message bodies in the RPC log messages without a recognised API cannot be redacted and are not logged", "message", record.Message)
+		})
+
+		return h.Handler.Handle(ctx, summaryRecord(record))
+	}
+
+	if !h.redacts(*apiKey) {
+		return h.Handler.Handle(ctx, record)
+	}
+
+	redactedRecord := slog.NewRecord(record.Time, record.Level, record.Message, record.PC)
+	record.Attrs(func(attr slog.Attr) bool {
+		redactedRecord.AddAttrs(h.redactAttr(*apiKey, attr))
+		return true
+	})
+
+	return h.Handler.Handle(ctx, redactedRecord)
+}
+
+func (h *redactingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	handler := &redactingHandler{Handler: h.Handler.WithAttrs(attrs), redaction: h.redaction, apiKey: h.apiKey}
+	for _, attr := range attrs {
+		if key, ok := apiKeyAttr(attr); ok {
+			handler.apiKey = &key
+		}
+	}
+
+	return handler
+}
+
+func (h *redactingHandler) WithGroup(name string) slog.Handler {
+	return &redactingHandler{Handler: h.Handler.WithGroup(name), redaction: h.redaction, apiKey: h.apiKey}
+}
+
+// summaryRecord returns the copy of the log record with only the summary attributes.
+func summaryRecord(record slog.Record) slog.Record {
+	summary := slog.NewRecord(record.Time, record.Level, record.Message, record.PC)
+	record.Attrs(func(attr slog.Attr) bool {
+		summary.AddAttrs(summaryAttr(attr))
+		return true
+	})
+
+	return summary
+}
+
+// summaryAttr returns the attribute when it is one of the summary attributes, and redacts it otherwise.
+func summaryAttr(attr slog.Attr) slog.Attr {
+	if slices.Contains(summaryAttrs, attr.Key) {
+		return attr
+	}
+
+	return slog.String(attr.Key, redacted)
+}
+
+// redacts returns true when the log records of the API can contain redacted data.
+func (h *redactingHandler) redacts(apiKey int16) bool {
+	switch apiKey {
+	case protocol.SaslAuthenticate:
+		return true
+	case protocol.DescribeConfigs, protocol.AlterConfigs, protocol.IncrementalAlterConfigs:
+		return h.redaction.Configs
+	case protocol.Produce, protocol.Fetch:
+		return h.redaction.records()
+	default:
+		return false
+	}
+}
+
+// redactAttr redacts the attribute of a log record of the API. The SaslAuthenticate RPCs keep only
+// their summary, because their whole bodies are about the credentials. The other attributes which are
+// not decoded message bodies, such as the bodies formatted as strings, cannot be looked into and keep
+// only the summary as well.
+func (h *redactingHandler) redactAttr(apiKey int16, attr slog.Attr) slog.Attr {
+	if apiKey == protocol.SaslAuthenticate {
+		return summaryAttr(attr)
+	}
+
+	value := attr.Value.Resolve()
+	if value.Kind() != slog.KindAny {
+		return summaryAttr(attr)
+	}
+
+	r := bodyRedactor{configs: h.redaction.Configs, records: h.redaction.records()}
+	if body, changed := r.redact(reflect.ValueOf(value.Any()), 0); changed {
+		return slog.Any(attr.Key, body.Interface())
+	}
+
+	return attr
+}
+
+// apiKeyAttr returns the API key when the attribute contains it.
+func apiKeyAttr(attr slog.Attr) (int16, bool) {
+	value := attr.Value.Resolve()
+
+	switch attr.Key {
+	case "apiKey":
+		switch value.Kind() {
+		case slog.KindInt64:
+			return int16(value.Int64()), true
+		case slog.KindUint64:
+			return int16(value.Uint64()), true
+		case slog.KindAny:
+			if key, ok := value.Any().(int16); ok {
+				return key, true
+			}
+		default:
+		}
+	case "api":
+		if value.Kind() == slog.KindString {
+			for _, key := range redactedAPIKeys {
+				if value.String() == messages.Name(key) {
+					return key, true
+				}
+			}
+		}
+	}
+
+	return 0, false
+}
+
+// bodyRedactor redacts the decoded message bodies. The configuration options are structs with the Name
+// and Value fields, and the record batches are the Records fields. The bodies are copied only when
+// something is redacted, so that the logged messages are not modified.
+type bodyRedactor struct {
+	configs bool
+	records bool
+}
+
+func (r bodyRedactor) redact(v reflect.Value, depth int) (reflect.Value, bool) {
+	if !v.IsValid() || depth > maxRedactedDepth {
+		return v, false
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			return v, false
+		}
+
+		elem, changed := r.redact(v.Elem(), depth+1)
+		if !changed {
+			return v, false
+		}
+
+		copied := reflect.New(v.Type().Elem())
+		copied.Elem().Set(elem)
+		return copied, true
+	case reflect.Interface:
+		if v.IsNil() {
+			return v, false
+		}
+
+		elem, changed := r.redact(v.Elem(), depth+1)
+		if !changed {
+			return v, false
+		}
+
+		copied := reflect.New(v.Type()).Elem()
+		copied.Set(elem)
+		return copied, true
+	case reflect.Slice, reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return v, false
+		}
+
+		var copied reflect.Value
+		for i := range v.Len() {
+			item, changed := r.redact(v.Index(i), depth+1)
+			if !changed {
+				continue
+			}
+
+			if !copied.IsValid() {
+				copied = reflect.New(v.Type()).Elem()
+				if v.Kind() == reflect.Slice {
+					copied.Set(reflect.MakeSlice(v.Type(), v.Len(), v.Len()))
+				}
+				reflect.Copy(copied, v)
+			}
+			copied.Index(i).Set(item)
+		}
+
+		return copied, copied.IsValid()
+	case reflect.Struct:
+		return r.redactStruct(v, depth)
+	default:
+		return v, false
+	}
+}
+
+func (r bodyRedactor) redactStruct(v reflect.Value, depth int) (reflect.Value, bool) {
+	sensitive := false
+	if name := v.FieldByName("Name"); r.configs && name.IsValid() && name.Kind() == reflect.String {
+		sensitive = sensitiveConfig(name.String())
+	}
+
+	var copied reflect.Value
+	for i := range v.NumField() {
+		field := v.Type().Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		var value reflect.Value
+		var changed bool
+		switch {
+		case sensitive && field.Name == "Value":
+			value, changed = redactString(v.Field(i))
+		case r.records && field.Name == "Records" && v.Field(i).Kind() == reflect.Slice && field.Type.Elem().Kind() == reflect.Uint8:
+			// The dumped bodies show the records only as bytes, so they are dropped
+			value, changed = reflect.Zero(field.Type), v.Field(i).Len() > 0
+		default:
+			value, changed = r.redact(v.Field(i), depth+1)
+		}
+
+		if !changed {
+			continue
+		}
+
+		if !copied.IsValid() {
+			copied = reflect.New(v.Type()).Elem()
+			copied.Set(v)
+		}
+		copied.Field(i).Set(value)
+	}
+
+	return copied, copied.IsValid()
+}
+
+// redactString replaces the string or the non-null string pointer.
+func redactString(v reflect.Value) (reflect.Value, bool) {
+	switch {
+	case v.Kind() == reflect.String:
+		return reflect.ValueOf(redacted).Convert(v.Type()), true
+	case v.Kind() == reflect.Pointer && !v.IsNil() && v.Type().Elem().Kind() == reflect.String:
+		copied := reflect.New(v.Type().Elem())
+		copied.Elem().Set(reflect.ValueOf(redacted).Convert(v.Type().Elem()))
+		return copied, true
+	default:
+		return v, false
+	}
+}
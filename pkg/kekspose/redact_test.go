@@ -0,0 +1,143 @@
+package kekspose
+
+import (
+	"bytes"
+	"log/slog"
+	"reflect"
+	"testing"
+
+	"github.com/scholzj/kekspose/pkg/kekspose/protocol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// The bodies mimic the shape of the decoded Kafka messages
+type testConfig struct {
+	Name  string
+	Value *string
+}
+
+type testConfigResult struct {
+	ResourceName string
+	Configs      []testConfig
+}
+
+type testDescribeConfigsResponse struct {
+	Results []testConfigResult
+}
+
+type testPartitionData struct {
+	Index   int32
+	Records []byte
+}
+
+type testProduceRequest struct {
+	Partitions []*testPartitionData
+	internal   string
+}
+
+func newRedactingLogger(output *bytes.Buffer, r redaction) *slog.Logger {
+	return slog.New(&redactingHandler{Handler: slog.NewTextHandler(output, &slog.HandlerOptions{Level: slog.LevelDebug}), redaction: r})
+}
+
+func TestValidateRedaction(t *testing.T) {
+	assert.NoError(t, ValidateRedaction([]string{"values", "headers", "configs"}))
+	assert.ErrorContains(t, ValidateRedaction([]string{"passwords"}), `unknown data "passwords" (use values, headers, or configs)`)
+}
+
+func TestRedactingHandlerMasksSaslAuthenticate(t *testing.T) {
+	var output bytes.Buffer
+	logger := newRedactingLogger(&output, redaction{}).With("node", 0)
+
+	logger.Debug("-> request", "apiKey", protocol.SaslAuthenticate, "correlationId", 3, "bodySize", 26, "body", struct{ AuthBytes []byte }{[]byte("\x00user\x00s3cr3t")})
+
+	assert.Contains(t, output.String(), "node=0 apiKey=36 correlationId=3 bodySize=26 body=[REDACTED]")
+	assert.NotContains(t, output.String(), "s3cr3t")
+}
+
+func TestRedactingHandlerMasksSensitiveConfigs(t *testing.T) {
+	password := "s3cr3t"
+	jaas := `org.apache.kafka.common.security.plain.PlainLoginModule required password="s3cr3t";`
+	retention := "604800000"
+	response := &testDescribeConfigsResponse{Results: []testConfigResult{{ResourceName: "0", Configs: []testConfig{
+		{Name: "ssl.keystore.password", Value: &password},
+		{Name: "listener.name.scram.scram-sha-512.sasl.jaas.config", Value: &jaas},
+		{Name: "log.retention.ms", Value: &retention},
+		{Name: "ssl.key.password"},
+	}}}}
+
+	var output bytes.Buffer
+	logger := newRedactingLogger(&output, redaction{Configs: true}).With("apiKey", protocol.DescribeConfigs)
+	logger.Debug("<- response", "body", response)
+
+	assert.NotContains(t, output.String(), "s3cr3t")
+	assert.Contains(t, output.String(), "log.retention.ms")
+
+	// The logged message is not modified
+	assert.Equal(t, "s3cr3t", *response.Results[0].Configs[0].Value)
+
+	redactedBody, changed := bodyRedactor{configs: true}.redact(reflect.ValueOf(response), 0)
+	require.True(t, changed)
+	configs := redactedBody.Interface().(*testDescribeConfigsResponse).Results[0].Configs
+	assert.Equal(t, redacted, *configs[0].Value)
+	assert.Equal(t, redacted, *configs[1].Value)
+	assert.Equal(t, retention, *configs[2].Value)
+	assert.Nil(t, configs[3].Value)
+
+	// Without the configs redaction, the values are logged
+	_, changed = bodyRedactor{records: true}.redact(reflect.ValueOf(response), 0)
+	assert.False(t, changed)
+}
+
+func TestRedactingHandlerDropsRecords(t *testing.T) {
+	request := testProduceRequest{Partitions: []*testPartitionData{{Index: 1, Records: []byte("secret-value")}}, internal: "kept"}
+
+	var output bytes.Buffer
+	newRedactingLogger(&output, redaction{Headers: true}).Debug("-> request", "api", "Produce", "body", request)
+	assert.NotContains(t, output.String(), "secret-value")
+
+	redactedBody, changed := bodyRedactor{records: true}.redact(reflect.ValueOf(request), 0)
+	require.True(t, changed)
+	assert.Nil(t, redactedBody.Interface().(testProduceRequest).Partitions[0].Records)
+	assert.Equal(t, int32(1), redactedBody.Interface().(testProduceRequest).Partitions[0].Index)
+	assert.Equal(t, "kept", redactedBody.Interface().(testProduceRequest).internal)
+	assert.Equal(t, []byte("secret-value"), request.Partitions[0].Records)
+
+	// The other APIs are not redacted
+	output.Reset()
+	newRedactingLogger(&output, redaction{Values: true, Headers: true, Configs: true}).Debug("-> request", "apiKey", protocol.Metadata, "body", "secret-value")
+	assert.Contains(t, output.String(), "body=secret-value")
+}
+
+func TestConnectionInspectorRedactsRecords(t *testing.T) {
+	var output bytes.Buffer
+	inspector := newRecordInspector(&output, rpcFilter{})
+	inspector.records.Redaction = redaction{Values: true, Headers: true}
+
+	inspector.Request(produceRequest(7, map[string][]byte{"orders": testRecordBatch(0, "order-1", `{"card": "4111"}`)}))
+	inspector.Close()
+
+	assert.Contains(t, output.String(), `key=order-1 value="[REDACTED] (16 bytes)" headers="[trace=[REDACTED] (3 bytes)]"`)
+	assert.NotContains(t, output.String(), "4111")
+}
+
+func TestRedactingHandlerFailsClosed(t *testing.T) {
+	var output bytes.Buffer
+	logger := newRedactingLogger(&output, redaction{}).With("node", 0)
+
+	// Without a recognised API, the debug records keep only their summary
+	logger.Debug("-> request", "key", protocol.SaslAuthenticate, "correlationId", 3, "payload", struct{ AuthBytes []byte }{[]byte("\x00user\x00s3cr3t")}, "text", "s3cr3t")
+	assert.Contains(t, output.String(), "node=0 key=[REDACTED] correlationId=3 payload=[REDACTED] text=[REDACTED]")
+	assert.NotContains(t, output.String(), "s3cr3t")
+
+	// The other records are passed as they are
+	output.Reset()
+	logger.Info("connection closed", "remote", "127.0.0.1:9092")
+	assert.Contains(t, output.String(), "remote=127.0.0.1:9092")
+
+	// The bodies of the redacted APIs which are not decoded cannot be looked into
+	output.Reset()
+	newRedactingLogger(&output, redaction{Configs: true}).Debug("<- response", "apiKey", protocol.DescribeConfigs, "body", "ssl.keystore.password=s3cr3t")
+	assert.Contains(t, output.String(), "apiKey=32 body=[REDACTED]")
+	assert.NotContains(t, output.String(), "s3cr3t")
+}
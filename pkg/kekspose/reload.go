@@ -0,0 +1,228 @@
+/*
+Copyright © 2025 Jakub Scholz
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kekspose
+
+import (
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+
+	keks2 "github.com/scholzj/kekspose/pkg/kekspose/keks"
+)
+
+// nodeChanges are the nodes added to and removed from an exposed listener by reloading the discovery.
+// The added nodes are mapped to their new local ports.
+type nodeChanges struct {
+	ClusterName  string           `json:"clusterName"`
+	Namespace    string           `json:"namespace"`
+	ListenerName string           `json:"listenerName"`
+	Added        map[int32]uint32 `json:"added,omitempty"`
+	Removed      []int32          `json:"removed,omitempty"`
+}
+
+// reload discovers the nodes of the exposed Kafka clusters again. The added nodes get new local ports
+// following the ports already in use, and their port forwarding starts once their pods are ready. The
+// port forwarding of the removed nodes is stopped. The open client connections keep the old port
+// mapping until they reconnect.
+func (s *liveSession) reload() ([]nodeChanges, error) {
+	s.reloadLock.Lock()
+	defer s.reloadLock.Unlock()
+
+	found, err := s.findNodes()
+	if err != nil {
+		return nil, err
+	}
+
+	// Only the reload changes the exposures, so they can be read without the lock here
+	nextPort := s.maxUsedPort() + 1
+	changes := make([]nodeChanges, 0)
+	mappings := make([]map[int32]uint32, len(s.exposures))
+	addedNodes := make([][]int32, len(s.exposures))
+	removedPods := make(map[string]bool)
+
+	for i, e := range s.exposures {
+		added, removed := diffNodes(e.Keks.Nodes, found[i])
+		if len(added) == 0 && len(removed) == 0 {
+			continue
+		}
+
+		change := nodeChanges{ClusterName: e.Name(), Namespace: e.Namespace, ListenerName: e.Keks.ListenerName, Removed: removed}
+		mapping := maps.Clone(e.PortMapping)
+
+		for _, nodeId := range removed {
+			delete(mapping, nodeId)
+			removedPods[e.Namespace+"/"+e.Keks.Nodes[nodeId]] = true
+		}
+
+		for _, nodeId := range added {
+			port, err := findFreePortBlock(nextPort, 1)
+			if err != nil {
+				return nil, fmt.Errorf("failed to find a free local port for node %d: %w", nodeId, err)
+			}
+
+			if change.Added == nil {
+				change.Added = make(map[int32]uint32, len(added))
+			}
+			mapping[nodeId] = port
+			change.Added[nodeId] = port
+			nextPort = port + 1
+		}
+
+		mappings[i] = mapping
+		addedNodes[i] = added
+		changes = append(changes, change)
+		slog.Info("Kafka nodes changed", "clusterName", e.Name(), "namespace", e.Namespace, "listenerName", e.Keks.ListenerName, "added", change.Added, "removed", removed)
+	}
+
+	if len(changes) == 0 {
+		slog.Info("Reloaded the Kafka nodes, nothing changed")
+		return changes, nil
+	}
+
+	// Stop the port forwarding of the removed nodes and forget their engines, so that they are not
+	// rebuilt anymore
+	removedForwarders := slices.DeleteFunc(s.allForwarders(), func(pf *PortForwarder) bool {
+		return !removedPods[pf.Namespace+"/"+pf.PodName]
+	})
+	for _, pf := range removedForwarders {
+		s.stopForwarder(pf)
+
+		for _, port := range pf.ForwardedPorts() {
+			if port.engine != nil {
+				s.k.engines.remove(port.engine)
+			}
+		}
+	}
+
+	// Rebuild the proxy engines with the new port mappings, so that the new connections advertise the
+	// added nodes and stop advertising the removed ones
+	s.k.engines.update(func() {
+		for i, e := range s.exposures {
+			if mappings[i] == nil {
+				continue
+			}
+
+			keks := *e.Keks
+			keks.Nodes = found[i]
+			e.Keks = &keks
+			e.PortMapping = mappings[i]
+		}
+	})
+
+	forwarders := make([]*PortForwarder, 0)
+	for i, e := range s.exposures {
+		if len(addedNodes[i]) > 0 {
+			forwarders = append(forwarders, s.k.prepareNodeForwarders(s.kubeconfig, s.kubeclient, e, addedNodes[i], len(s.exposures) > 1)...)
+		}
+	}
+
+	forwarders = groupByPod(forwarders)
+	for _, pf := range forwarders {
+		s.k.configurePortForwarder(pf)
+	}
+
+	if len(forwarders) > 0 && s.addForwarders(forwarders) {
+		go s.k.startWhenReady(s.ctx, s.kubeclient, forwarders, s.start)
+	}
+
+	if s.k.SessionName != "" {
+		if err := s.saveSession(); err != nil {
+			return changes, err
+		}
+	}
+
+	return changes, nil
+}
+
+// findNodes finds the current nodes of each exposure the same way as when the port forwarding
+// started.
+func (s *liveSession) findNodes() ([]map[int32]string, error) {
+	found := make([]map[int32]string, len(s.exposures))
+
+	if s.k.PodSelector != "" {
+		for i, e := range s.exposures {
+			keks, err := keks2.BakeKeksFromPods(s.kubeclient, e.Namespace, s.k.PodSelector, s.k.PodPort, s.k.PodTLS, s.k.NodeIdRule, s.k.AllowUnready)
+			if err != nil {
+				return nil, fmt.Errorf("failed to find the Kafka pods: %w", err)
+			}
+
+			found[i] = keks.Nodes
+		}
+
+		return found, nil
+	}
+
+	kekses := make(map[clusterReference][]*keks2.Keks)
+	for i, e := range s.exposures {
+		if _, done := kekses[e.clusterReference]; !done {
+			listeners, err := keks2.BakeKeksForListeners(s.strimziclient, e.Namespace, e.ClusterName, s.k.ListenerNames, s.k.AllowUnready, s.k.AllowInsecureTLS)
+			if err != nil {
+				return nil, fmt.Errorf("failed to find the Kafka cluster with a suitable listener: %w", err)
+			}
+
+			if s.k.DiscoverNodes {
+				if err := s.k.discoverNodes(s.kubeconfig, s.kubeclient, e.clusterReference, listeners); err != nil {
+					return nil, err
+				}
+			}
+
+			kekses[e.clusterReference] = listeners
+		}
+
+		index := slices.IndexFunc(kekses[e.clusterReference], func(keks *keks2.Keks) bool { return keks.ListenerName == e.Keks.ListenerName })
+		if index < 0 {
+			return nil, fmt.Errorf("listener %s of Kafka cluster %s in namespace %s was not found", e.Keks.ListenerName, e.ClusterName, e.Namespace)
+		}
+
+		found[i] = kekses[e.clusterReference][index].Nodes
+	}
+
+	return found, nil
+}
+
+// maxUsedPort returns the highest local port used by the Kafka nodes and the HTTP APIs.
+func (s *liveSession) maxUsedPort() uint32 {
+	var highest uint32
+	for _, e := range s.exposures {
+		highest = max(highest, maxPort(e.PortMapping))
+	}
+
+	for _, e := range s.endpoints {
+		highest = max(highest, e.LocalPort, e.ForwardPort)
+	}
+
+	return highest
+}
+
+// diffNodes returns the sorted IDs of the nodes which were added and removed. Nodes which moved to a
+// different pod are both removed and added.
+func diffNodes(current map[int32]string, found map[int32]string) (added []int32, removed []int32) {
+	for _, nodeId := range sortedNodeIDs(found) {
+		if pod, known := current[nodeId]; !known || pod != found[nodeId] {
+			added = append(added, nodeId)
+		}
+	}
+
+	for _, nodeId := range sortedNodeIDs(current) {
+		if pod, known := found[nodeId]; !known || pod != current[nodeId] {
+			removed = append(removed, nodeId)
+		}
+	}
+
+	return added, removed
+}
@@ -0,0 +1,85 @@
+package kekspose
+
+import (
+	"testing"
+
+	keks2 "github.com/scholzj/kekspose/pkg/kekspose/keks"
+	"github.com/scholzj/proksy"
+	kafkav1 "github.com/scholzj/strimzi-go/pkg/apis/kafka.strimzi.io/v1"
+	strimzifake "github.com/scholzj/strimzi-go/pkg/client/clientset/versioned/fake"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffNodes(t *testing.T) {
+	current := map[int32]string{0: "my-cluster-pool-0", 1: "my-cluster-pool-1", 2: "my-cluster-pool-2"}
+
+	added, removed := diffNodes(current, current)
+	assert.Empty(t, added)
+	assert.Empty(t, removed)
+
+	added, removed = diffNodes(current, map[int32]string{0: "my-cluster-pool-0", 1: "my-cluster-pool-1", 2: "my-cluster-pool-2", 4: "my-cluster-pool-4", 3: "my-cluster-pool-3"})
+	assert.Equal(t, []int32{3, 4}, added)
+	assert.Empty(t, removed)
+
+	added, removed = diffNodes(current, map[int32]string{0: "my-cluster-pool-0"})
+	assert.Empty(t, added)
+	assert.Equal(t, []int32{1, 2}, removed)
+
+	// The node moved to another pod
+	added, removed = diffNodes(current, map[int32]string{0: "my-cluster-pool-0", 1: "my-cluster-other-1", 2: "my-cluster-pool-2"})
+	assert.Equal(t, []int32{1}, added)
+	assert.Equal(t, []int32{1}, removed)
+}
+
+func TestMaxUsedPort(t *testing.T) {
+	s := &liveSession{
+		exposures: []*exposure{
+			{Keks: &keks2.Keks{}, PortMapping: map[int32]uint32{0: 50000, 1: 50001}},
+			{Keks: &keks2.Keks{}, PortMapping: map[int32]uint32{0: 50010}},
+		},
+	}
+	assert.Equal(t, uint32(50010), s.maxUsedPort())
+
+	s.endpoints = []*endpointExposure{{LocalPort: 50011, ForwardPort: 50012}}
+	assert.Equal(t, uint32(50012), s.maxUsedPort())
+}
+
+func TestReloadRemovesNodes(t *testing.T) {
+	strimziclient := strimzifake.NewSimpleClientset()
+	createReadyKafka(t, strimziclient, "my-cluster", kafkav1.GenericKafkaListener{Name: "plain", Port: 9092})
+	createBrokerNodePool(t, strimziclient, "my-cluster", "pool-a", 0)
+
+	k := &Kekspose{}
+	e := &exposure{
+		clusterReference: clusterReference{Namespace: "my-namespace", ClusterName: "my-cluster"},
+		Keks:             &keks2.Keks{Nodes: map[int32]string{0: "my-cluster-pool-a-0", 1: "my-cluster-pool-a-1"}, Port: 9092, ListenerName: "plain"},
+		PortMapping:      map[int32]uint32{0: 50000, 1: 50001},
+	}
+
+	forwarders := make([]*PortForwarder, 0, 2)
+	for _, nodeId := range []int32{0, 1} {
+		engine := k.engines.add(func() *proksy.Engine { return proksy.NewEngine() })
+		forwarders = append(forwarders, &PortForwarder{
+			Namespace:     "my-namespace",
+			PodName:       e.Keks.Nodes[nodeId],
+			ForwardedPort: ForwardedPort{NodeId: nodeId, LocalPort: e.PortMapping[nodeId], RemotePort: 9092, ProxyFunc: engine.Load, engine: engine},
+			Stop:          make(chan struct{}),
+		})
+	}
+
+	s := &liveSession{k: k, strimziclient: strimziclient, exposures: []*exposure{e}}
+	s.addForwarders(forwarders)
+
+	changes, err := s.reload()
+
+	require.NoError(t, err)
+	require.Len(t, changes, 1)
+	assert.Equal(t, []int32{1}, changes[0].Removed)
+	assert.Equal(t, map[int32]uint32{0: 50000}, e.PortMapping)
+	assert.Equal(t, []*PortForwarder{forwarders[0]}, s.allForwarders())
+	assert.Len(t, k.engines.builds, 1)
+	assert.Len(t, *k.engines.current.Load(), 1)
+	assert.NotNil(t, forwarders[0].engine.Load())
+	assert.Nil(t, forwarders[1].engine.Load())
+}
@@ -0,0 +1,90 @@
+/*
+Copyright © 2025 Jakub Scholz
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kekspose
+
+import (
+	"fmt"
+	"path"
+	"slices"
+
+	"github.com/scholzj/kekspose/pkg/kekspose/protocol"
+)
+
+// rpcFilter selects the logged RPCs by the client ID, the topics, and the consumer groups of their
+// requests. Each of them is a list of patterns, which can use the * and ? wildcards and the [...]
+// character classes. An RPC is logged when it matches at least one pattern of each configured list.
+type rpcFilter struct {
+	ClientIDs []string
+	Topics    []string
+	Groups    []string
+}
+
+// ValidatePatterns checks that the patterns used for filtering the logged RPCs are valid.
+func ValidatePatterns(patterns []string) error {
+	for _, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+	}
+
+	return nil
+}
+
+// active returns true when the filter selects only some RPCs.
+func (f rpcFilter) active() bool {
+	return len(f.ClientIDs) > 0 || len(f.Topics) > 0 || len(f.Groups) > 0
+}
+
+// matches checks whether the request should be logged. The topics identified by their IDs are matched
+// using their names found by topicName. The topics with unknown names do not match.
+func (f rpcFilter) matches(header protocol.RequestHeader, resources protocol.Resources, topicName func(protocol.UUID) (string, bool)) bool {
+	if len(f.ClientIDs) > 0 && !matchesAny(f.ClientIDs, header.ClientID) {
+		return false
+	}
+
+	if len(f.Topics) > 0 {
+		topics := slices.Clone(resources.Topics)
+		for _, id := range resources.TopicIDs {
+			if name, found := topicName(id); found {
+				topics = append(topics, name)
+			}
+		}
+
+		if !matchesAny(f.Topics, topics...) {
+			return false
+		}
+	}
+
+	if len(f.Groups) > 0 && !matchesAny(f.Groups, resources.Groups...) {
+		return false
+	}
+
+	return true
+}
+
+// matchesAny returns true when any of the values matches any of the patterns.
+func matchesAny(patterns []string, values ...string) bool {
+	for _, value := range values {
+		for _, pattern := range patterns {
+			if matched, _ := path.Match(pattern, value); matched {
+				return true
+			}
+		}
+	}
+
+	return false
+}
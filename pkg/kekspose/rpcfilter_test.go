@@ -0,0 +1,50 @@
+package kekspose
+
+import (
+	"testing"
+
+	"github.com/scholzj/kekspose/pkg/kekspose/protocol"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidatePatterns(t *testing.T) {
+	assert.NoError(t, ValidatePatterns(nil))
+	assert.NoError(t, ValidatePatterns([]string{"orders", "orders-*", "consumer-?", "[a-c]*"}))
+	assert.Error(t, ValidatePatterns([]string{"orders", "[invalid"}))
+}
+
+func TestRPCFilterMatches(t *testing.T) {
+	topicID := protocol.UUID{1}
+	topicName := func(id protocol.UUID) (string, bool) {
+		if id == topicID {
+			return "orders-eu", true
+		}
+
+		return "", false
+	}
+
+	header := protocol.RequestHeader{APIKey: protocol.Fetch, ClientID: "console-consumer-1"}
+	fetch := protocol.Resources{Topics: []string{"payments"}, TopicIDs: []protocol.UUID{topicID, {2}}}
+	join := protocol.Resources{Groups: []string{"my-group"}}
+
+	assert.False(t, rpcFilter{}.active())
+	assert.True(t, rpcFilter{}.matches(header, fetch, topicName))
+
+	filter := rpcFilter{ClientIDs: []string{"console-*"}}
+	assert.True(t, filter.active())
+	assert.True(t, filter.matches(header, fetch, topicName))
+	assert.False(t, filter.matches(protocol.RequestHeader{ClientID: "producer-1"}, fetch, topicName))
+
+	// Topics are matched by their names and by the names of their IDs
+	assert.True(t, rpcFilter{Topics: []string{"payments"}}.matches(header, fetch, topicName))
+	assert.True(t, rpcFilter{Topics: []string{"orders-*"}}.matches(header, fetch, topicName))
+	assert.False(t, rpcFilter{Topics: []string{"invoices"}}.matches(header, fetch, topicName))
+	assert.False(t, rpcFilter{Topics: []string{"orders-*"}}.matches(header, join, topicName))
+
+	assert.True(t, rpcFilter{Groups: []string{"my-*"}}.matches(header, join, topicName))
+	assert.False(t, rpcFilter{Groups: []string{"my-*"}}.matches(header, fetch, topicName))
+
+	// All configured lists have to match
+	assert.False(t, rpcFilter{ClientIDs: []string{"console-*"}, Groups: []string{"other-group"}}.matches(header, join, topicName))
+	assert.True(t, rpcFilter{ClientIDs: []string{"console-*"}, Groups: []string{"other-group", "my-group"}}.matches(header, join, topicName))
+}
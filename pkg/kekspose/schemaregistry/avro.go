@@ -0,0 +1,254 @@
+/*
+Copyright © 2025 Jakub Scholz
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package schemaregistry
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/hamba/avro/v2"
+)
+
+// maxDepth limits the nesting of the decoded values, so that malicious data of recursive schemas
+// cannot exhaust the stack
+const maxDepth = 100
+
+// avroDecoder decodes the Avro payloads with the binary encoding.
+type avroDecoder struct {
+	schema avro.Schema
+}
+
+// compileAvro parses the Avro schema. The referenced schemas define the named types used by the schema.
+func compileAvro(schema string, referenced []namedSchema) (*avroDecoder, error) {
+	cache := &avro.SchemaCache{}
+
+	for _, ref := range referenced {
+		if _, err := avro.ParseWithCache(ref.schema, "", cache); err != nil {
+			return nil, fmt.Errorf("invalid referenced schema %s: %w", ref.name, err)
+		}
+	}
+
+	root, err := avro.ParseWithCache(schema, "", cache)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Avro schema: %w", err)
+	}
+
+	return &avroDecoder{schema: root}, nil
+}
+
+// decode decodes the payload as JSON values. The records keep the order of their fields, unlike the
+// generic decoding of the Avro library, which decodes them as maps.
+func (d *avroDecoder) decode(payload []byte) (any, error) {
+	r := &avroReader{Reader: avro.NewReader(nil, 0).Reset(payload), items: len(payload)}
+
+	value, err := r.value(d.schema, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.Error != nil {
+		return nil, r.Error
+	}
+
+	return value, nil
+}
+
+// avroReader reads the values in the Avro binary encoding.
+type avroReader struct {
+	*avro.Reader
+
+	// items is how many more items the arrays and maps can have
+	items int
+}
+
+// blockCount reads the number of items of the next block of an array or a map.
+func (r *avroReader) blockCount() (int, error) {
+	count, _ := r.ReadBlockHeader()
+	if r.Error != nil {
+		return 0, r.Error
+	}
+
+	// Every item takes at least one byte unless it is null, so more items come from corrupted data
+	if count < 0 || count > int64(r.items) {
+		return 0, fmt.Errorf("invalid block of %d items", count)
+	}
+	r.items -= int(count)
+
+	return int(count), nil
+}
+
+func (r *avroReader) value(schema avro.Schema, depth int) (any, error) {
+	if depth > maxDepth {
+		return nil, fmt.Errorf("values nested deeper than %d levels", maxDepth)
+	}
+
+	var logical avro.LogicalSchema
+	if s, ok := schema.(avro.LogicalTypeSchema); ok {
+		logical = s.Logical()
+	}
+
+	switch s := schema.(type) {
+	case *avro.RefSchema:
+		return r.value(s.Schema(), depth)
+	case *avro.NullSchema:
+		return nil, nil
+	case *avro.PrimitiveSchema:
+		switch s.Type() {
+		case avro.Boolean:
+			return r.ReadBool(), nil
+		case avro.Int:
+			return logicalInteger(logical, int64(r.ReadInt())), nil
+		case avro.Long:
+			return logicalInteger(logical, r.ReadLong()), nil
+		case avro.Float:
+			return float(float64(r.ReadFloat())), nil
+		case avro.Double:
+			return float(r.ReadDouble()), nil
+		case avro.String:
+			return r.ReadString(), nil
+		case avro.Bytes:
+			return logicalBytes(logical, r.ReadBytes()), nil
+		}
+	case *avro.FixedSchema:
+		b := make([]byte, s.Size())
+		r.Read(b)
+		return logicalBytes(logical, b), nil
+	case *avro.RecordSchema:
+		record := make(object, 0, len(s.Fields()))
+		for _, field := range s.Fields() {
+			v, err := r.value(field.Type(), depth+1)
+			if err != nil {
+				return nil, err
+			}
+			record = append(record, member{key: field.Name(), value: v})
+		}
+		return record, nil
+	case *avro.EnumSchema:
+		index := r.ReadInt()
+		symbol, found := s.Symbol(int(index))
+		if !found && r.Error == nil {
+			return nil, fmt.Errorf("invalid symbol %d of enum %s", index, s.FullName())
+		}
+		return symbol, nil
+	case *avro.ArraySchema:
+		items := make([]any, 0)
+		for {
+			count, err := r.blockCount()
+			if err != nil || count == 0 {
+				return items, err
+			}
+
+			for range count {
+				v, err := r.value(s.Items(), depth+1)
+				if err != nil {
+					return nil, err
+				}
+				items = append(items, v)
+			}
+		}
+	case *avro.MapSchema:
+		entries := make(object, 0)
+		for {
+			count, err := r.blockCount()
+			if err != nil || count == 0 {
+				return entries, err
+			}
+
+			for range count {
+				key := r.ReadString()
+				v, err := r.value(s.Values(), depth+1)
+				if err != nil {
+					return nil, err
+				}
+				entries = append(entries, member{key: key, value: v})
+			}
+		}
+	case *avro.UnionSchema:
+		index := r.ReadLong()
+		if r.Error != nil {
+			return nil, r.Error
+		}
+		if index < 0 || index >= int64(len(s.Types())) {
+			return nil, fmt.Errorf("invalid union branch %d", index)
+		}
+		return r.value(s.Types()[index], depth+1)
+	}
+
+	return nil, fmt.Errorf("unsupported Avro type %s", schema.Type())
+}
+
+// logicalInteger returns the dates and times as strings. The other integers are returned as they are.
+func logicalInteger(logical avro.LogicalSchema, v int64) any {
+	if logical == nil {
+		return v
+	}
+
+	switch logical.Type() {
+	case avro.Date:
+		return time.Unix(v*24*60*60, 0).UTC().Format(time.DateOnly)
+	case avro.TimeMillis:
+		return time.UnixMilli(v).UTC().Format("15:04:05.999")
+	case avro.TimeMicros:
+		return time.UnixMicro(v).UTC().Format("15:04:05.999999")
+	case avro.TimestampMillis:
+		return time.UnixMilli(v).UTC().Format(time.RFC3339Nano)
+	case avro.TimestampMicros:
+		return time.UnixMicro(v).UTC().Format(time.RFC3339Nano)
+	case avro.LocalTimestampMillis:
+		return time.UnixMilli(v).UTC().Format("2006-01-02T15:04:05.999")
+	case avro.LocalTimestampMicros:
+		return time.UnixMicro(v).UTC().Format("2006-01-02T15:04:05.999999")
+	default:
+		return v
+	}
+}
+
+// logicalBytes returns the decimals as strings. The other bytes are returned as they are.
+func logicalBytes(logical avro.LogicalSchema, b []byte) any {
+	if d, ok := logical.(*avro.DecimalLogicalSchema); ok {
+		return decimal(b, d.Scale())
+	}
+
+	return b
+}
+
+// decimal returns the decimal encoded as the big-endian two's complement of its unscaled value as a
+// string, so that it does not lose its precision.
+func decimal(b []byte, scale int) string {
+	unscaled := new(big.Int).SetBytes(b)
+	if len(b) > 0 && b[0]&0x80 != 0 {
+		unscaled.Sub(unscaled, new(big.Int).Lsh(big.NewInt(1), uint(len(b)*8)))
+	}
+
+	digits := unscaled.String()
+	if scale <= 0 {
+		return digits
+	}
+
+	sign := ""
+	if strings.HasPrefix(digits, "-") {
+		sign, digits = "-", digits[1:]
+	}
+
+	if len(digits) <= scale {
+		digits = strings.Repeat("0", scale-len(digits)+1) + digits
+	}
+
+	return sign + digits[:len(digits)-scale] + "." + digits[len(digits)-scale:]
+}
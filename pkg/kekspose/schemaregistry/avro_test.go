@@ -0,0 +1,139 @@
+package schemaregistry
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// avroEncoder encodes the values in the Avro binary encoding.
+type avroEncoder []byte
+
+func (e avroEncoder) long(v int64) avroEncoder {
+	return binary.AppendVarint(e, v)
+}
+
+func (e avroEncoder) string(s string) avroEncoder {
+	return append(e.long(int64(len(s))), s...)
+}
+
+func (e avroEncoder) double(f float64) avroEncoder {
+	return binary.LittleEndian.AppendUint64(e, math.Float64bits(f))
+}
+
+func decodeAvro(t *testing.T, schema string, payload []byte) string {
+	d, err := compileAvro(schema, nil)
+	require.NoError(t, err)
+
+	value, err := d.decode(payload)
+	require.NoError(t, err)
+
+	decoded, err := marshal(value)
+	require.NoError(t, err)
+
+	return string(decoded)
+}
+
+func TestDecodeAvroRecord(t *testing.T) {
+	schema := `{
+		"type": "record", "name": "Order", "namespace": "shop",
+		"fields": [
+			{"name": "id", "type": "long"},
+			{"name": "customer", "type": "string"},
+			{"name": "express", "type": "boolean"},
+			{"name": "price", "type": "double"},
+			{"name": "status", "type": {"type": "enum", "name": "Status", "symbols": ["NEW", "PAID", "SHIPPED"]}},
+			{"name": "items", "type": {"type": "array", "items": "string"}},
+			{"name": "attributes", "type": {"type": "map", "values": "long"}},
+			{"name": "note", "type": ["null", "string"]},
+			{"name": "checksum", "type": {"type": "fixed", "name": "MD5", "size": 2}}
+		]
+	}`
+
+	payload := avroEncoder{}.long(42).string("Joe & Co.")
+	payload = append(payload, 1)
+	payload = payload.double(12.5).long(1)
+	payload = payload.long(2).string("keks").string("cookie").long(0)
+	payload = payload.long(-1).long(3).string("qty").long(3).long(0)
+	payload = payload.long(0)
+	payload = append(payload, 0xca, 0xfe)
+
+	assert.Equal(t, `{"id":42,"customer":"Joe & Co.","express":true,"price":12.5,"status":"PAID","items":["keks","cookie"],"attributes":{"qty":3},"note":null,"checksum":"yv4="}`, decodeAvro(t, schema, payload))
+}
+
+func TestDecodeAvroRecursiveRecord(t *testing.T) {
+	schema := `{
+		"type": "record", "name": "Node",
+		"fields": [
+			{"name": "value", "type": "int"},
+			{"name": "next", "type": ["null", "Node"]}
+		]
+	}`
+
+	payload := avroEncoder{}.long(1).long(1).long(2).long(0)
+	assert.Equal(t, `{"value":1,"next":{"value":2,"next":null}}`, decodeAvro(t, schema, payload))
+
+	d, err := compileAvro(schema, nil)
+	require.NoError(t, err)
+
+	var deep avroEncoder
+	for range 200 {
+		deep = deep.long(1).long(1)
+	}
+	_, err = d.decode(deep)
+	assert.ErrorContains(t, err, "values nested deeper than 100 levels")
+}
+
+func TestDecodeAvroLogicalTypes(t *testing.T) {
+	schema := `{
+		"type": "record", "name": "Payment",
+		"fields": [
+			{"name": "day", "type": {"type": "int", "logicalType": "date"}},
+			{"name": "at", "type": {"type": "long", "logicalType": "timestamp-millis"}},
+			{"name": "amount", "type": {"type": "bytes", "logicalType": "decimal", "precision": 6, "scale": 2}},
+			{"name": "refund", "type": {"type": "bytes", "logicalType": "decimal", "precision": 6, "scale": 2}},
+			{"name": "id", "type": {"type": "string", "logicalType": "uuid"}}
+		]
+	}`
+
+	payload := avroEncoder{}.long(20000).long(1728000000123)
+	payload = append(payload.long(2), 0x30, 0x39)
+	payload = append(payload.long(1), 0xfb)
+	payload = payload.string("4d2c4a4e-b1a0-4b39-9b8f-4b35e6cb6d0a")
+
+	assert.Equal(t, `{"day":"2024-10-04","at":"2024-10-04T00:00:00.123Z","amount":"123.45","refund":"-0.05","id":"4d2c4a4e-b1a0-4b39-9b8f-4b35e6cb6d0a"}`, decodeAvro(t, schema, payload))
+}
+
+func TestDecodeAvroSpecialFloats(t *testing.T) {
+	assert.Equal(t, `"NaN"`, decodeAvro(t, `"double"`, avroEncoder{}.double(math.NaN())))
+	assert.Equal(t, `"+Inf"`, decodeAvro(t, `"double"`, avroEncoder{}.double(math.Inf(1))))
+}
+
+func TestDecodeAvroMalformed(t *testing.T) {
+	d, err := compileAvro(`{"type": "array", "items": "string"}`, nil)
+	require.NoError(t, err)
+
+	_, err = d.decode(avroEncoder{}.long(1000000).string("keks"))
+	assert.ErrorContains(t, err, "invalid block of 1000000 items")
+
+	_, err = d.decode(avroEncoder{}.long(1).long(10))
+	assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+
+	d, err = compileAvro(`["null", "string"]`, nil)
+	require.NoError(t, err)
+
+	_, err = d.decode(avroEncoder{}.long(2))
+	assert.ErrorContains(t, err, "invalid union branch 2")
+}
+
+func TestCompileAvroInvalid(t *testing.T) {
+	_, err := compileAvro(`{"type": "record", "name": "Order", "fields": [{"name": "customer", "type": "Customer"}]}`, nil)
+	assert.ErrorContains(t, err, "invalid Avro schema: avro: unknown type: Customer")
+
+	_, err = compileAvro(`{"type":`, nil)
+	assert.ErrorContains(t, err, "invalid Avro schema")
+}
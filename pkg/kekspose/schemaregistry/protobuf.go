@@ -0,0 +1,131 @@
+/*
+Copyright © 2025 Jakub Scholz
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package schemaregistry
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+
+	"github.com/bufbuild/protocompile"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// protoSchemaFile is the name of the compiled schema. Its imports are the names of the referenced
+// schemas.
+const protoSchemaFile = "schema.proto"
+
+// protoDecoder decodes the Protobuf payloads. The payloads start with the indexes of the message in
+// the schema, which can define more messages.
+type protoDecoder struct {
+	file protoreflect.FileDescriptor
+}
+
+// compileProtobuf compiles the Protobuf schema. The referenced schemas are the imported .proto files.
+// The well-known types of Google can be imported without a reference.
+func compileProtobuf(schema string, referenced []namedSchema) (*protoDecoder, error) {
+	sources := map[string]string{protoSchemaFile: schema}
+	for _, ref := range referenced {
+		sources[ref.name] = ref.schema
+	}
+
+	compiler := protocompile.Compiler{
+		Resolver: protocompile.WithStandardImports(&protocompile.SourceResolver{
+			Accessor: protocompile.SourceAccessorFromMap(sources),
+		}),
+	}
+
+	files, err := compiler.Compile(context.Background(), protoSchemaFile)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Protobuf schema: %w", err)
+	}
+
+	if files[0].Messages().Len() == 0 {
+		return nil, fmt.Errorf("schema does not define any message")
+	}
+
+	return &protoDecoder{file: files[0]}, nil
+}
+
+// decode decodes the payload as JSON with the field names used in the schema. The fields which are not
+// in the schema are skipped.
+func (d *protoDecoder) decode(payload []byte) (any, error) {
+	descriptor, payload, err := d.message(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	message := dynamicpb.NewMessage(descriptor)
+	if err := (proto.UnmarshalOptions{RecursionLimit: maxDepth}).Unmarshal(payload, message); err != nil {
+		return nil, err
+	}
+
+	decoded, err := protojson.MarshalOptions{UseProtoNames: true}.Marshal(message)
+	if err != nil {
+		return nil, err
+	}
+
+	// The output of protojson is deliberately unstable, so it is compacted
+	var compacted bytes.Buffer
+	if err := json.Compact(&compacted, decoded); err != nil {
+		return nil, err
+	}
+
+	return json.RawMessage(compacted.Bytes()), nil
+}
+
+// message reads the indexes of the message at the start of the payload and finds the message. The
+// first index is the index of a top-level message, the next ones are the indexes of its nested
+// messages. A single zero, which stands for the first message, is encoded as a zero count.
+func (d *protoDecoder) message(payload []byte) (protoreflect.MessageDescriptor, []byte, error) {
+	count, n := binary.Varint(payload)
+	if n <= 0 || count < 0 || count > int64(len(payload)) {
+		return nil, nil, fmt.Errorf("invalid message indexes")
+	}
+	payload = payload[n:]
+
+	indexes := []int64{0}
+	if count > 0 {
+		indexes = indexes[:0]
+		for range count {
+			index, n := binary.Varint(payload)
+			if n <= 0 {
+				return nil, nil, fmt.Errorf("invalid message indexes")
+			}
+			payload = payload[n:]
+			indexes = append(indexes, index)
+		}
+	}
+
+	messages := d.file.Messages()
+	var m protoreflect.MessageDescriptor
+	for _, index := range indexes {
+		if index < 0 || index >= int64(messages.Len()) {
+			return nil, nil, fmt.Errorf("message indexes %v are not in the schema", indexes)
+		}
+
+		m = messages.Get(int(index))
+		messages = m.Messages()
+	}
+
+	return m, payload, nil
+}
@@ -0,0 +1,188 @@
+package schemaregistry
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+const testProtoSchema = `
+syntax = "proto3";
+package shop;
+
+import "common/address.proto";
+
+option java_package = "io.kekspose.shop"; // Options are skipped
+
+/* The first message */
+message Customer {
+  string name = 1;
+  common.Address address = 2 [deprecated = true];
+}
+
+message Order {
+  enum Status {
+    option allow_alias = true;
+    NEW = 0;
+    PAID = 1;
+    SETTLED = 1;
+  }
+
+  message Item {
+    string sku = 1;
+    sint32 quantity = 2;
+  }
+
+  reserved 8, 9;
+
+  int64 id = 1;
+  Status status = 2;
+  repeated Item items = 3;
+  repeated int32 ratings = 4;
+  map<string, double> prices = 5;
+  oneof payment {
+    string card = 6;
+    bytes token = 7;
+  }
+  Customer customer = 10;
+}
+
+service Orders {
+  rpc Get(Order) returns (Order) { option idempotency_level = NO_SIDE_EFFECTS; }
+}
+`
+
+const testAddressSchema = `
+syntax = "proto3";
+package common;
+
+message Address {
+  string city = 1;
+}
+`
+
+func compileTestProtobuf(t *testing.T) *protoDecoder {
+	d, err := compileProtobuf(testProtoSchema, []namedSchema{{name: "common/address.proto", schema: testAddressSchema}})
+	require.NoError(t, err)
+
+	return d
+}
+
+// messageIndexes encodes the indexes of the message in the schema.
+func messageIndexes(indexes ...int64) []byte {
+	b := binary.AppendVarint(nil, int64(len(indexes)))
+	for _, index := range indexes {
+		b = binary.AppendVarint(b, index)
+	}
+
+	return b
+}
+
+func TestDecodeProtobuf(t *testing.T) {
+	d := compileTestProtobuf(t)
+
+	var address []byte
+	address = protowire.AppendTag(address, 1, protowire.BytesType)
+	address = protowire.AppendString(address, "Prague")
+
+	var customer []byte
+	customer = protowire.AppendTag(customer, 1, protowire.BytesType)
+	customer = protowire.AppendString(customer, "Joe")
+	customer = protowire.AppendTag(customer, 2, protowire.BytesType)
+	customer = protowire.AppendBytes(customer, address)
+
+	var item []byte
+	item = protowire.AppendTag(item, 1, protowire.BytesType)
+	item = protowire.AppendString(item, "keks")
+	item = protowire.AppendTag(item, 2, protowire.VarintType)
+	item = protowire.AppendVarint(item, protowire.EncodeZigZag(-2))
+
+	var ratings []byte
+	ratings = protowire.AppendVarint(ratings, 5)
+	ratings = protowire.AppendVarint(ratings, 3)
+
+	var price []byte
+	price = protowire.AppendTag(price, 1, protowire.BytesType)
+	price = protowire.AppendString(price, "keks")
+	price = protowire.AppendTag(price, 2, protowire.Fixed64Type)
+	price = protowire.AppendFixed64(price, 0x3ff8000000000000)
+
+	var order []byte
+	order = protowire.AppendTag(order, 1, protowire.VarintType)
+	order = protowire.AppendVarint(order, 42)
+	order = protowire.AppendTag(order, 2, protowire.VarintType)
+	order = protowire.AppendVarint(order, 1)
+	order = protowire.AppendTag(order, 3, protowire.BytesType)
+	order = protowire.AppendBytes(order, item)
+	order = protowire.AppendTag(order, 4, protowire.BytesType)
+	order = protowire.AppendBytes(order, ratings)
+	order = protowire.AppendTag(order, 4, protowire.VarintType)
+	order = protowire.AppendVarint(order, 1)
+	order = protowire.AppendTag(order, 5, protowire.BytesType)
+	order = protowire.AppendBytes(order, price)
+	order = protowire.AppendTag(order, 10, protowire.BytesType)
+	order = protowire.AppendBytes(order, customer)
+	order = protowire.AppendTag(order, 7, protowire.BytesType)
+	order = protowire.AppendBytes(order, []byte{0xca, 0xfe})
+	order = protowire.AppendTag(order, 15, protowire.VarintType)
+	order = protowire.AppendVarint(order, 7)
+
+	value, err := d.decode(append(messageIndexes(1), order...))
+	require.NoError(t, err)
+
+	decoded, err := marshal(value)
+	require.NoError(t, err)
+	assert.Equal(t, `{"id":"42","status":"PAID","items":[{"sku":"keks","quantity":-2}],"ratings":[5,3,1],"prices":{"keks":1.5},"token":"yv4=","customer":{"name":"Joe","address":{"city":"Prague"}}}`, string(decoded))
+}
+
+func TestDecodeProtobufMessageIndexes(t *testing.T) {
+	d := compileTestProtobuf(t)
+
+	var customer []byte
+	customer = protowire.AppendTag(customer, 1, protowire.BytesType)
+	customer = protowire.AppendString(customer, "Joe")
+
+	// A single zero index is encoded as a zero count
+	value, err := d.decode(append([]byte{0}, customer...))
+	require.NoError(t, err)
+	decoded, err := marshal(value)
+	require.NoError(t, err)
+	assert.Equal(t, `{"name":"Joe"}`, string(decoded))
+
+	value, err = d.decode(append(messageIndexes(1, 0), customer...))
+	require.NoError(t, err)
+	decoded, err = marshal(value)
+	require.NoError(t, err)
+	assert.Equal(t, `{"sku":"Joe"}`, string(decoded))
+
+	// The map entries are nested messages as well
+	_, err = d.decode(append(messageIndexes(1, 2), customer...))
+	assert.ErrorContains(t, err, "message indexes [1 2] are not in the schema")
+}
+
+func TestDecodeProtobufMalformed(t *testing.T) {
+	d := compileTestProtobuf(t)
+
+	_, err := d.decode(append(messageIndexes(1), 0x1a, 0x10))
+	assert.Error(t, err)
+
+	_, err = d.decode([]byte{0x7f})
+	assert.ErrorContains(t, err, "invalid message indexes")
+}
+
+func TestCompileProtobufInvalid(t *testing.T) {
+	_, err := compileProtobuf(`syntax = "proto3"; message Order { int64 id = ; }`, nil)
+	assert.ErrorContains(t, err, `schema.proto:1:47: syntax error: unexpected ';'`)
+
+	_, err = compileProtobuf(`syntax = "proto3"; message Order { int64 id = 1;`, nil)
+	assert.ErrorContains(t, err, "schema.proto:1:49: syntax error: unexpected $end")
+
+	_, err = compileProtobuf(`syntax = "proto3"; import "common/address.proto"; message Order { common.Address address = 1; }`, nil)
+	assert.ErrorContains(t, err, "common/address.proto")
+
+	_, err = compileProtobuf(`syntax = "proto3";`, nil)
+	assert.ErrorContains(t, err, "schema does not define any message")
+}
@@ -0,0 +1,293 @@
+/*
+Copyright © 2025 Jakub Scholz
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package schemaregistry decodes the records serialized in the Confluent wire format. It looks up their
+// schemas in a schema registry using the Confluent Schema Registry API and decodes the Avro, Protobuf,
+// and JSON Schema payloads to JSON.
+package schemaregistry
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// requestTimeout limits the requests to the schema registry
+	requestTimeout = 10 * time.Second
+
+	// failureTTL is how long a failed lookup of a schema is remembered before it is tried again
+	failureTTL = 30 * time.Second
+
+	// maxReferences limits the number of the schemas referenced by one schema, including the indirect
+	// references
+	maxReferences = 100
+
+	// maxResponseSize limits the size of the responses of the schema registry
+	maxResponseSize = 16 << 20
+)
+
+// ErrNotWireFormat is returned when the data does not use the Confluent wire format.
+var ErrNotWireFormat = errors.New("data does not use the Confluent wire format")
+
+// SchemaType is the type of schema.
+type SchemaType string
+
+const (
+	Avro       SchemaType = "AVRO"
+	Protobuf   SchemaType = "PROTOBUF"
+	JSONSchema SchemaType = "JSON"
+)
+
+// decoder decodes the payload following the schema ID.
+type decoder interface {
+	decode(payload []byte) (any, error)
+}
+
+// Client looks up the schemas in the schema registry and decodes the records using them. The schemas
+// are cached, so each schema is looked up only once. It is safe for concurrent use.
+type Client struct {
+	url    string
+	client *http.Client
+
+	lock    sync.Mutex
+	lookups map[int32]*lookup
+}
+
+// lookup is a lookup of a schema in progress or completed.
+type lookup struct {
+	done     chan struct{}
+	decoder  decoder
+	err      error
+	failedAt time.Time
+}
+
+// expired returns true when the lookup failed long enough ago to be tried again.
+func (l *lookup) expired() bool {
+	select {
+	case <-l.done:
+		return l.err != nil && time.Since(l.failedAt) > failureTTL
+	default:
+		return false
+	}
+}
+
+// NewClient creates a client of the schema registry at the URL. The URL can contain a path, such as
+// the path of the Confluent-compatible API of another registry, and the credentials for the basic
+// authentication.
+func NewClient(registryURL string) (*Client, error) {
+	u, err := url.Parse(registryURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schema registry URL: %w", err)
+	}
+
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid schema registry URL %q: use an http or https URL", registryURL)
+	}
+
+	return &Client{url: strings.TrimSuffix(registryURL, "/"), client: &http.Client{Timeout: requestTimeout}, lookups: make(map[int32]*lookup)}, nil
+}
+
+// SchemaID returns the schema ID of the data in the Confluent wire format. The data starts with a zero
+// magic byte followed by the schema ID.
+func SchemaID(data []byte) (int32, bool) {
+	if len(data) < 5 || data[0] != 0 {
+		return 0, false
+	}
+
+	return int32(binary.BigEndian.Uint32(data[1:])), true
+}
+
+// Decode decodes the data in the Confluent wire format using its schema and returns it as JSON.
+func (c *Client) Decode(ctx context.Context, data []byte) ([]byte, error) {
+	id, ok := SchemaID(data)
+	if !ok {
+		return nil, ErrNotWireFormat
+	}
+
+	d, err := c.decoder(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := d.decode(data[5:])
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode the data with schema %d: %w", id, err)
+	}
+
+	return marshal(value)
+}
+
+// decoder returns the decoder of the schema. Only the first caller looks up the schema, while the
+// others wait for it.
+func (c *Client) decoder(ctx context.Context, id int32) (decoder, error) {
+	c.lock.Lock()
+	l, found := c.lookups[id]
+	if found && !l.expired() {
+		c.lock.Unlock()
+
+		select {
+		case <-l.done:
+			return l.decoder, l.err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	l = &lookup{done: make(chan struct{})}
+	c.lookups[id] = l
+	c.lock.Unlock()
+
+	l.decoder, l.err = c.load(ctx, id)
+	l.failedAt = time.Now()
+	close(l.done)
+
+	return l.decoder, l.err
+}
+
+// schemaResponse is the schema returned by the schema registry. The Avro schemas do not have the type.
+type schemaResponse struct {
+	Schema     string      `json:"schema"`
+	SchemaType SchemaType  `json:"schemaType"`
+	References []reference `json:"references"`
+}
+
+// reference is a reference to a schema used by another schema. The name is the name used by the
+// referencing schema, such as the imported file of the Protobuf schemas.
+type reference struct {
+	Name    string `json:"name"`
+	Subject string `json:"subject"`
+	Version int    `json:"version"`
+}
+
+// namedSchema is a referenced schema with the name used by the referencing schema.
+type namedSchema struct {
+	name   string
+	schema string
+}
+
+// load looks up the schema and its references and compiles its decoder.
+func (c *Client) load(ctx context.Context, id int32) (decoder, error) {
+	var schema schemaResponse
+	if err := c.get(ctx, "/schemas/ids/"+strconv.Itoa(int(id)), &schema); err != nil {
+		return nil, fmt.Errorf("failed to get schema %d: %w", id, err)
+	}
+
+	var referenced []namedSchema
+	if err := c.references(ctx, schema.References, make(map[reference]bool), &referenced); err != nil {
+		return nil, fmt.Errorf("failed to get the references of schema %d: %w", id, err)
+	}
+
+	var d decoder
+	var err error
+	switch schema.SchemaType {
+	case "", Avro:
+		d, err = compileAvro(schema.Schema, referenced)
+	case Protobuf:
+		d, err = compileProtobuf(schema.Schema, referenced)
+	case JSONSchema:
+		d = jsonDecoder{}
+	default:
+		err = fmt.Errorf("unsupported schema type %s", schema.SchemaType)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile schema %d: %w", id, err)
+	}
+
+	return d, nil
+}
+
+// references looks up the referenced schemas, including the schemas referenced by them. The schemas
+// referenced by other schemas come first.
+func (c *Client) references(ctx context.Context, references []reference, seen map[reference]bool, referenced *[]namedSchema) error {
+	for _, ref := range references {
+		if seen[ref] {
+			continue
+		}
+		seen[ref] = true
+
+		if len(seen) > maxReferences {
+			return fmt.Errorf("more than %d referenced schemas", maxReferences)
+		}
+
+		var schema schemaResponse
+		if err := c.get(ctx, "/subjects/"+url.PathEscape(ref.Subject)+"/versions/"+strconv.Itoa(ref.Version), &schema); err != nil {
+			return fmt.Errorf("failed to get version %d of subject %s: %w", ref.Version, ref.Subject, err)
+		}
+
+		if err := c.references(ctx, schema.References, seen, referenced); err != nil {
+			return err
+		}
+
+		*referenced = append(*referenced, namedSchema{name: ref.Name, schema: schema.Schema})
+	}
+
+	return nil
+}
+
+// get sends a GET request to the schema registry and decodes its JSON response.
+func (c *Client) get(ctx context.Context, path string, response any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.url+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/vnd.schemaregistry.v1+json, application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		var registryError struct {
+			Message string `json:"message"`
+		}
+		if json.Unmarshal(body, &registryError) == nil && registryError.Message != "" {
+			return fmt.Errorf("schema registry responded with %s: %s", resp.Status, registryError.Message)
+		}
+
+		return fmt.Errorf("schema registry responded with %s", resp.Status)
+	}
+
+	return json.Unmarshal(body, response)
+}
+
+// jsonDecoder decodes the payloads of the JSON schemas, which are JSON documents.
+type jsonDecoder struct{}
+
+func (jsonDecoder) decode(payload []byte) (any, error) {
+	if !json.Valid(payload) {
+		return nil, errors.New("invalid JSON")
+	}
+
+	return json.RawMessage(payload), nil
+}
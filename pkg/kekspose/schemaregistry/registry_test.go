@@ -0,0 +1,156 @@
+package schemaregistry
+
+import (
+	"context"
+	"encoding/binary"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testRegistry is a stand-in for the schema registry which responds with the JSON bodies by their paths.
+type testRegistry struct {
+	*httptest.Server
+	requests atomic.Int32
+}
+
+func newTestRegistry(t *testing.T, responses map[string]string) *testRegistry {
+	r := &testRegistry{}
+	r.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		r.requests.Add(1)
+
+		body, found := responses[req.URL.EscapedPath()]
+		if !found {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"error_code":40403,"message":"Schema not found"}`))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/vnd.schemaregistry.v1+json")
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(r.Close)
+
+	return r
+}
+
+func newTestClient(t *testing.T, registry *testRegistry) *Client {
+	client, err := NewClient(registry.URL)
+	require.NoError(t, err)
+
+	return client
+}
+
+// wireFormat returns the payload in the Confluent wire format.
+func wireFormat(id int32, payload []byte) []byte {
+	return append(binary.BigEndian.AppendUint32([]byte{0}, uint32(id)), payload...)
+}
+
+func TestNewClient(t *testing.T) {
+	_, err := NewClient("http://localhost:8081/apis/ccompat/v7/")
+	assert.NoError(t, err)
+
+	_, err = NewClient("localhost:8081")
+	assert.ErrorContains(t, err, "use an http or https URL")
+
+	_, err = NewClient("ftp://localhost")
+	assert.Error(t, err)
+}
+
+func TestSchemaID(t *testing.T) {
+	id, ok := SchemaID([]byte{0, 0, 0, 1, 2, 42})
+	assert.True(t, ok)
+	assert.Equal(t, int32(258), id)
+
+	_, ok = SchemaID([]byte{0, 0, 0, 1})
+	assert.False(t, ok)
+
+	_, ok = SchemaID([]byte(`{"id":1}`))
+	assert.False(t, ok)
+}
+
+func TestDecodeJSONSchema(t *testing.T) {
+	registry := newTestRegistry(t, map[string]string{
+		"/schemas/ids/7": `{"schemaType":"JSON","schema":"{\"type\":\"object\"}"}`,
+	})
+	client := newTestClient(t, registry)
+
+	decoded, err := client.Decode(context.Background(), wireFormat(7, []byte(`{ "id": 1, "name": "<keks>" }`)))
+	require.NoError(t, err)
+	assert.Equal(t, `{"id":1,"name":"<keks>"}`, string(decoded))
+
+	_, err = client.Decode(context.Background(), wireFormat(7, []byte(`{"id":`)))
+	assert.ErrorContains(t, err, "failed to decode the data with schema 7: invalid JSON")
+
+	_, err = client.Decode(context.Background(), []byte(`{"id":1}`))
+	assert.ErrorIs(t, err, ErrNotWireFormat)
+}
+
+func TestDecodeCachesSchemas(t *testing.T) {
+	registry := newTestRegistry(t, map[string]string{
+		"/schemas/ids/1": `{"schema":"\"string\""}`,
+	})
+	client := newTestClient(t, registry)
+
+	payload := wireFormat(1, append(binary.AppendVarint(nil, 4), "keks"...))
+
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Go(func() {
+			decoded, err := client.Decode(context.Background(), payload)
+			assert.NoError(t, err)
+			assert.Equal(t, `"keks"`, string(decoded))
+		})
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(1), registry.requests.Load())
+}
+
+func TestDecodeRemembersFailures(t *testing.T) {
+	registry := newTestRegistry(t, map[string]string{})
+	client := newTestClient(t, registry)
+
+	_, err := client.Decode(context.Background(), wireFormat(5, nil))
+	assert.ErrorContains(t, err, "failed to get schema 5: schema registry responded with 404 Not Found: Schema not found")
+
+	_, err = client.Decode(context.Background(), wireFormat(5, nil))
+	assert.Error(t, err)
+	assert.Equal(t, int32(1), registry.requests.Load())
+}
+
+func TestDecodeUnsupportedSchemaType(t *testing.T) {
+	registry := newTestRegistry(t, map[string]string{
+		"/schemas/ids/3": `{"schemaType":"XML","schema":"<schema/>"}`,
+	})
+	client := newTestClient(t, registry)
+
+	_, err := client.Decode(context.Background(), wireFormat(3, nil))
+	assert.ErrorContains(t, err, "failed to compile schema 3: unsupported schema type XML")
+}
+
+func TestDecodeWithReferences(t *testing.T) {
+	registry := newTestRegistry(t, map[string]string{
+		"/schemas/ids/10": `{
+			"schema": "{\"type\":\"record\",\"name\":\"Order\",\"namespace\":\"shop\",\"fields\":[{\"name\":\"customer\",\"type\":\"Customer\"}]}",
+			"references": [{"name": "shop.Customer", "subject": "customers/value", "version": 2}]
+		}`,
+		"/subjects/customers%2Fvalue/versions/2": `{
+			"schema": "{\"type\":\"record\",\"name\":\"Customer\",\"namespace\":\"shop\",\"fields\":[{\"name\":\"address\",\"type\":\"common.Address\"}]}",
+			"references": [{"name": "common.Address", "subject": "addresses", "version": 1}]
+		}`,
+		"/subjects/addresses/versions/1": `{
+			"schema": "{\"type\":\"record\",\"name\":\"Address\",\"namespace\":\"common\",\"fields\":[{\"name\":\"city\",\"type\":\"string\"}]}"
+		}`,
+	})
+	client := newTestClient(t, registry)
+
+	decoded, err := client.Decode(context.Background(), wireFormat(10, append(binary.AppendVarint(nil, 6), "Prague"...)))
+	require.NoError(t, err)
+	assert.Equal(t, `{"customer":{"address":{"city":"Prague"}}}`, string(decoded))
+}
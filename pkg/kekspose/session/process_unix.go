@@ -0,0 +1,40 @@
+//go:build !windows
+
+/*
+Copyright © 2025 Jakub Scholz
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package session
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// Detach starts the command in a new session, so it keeps running when the terminal is closed.
+func Detach(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+}
+
+func processRunning(pid int) bool {
+	// Signal 0 only checks that the process exists and can be signalled
+	return pid > 0 && syscall.Kill(pid, syscall.Signal(0)) == nil
+}
+
+// stopProcess sends SIGTERM, which triggers the same graceful shutdown as Ctrl+C.
+func stopProcess(process *os.Process) error {
+	return process.Signal(syscall.SIGTERM)
+}
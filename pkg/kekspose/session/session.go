@@ -0,0 +1,243 @@
+/*
+Copyright © 2025 Jakub Scholz
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package session
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// EnvName is the environment variable with the session name passed to the Keksposé process running
+// in the background.
+const EnvName = "KEKSPOSE_SESSION"
+
+var validName = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._-]*$`)
+
+// Session is the state of a Keksposé instance running in the background. It is stored in a state
+// file once the port forwarding is ready and removed when the instance stops.
+type Session struct {
+	Name string `json:"name"`
+	PID  int    `json:"pid"`
+	// ProcessStart is the start time of the process. Together with the PID, it identifies the process
+	// of the session, so that another process which got the same PID later is not mistaken for it.
+	ProcessStart string     `json:"processStart,omitempty"`
+	StartedAt    time.Time  `json:"startedAt"`
+	LogFile      string     `json:"logFile,omitempty"`
+	Clusters     []Cluster  `json:"clusters"`
+	Endpoints    []Endpoint `json:"endpoints,omitempty"`
+	// AdminAddress is the address of the admin API and AdminTokenFile is the file with its token. They
+	// are empty when the admin API is disabled.
+	AdminAddress   string `json:"adminAddress,omitempty"`
+	AdminTokenFile string `json:"adminTokenFile,omitempty"`
+}
+
+// Cluster is an exposed listener of a Kafka cluster.
+type Cluster struct {
+	Name             string           `json:"name"`
+	Namespace        string           `json:"namespace"`
+	ListenerName     string           `json:"listenerName"`
+	BootstrapAddress string           `json:"bootstrapAddress"`
+	PortMapping      map[int32]uint32 `json:"portMapping"`
+}
+
+// Endpoint is a forwarded HTTP API.
+type Endpoint struct {
+	Kind      string `json:"kind"`
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	URL       string `json:"url"`
+}
+
+// Dir returns the default directory with the state files and logs of the sessions.
+func Dir() (string, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find the directory for the session state: %w", err)
+	}
+
+	return filepath.Join(cacheDir, "kekspose", "sessions"), nil
+}
+
+// ValidateName checks that the session name can be used as a file name.
+func ValidateName(name string) error {
+	if !validName.MatchString(name) {
+		return fmt.Errorf("invalid session name %q. Use only letters, digits, dots, dashes, and underscores", name)
+	}
+
+	return nil
+}
+
+// StatePath returns the path of the state file of the session.
+func StatePath(dir string, name string) string {
+	return filepath.Join(dir, name+".json")
+}
+
+// LogPath returns the path of the log file of the session.
+func LogPath(dir string, name string) string {
+	return filepath.Join(dir, name+".log")
+}
+
+// TokenPath returns the path of the file with the admin API token of the session.
+func TokenPath(dir string, name string) string {
+	return filepath.Join(dir, name+".token")
+}
+
+// Save writes the state file of the session. The file is replaced atomically, so readers never see a
+// partially written state.
+func Save(dir string, s *Session) error {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return fmt.Errorf("failed to create the session directory %s: %w", dir, err)
+	}
+
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode the session %s: %w", s.Name, err)
+	}
+
+	tmp, err := os.CreateTemp(dir, s.Name+".json.*")
+	if err != nil {
+		return fmt.Errorf("failed to write the session %s: %w", s.Name, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to write the session %s: %w", s.Name, err)
+	}
+
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write the session %s: %w", s.Name, err)
+	}
+
+	if err := os.Rename(tmp.Name(), StatePath(dir, s.Name)); err != nil {
+		return fmt.Errorf("failed to write the session %s: %w", s.Name, err)
+	}
+
+	return nil
+}
+
+// Load reads the state file of the session. It returns an error wrapping os.ErrNotExist when the
+// session does not exist.
+func Load(dir string, name string) (*Session, error) {
+	data, err := os.ReadFile(StatePath(dir, name))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the session %s: %w", name, err)
+	}
+
+	s := &Session{}
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, fmt.Errorf("failed to decode the session %s: %w", name, err)
+	}
+
+	return s, nil
+}
+
+// List reads all sessions from the directory sorted by their names. A missing directory means that
+// there are no sessions.
+func List(dir string) ([]*Session, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to list the sessions in %s: %w", dir, err)
+	}
+
+	sessions := make([]*Session, 0, len(entries))
+	for _, entry := range entries {
+		name, isState := strings.CutSuffix(entry.Name(), ".json")
+		if entry.IsDir() || !isState {
+			continue
+		}
+
+		s, err := Load(dir, name)
+		if err != nil {
+			return nil, err
+		}
+
+		sessions = append(sessions, s)
+	}
+
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].Name < sessions[j].Name })
+	return sessions, nil
+}
+
+// Remove deletes the state file of the session. The log file is kept for troubleshooting.
+func Remove(dir string, name string) error {
+	if err := os.Remove(StatePath(dir, name)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove the session %s: %w", name, err)
+	}
+
+	return nil
+}
+
+// ProcessStart returns the start time of the process with the given PID in a platform-specific
+// format. It returns an empty string when the start time is not known.
+func ProcessStart(pid int) string {
+	return processStart(pid)
+}
+
+// Running checks whether the process of the session is still running. The process with the PID of the
+// session has to have the same start time as well. The start time is not checked when it is not known.
+func (s *Session) Running() bool {
+	if !processRunning(s.PID) {
+		return false
+	}
+
+	return s.ProcessStart == "" || processStart(s.PID) == s.ProcessStart
+}
+
+// Check verifies that the bootstrap addresses of all exposed clusters accept connections.
+func (s *Session) Check(timeout time.Duration) error {
+	for _, c := range s.Clusters {
+		address, _, _ := strings.Cut(c.BootstrapAddress, ",")
+
+		conn, err := net.DialTimeout("tcp", address, timeout)
+		if err != nil {
+			return fmt.Errorf("cluster %s/%s is not reachable on %s: %w", c.Namespace, c.Name, address, err)
+		}
+		_ = conn.Close()
+	}
+
+	return nil
+}
+
+// Stop asks the process of the session to shut down gracefully. It refuses to stop a process which is
+// not the process of the session anymore.
+func (s *Session) Stop() error {
+	if !s.Running() {
+		return fmt.Errorf("the process %d of the session %s is not running anymore", s.PID, s.Name)
+	}
+
+	process, err := os.FindProcess(s.PID)
+	if err != nil {
+		return fmt.Errorf("failed to find the process %d of the session %s: %w", s.PID, s.Name, err)
+	}
+
+	if err := stopProcess(process); err != nil {
+		return fmt.Errorf("failed to stop the process %d of the session %s: %w", s.PID, s.Name, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,100 @@
+package session
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateName(t *testing.T) {
+	assert.NoError(t, ValidateName("my-cluster"))
+	assert.NoError(t, ValidateName("my_cluster.2"))
+	assert.EqualError(t, ValidateName(""), `invalid session name "". Use only letters, digits, dots, dashes, and underscores`)
+	assert.Error(t, ValidateName("../my-cluster"))
+	assert.Error(t, ValidateName("my/cluster"))
+	assert.Error(t, ValidateName(".hidden"))
+}
+
+func TestSaveLoadListRemove(t *testing.T) {
+	dir := t.TempDir()
+
+	sessions, err := List(dir + "/missing")
+	require.NoError(t, err)
+	assert.Empty(t, sessions)
+
+	second := &Session{Name: "second", PID: 2, StartedAt: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+	first := &Session{
+		Name:      "first",
+		PID:       1,
+		StartedAt: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+		LogFile:   LogPath(dir, "first"),
+		Clusters: []Cluster{{
+			Name:             "my-cluster",
+			Namespace:        "myproject",
+			ListenerName:     "plain",
+			BootstrapAddress: "localhost:50000,localhost:50001",
+			PortMapping:      map[int32]uint32{0: 50000, 1: 50001},
+		}},
+		Endpoints: []Endpoint{{Kind: "KafkaConnect", Name: "my-connect", Namespace: "myproject", URL: "http://localhost:50002"}},
+	}
+
+	require.NoError(t, Save(dir, second))
+	require.NoError(t, Save(dir, first))
+
+	// Other files in the directory are ignored
+	require.NoError(t, os.WriteFile(LogPath(dir, "first"), []byte("log"), 0o600))
+
+	loaded, err := Load(dir, "first")
+	require.NoError(t, err)
+	assert.Equal(t, first, loaded)
+
+	sessions, err = List(dir)
+	require.NoError(t, err)
+	require.Len(t, sessions, 2)
+	assert.Equal(t, "first", sessions[0].Name)
+	assert.Equal(t, "second", sessions[1].Name)
+
+	require.NoError(t, Remove(dir, "first"))
+	require.NoError(t, Remove(dir, "first"))
+	_, err = Load(dir, "first")
+	assert.ErrorIs(t, err, os.ErrNotExist)
+
+	// The log file is kept
+	assert.FileExists(t, LogPath(dir, "first"))
+}
+
+func TestRunning(t *testing.T) {
+	assert.True(t, (&Session{PID: os.Getpid()}).Running())
+	assert.False(t, (&Session{PID: 0}).Running())
+}
+
+func TestRunningChecksProcessStart(t *testing.T) {
+	start := ProcessStart(os.Getpid())
+	if start == "" {
+		t.Skip("the start time of the processes is not known on this platform")
+	}
+
+	assert.True(t, (&Session{PID: os.Getpid(), ProcessStart: start}).Running())
+
+	// A different process which got the PID of the session later
+	stale := &Session{Name: "my-cluster", PID: os.Getpid(), ProcessStart: start + "1"}
+	assert.False(t, stale.Running())
+	assert.EqualError(t, stale.Stop(), fmt.Sprintf("the process %d of the session my-cluster is not running anymore", os.Getpid()))
+}
+
+func TestCheck(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	address := listener.Addr().String()
+
+	s := &Session{Clusters: []Cluster{{Name: "my-cluster", Namespace: "myproject", BootstrapAddress: address + ",127.0.0.1:1"}}}
+	assert.NoError(t, s.Check(time.Second))
+
+	require.NoError(t, listener.Close())
+	assert.ErrorContains(t, s.Check(time.Second), "cluster myproject/my-cluster is not reachable on "+address)
+}
@@ -0,0 +1,45 @@
+//go:build linux
+
+/*
+Copyright © 2025 Jakub Scholz
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package session
+
+import (
+	"os"
+	"strconv"
+	"strings"
+)
+
+// processStart returns the start time of the process in clock ticks since the boot. It is the 22nd
+// field of /proc/<pid>/stat. The fields are counted after the command name, which can contain spaces.
+func processStart(pid int) string {
+	stat, err := os.ReadFile("/proc/" + strconv.Itoa(pid) + "/stat")
+	if err != nil {
+		return ""
+	}
+
+	_, fields, found := strings.Cut(string(stat), ") ")
+	if !found {
+		return ""
+	}
+
+	if values := strings.Fields(fields); len(values) > 19 {
+		return values[19]
+	}
+
+	return ""
+}